@@ -80,10 +80,13 @@ func main() {
 	)
 
 	// Create toolbox
-	toolbox := llm.NewToolbox(calculatorTool, weatherTool)
+	toolbox, err := llm.NewToolbox(calculatorTool, weatherTool)
+	if err != nil {
+		log.Fatalf("Failed to create toolbox: %v", err)
+	}
 
 	// Create agent
-	agent := llm.NewAgent(openaiLLM, toolbox)
+	agent := llm.NewAgent(openaiLLM, toolbox.Tools())
 
 	// Create conversation history
 	history := llm.NewHistory(
@@ -7,8 +7,8 @@ import (
 	"os"
 
 	openai "github.com/petrjanda/frax/pkg/adapters/openai"
-	"github.com/petrjanda/frax/pkg/adapters/openai/schemas"
 	llm "github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/schemas"
 )
 
 // Person represents a person with structured data
@@ -9,8 +9,8 @@ import (
 	"time"
 
 	"github.com/petrjanda/frax/pkg/adapters/openai"
-	"github.com/petrjanda/frax/pkg/adapters/openai/schemas"
 	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/schemas"
 )
 
 // Flight represents a flight booking
@@ -42,7 +42,7 @@ type FlightBookingRequest struct {
 	From       string    `json:"from" jsonschema:"required"`
 	To         string    `json:"to" jsonschema:"required"`
 	Date       time.Time `json:"date" jsonschema:"required,description=Must be in RFC3339 format (e.g. 2024-01-01T15:04:05Z)"`
-	Class      string    `json:"class" jsonschema:"required"`
+	Class      string    `json:"class" jsonschema:"required,enum=economy,enum=premium_economy,enum=business,enum=first"`
 	Passengers int       `json:"passengers" jsonschema:"required"`
 }
 
@@ -130,7 +130,7 @@ func main() {
 
 	// Create agent with tools and retry configuration
 	agent := llm.NewAgent(openaiLLM,
-		llm.NewToolbox(flightTool, hotelTool),
+		llm.MustNewToolbox(flightTool, hotelTool).Tools(),
 
 		llm.WithMaxRetries(3),                    // Allow up to 3 retries
 		llm.WithRetryDelay(200*time.Millisecond), // Start with 200ms delay
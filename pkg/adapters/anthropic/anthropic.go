@@ -0,0 +1,286 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/schemas"
+)
+
+// AnthropicAdapter implements the LLM interface using Anthropic's Messages API
+type AnthropicAdapter struct {
+	client    *anthropic.Client
+	model     string
+	maxTokens int
+
+	clientOptions   []option.RequestOption
+	schemaGenerator schemas.SchemaGenerator
+}
+
+// AnthropicAdapterOpts represents options for configuring the Anthropic adapter
+type AnthropicAdapterOpts = func(*AnthropicAdapter)
+
+// WithModel sets the model to use for the Anthropic adapter
+func WithModel(model string) AnthropicAdapterOpts {
+	return func(a *AnthropicAdapter) {
+		a.model = model
+	}
+}
+
+// WithMaxTokens sets the completion token limit used when the request doesn't set one,
+// Anthropic requires a limit on every call
+func WithMaxTokens(maxTokens int) AnthropicAdapterOpts {
+	return func(a *AnthropicAdapter) {
+		a.maxTokens = maxTokens
+	}
+}
+
+// WithClientOptions passes additional request options to the underlying Anthropic client,
+// e.g. option.WithBaseURL or option.WithHTTPClient
+func WithClientOptions(opts ...option.RequestOption) AnthropicAdapterOpts {
+	return func(a *AnthropicAdapter) {
+		a.clientOptions = append(a.clientOptions, opts...)
+	}
+}
+
+// WithSchemaGenerator sets the generator used for the input schemas of tools generating them from a
+// Go type, such as generic tools. Defaults to schemas.NewAnthropicSchemaGenerator.
+func WithSchemaGenerator(generator schemas.SchemaGenerator) AnthropicAdapterOpts {
+	return func(a *AnthropicAdapter) {
+		a.schemaGenerator = generator
+	}
+}
+
+// NewAnthropicAdapter creates a new Anthropic adapter with the given API key and options
+func NewAnthropicAdapter(apiKey string, opts ...AnthropicAdapterOpts) (*AnthropicAdapter, error) {
+	adapter := &AnthropicAdapter{
+		model:     string(anthropic.ModelClaudeSonnet4_20250514), // default model
+		maxTokens: 4096,
+
+		schemaGenerator: schemas.NewAnthropicSchemaGenerator(),
+	}
+
+	for _, opt := range opts {
+		opt(adapter)
+	}
+
+	client := anthropic.NewClient(append([]option.RequestOption{option.WithAPIKey(apiKey)}, adapter.clientOptions...)...)
+	adapter.client = &client
+
+	return adapter, nil
+}
+
+// Invoke implements the LLM interface by calling Anthropic's Messages API
+func (a *AnthropicAdapter) Invoke(ctx context.Context, request *llm.LLMRequest) (*llm.LLMResponse, error) {
+	params, err := a.newMessageParams(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Messages.New(ctx, params)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	return convertResponse(resp), nil
+}
+
+// newMessageParams translates our request into Anthropic's message parameters
+func (a *AnthropicAdapter) newMessageParams(request *llm.LLMRequest) (anthropic.MessageNewParams, error) {
+	// Anthropic takes system messages out of the turns, so user messages delimit the examples
+	history := append(llm.ExampleMessages(request.Examples, func(content string) llm.Message {
+		return llm.NewUserMessage(content)
+	}), request.History...)
+	if request.ToolResultDelivery == llm.ToolResultDeliveryText {
+		history = llm.ToolMessagesAsText(history)
+	}
+
+	system, messages := convertMessages(request.System, llm.DropOrphanedToolResults(llm.ExpandToolCalls(history)))
+
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.Model(a.model),
+		MaxTokens: int64(a.maxTokens),
+		System:    system,
+		Messages:  messages,
+	}
+
+	if request.MaxCompletionTokens > 0 {
+		params.MaxTokens = int64(request.MaxCompletionTokens)
+	}
+
+	if request.Temperature != nil {
+		params.Temperature = anthropic.Float(*request.Temperature)
+	}
+
+	if request.TopP != nil {
+		params.TopP = anthropic.Float(*request.TopP)
+	}
+
+	params.StopSequences = request.Stop
+
+	if request.ToolUsage != nil && len(request.Tools) > 0 {
+		tools, err := convertTools(request.Tools, a.schemaGenerator)
+		if err != nil {
+			return params, err
+		}
+		params.Tools = tools
+
+		toolChoice, err := convertToolUsage(request.ToolUsage, request.Tools)
+		if err != nil {
+			return params, fmt.Errorf("failed to convert tool usage: %w", err)
+		}
+		if parallel := request.ParallelToolCalls; parallel != nil && !*parallel {
+			disableParallelToolUse(&toolChoice)
+		}
+		params.ToolChoice = toolChoice
+	}
+
+	return params, nil
+}
+
+// convertMessages splits our history into Anthropic's top-level system prompt and its message turns.
+// Consecutive messages of the same role are merged into one turn, so parallel tool calls and
+// their results travel together as Anthropic requires.
+func convertMessages(system string, history llm.History) ([]anthropic.TextBlockParam, []anthropic.MessageParam) {
+	var systemBlocks []anthropic.TextBlockParam
+	if strings.TrimSpace(system) != "" {
+		systemBlocks = append(systemBlocks, anthropic.TextBlockParam{Text: system})
+	}
+
+	var messages []anthropic.MessageParam
+	add := func(role anthropic.MessageParamRole, block anthropic.ContentBlockParamUnion) {
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, block)
+			return
+		}
+		messages = append(messages, anthropic.MessageParam{Role: role, Content: []anthropic.ContentBlockParamUnion{block}})
+	}
+
+	for _, msg := range history {
+		switch m := msg.(type) {
+		case *llm.SystemMessage:
+			systemBlocks = append(systemBlocks, anthropic.TextBlockParam{Text: m.Content})
+		case *llm.DeveloperMessage:
+			systemBlocks = append(systemBlocks, anthropic.TextBlockParam{Text: m.Content})
+
+		case *llm.UserMessage:
+			add(anthropic.MessageParamRoleUser, anthropic.NewTextBlock(m.Content))
+
+		case *llm.AssistantMessage:
+			if m.Content == "" {
+				continue
+			}
+			add(anthropic.MessageParamRoleAssistant, anthropic.NewTextBlock(m.Content))
+
+		case *llm.ToolCallMessage:
+			add(anthropic.MessageParamRoleAssistant, anthropic.NewToolUseBlock(m.ToolCall.ID, toolInput(m.ToolCall.Args), m.ToolCall.Name))
+
+		case *llm.ToolResultMessage:
+			add(anthropic.MessageParamRoleUser, anthropic.ContentBlockParamUnion{OfToolResult: &anthropic.ToolResultBlockParam{
+				ToolUseID: m.ToolCall.ID,
+				Content: []anthropic.ToolResultBlockParamContentUnion{
+					{OfText: &anthropic.TextBlockParam{Text: string(m.Result)}},
+				},
+			}})
+
+		case *llm.ToolErrorMessage:
+			continue
+		}
+	}
+
+	return systemBlocks, messages
+}
+
+// toolInput returns the tool call arguments as a JSON object, Anthropic rejects empty or non-object input
+func toolInput(args json.RawMessage) json.RawMessage {
+	var input map[string]any
+	if err := json.Unmarshal(args, &input); err != nil || input == nil {
+		return json.RawMessage(`{}`)
+	}
+
+	return args
+}
+
+// convertTools converts our Tool interface to Anthropic's tool definitions, generating the input
+// schemas of tools that support it with the generator
+func convertTools(tools []llm.Tool, generator schemas.SchemaGenerator) ([]anthropic.ToolUnionParam, error) {
+	var anthropicTools []anthropic.ToolUnionParam
+
+	for _, tool := range tools {
+		var schema map[string]any
+		if err := json.Unmarshal(llm.InputSchemaFor(tool, generator), &schema); err != nil {
+			return nil, fmt.Errorf("invalid input schema of tool %s: %w", tool.Name(), err)
+		}
+
+		inputSchema := anthropic.ToolInputSchemaParam{Properties: schema["properties"]}
+		if required, ok := schema["required"].([]any); ok {
+			for _, field := range required {
+				if name, ok := field.(string); ok {
+					inputSchema.Required = append(inputSchema.Required, name)
+				}
+			}
+		}
+
+		// Keep the remaining keywords such as additionalProperties
+		for key, value := range schema {
+			if key == "type" || key == "properties" || key == "required" {
+				continue
+			}
+			if inputSchema.ExtraFields == nil {
+				inputSchema.ExtraFields = make(map[string]any)
+			}
+			inputSchema.ExtraFields[key] = value
+		}
+
+		toolParam := anthropic.ToolUnionParamOfTool(inputSchema, tool.Name())
+		toolParam.OfTool.Description = anthropic.String(tool.Description())
+		anthropicTools = append(anthropicTools, toolParam)
+	}
+
+	return anthropicTools, nil
+}
+
+// convertResponse translates Anthropic's message into our response
+func convertResponse(resp *anthropic.Message) *llm.LLMResponse {
+	response := llm.NewLLMResponse()
+	response.FinishReason = string(resp.StopReason)
+	response.Usage = &llm.Usage{
+		PromptTokens:     int(resp.Usage.InputTokens),
+		CompletionTokens: int(resp.Usage.OutputTokens),
+		TotalTokens:      int(resp.Usage.InputTokens + resp.Usage.OutputTokens),
+	}
+
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			response.AddMessage(&llm.AssistantMessage{Content: block.Text})
+		case "tool_use":
+			response.AddToolCall(&llm.ToolCall{
+				ID:   block.ID,
+				Name: block.Name,
+				Args: block.Input,
+			})
+		}
+	}
+
+	return response
+}
+
+// Close is a no-op, the Anthropic client holds no resources to release
+func (a *AnthropicAdapter) Close() error {
+	return nil
+}
+
+// Capabilities reports the features supported by the Anthropic adapter
+func (a *AnthropicAdapter) Capabilities() llm.Capabilities {
+	return llm.Capabilities{
+		ForcedTools:       true,
+		ParallelToolCalls: true,
+	}
+}
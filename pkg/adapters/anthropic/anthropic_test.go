@@ -0,0 +1,275 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func newRecordingAdapter(t *testing.T, response string) (*AnthropicAdapter, *llmtest.RecordingTransport) {
+	transport := llmtest.NewRecordingTransport(response)
+
+	adapter, err := NewAnthropicAdapter("test-key", WithClientOptions(
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+		option.WithMaxRetries(0),
+	))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	return adapter, transport
+}
+
+const messageResponse = `{
+	"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-4-20250514",
+	"content": [
+		{"type": "text", "text": "Let me calculate."},
+		{"type": "tool_use", "id": "toolu_1", "name": "calculator", "input": {"a": 2}}
+	],
+	"stop_reason": "tool_use",
+	"usage": {"input_tokens": 20, "output_tokens": 10}
+}`
+
+func TestInvoke(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, messageResponse)
+
+	first := &llm.ToolCall{ID: "toolu_a", Name: "calculator", Args: json.RawMessage(`{"a": 1}`)}
+	second := &llm.ToolCall{ID: "toolu_b", Name: "calculator", Args: json.RawMessage(`{"a": 2}`)}
+
+	request := llm.NewLLMRequest(
+		llm.NewHistory(
+			llm.NewUserMessage("Add things"),
+			llm.NewToolCallMessage(first),
+			llm.NewToolCallMessage(second),
+			llm.NewToolResultMessage(first, json.RawMessage(`{"result": 1}`)),
+			llm.NewToolResultMessage(second, json.RawMessage(`{"result": 2}`)),
+		),
+		llm.WithSystem("You are a calculator."),
+		llm.WithTools(llmtest.NewMockTool("calculator")),
+		llm.WithToolUsage(llm.ForceTool("calculator")),
+	)
+
+	response, err := adapter.Invoke(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body := transport.Requests[0]
+
+	// The system prompt is a top-level field, not a message
+	system, _ := body["system"].([]any)
+	if len(system) != 1 || system[0].(map[string]any)["text"] != "You are a calculator." {
+		t.Errorf("Expected the top-level system prompt, got %v", body["system"])
+	}
+
+	// User, assistant with both tool uses, user with both tool results
+	messages := body["messages"].([]any)
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d: %v", len(messages), messages)
+	}
+
+	expectedRoles := []string{"user", "assistant", "user"}
+	expectedBlocks := []string{"text", "tool_use", "tool_result"}
+	for i, raw := range messages {
+		message := raw.(map[string]any)
+		if message["role"] != expectedRoles[i] {
+			t.Errorf("Expected message %d to have role %s, got %v", i, expectedRoles[i], message["role"])
+		}
+
+		for _, block := range message["content"].([]any) {
+			if kind := block.(map[string]any)["type"]; kind != expectedBlocks[i] {
+				t.Errorf("Expected message %d to hold %s blocks, got %v", i, expectedBlocks[i], kind)
+			}
+		}
+	}
+
+	if results := messages[2].(map[string]any)["content"].([]any); len(results) != 2 || results[1].(map[string]any)["tool_use_id"] != "toolu_b" {
+		t.Errorf("Expected both tool results in one turn, got %v", results)
+	}
+
+	if choice := body["tool_choice"].(map[string]any); choice["type"] != "tool" || choice["name"] != "calculator" {
+		t.Errorf("Expected a forced calculator tool choice, got %v", choice)
+	}
+
+	tools := body["tools"].([]any)
+	schema := tools[0].(map[string]any)["input_schema"].(map[string]any)
+	if schema["type"] != "object" || schema["additionalProperties"] != false || len(schema["required"].([]any)) != 1 {
+		t.Errorf("Expected the tool's input schema, got %v", schema)
+	}
+
+	if body["max_tokens"] != 4096.0 {
+		t.Errorf("Expected the default token limit, got %v", body["max_tokens"])
+	}
+
+	// The response carries both the text and the tool call
+	if content := response.Messages[0].(*llm.AssistantMessage).Content; content != "Let me calculate." {
+		t.Errorf("Expected the assistant text, got %q", content)
+	}
+
+	toolCalls := response.ToolCalls()
+	if len(toolCalls) != 1 || toolCalls[0].ID != "toolu_1" || string(toolCalls[0].Args) != `{"a": 2}` {
+		t.Errorf("Expected the tool call, got %+v", toolCalls)
+	}
+
+	if response.FinishReason != "tool_use" || response.Usage.TotalTokens != 30 {
+		t.Errorf("Expected stop reason and usage, got %q %+v", response.FinishReason, response.Usage)
+	}
+}
+
+type address struct {
+	City string `json:"city" jsonschema:"required"`
+}
+
+type travelInput struct {
+	From address `json:"from" jsonschema:"required"`
+	To   address `json:"to" jsonschema:"required"`
+}
+
+func TestInvokeFewShotExamples(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, messageResponse)
+
+	example := llm.NewHistory(llm.NewUserMessage("What is 1 plus 2?"), &llm.AssistantMessage{Content: "3"})
+	request := llm.NewLLMRequest(
+		llm.NewHistory(llm.NewUserMessage("What is 15 plus 27?")),
+		llm.WithFewShotExamples([]llm.History{example}),
+	)
+
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The delimiters join the example's first turn and the real conversation's first turn
+	messages := transport.Requests[0]["messages"].([]any)
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d: %v", len(messages), messages)
+	}
+
+	expected := [][]string{
+		{"Example 1 (for illustration only, not part of the conversation):", "What is 1 plus 2?"},
+		{"3"},
+		{"End of examples. The actual conversation follows.", "What is 15 plus 27?"},
+	}
+	for i, role := range []string{"user", "assistant", "user"} {
+		turn := messages[i].(map[string]any)
+		if turn["role"] != role {
+			t.Errorf("Expected turn %d to have role %s, got %v", i, role, turn["role"])
+		}
+
+		var texts []string
+		for _, part := range turn["content"].([]any) {
+			texts = append(texts, part.(map[string]any)["text"].(string))
+		}
+		if !slices.Equal(texts, expected[i]) {
+			t.Errorf("Expected turn %d to hold %v, got %v", i, expected[i], texts)
+		}
+	}
+}
+
+func TestInvokeGenericToolSchema(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, messageResponse)
+
+	tool := llm.CreateTool("travel", "Plans a trip", func(ctx context.Context, input travelInput) (string, error) {
+		return "", nil
+	})
+
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Plan a trip")),
+		llm.WithTools(tool),
+		llm.WithToolUsage(llm.AutoToolSelection()),
+	)
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tools := transport.Requests[0]["tools"].([]any)
+	schema := tools[0].(map[string]any)["input_schema"].(map[string]any)
+
+	if schema["additionalProperties"] != false {
+		t.Errorf("Expected the input schema to forbid additional properties, got %v", schema)
+	}
+	from := schema["properties"].(map[string]any)["from"].(map[string]any)
+	if from["$ref"] != "#/$defs/address" {
+		t.Errorf("Expected nested structs to be referenced, got %v", from)
+	}
+	if _, ok := schema["$defs"].(map[string]any)["address"]; !ok {
+		t.Errorf("Expected the definitions to be sent, got %v", schema)
+	}
+}
+
+func TestInvokeDisablesParallelToolUse(t *testing.T) {
+	tests := []struct {
+		name      string
+		toolUsage llm.ToolUsage
+	}{
+		{name: "Auto", toolUsage: llm.AutoToolSelection()},
+		{name: "Forced", toolUsage: llm.ForceTool("calculator")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, transport := newRecordingAdapter(t, messageResponse)
+
+			request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Add things")),
+				llm.WithTools(llmtest.NewMockTool("calculator")),
+				llm.WithToolUsage(tt.toolUsage),
+				llm.WithParallelToolCalls(false),
+			)
+
+			if _, err := adapter.Invoke(context.Background(), request); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			toolChoice := transport.Requests[0]["tool_choice"].(map[string]any)
+			if toolChoice["disable_parallel_tool_use"] != true {
+				t.Errorf("Expected parallel tool use to be disabled, got %v", toolChoice)
+			}
+		})
+	}
+
+	// Parallel tool use stays the provider default unless it is forbidden
+	adapter, transport := newRecordingAdapter(t, messageResponse)
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Add things")),
+		llm.WithTools(llmtest.NewMockTool("calculator")),
+		llm.WithParallelToolCalls(true),
+	)
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if toolChoice := transport.Requests[0]["tool_choice"].(map[string]any); toolChoice["disable_parallel_tool_use"] != nil {
+		t.Errorf("Expected the default parallel tool use, got %v", toolChoice)
+	}
+}
+
+func TestInvokeSamplingParams(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, messageResponse)
+
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")),
+		llm.WithTemperature(0.0),
+		llm.WithMaxCompletionTokens(100),
+		llm.WithStop("###"),
+	)
+
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body := transport.Requests[0]
+	if body["temperature"] != 0.0 {
+		t.Errorf("Expected temperature 0, got %v", body["temperature"])
+	}
+	if body["max_tokens"] != 100.0 {
+		t.Errorf("Expected max_tokens 100, got %v", body["max_tokens"])
+	}
+	if stop, _ := body["stop_sequences"].([]any); len(stop) != 1 || stop[0] != "###" {
+		t.Errorf("Expected stop_sequences [###], got %v", body["stop_sequences"])
+	}
+	if _, ok := body["tools"]; ok {
+		t.Errorf("Expected no tools, got %v", body["tools"])
+	}
+}
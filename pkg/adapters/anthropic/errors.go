@@ -0,0 +1,21 @@
+package anthropic
+
+import (
+	"errors"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// providerError wraps a failed API call as an llm.LLMProviderError carrying the HTTP status, if any
+func providerError(err error) error {
+	providerErr := &llm.LLMProviderError{Provider: "Anthropic", Err: err}
+
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		providerErr.StatusCode = apiErr.StatusCode
+	}
+
+	return providerErr
+}
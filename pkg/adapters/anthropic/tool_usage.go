@@ -0,0 +1,42 @@
+package anthropic
+
+import (
+	"fmt"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// convertToolUsage converts our ToolUsage interface to Anthropic's tool_choice
+func convertToolUsage(toolUsage llm.ToolUsage, tools []llm.Tool) (anthropic.ToolChoiceUnionParam, error) {
+	switch toolUsage.Type() {
+	case llm.ToolUsageForced:
+		if forced, ok := toolUsage.(*llm.ForcedToolUsage); ok {
+			if _, err := llm.FindTool(forced.ToolName, tools); err != nil {
+				return anthropic.ToolChoiceUnionParam{}, fmt.Errorf("forced tool %s not available", forced.ToolName)
+			}
+
+			return anthropic.ToolChoiceParamOfTool(forced.ToolName), nil
+		}
+
+	case llm.ToolUsageNone:
+		none := anthropic.NewToolChoiceNoneParam()
+		return anthropic.ToolChoiceUnionParam{OfNone: &none}, nil
+	}
+
+	return anthropic.ToolChoiceUnionParam{OfAuto: &anthropic.ToolChoiceAutoParam{}}, nil
+}
+
+// disableParallelToolUse limits the model to at most one tool call per turn. A tool choice of none
+// calls no tools, so it has no such setting.
+func disableParallelToolUse(toolChoice *anthropic.ToolChoiceUnionParam) {
+	switch {
+	case toolChoice.OfAuto != nil:
+		toolChoice.OfAuto.DisableParallelToolUse = anthropic.Bool(true)
+	case toolChoice.OfAny != nil:
+		toolChoice.OfAny.DisableParallelToolUse = anthropic.Bool(true)
+	case toolChoice.OfTool != nil:
+		toolChoice.OfTool.DisableParallelToolUse = anthropic.Bool(true)
+	}
+}
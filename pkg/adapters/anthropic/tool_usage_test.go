@@ -0,0 +1,40 @@
+package anthropic
+
+import (
+	"testing"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func TestConvertToolUsage(t *testing.T) {
+	tools := []llm.Tool{llmtest.NewMockTool("calculator")}
+
+	auto, err := convertToolUsage(llm.AutoToolSelection(), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if auto.OfAuto == nil {
+		t.Errorf("Expected auto tool choice, got %+v", auto)
+	}
+
+	forced, err := convertToolUsage(llm.ForceTool("calculator"), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if forced.OfTool == nil || forced.OfTool.Name != "calculator" {
+		t.Errorf("Expected forced calculator tool choice, got %+v", forced)
+	}
+
+	none, err := convertToolUsage(llm.ForceNoTools(), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if none.OfNone == nil {
+		t.Errorf("Expected none tool choice, got %+v", none)
+	}
+
+	if _, err := convertToolUsage(llm.ForceTool("missing"), tools); err == nil {
+		t.Error("Expected an error forcing an unavailable tool")
+	}
+}
@@ -0,0 +1,345 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// BedrockAdapter implements the LLM interface using the AWS Bedrock Runtime Converse API,
+// which exposes Anthropic, Amazon Titan and other hosted models behind one message and tool schema
+type BedrockAdapter struct {
+	client *bedrockruntime.Client
+	model  string
+
+	region     string
+	httpClient *http.Client
+	baseURL    string
+}
+
+// BedrockAdapterOpts represents options for configuring the Bedrock adapter
+type BedrockAdapterOpts = func(*BedrockAdapter)
+
+// WithModel sets the Bedrock model ID or inference profile to use
+func WithModel(model string) BedrockAdapterOpts {
+	return func(a *BedrockAdapter) {
+		a.model = model
+	}
+}
+
+// WithRegion overrides the AWS region resolved from the environment and shared config
+func WithRegion(region string) BedrockAdapterOpts {
+	return func(a *BedrockAdapter) {
+		a.region = region
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for API calls, e.g. to add a proxy or tracing transport
+func WithHTTPClient(client *http.Client) BedrockAdapterOpts {
+	return func(a *BedrockAdapter) {
+		a.httpClient = client
+	}
+}
+
+// WithBaseURL points the adapter at a different Bedrock Runtime endpoint, e.g. a VPC endpoint
+func WithBaseURL(url string) BedrockAdapterOpts {
+	return func(a *BedrockAdapter) {
+		a.baseURL = url
+	}
+}
+
+// NewBedrockAdapter creates a new Bedrock adapter with the given options.
+// Credentials and region come from the standard AWS config chain (environment, shared config, IAM role).
+func NewBedrockAdapter(opts ...BedrockAdapterOpts) (*BedrockAdapter, error) {
+	adapter := &BedrockAdapter{
+		model: "anthropic.claude-3-5-sonnet-20240620-v1:0", // default model
+	}
+
+	for _, opt := range opts {
+		opt(adapter)
+	}
+
+	var loadOpts []func(*config.LoadOptions) error
+	if adapter.region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(adapter.region))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(), loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	adapter.client = bedrockruntime.NewFromConfig(cfg, func(o *bedrockruntime.Options) {
+		if adapter.httpClient != nil {
+			o.HTTPClient = adapter.httpClient
+		}
+		if adapter.baseURL != "" {
+			o.BaseEndpoint = aws.String(adapter.baseURL)
+		}
+	})
+
+	return adapter, nil
+}
+
+// Invoke implements the LLM interface by calling Bedrock's Converse API
+func (a *BedrockAdapter) Invoke(ctx context.Context, request *llm.LLMRequest) (*llm.LLMResponse, error) {
+	input, err := a.newConverseInput(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Converse(ctx, input)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	return convertResponse(resp)
+}
+
+// newConverseInput translates our request into a Converse request
+func (a *BedrockAdapter) newConverseInput(request *llm.LLMRequest) (*bedrockruntime.ConverseInput, error) {
+	// Converse has no tool choice forbidding calls, so tools are left out instead. It rejects tool
+	// blocks without declared tools though, so the tool messages are sent as text then.
+	noTools := request.ToolUsage != nil && request.ToolUsage.Type() == llm.ToolUsageNone
+
+	// The Converse API keeps system content apart from the messages, so user messages delimit the examples
+	history := append(llm.ExampleMessages(request.Examples, func(content string) llm.Message {
+		return llm.NewUserMessage(content)
+	}), request.History...)
+	if request.ToolResultDelivery == llm.ToolResultDeliveryText || noTools {
+		history = llm.ToolMessagesAsText(history)
+	}
+
+	system, messages, err := convertMessages(request.System, llm.DropOrphanedToolResults(llm.ExpandToolCalls(history)))
+	if err != nil {
+		return nil, err
+	}
+
+	input := &bedrockruntime.ConverseInput{
+		ModelId:  aws.String(a.model),
+		System:   system,
+		Messages: messages,
+	}
+
+	inference := &types.InferenceConfiguration{StopSequences: request.Stop}
+	if request.MaxCompletionTokens > 0 {
+		inference.MaxTokens = aws.Int32(int32(request.MaxCompletionTokens))
+	}
+	if request.Temperature != nil {
+		inference.Temperature = aws.Float32(float32(*request.Temperature))
+	}
+	if request.TopP != nil {
+		inference.TopP = aws.Float32(float32(*request.TopP))
+	}
+	if inference.MaxTokens != nil || inference.Temperature != nil || inference.TopP != nil || len(inference.StopSequences) > 0 {
+		input.InferenceConfig = inference
+	}
+
+	if request.ToolUsage != nil && len(request.Tools) > 0 && !noTools {
+		tools, err := convertTools(request.Tools)
+		if err != nil {
+			return nil, err
+		}
+
+		toolChoice, err := convertToolUsage(request.ToolUsage, request.Tools)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert tool usage: %w", err)
+		}
+
+		input.ToolConfig = &types.ToolConfiguration{Tools: tools, ToolChoice: toolChoice}
+	}
+
+	return input, nil
+}
+
+// convertMessages splits our history into Converse's system blocks and its messages.
+// Converse expects user and assistant turns to alternate, so consecutive messages of the same role,
+// such as parallel tool calls and their results, are merged into one message.
+func convertMessages(system string, history llm.History) ([]types.SystemContentBlock, []types.Message, error) {
+	var systemBlocks []types.SystemContentBlock
+	if strings.TrimSpace(system) != "" {
+		systemBlocks = append(systemBlocks, &types.SystemContentBlockMemberText{Value: system})
+	}
+
+	var messages []types.Message
+	add := func(role types.ConversationRole, block types.ContentBlock) {
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, block)
+			return
+		}
+		messages = append(messages, types.Message{Role: role, Content: []types.ContentBlock{block}})
+	}
+
+	for _, msg := range history {
+		switch m := msg.(type) {
+		case *llm.SystemMessage:
+			systemBlocks = append(systemBlocks, &types.SystemContentBlockMemberText{Value: m.Content})
+		case *llm.DeveloperMessage:
+			systemBlocks = append(systemBlocks, &types.SystemContentBlockMemberText{Value: m.Content})
+
+		case *llm.UserMessage:
+			add(types.ConversationRoleUser, &types.ContentBlockMemberText{Value: m.Content})
+
+		case *llm.AssistantMessage:
+			if m.Content == "" {
+				continue
+			}
+			add(types.ConversationRoleAssistant, &types.ContentBlockMemberText{Value: m.Content})
+
+		case *llm.ToolCallMessage:
+			args, err := toolInput(m.ToolCall.Args)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid arguments of tool call %s: %w", m.ToolCall.Name, err)
+			}
+			add(types.ConversationRoleAssistant, &types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+				ToolUseId: aws.String(m.ToolCall.ID),
+				Name:      aws.String(m.ToolCall.Name),
+				Input:     document.NewLazyDocument(args),
+			}})
+
+		case *llm.ToolResultMessage:
+			add(types.ConversationRoleUser, &types.ContentBlockMemberToolResult{Value: types.ToolResultBlock{
+				ToolUseId: aws.String(m.ToolCall.ID),
+				Content:   []types.ToolResultContentBlock{toolResultContent(m.Result)},
+			}})
+
+		case *llm.ToolErrorMessage:
+			continue
+		}
+	}
+
+	return systemBlocks, messages, nil
+}
+
+// toolInput decodes tool call arguments into the object Converse expects, empty arguments are an empty object
+func toolInput(args json.RawMessage) (map[string]any, error) {
+	decoded := map[string]any{}
+	if strings.TrimSpace(string(args)) == "" {
+		return decoded, nil
+	}
+
+	if err := json.Unmarshal(args, &decoded); err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+// toolResultContent wraps a tool result into a Converse content block. Objects are sent as JSON,
+// any other result, including plain text error messages, is sent as text.
+func toolResultContent(result json.RawMessage) types.ToolResultContentBlock {
+	var decoded map[string]any
+	if err := json.Unmarshal(result, &decoded); err == nil && decoded != nil {
+		return &types.ToolResultContentBlockMemberJson{Value: document.NewLazyDocument(decoded)}
+	}
+
+	var text string
+	if err := json.Unmarshal(result, &text); err == nil {
+		return &types.ToolResultContentBlockMemberText{Value: text}
+	}
+
+	return &types.ToolResultContentBlockMemberText{Value: string(result)}
+}
+
+// convertTools converts our Tool interface to Converse's tool specifications
+func convertTools(tools []llm.Tool) ([]types.Tool, error) {
+	converted := make([]types.Tool, 0, len(tools))
+
+	for _, tool := range tools {
+		var schema map[string]any
+		if err := json.Unmarshal(tool.InputSchemaRaw(), &schema); err != nil {
+			return nil, fmt.Errorf("invalid input schema of tool %s: %w", tool.Name(), err)
+		}
+
+		converted = append(converted, &types.ToolMemberToolSpec{Value: types.ToolSpecification{
+			Name:        aws.String(tool.Name()),
+			Description: aws.String(tool.Description()),
+			InputSchema: &types.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(schema)},
+		}})
+	}
+
+	return converted, nil
+}
+
+// blockedStopReasons are the stop reasons of a response withheld by Bedrock's filters or guardrails
+var blockedStopReasons = map[types.StopReason]bool{
+	types.StopReasonContentFiltered:     true,
+	types.StopReasonGuardrailIntervened: true,
+}
+
+// convertResponse translates a Converse response into our response
+func convertResponse(resp *bedrockruntime.ConverseOutput) (*llm.LLMResponse, error) {
+	response := llm.NewLLMResponse()
+	response.FinishReason = string(resp.StopReason)
+
+	if usage := resp.Usage; usage != nil {
+		response.Usage = &llm.Usage{
+			PromptTokens:     int(aws.ToInt32(usage.InputTokens)),
+			CompletionTokens: int(aws.ToInt32(usage.OutputTokens)),
+			TotalTokens:      int(aws.ToInt32(usage.TotalTokens)),
+		}
+	}
+
+	if blockedStopReasons[resp.StopReason] {
+		response.Blocked = &llm.SafetyBlock{Reason: string(resp.StopReason)}
+	}
+
+	output, ok := resp.Output.(*types.ConverseOutputMemberMessage)
+	if !ok {
+		return response, nil
+	}
+
+	for _, block := range output.Value.Content {
+		switch b := block.(type) {
+		case *types.ContentBlockMemberText:
+			if b.Value != "" {
+				response.AddMessage(&llm.AssistantMessage{Content: b.Value})
+			}
+
+		case *types.ContentBlockMemberToolUse:
+			name := aws.ToString(b.Value.Name)
+
+			var input any
+			if b.Value.Input != nil {
+				if err := b.Value.Input.UnmarshalSmithyDocument(&input); err != nil {
+					return nil, fmt.Errorf("failed to decode arguments of tool call %s: %w", name, err)
+				}
+			}
+			if input == nil {
+				input = map[string]any{}
+			}
+
+			args, err := json.Marshal(input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode arguments of tool call %s: %w", name, err)
+			}
+
+			response.AddToolCall(&llm.ToolCall{ID: aws.ToString(b.Value.ToolUseId), Name: name, Args: args})
+		}
+	}
+
+	return response, nil
+}
+
+// Close is a no-op, the Bedrock client holds no resources to release
+func (a *BedrockAdapter) Close() error {
+	return nil
+}
+
+// Capabilities reports the features supported by the Bedrock adapter
+func (a *BedrockAdapter) Capabilities() llm.Capabilities {
+	return llm.Capabilities{
+		ForcedTools:       true,
+		ParallelToolCalls: true,
+	}
+}
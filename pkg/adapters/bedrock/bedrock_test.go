@@ -0,0 +1,243 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func newRecordingAdapter(t *testing.T, response string) (*BedrockAdapter, *llmtest.RecordingTransport) {
+	// Credentials are resolved through the standard AWS chain, starting with the environment
+	t.Setenv("AWS_ACCESS_KEY_ID", "test-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+
+	transport := llmtest.NewRecordingTransport(response)
+
+	adapter, err := NewBedrockAdapter(WithRegion("us-east-1"), WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	return adapter, transport
+}
+
+const converseResponse = `{
+	"output": {"message": {"role": "assistant", "content": [
+		{"text": "Let me calculate."},
+		{"toolUse": {"toolUseId": "tooluse_1", "name": "calculator", "input": {"a": 2}}}
+	]}},
+	"stopReason": "tool_use",
+	"usage": {"inputTokens": 20, "outputTokens": 10, "totalTokens": 30}
+}`
+
+func TestInvoke(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, converseResponse)
+
+	first := &llm.ToolCall{ID: "call_a", Name: "calculator", Args: json.RawMessage(`{"a": 1}`)}
+	second := &llm.ToolCall{ID: "call_b", Name: "calculator", Args: json.RawMessage(`{"a": 2}`)}
+
+	request := llm.NewLLMRequest(
+		llm.NewHistory(
+			llm.NewUserMessage("Add things"),
+			llm.NewToolCallMessage(first),
+			llm.NewToolCallMessage(second),
+			llm.NewToolResultMessage(first, json.RawMessage(`{"result": 1}`)),
+			llm.NewToolResultMessage(second, json.RawMessage(`"two"`)),
+		),
+		llm.WithSystem("You are a calculator."),
+		llm.WithTools(llmtest.NewMockTool("calculator")),
+		llm.WithToolUsage(llm.ForceTool("calculator")),
+		llm.WithMaxCompletionTokens(100),
+		llm.WithTemperature(0.0),
+	)
+
+	response, err := adapter.Invoke(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if path := transport.Paths[0]; path != "/model/"+adapter.model+"/converse" {
+		t.Errorf("Expected the converse path of the model, got %s", path)
+	}
+
+	body := transport.Requests[0]
+
+	// The system prompt goes into the dedicated field, not the messages
+	system := body["system"].([]any)
+	if len(system) != 1 || system[0].(map[string]any)["text"] != "You are a calculator." {
+		t.Errorf("Expected the system prompt, got %v", body["system"])
+	}
+
+	// Both tool calls and both tool results are merged into single turns
+	messages := body["messages"].([]any)
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d: %v", len(messages), messages)
+	}
+
+	expectedRoles := []string{"user", "assistant", "user"}
+	for i, raw := range messages {
+		if role := raw.(map[string]any)["role"]; role != expectedRoles[i] {
+			t.Errorf("Expected message %d to have role %s, got %v", i, expectedRoles[i], role)
+		}
+	}
+
+	toolUses := messages[1].(map[string]any)["content"].([]any)
+	if len(toolUses) != 2 {
+		t.Fatalf("Expected 2 tool uses, got %v", toolUses)
+	}
+	toolUse := toolUses[0].(map[string]any)["toolUse"].(map[string]any)
+	if toolUse["toolUseId"] != "call_a" || toolUse["input"].(map[string]any)["a"] != 1.0 {
+		t.Errorf("Expected the first tool use, got %v", toolUse)
+	}
+
+	results := messages[2].(map[string]any)["content"].([]any)
+	object := results[0].(map[string]any)["toolResult"].(map[string]any)["content"].([]any)[0].(map[string]any)
+	if object["json"].(map[string]any)["result"] != 1.0 {
+		t.Errorf("Expected an object result as json, got %v", object)
+	}
+	text := results[1].(map[string]any)["toolResult"].(map[string]any)["content"].([]any)[0].(map[string]any)
+	if text["text"] != "two" {
+		t.Errorf("Expected a non-object result as text, got %v", text)
+	}
+
+	toolConfig := body["toolConfig"].(map[string]any)
+	if choice := toolConfig["toolChoice"].(map[string]any)["tool"].(map[string]any); choice["name"] != "calculator" {
+		t.Errorf("Expected the tool choice of calculator, got %v", choice)
+	}
+	spec := toolConfig["tools"].([]any)[0].(map[string]any)["toolSpec"].(map[string]any)
+	if spec["name"] != "calculator" || spec["inputSchema"].(map[string]any)["json"].(map[string]any)["type"] != "object" {
+		t.Errorf("Expected the calculator spec, got %v", spec)
+	}
+
+	inference := body["inferenceConfig"].(map[string]any)
+	if inference["maxTokens"] != 100.0 || inference["temperature"] != 0.0 {
+		t.Errorf("Expected maxTokens 100 and temperature 0, got %v", inference)
+	}
+
+	// The response carries both the text and the tool call
+	if content := response.Messages[0].(*llm.AssistantMessage).Content; content != "Let me calculate." {
+		t.Errorf("Expected the assistant text, got %q", content)
+	}
+
+	toolCalls := response.ToolCalls()
+	if len(toolCalls) != 1 || toolCalls[0].ID != "tooluse_1" || toolCalls[0].Name != "calculator" || string(toolCalls[0].Args) != `{"a":2}` {
+		t.Errorf("Expected the tool call, got %+v", toolCalls)
+	}
+
+	if response.FinishReason != "tool_use" || response.Usage.TotalTokens != 30 {
+		t.Errorf("Expected finish reason and usage, got %q %+v", response.FinishReason, response.Usage)
+	}
+}
+
+func TestInvokeFewShotExamples(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, converseResponse)
+
+	example := llm.NewHistory(llm.NewUserMessage("What is 1 plus 2?"), &llm.AssistantMessage{Content: "3"})
+	request := llm.NewLLMRequest(
+		llm.NewHistory(llm.NewUserMessage("What is 15 plus 27?")),
+		llm.WithFewShotExamples([]llm.History{example}),
+	)
+
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The delimiters join the example's first turn and the real conversation's first turn
+	messages := transport.Requests[0]["messages"].([]any)
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d: %v", len(messages), messages)
+	}
+
+	expected := [][]string{
+		{"Example 1 (for illustration only, not part of the conversation):", "What is 1 plus 2?"},
+		{"3"},
+		{"End of examples. The actual conversation follows.", "What is 15 plus 27?"},
+	}
+	for i, role := range []string{"user", "assistant", "user"} {
+		turn := messages[i].(map[string]any)
+		if turn["role"] != role {
+			t.Errorf("Expected turn %d to have role %s, got %v", i, role, turn["role"])
+		}
+
+		var texts []string
+		for _, part := range turn["content"].([]any) {
+			texts = append(texts, part.(map[string]any)["text"].(string))
+		}
+		if !slices.Equal(texts, expected[i]) {
+			t.Errorf("Expected turn %d to hold %v, got %v", i, expected[i], texts)
+		}
+	}
+}
+
+func TestNewConverseInputNoToolUsage(t *testing.T) {
+	adapter, _ := newRecordingAdapter(t, "")
+
+	toolCall := &llm.ToolCall{ID: "call_1", Name: "calculator", Args: json.RawMessage(`{"x":1}`)}
+	history := llm.NewHistory(
+		llm.NewUserMessage("Add one"),
+		&llm.ToolCallMessage{ToolCall: toolCall},
+		llm.NewToolResultMessage(toolCall, json.RawMessage(`2`)),
+		llm.NewUserMessage("Summarize"),
+	)
+
+	input, err := adapter.newConverseInput(llm.NewLLMRequest(history,
+		llm.WithTools(llmtest.NewMockTool("calculator")),
+		llm.WithToolUsage(llm.ForceNoTools()),
+	))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if input.ToolConfig != nil {
+		t.Errorf("Expected no tools to be declared, got %+v", input.ToolConfig)
+	}
+
+	for _, message := range input.Messages {
+		for _, block := range message.Content {
+			if _, ok := block.(*types.ContentBlockMemberText); !ok {
+				t.Errorf("Expected only text blocks, got %T", block)
+			}
+		}
+	}
+}
+
+func TestInvokeBlocked(t *testing.T) {
+	adapter, _ := newRecordingAdapter(t, `{
+		"output": {"message": {"role": "assistant", "content": [{"text": "Sorry, I can't help with that."}]}},
+		"stopReason": "guardrail_intervened"
+	}`)
+
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.Blocked == nil || response.Blocked.Reason != "guardrail_intervened" {
+		t.Errorf("Expected the response to be blocked by the guardrail, got %+v", response.Blocked)
+	}
+}
+
+func TestInvokeProviderError(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, `{"message": "Malformed input request"}`)
+	transport.Status = http.StatusBadRequest
+
+	_, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+
+	var providerErr *llm.LLMProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("Expected an LLMProviderError, got %v", err)
+	}
+	if providerErr.Provider != "Bedrock" || providerErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a Bedrock error with status 400, got %+v", providerErr)
+	}
+}
@@ -0,0 +1,21 @@
+package bedrock
+
+import (
+	"errors"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// providerError wraps a failed API call as an llm.LLMProviderError carrying the HTTP status, if any
+func providerError(err error) error {
+	providerErr := &llm.LLMProviderError{Provider: "Bedrock", Err: err}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		providerErr.StatusCode = respErr.HTTPStatusCode()
+	}
+
+	return providerErr
+}
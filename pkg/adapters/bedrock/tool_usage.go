@@ -0,0 +1,26 @@
+package bedrock
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// convertToolUsage converts our ToolUsage interface to Converse's toolChoice
+func convertToolUsage(toolUsage llm.ToolUsage, tools []llm.Tool) (types.ToolChoice, error) {
+	switch toolUsage.Type() {
+	case llm.ToolUsageForced:
+		if forced, ok := toolUsage.(*llm.ForcedToolUsage); ok {
+			if _, err := llm.FindTool(forced.ToolName, tools); err != nil {
+				return nil, fmt.Errorf("forced tool %s not available", forced.ToolName)
+			}
+
+			return &types.ToolChoiceMemberTool{Value: types.SpecificToolChoice{Name: aws.String(forced.ToolName)}}, nil
+		}
+	}
+
+	return &types.ToolChoiceMemberAuto{}, nil
+}
@@ -0,0 +1,35 @@
+package bedrock
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func TestConvertToolUsage(t *testing.T) {
+	tools := []llm.Tool{llmtest.NewMockTool("calculator")}
+
+	auto, err := convertToolUsage(llm.AutoToolSelection(), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := auto.(*types.ToolChoiceMemberAuto); !ok {
+		t.Errorf("Expected auto tool choice, got %T", auto)
+	}
+
+	forced, err := convertToolUsage(llm.ForceTool("calculator"), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if choice, ok := forced.(*types.ToolChoiceMemberTool); !ok || aws.ToString(choice.Value.Name) != "calculator" {
+		t.Errorf("Expected tool choice of calculator, got %+v", forced)
+	}
+
+	if _, err := convertToolUsage(llm.ForceTool("missing"), tools); err == nil {
+		t.Error("Expected an error forcing an unavailable tool")
+	}
+}
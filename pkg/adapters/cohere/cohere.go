@@ -0,0 +1,339 @@
+package cohere
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// CohereAdapter implements the LLM interface using Cohere's Chat API v2
+type CohereAdapter struct {
+	apiKey string
+	model  string
+
+	httpClient *http.Client
+	baseURL    string
+}
+
+// CohereAdapterOpts represents options for configuring the Cohere adapter
+type CohereAdapterOpts = func(*CohereAdapter)
+
+// WithModel sets the model to use for the Cohere adapter
+func WithModel(model string) CohereAdapterOpts {
+	return func(a *CohereAdapter) {
+		a.model = model
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for API calls, e.g. to add a proxy or tracing transport
+func WithHTTPClient(client *http.Client) CohereAdapterOpts {
+	return func(a *CohereAdapter) {
+		a.httpClient = client
+	}
+}
+
+// WithBaseURL points the adapter at a different endpoint of the Cohere API, e.g. a private deployment
+func WithBaseURL(url string) CohereAdapterOpts {
+	return func(a *CohereAdapter) {
+		a.baseURL = url
+	}
+}
+
+// NewCohereAdapter creates a new Cohere adapter with the given API key and options
+func NewCohereAdapter(apiKey string, opts ...CohereAdapterOpts) (*CohereAdapter, error) {
+	adapter := &CohereAdapter{
+		apiKey:     apiKey,
+		model:      "command-r-plus-08-2024", // default model
+		httpClient: http.DefaultClient,
+		baseURL:    "https://api.cohere.com",
+	}
+
+	for _, opt := range opts {
+		opt(adapter)
+	}
+
+	return adapter, nil
+}
+
+// chatRequest is the body of a v2 chat request
+type chatRequest struct {
+	Model         string        `json:"model"`
+	Messages      []chatMessage `json:"messages"`
+	Tools         []chatTool    `json:"tools,omitempty"`
+	ToolChoice    string        `json:"tool_choice,omitempty"`
+	Temperature   *float64      `json:"temperature,omitempty"`
+	P             *float64      `json:"p,omitempty"`
+	MaxTokens     int           `json:"max_tokens,omitempty"`
+	StopSequences []string      `json:"stop_sequences,omitempty"`
+	Seed          *int64        `json:"seed,omitempty"`
+}
+
+// chatMessage is a message of the conversation. Content is a string for user, system and assistant
+// messages and a list of documents for tool results; an assistant turn calling tools carries its text
+// as the tool plan.
+type chatMessage struct {
+	Role       string         `json:"role"`
+	Content    any            `json:"content,omitempty"`
+	ToolPlan   string         `json:"tool_plan,omitempty"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+type chatToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function chatFunctionCall `json:"function"`
+}
+
+type chatFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// toolResultDocument wraps a tool result, Cohere grounds its answer on such documents
+type toolResultDocument struct {
+	Type     string `json:"type"`
+	Document struct {
+		Data string `json:"data"`
+	} `json:"document"`
+}
+
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+type chatFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// chatResponse is the body of a v2 chat response
+type chatResponse struct {
+	FinishReason string `json:"finish_reason"`
+	Message      struct {
+		ToolPlan  string         `json:"tool_plan"`
+		ToolCalls []chatToolCall `json:"tool_calls"`
+		Content   []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"message"`
+	Usage *struct {
+		Tokens struct {
+			InputTokens  float64 `json:"input_tokens"`
+			OutputTokens float64 `json:"output_tokens"`
+		} `json:"tokens"`
+	} `json:"usage"`
+}
+
+// Invoke implements the LLM interface by calling Cohere's v2 chat endpoint
+func (a *CohereAdapter) Invoke(ctx context.Context, request *llm.LLMRequest) (*llm.LLMResponse, error) {
+	chatReq, err := a.newChatRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(a.baseURL, "/")+"/v2/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+a.apiKey)
+
+	httpResp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, providerError(0, err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, providerError(httpResp.StatusCode, err)
+	}
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, providerError(httpResp.StatusCode, apiError(httpResp.StatusCode, respBody))
+	}
+
+	var resp chatResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return convertResponse(&resp), nil
+}
+
+// newChatRequest translates our request into a Cohere v2 chat request
+func (a *CohereAdapter) newChatRequest(request *llm.LLMRequest) (*chatRequest, error) {
+	history := append(llm.ExampleMessages(request.Examples, func(content string) llm.Message {
+		return llm.NewSystemMessage(content)
+	}), request.History...)
+	if request.ToolResultDelivery == llm.ToolResultDeliveryText {
+		history = llm.ToolMessagesAsText(history)
+	}
+
+	chatReq := &chatRequest{
+		Model:         a.model,
+		Messages:      convertMessages(request.System, llm.DropOrphanedToolResults(llm.ExpandToolCalls(history))),
+		Temperature:   request.Temperature,
+		P:             request.TopP,
+		MaxTokens:     request.MaxCompletionTokens,
+		StopSequences: request.Stop,
+		Seed:          request.Seed,
+	}
+
+	if request.ToolUsage != nil && len(request.Tools) > 0 {
+		toolChoice, tools, err := convertToolUsage(request.ToolUsage, request.Tools)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert tool usage: %w", err)
+		}
+
+		chatReq.ToolChoice = toolChoice
+		chatReq.Tools = convertTools(tools)
+	}
+
+	return chatReq, nil
+}
+
+// convertMessages converts our flat history into Cohere v2 messages. The tool calls of a turn are
+// grouped into one assistant message, whose text becomes the tool plan, and each tool result answers
+// its originating call by ID.
+func convertMessages(system string, history llm.History) []chatMessage {
+	var messages []chatMessage
+	if strings.TrimSpace(system) != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: system})
+	}
+
+	for _, msg := range history {
+		switch m := msg.(type) {
+		case *llm.SystemMessage:
+			messages = append(messages, chatMessage{Role: "system", Content: m.Content})
+		case *llm.DeveloperMessage:
+			messages = append(messages, chatMessage{Role: "system", Content: m.Content})
+
+		case *llm.UserMessage:
+			messages = append(messages, chatMessage{Role: "user", Content: m.Content})
+
+		case *llm.AssistantMessage:
+			if m.Content == "" {
+				continue
+			}
+			messages = append(messages, chatMessage{Role: "assistant", Content: m.Content})
+
+		case *llm.ToolCallMessage:
+			toolCall := chatToolCall{
+				ID:       m.ToolCall.ID,
+				Type:     "function",
+				Function: chatFunctionCall{Name: m.ToolCall.Name, Arguments: string(m.ToolCall.Args)},
+			}
+
+			if n := len(messages); n > 0 && messages[n-1].Role == "assistant" {
+				last := &messages[n-1]
+				if text, ok := last.Content.(string); ok {
+					last.ToolPlan = text
+					last.Content = nil
+				}
+				last.ToolCalls = append(last.ToolCalls, toolCall)
+				continue
+			}
+			messages = append(messages, chatMessage{Role: "assistant", ToolCalls: []chatToolCall{toolCall}})
+
+		case *llm.ToolResultMessage:
+			document := toolResultDocument{Type: "document"}
+			document.Document.Data = string(m.Result)
+
+			messages = append(messages, chatMessage{
+				Role:       "tool",
+				ToolCallID: m.ToolCall.ID,
+				Content:    []toolResultDocument{document},
+			})
+
+		case *llm.ToolErrorMessage:
+			continue
+		}
+	}
+
+	return messages
+}
+
+// convertTools converts our Tool interface to Cohere function tools
+func convertTools(tools []llm.Tool) []chatTool {
+	converted := make([]chatTool, 0, len(tools))
+
+	for _, tool := range tools {
+		converted = append(converted, chatTool{
+			Type: "function",
+			Function: chatFunction{
+				Name:        tool.Name(),
+				Description: tool.Description(),
+				Parameters:  tool.InputSchemaRaw(),
+			},
+		})
+	}
+
+	return converted
+}
+
+// convertResponse translates Cohere's response message into our response. The tool plan, Cohere's
+// reasoning ahead of its tool calls, is kept as the assistant's text.
+func convertResponse(resp *chatResponse) *llm.LLMResponse {
+	response := llm.NewLLMResponse()
+	response.FinishReason = resp.FinishReason
+
+	if usage := resp.Usage; usage != nil {
+		response.Usage = &llm.Usage{
+			PromptTokens:     int(usage.Tokens.InputTokens),
+			CompletionTokens: int(usage.Tokens.OutputTokens),
+			TotalTokens:      int(usage.Tokens.InputTokens + usage.Tokens.OutputTokens),
+		}
+	}
+
+	var text strings.Builder
+	text.WriteString(resp.Message.ToolPlan)
+	for _, content := range resp.Message.Content {
+		if content.Type == "text" {
+			text.WriteString(content.Text)
+		}
+	}
+	if text.Len() > 0 {
+		response.AddMessage(&llm.AssistantMessage{Content: text.String()})
+	}
+
+	for _, toolCall := range resp.Message.ToolCalls {
+		args := toolCall.Function.Arguments
+		if strings.TrimSpace(args) == "" {
+			args = "{}"
+		}
+
+		response.AddToolCall(&llm.ToolCall{ID: toolCall.ID, Name: toolCall.Function.Name, Args: json.RawMessage(args)})
+	}
+
+	return response
+}
+
+// Close is a no-op, the adapter holds no resources to release
+func (a *CohereAdapter) Close() error {
+	return nil
+}
+
+// Capabilities reports the features supported by the Cohere adapter
+func (a *CohereAdapter) Capabilities() llm.Capabilities {
+	return llm.Capabilities{
+		ForcedTools:       true,
+		ParallelToolCalls: true,
+	}
+}
@@ -0,0 +1,182 @@
+package cohere
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func newRecordingAdapter(t *testing.T, response string) (*CohereAdapter, *llmtest.RecordingTransport) {
+	transport := llmtest.NewRecordingTransport(response)
+
+	adapter, err := NewCohereAdapter("test-key", WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	return adapter, transport
+}
+
+const chatResponseBody = `{
+	"id": "resp_1",
+	"finish_reason": "TOOL_CALL",
+	"message": {
+		"role": "assistant",
+		"tool_plan": "I will use the calculator.",
+		"tool_calls": [{"id": "calculator_1", "type": "function", "function": {"name": "calculator", "arguments": "{\"a\":2}"}}]
+	},
+	"usage": {"billed_units": {"input_tokens": 18, "output_tokens": 9}, "tokens": {"input_tokens": 20, "output_tokens": 10}}
+}`
+
+func TestInvoke(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, chatResponseBody)
+
+	first := &llm.ToolCall{ID: "call_a", Name: "calculator", Args: json.RawMessage(`{"a": 1}`)}
+	second := &llm.ToolCall{ID: "call_b", Name: "calculator", Args: json.RawMessage(`{"a": 2}`)}
+
+	request := llm.NewLLMRequest(
+		llm.NewHistory(
+			llm.NewUserMessage("Add things"),
+			&llm.AssistantMessage{Content: "Let me add them.", ToolCalls: []*llm.ToolCall{first, second}},
+			llm.NewToolResultMessage(first, json.RawMessage(`{"result": 1}`)),
+			llm.NewToolResultMessage(second, json.RawMessage(`{"result": 2}`)),
+		),
+		llm.WithSystem("You are a calculator."),
+		llm.WithTools(llmtest.NewMockTool("calculator"), llmtest.NewMockTool("weather")),
+		llm.WithToolUsage(llm.ForceTool("calculator")),
+		llm.WithTopP(0.5),
+	)
+
+	response, err := adapter.Invoke(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if transport.Paths[0] != "/v2/chat" {
+		t.Errorf("Expected the v2 chat endpoint, got %s", transport.Paths[0])
+	}
+
+	body := transport.Requests[0]
+
+	messages := body["messages"].([]any)
+	expectedRoles := []string{"system", "user", "assistant", "tool", "tool"}
+	if len(messages) != len(expectedRoles) {
+		t.Fatalf("Expected %d messages, got %d: %v", len(expectedRoles), len(messages), messages)
+	}
+	for i, raw := range messages {
+		if role := raw.(map[string]any)["role"]; role != expectedRoles[i] {
+			t.Errorf("Expected message %d to have role %s, got %v", i, expectedRoles[i], role)
+		}
+	}
+
+	// The turn's text becomes the tool plan of the assistant message carrying both calls
+	assistant := messages[2].(map[string]any)
+	if assistant["tool_plan"] != "Let me add them." || len(assistant["tool_calls"].([]any)) != 2 {
+		t.Errorf("Expected one assistant turn with the plan and both calls, got %v", assistant)
+	}
+	if _, ok := assistant["content"]; ok {
+		t.Errorf("Expected no content next to the tool plan, got %v", assistant["content"])
+	}
+
+	// Each result answers its originating call with the result as a document
+	result := messages[4].(map[string]any)
+	document := result["content"].([]any)[0].(map[string]any)["document"].(map[string]any)
+	if result["tool_call_id"] != "call_b" || document["data"] != `{"result": 2}` {
+		t.Errorf("Expected the second result as a document, got %v", result)
+	}
+
+	if body["tool_choice"] != "REQUIRED" {
+		t.Errorf("Expected tool_choice REQUIRED, got %v", body["tool_choice"])
+	}
+	if tools := body["tools"].([]any); len(tools) != 1 || tools[0].(map[string]any)["function"].(map[string]any)["name"] != "calculator" {
+		t.Errorf("Expected only the calculator definition, got %v", tools)
+	}
+	if body["p"] != 0.5 {
+		t.Errorf("Expected p 0.5, got %v", body["p"])
+	}
+
+	if content := response.Messages[0].(*llm.AssistantMessage).Content; content != "I will use the calculator." {
+		t.Errorf("Expected the tool plan as text, got %q", content)
+	}
+
+	calls := response.ToolCalls()
+	if len(calls) != 1 || calls[0].ID != "calculator_1" || string(calls[0].Args) != `{"a":2}` {
+		t.Errorf("Expected the tool call, got %+v", calls)
+	}
+
+	if response.FinishReason != "TOOL_CALL" || response.Usage.TotalTokens != 30 {
+		t.Errorf("Expected finish reason and usage, got %q %+v", response.FinishReason, response.Usage)
+	}
+}
+
+func TestInvokeFewShotExamples(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, chatResponseBody)
+
+	example := llm.NewHistory(llm.NewUserMessage("What is 1 plus 2?"), &llm.AssistantMessage{Content: "3"})
+	request := llm.NewLLMRequest(
+		llm.NewHistory(llm.NewUserMessage("What is 15 plus 27?")),
+		llm.WithFewShotExamples([]llm.History{example}),
+	)
+
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// System messages delimit the examples from the real conversation
+	messages := transport.Requests[0]["messages"].([]any)
+	expected := [][2]string{
+		{"system", "Example 1 (for illustration only, not part of the conversation):"},
+		{"user", "What is 1 plus 2?"},
+		{"assistant", "3"},
+		{"system", "End of examples. The actual conversation follows."},
+		{"user", "What is 15 plus 27?"},
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d messages, got %d: %v", len(expected), len(messages), messages)
+	}
+
+	for i, raw := range messages {
+		message := raw.(map[string]any)
+		if message["role"] != expected[i][0] || message["content"] != expected[i][1] {
+			t.Errorf("Expected message %d to be %v, got %v", i, expected[i], message)
+		}
+	}
+}
+
+func TestInvokeText(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, `{"finish_reason": "COMPLETE", "message": {"role": "assistant", "content": [{"type": "text", "text": "Four."}]}}`)
+
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("2 + 2?"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if content := response.Messages[0].(*llm.AssistantMessage).Content; content != "Four." {
+		t.Errorf("Expected the text content, got %q", content)
+	}
+
+	if _, ok := transport.Requests[0]["tools"]; ok {
+		t.Errorf("Expected no tools, got %v", transport.Requests[0]["tools"])
+	}
+}
+
+func TestInvokeProviderError(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, `{"message": "invalid api token"}`)
+	transport.Status = http.StatusUnauthorized
+
+	_, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+
+	var providerErr *llm.LLMProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("Expected an LLMProviderError, got %v", err)
+	}
+	if providerErr.Provider != "Cohere" || providerErr.StatusCode != http.StatusUnauthorized || !strings.Contains(err.Error(), "invalid api token") {
+		t.Errorf("Expected a Cohere error with status 401, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package cohere
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// providerError wraps a failed API call as an llm.LLMProviderError carrying the HTTP status, zero when no response was received
+func providerError(statusCode int, err error) error {
+	return &llm.LLMProviderError{Provider: "Cohere", StatusCode: statusCode, Err: err}
+}
+
+// apiError extracts the message of an error response, falling back to the status text
+func apiError(statusCode int, body []byte) error {
+	var decoded struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &decoded); err == nil && decoded.Message != "" {
+		return fmt.Errorf("%d %s: %s", statusCode, http.StatusText(statusCode), decoded.Message)
+	}
+
+	return fmt.Errorf("%d %s", statusCode, http.StatusText(statusCode))
+}
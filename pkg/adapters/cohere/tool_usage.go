@@ -0,0 +1,29 @@
+package cohere
+
+import (
+	"fmt"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// convertToolUsage converts our ToolUsage interface to Cohere's tool_choice and the tools to declare.
+// Cohere can only require some tool call, so forcing a tool declares only that one. Auto leaves
+// tool_choice unset, which is Cohere's default.
+func convertToolUsage(toolUsage llm.ToolUsage, tools []llm.Tool) (string, []llm.Tool, error) {
+	switch toolUsage.Type() {
+	case llm.ToolUsageForced:
+		if forced, ok := toolUsage.(*llm.ForcedToolUsage); ok {
+			tool, err := llm.FindTool(forced.ToolName, tools)
+			if err != nil {
+				return "", nil, fmt.Errorf("forced tool %s not available", forced.ToolName)
+			}
+
+			return "REQUIRED", []llm.Tool{tool}, nil
+		}
+
+	case llm.ToolUsageNone:
+		return "NONE", tools, nil
+	}
+
+	return "", tools, nil
+}
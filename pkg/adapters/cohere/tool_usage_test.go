@@ -0,0 +1,40 @@
+package cohere
+
+import (
+	"testing"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func TestConvertToolUsage(t *testing.T) {
+	tools := []llm.Tool{llmtest.NewMockTool("calculator"), llmtest.NewMockTool("weather")}
+
+	choice, declared, err := convertToolUsage(llm.AutoToolSelection(), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if choice != "" || len(declared) != 2 {
+		t.Errorf("Expected the default tool choice with all tools, got %q with %d tools", choice, len(declared))
+	}
+
+	choice, declared, err = convertToolUsage(llm.ForceTool("weather"), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if choice != "REQUIRED" || len(declared) != 1 || declared[0].Name() != "weather" {
+		t.Errorf("Expected REQUIRED with only the weather tool, got %s with %v", choice, declared)
+	}
+
+	choice, declared, err = convertToolUsage(llm.ForceNoTools(), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if choice != "NONE" || len(declared) != 2 {
+		t.Errorf("Expected NONE with all tools, got %s with %d tools", choice, len(declared))
+	}
+
+	if _, _, err := convertToolUsage(llm.ForceTool("missing"), tools); err == nil {
+		t.Error("Expected an error forcing an unavailable tool")
+	}
+}
@@ -0,0 +1,21 @@
+package gemini
+
+import (
+	"errors"
+
+	"google.golang.org/genai"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// providerError wraps a failed API call as an llm.LLMProviderError carrying the HTTP status, if any
+func providerError(err error) error {
+	providerErr := &llm.LLMProviderError{Provider: "Gemini", Err: err}
+
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		providerErr.StatusCode = apiErr.Code
+	}
+
+	return providerErr
+}
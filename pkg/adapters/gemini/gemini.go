@@ -0,0 +1,394 @@
+package gemini
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// GeminiAdapter implements the LLM interface using Google's Gemini API
+type GeminiAdapter struct {
+	client *genai.Client
+	model  string
+
+	httpClient *http.Client
+	baseURL    string
+}
+
+// GeminiAdapterOpts represents options for configuring the Gemini adapter
+type GeminiAdapterOpts = func(*GeminiAdapter)
+
+// WithModel sets the model to use for the Gemini adapter
+func WithModel(model string) GeminiAdapterOpts {
+	return func(a *GeminiAdapter) {
+		a.model = model
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for API calls, e.g. to add a proxy or tracing transport
+func WithHTTPClient(client *http.Client) GeminiAdapterOpts {
+	return func(a *GeminiAdapter) {
+		a.httpClient = client
+	}
+}
+
+// WithBaseURL points the adapter at a different endpoint of the Gemini API, e.g. a regional gateway
+func WithBaseURL(url string) GeminiAdapterOpts {
+	return func(a *GeminiAdapter) {
+		a.baseURL = url
+	}
+}
+
+// NewGeminiAdapter creates a new Gemini adapter with the given API key and options
+func NewGeminiAdapter(apiKey string, opts ...GeminiAdapterOpts) (*GeminiAdapter, error) {
+	adapter := &GeminiAdapter{
+		model: "gemini-2.5-flash", // default model
+	}
+
+	for _, opt := range opts {
+		opt(adapter)
+	}
+
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      apiKey,
+		Backend:     genai.BackendGeminiAPI,
+		HTTPClient:  adapter.httpClient,
+		HTTPOptions: genai.HTTPOptions{BaseURL: adapter.baseURL},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+	adapter.client = client
+
+	return adapter, nil
+}
+
+// Invoke implements the LLM interface by calling Gemini's generateContent endpoint
+func (a *GeminiAdapter) Invoke(ctx context.Context, request *llm.LLMRequest) (*llm.LLMResponse, error) {
+	contents, config, err := a.newGenerateParams(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Models.GenerateContent(ctx, a.model, contents, config)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	return convertResponse(resp)
+}
+
+// newGenerateParams translates our request into Gemini's contents and generation config
+func (a *GeminiAdapter) newGenerateParams(request *llm.LLMRequest) ([]*genai.Content, *genai.GenerateContentConfig, error) {
+	// Gemini has no system turns, the examples are delimited by user messages
+	history := append(llm.ExampleMessages(request.Examples, func(content string) llm.Message {
+		return llm.NewUserMessage(content)
+	}), request.History...)
+	if request.ToolResultDelivery == llm.ToolResultDeliveryText {
+		history = llm.ToolMessagesAsText(history)
+	}
+
+	system, contents, err := convertMessages(request.System, llm.DropOrphanedToolResults(llm.ExpandToolCalls(history)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: system,
+		StopSequences:     request.Stop,
+		SafetySettings:    convertSafetySettings(request.SafetySettings),
+	}
+
+	if request.MaxCompletionTokens > 0 {
+		config.MaxOutputTokens = int32(request.MaxCompletionTokens)
+	}
+
+	if request.Temperature != nil {
+		config.Temperature = genai.Ptr(float32(*request.Temperature))
+	}
+
+	if request.TopP != nil {
+		config.TopP = genai.Ptr(float32(*request.TopP))
+	}
+
+	if request.Seed != nil {
+		config.Seed = genai.Ptr(int32(*request.Seed))
+	}
+
+	if request.ToolUsage != nil && len(request.Tools) > 0 {
+		tools, err := convertTools(request.Tools)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.Tools = tools
+
+		toolConfig, err := convertToolUsage(request.ToolUsage, request.Tools)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert tool usage: %w", err)
+		}
+		config.ToolConfig = toolConfig
+	}
+
+	return contents, config, nil
+}
+
+// convertMessages splits our history into Gemini's system instruction and its contents.
+// Gemini expects user and model turns to alternate, so consecutive messages of the same role,
+// such as adjacent user messages or parallel tool calls, are merged into one content.
+func convertMessages(system string, history llm.History) (*genai.Content, []*genai.Content, error) {
+	var systemParts []*genai.Part
+	if strings.TrimSpace(system) != "" {
+		systemParts = append(systemParts, genai.NewPartFromText(system))
+	}
+
+	var contents []*genai.Content
+	add := func(role string, part *genai.Part) {
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, part)
+			return
+		}
+		contents = append(contents, &genai.Content{Role: role, Parts: []*genai.Part{part}})
+	}
+
+	for _, msg := range history {
+		switch m := msg.(type) {
+		case *llm.SystemMessage:
+			systemParts = append(systemParts, genai.NewPartFromText(m.Content))
+		case *llm.DeveloperMessage:
+			systemParts = append(systemParts, genai.NewPartFromText(m.Content))
+
+		case *llm.UserMessage:
+			add(genai.RoleUser, genai.NewPartFromText(m.Content))
+
+		case *llm.AssistantMessage:
+			if m.Content == "" {
+				continue
+			}
+			add(genai.RoleModel, genai.NewPartFromText(m.Content))
+
+		case *llm.ToolCallMessage:
+			args, err := functionArgs(m.ToolCall.Args)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid arguments of tool call %s: %w", m.ToolCall.Name, err)
+			}
+			add(genai.RoleModel, &genai.Part{FunctionCall: &genai.FunctionCall{
+				ID:   m.ToolCall.ID,
+				Name: m.ToolCall.Name,
+				Args: args,
+			}})
+
+		case *llm.ToolResultMessage:
+			add(genai.RoleUser, &genai.Part{FunctionResponse: &genai.FunctionResponse{
+				ID:       m.ToolCall.ID,
+				Name:     m.ToolCall.Name,
+				Response: functionResponse(m.Result),
+			}})
+
+		case *llm.ToolErrorMessage:
+			continue
+		}
+	}
+
+	var systemInstruction *genai.Content
+	if len(systemParts) > 0 {
+		systemInstruction = &genai.Content{Parts: systemParts}
+	}
+
+	return systemInstruction, contents, nil
+}
+
+// functionArgs decodes tool call arguments into the object Gemini expects, empty arguments are no arguments
+func functionArgs(args json.RawMessage) (map[string]any, error) {
+	if strings.TrimSpace(string(args)) == "" {
+		return nil, nil
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(args, &decoded); err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+// functionResponse wraps a tool result into the object Gemini expects. Objects are sent as they are,
+// any other result, including plain text error messages, goes under the "output" key.
+func functionResponse(result json.RawMessage) map[string]any {
+	var decoded any
+	if err := json.Unmarshal(result, &decoded); err != nil {
+		return map[string]any{"output": string(result)}
+	}
+
+	if object, ok := decoded.(map[string]any); ok {
+		return object
+	}
+
+	return map[string]any{"output": decoded}
+}
+
+// convertTools converts our Tool interface to Gemini's function declarations
+func convertTools(tools []llm.Tool) ([]*genai.Tool, error) {
+	declarations := make([]*genai.FunctionDeclaration, 0, len(tools))
+
+	for _, tool := range tools {
+		var schema map[string]any
+		if err := json.Unmarshal(tool.InputSchemaRaw(), &schema); err != nil {
+			return nil, fmt.Errorf("invalid input schema of tool %s: %w", tool.Name(), err)
+		}
+
+		declarations = append(declarations, &genai.FunctionDeclaration{
+			Name:                 tool.Name(),
+			Description:          tool.Description(),
+			ParametersJsonSchema: schema,
+		})
+	}
+
+	return []*genai.Tool{{FunctionDeclarations: declarations}}, nil
+}
+
+var harmCategories = map[llm.HarmCategory]genai.HarmCategory{
+	llm.HarmCategoryHarassment:       genai.HarmCategoryHarassment,
+	llm.HarmCategoryHateSpeech:       genai.HarmCategoryHateSpeech,
+	llm.HarmCategorySexuallyExplicit: genai.HarmCategorySexuallyExplicit,
+	llm.HarmCategoryDangerousContent: genai.HarmCategoryDangerousContent,
+}
+
+var safetyThresholds = map[llm.SafetyThreshold]genai.HarmBlockThreshold{
+	llm.SafetyBlockNone:           genai.HarmBlockThresholdBlockNone,
+	llm.SafetyBlockLowAndAbove:    genai.HarmBlockThresholdBlockLowAndAbove,
+	llm.SafetyBlockMediumAndAbove: genai.HarmBlockThresholdBlockMediumAndAbove,
+	llm.SafetyBlockOnlyHigh:       genai.HarmBlockThresholdBlockOnlyHigh,
+}
+
+// convertSafetySettings converts our safety settings to Gemini's, categories Gemini doesn't know are left out
+func convertSafetySettings(settings []llm.SafetySetting) []*genai.SafetySetting {
+	var converted []*genai.SafetySetting
+
+	for _, setting := range settings {
+		category, ok := harmCategories[setting.Category]
+		if !ok {
+			continue
+		}
+		threshold, ok := safetyThresholds[setting.Threshold]
+		if !ok {
+			continue
+		}
+
+		converted = append(converted, &genai.SafetySetting{Category: category, Threshold: threshold})
+	}
+
+	return converted
+}
+
+// blockedFinishReasons are the finish reasons of a candidate withheld by Gemini's filters
+var blockedFinishReasons = map[genai.FinishReason]bool{
+	genai.FinishReasonSafety:            true,
+	genai.FinishReasonBlocklist:         true,
+	genai.FinishReasonProhibitedContent: true,
+	genai.FinishReasonSPII:              true,
+}
+
+// convertResponse translates Gemini's first candidate into our response
+func convertResponse(resp *genai.GenerateContentResponse) (*llm.LLMResponse, error) {
+	response := llm.NewLLMResponse()
+
+	if usage := resp.UsageMetadata; usage != nil {
+		response.Usage = &llm.Usage{
+			PromptTokens:     int(usage.PromptTokenCount),
+			CompletionTokens: int(usage.CandidatesTokenCount),
+			TotalTokens:      int(usage.TotalTokenCount),
+		}
+	}
+
+	// A blocked prompt comes back without any candidates
+	if feedback := resp.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		response.Blocked = &llm.SafetyBlock{
+			Reason:     string(feedback.BlockReason),
+			Categories: blockedCategories(feedback.SafetyRatings),
+		}
+		return response, nil
+	}
+
+	if len(resp.Candidates) == 0 {
+		return nil, fmt.Errorf("no candidates in response")
+	}
+
+	candidate := resp.Candidates[0]
+	response.FinishReason = string(candidate.FinishReason)
+
+	if blockedFinishReasons[candidate.FinishReason] {
+		response.Blocked = &llm.SafetyBlock{
+			Reason:     string(candidate.FinishReason),
+			Categories: blockedCategories(candidate.SafetyRatings),
+		}
+	}
+
+	if candidate.Content == nil {
+		return response, nil
+	}
+
+	for _, part := range candidate.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			args, err := json.Marshal(part.FunctionCall.Args)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode arguments of tool call %s: %w", part.FunctionCall.Name, err)
+			}
+			if part.FunctionCall.Args == nil {
+				args = []byte(`{}`)
+			}
+
+			// The Gemini API doesn't always assign call IDs, results are matched by name instead. The
+			// generated ones must be unique across the conversation, trimming pairs results with calls by ID.
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = "call_" + rand.Text()
+			}
+
+			response.AddToolCall(&llm.ToolCall{ID: id, Name: part.FunctionCall.Name, Args: args})
+
+		case part.Text != "" && !part.Thought:
+			response.AddMessage(&llm.AssistantMessage{Content: part.Text})
+		}
+	}
+
+	return response, nil
+}
+
+// blockedCategories lists the categories of the ratings that caused a block
+func blockedCategories(ratings []*genai.SafetyRating) []llm.HarmCategory {
+	var categories []llm.HarmCategory
+
+	for _, rating := range ratings {
+		if !rating.Blocked {
+			continue
+		}
+		for ours, theirs := range harmCategories {
+			if theirs == rating.Category {
+				categories = append(categories, ours)
+			}
+		}
+	}
+
+	return categories
+}
+
+// Close is a no-op, the Gemini client holds no resources to release
+func (a *GeminiAdapter) Close() error {
+	return nil
+}
+
+// Capabilities reports the features supported by the Gemini adapter
+func (a *GeminiAdapter) Capabilities() llm.Capabilities {
+	return llm.Capabilities{
+		ForcedTools:       true,
+		ParallelToolCalls: true,
+	}
+}
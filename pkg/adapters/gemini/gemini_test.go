@@ -0,0 +1,276 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func newRecordingAdapter(t *testing.T, response string) (*GeminiAdapter, *llmtest.RecordingTransport) {
+	transport := llmtest.NewRecordingTransport(response)
+
+	adapter, err := NewGeminiAdapter("test-key", WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	return adapter, transport
+}
+
+const generateResponse = `{
+	"candidates": [{
+		"content": {"role": "model", "parts": [
+			{"text": "Let me calculate."},
+			{"functionCall": {"name": "calculator", "args": {"a": 2}}}
+		]},
+		"finishReason": "STOP"
+	}],
+	"usageMetadata": {"promptTokenCount": 20, "candidatesTokenCount": 10, "totalTokenCount": 30}
+}`
+
+func TestInvoke(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, generateResponse)
+
+	first := &llm.ToolCall{ID: "call_a", Name: "calculator", Args: json.RawMessage(`{"a": 1}`)}
+	second := &llm.ToolCall{ID: "call_b", Name: "calculator", Args: json.RawMessage(`{"a": 2}`)}
+
+	request := llm.NewLLMRequest(
+		llm.NewHistory(
+			llm.NewUserMessage("Add things"),
+			llm.NewUserMessage("Quickly please"),
+			llm.NewToolCallMessage(first),
+			llm.NewToolCallMessage(second),
+			llm.NewToolResultMessage(first, json.RawMessage(`{"result": 1}`)),
+			llm.NewToolResultMessage(second, json.RawMessage(`2`)),
+		),
+		llm.WithSystem("You are a calculator."),
+		llm.WithTools(llmtest.NewMockTool("calculator")),
+		llm.WithToolUsage(llm.ForceTool("calculator")),
+	)
+
+	response, err := adapter.Invoke(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body := transport.Requests[0]
+
+	// The system prompt goes into the dedicated field, not the contents
+	system := body["systemInstruction"].(map[string]any)["parts"].([]any)
+	if len(system) != 1 || system[0].(map[string]any)["text"] != "You are a calculator." {
+		t.Errorf("Expected the system instruction, got %v", body["systemInstruction"])
+	}
+
+	// Both user messages, both function calls and both function responses are merged into single turns
+	contents := body["contents"].([]any)
+	if len(contents) != 3 {
+		t.Fatalf("Expected 3 contents, got %d: %v", len(contents), contents)
+	}
+
+	expectedRoles := []string{"user", "model", "user"}
+	expectedParts := []string{"text", "functionCall", "functionResponse"}
+	for i, raw := range contents {
+		content := raw.(map[string]any)
+		if content["role"] != expectedRoles[i] {
+			t.Errorf("Expected content %d to have role %s, got %v", i, expectedRoles[i], content["role"])
+		}
+
+		parts := content["parts"].([]any)
+		if len(parts) != 2 {
+			t.Errorf("Expected content %d to hold 2 parts, got %d", i, len(parts))
+		}
+		for _, part := range parts {
+			if _, ok := part.(map[string]any)[expectedParts[i]]; !ok {
+				t.Errorf("Expected content %d to hold %s parts, got %v", i, expectedParts[i], part)
+			}
+		}
+	}
+
+	responses := contents[2].(map[string]any)["parts"].([]any)
+	wrapped := responses[1].(map[string]any)["functionResponse"].(map[string]any)
+	if wrapped["name"] != "calculator" || wrapped["response"].(map[string]any)["output"] != 2.0 {
+		t.Errorf("Expected a non-object result under output, got %v", wrapped)
+	}
+
+	config := body["toolConfig"].(map[string]any)["functionCallingConfig"].(map[string]any)
+	if config["mode"] != "ANY" || config["allowedFunctionNames"].([]any)[0] != "calculator" {
+		t.Errorf("Expected calling mode ANY restricted to calculator, got %v", config)
+	}
+
+	declarations := body["tools"].([]any)[0].(map[string]any)["functionDeclarations"].([]any)
+	if len(declarations) != 1 || declarations[0].(map[string]any)["name"] != "calculator" {
+		t.Errorf("Expected the calculator declaration, got %v", declarations)
+	}
+
+	// The response carries both the text and the tool call
+	if content := response.Messages[0].(*llm.AssistantMessage).Content; content != "Let me calculate." {
+		t.Errorf("Expected the assistant text, got %q", content)
+	}
+
+	toolCalls := response.ToolCalls()
+	if len(toolCalls) != 1 || toolCalls[0].Name != "calculator" || toolCalls[0].ID == "" || string(toolCalls[0].Args) != `{"a":2}` {
+		t.Errorf("Expected the tool call, got %+v", toolCalls)
+	}
+
+	if response.FinishReason != "STOP" || response.Usage.TotalTokens != 30 {
+		t.Errorf("Expected finish reason and usage, got %q %+v", response.FinishReason, response.Usage)
+	}
+}
+
+func TestInvokeFewShotExamples(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, generateResponse)
+
+	example := llm.NewHistory(llm.NewUserMessage("What is 1 plus 2?"), &llm.AssistantMessage{Content: "3"})
+	request := llm.NewLLMRequest(
+		llm.NewHistory(llm.NewUserMessage("What is 15 plus 27?")),
+		llm.WithFewShotExamples([]llm.History{example}),
+	)
+
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The delimiters join the example's first turn and the real conversation's first turn
+	contents := transport.Requests[0]["contents"].([]any)
+	if len(contents) != 3 {
+		t.Fatalf("Expected 3 contents, got %d: %v", len(contents), contents)
+	}
+
+	expected := [][]string{
+		{"Example 1 (for illustration only, not part of the conversation):", "What is 1 plus 2?"},
+		{"3"},
+		{"End of examples. The actual conversation follows.", "What is 15 plus 27?"},
+	}
+	for i, role := range []string{"user", "model", "user"} {
+		turn := contents[i].(map[string]any)
+		if turn["role"] != role {
+			t.Errorf("Expected turn %d to have role %s, got %v", i, role, turn["role"])
+		}
+
+		var texts []string
+		for _, part := range turn["parts"].([]any) {
+			texts = append(texts, part.(map[string]any)["text"].(string))
+		}
+		if !slices.Equal(texts, expected[i]) {
+			t.Errorf("Expected turn %d to hold %v, got %v", i, expected[i], texts)
+		}
+	}
+}
+
+func TestInvokeToolCallIDsSurviveTrimming(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, `{
+		"candidates": [{"content": {"role": "model", "parts": [{"functionCall": {"name": "calculator", "args": {"a": 1}}}]}}]
+	}`)
+
+	history := llm.NewHistory(llm.NewUserMessage("Add things twice"))
+	for range 2 {
+		response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(history))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		toolCall := response.ToolCalls()[0]
+		history = append(history, llm.NewToolCallMessage(toolCall), llm.NewToolResultMessage(toolCall, json.RawMessage(`1`)))
+	}
+
+	if first, second := history[1].(*llm.ToolCallMessage).ToolCall.ID, history[3].(*llm.ToolCallMessage).ToolCall.ID; first == second {
+		t.Fatalf("Expected unique IDs for calls of different turns, got %s twice", first)
+	}
+
+	// Only the last call and its result remain, the first pair is not pulled back in
+	trimmed := llm.KeepLastMessages(2)(history)
+	if _, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(trimmed)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	contents := transport.Requests[2]["contents"].([]any)
+	if len(contents) != 2 {
+		t.Fatalf("Expected the last tool call and result, got %d contents: %v", len(contents), contents)
+	}
+	for i, role := range []string{"model", "user"} {
+		if content := contents[i].(map[string]any); content["role"] != role || len(content["parts"].([]any)) != 1 {
+			t.Errorf("Expected content %d to be a single %s part, got %v", i, role, content)
+		}
+	}
+}
+
+func TestInvokeSamplingAndSafetySettings(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, generateResponse)
+
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")),
+		llm.WithTemperature(0.0),
+		llm.WithMaxCompletionTokens(100),
+		llm.WithStop("###"),
+		llm.WithSafetySettings(llm.SafetySetting{Category: llm.HarmCategoryHarassment, Threshold: llm.SafetyBlockOnlyHigh}),
+	)
+
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body := transport.Requests[0]
+	generation := body["generationConfig"].(map[string]any)
+	if generation["temperature"] != 0.0 {
+		t.Errorf("Expected temperature 0, got %v", generation["temperature"])
+	}
+	if generation["maxOutputTokens"] != 100.0 {
+		t.Errorf("Expected maxOutputTokens 100, got %v", generation["maxOutputTokens"])
+	}
+	if stop, _ := generation["stopSequences"].([]any); len(stop) != 1 || stop[0] != "###" {
+		t.Errorf("Expected stopSequences [###], got %v", generation["stopSequences"])
+	}
+
+	settings, _ := body["safetySettings"].([]any)
+	if len(settings) != 1 {
+		t.Fatalf("Expected one safety setting, got %v", body["safetySettings"])
+	}
+	if setting := settings[0].(map[string]any); setting["category"] != "HARM_CATEGORY_HARASSMENT" || setting["threshold"] != "BLOCK_ONLY_HIGH" {
+		t.Errorf("Expected the harassment setting, got %v", setting)
+	}
+
+	if _, ok := body["tools"]; ok {
+		t.Errorf("Expected no tools, got %v", body["tools"])
+	}
+}
+
+func TestInvokeBlocked(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		reason   string
+	}{
+		{
+			name:     "blocked prompt",
+			response: `{"promptFeedback": {"blockReason": "SAFETY", "safetyRatings": [{"category": "HARM_CATEGORY_HATE_SPEECH", "probability": "HIGH", "blocked": true}]}}`,
+			reason:   "SAFETY",
+		},
+		{
+			name:     "blocked candidate",
+			response: `{"candidates": [{"finishReason": "SAFETY", "safetyRatings": [{"category": "HARM_CATEGORY_HATE_SPEECH", "probability": "HIGH", "blocked": true}]}]}`,
+			reason:   "SAFETY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, _ := newRecordingAdapter(t, tt.response)
+
+			response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if response.Blocked == nil || response.Blocked.Reason != tt.reason {
+				t.Fatalf("Expected the response to be blocked with %s, got %+v", tt.reason, response.Blocked)
+			}
+			if categories := response.Blocked.Categories; len(categories) != 1 || categories[0] != llm.HarmCategoryHateSpeech {
+				t.Errorf("Expected hate speech category, got %v", categories)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package gemini
+
+import (
+	"fmt"
+
+	"google.golang.org/genai"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// convertToolUsage converts our ToolUsage interface to Gemini's function calling config.
+// Returns nil when no specific tool choice is needed (auto/default behavior)
+func convertToolUsage(toolUsage llm.ToolUsage, tools []llm.Tool) (*genai.ToolConfig, error) {
+	switch toolUsage.Type() {
+	case llm.ToolUsageForced:
+		if forced, ok := toolUsage.(*llm.ForcedToolUsage); ok {
+			if _, err := llm.FindTool(forced.ToolName, tools); err != nil {
+				return nil, fmt.Errorf("forced tool %s not available", forced.ToolName)
+			}
+
+			return &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{
+				Mode:                 genai.FunctionCallingConfigModeAny,
+				AllowedFunctionNames: []string{forced.ToolName},
+			}}, nil
+		}
+
+	case llm.ToolUsageNone:
+		return &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{
+			Mode: genai.FunctionCallingConfigModeNone,
+		}}, nil
+	}
+
+	return nil, nil
+}
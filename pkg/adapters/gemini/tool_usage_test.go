@@ -0,0 +1,43 @@
+package gemini
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func TestConvertToolUsage(t *testing.T) {
+	tools := []llm.Tool{llmtest.NewMockTool("calculator")}
+
+	auto, err := convertToolUsage(llm.AutoToolSelection(), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if auto != nil {
+		t.Errorf("Expected no tool config for auto tool usage, got %+v", auto)
+	}
+
+	forced, err := convertToolUsage(llm.ForceTool("calculator"), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := forced.FunctionCallingConfig
+	if config.Mode != genai.FunctionCallingConfigModeAny || len(config.AllowedFunctionNames) != 1 || config.AllowedFunctionNames[0] != "calculator" {
+		t.Errorf("Expected mode ANY restricted to calculator, got %+v", config)
+	}
+
+	none, err := convertToolUsage(llm.ForceNoTools(), tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if none == nil || none.FunctionCallingConfig.Mode != genai.FunctionCallingConfigModeNone {
+		t.Errorf("Expected mode NONE, got %+v", none)
+	}
+
+	if _, err := convertToolUsage(llm.ForceTool("missing"), tools); err == nil {
+		t.Error("Expected an error forcing an unavailable tool")
+	}
+}
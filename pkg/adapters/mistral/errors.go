@@ -0,0 +1,38 @@
+package mistral
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// providerError wraps a failed API call as an llm.LLMProviderError carrying the HTTP status, zero when no response was received
+func providerError(statusCode int, err error) error {
+	return &llm.LLMProviderError{Provider: "Mistral", StatusCode: statusCode, Err: err}
+}
+
+// apiError extracts the message of an error response, falling back to the status text
+func apiError(statusCode int, body []byte) error {
+	var decoded struct {
+		Message any `json:"message"`
+		Detail  any `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		for _, message := range []any{decoded.Message, decoded.Detail} {
+			switch m := message.(type) {
+			case string:
+				if m != "" {
+					return fmt.Errorf("%d %s: %s", statusCode, http.StatusText(statusCode), m)
+				}
+			case nil:
+			default:
+				encoded, _ := json.Marshal(m)
+				return fmt.Errorf("%d %s: %s", statusCode, http.StatusText(statusCode), encoded)
+			}
+		}
+	}
+
+	return fmt.Errorf("%d %s", statusCode, http.StatusText(statusCode))
+}
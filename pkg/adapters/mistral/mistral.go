@@ -0,0 +1,400 @@
+package mistral
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// MistralAdapter implements the LLM interface using Mistral's chat completions API (La Plateforme).
+// The wire format is close to OpenAI's, so it also works with Mistral-compatible endpoints.
+type MistralAdapter struct {
+	apiKey string
+	model  string
+
+	httpClient *http.Client
+	baseURL    string
+}
+
+// MistralAdapterOpts represents options for configuring the Mistral adapter
+type MistralAdapterOpts = func(*MistralAdapter)
+
+// WithModel sets the model to use for the Mistral adapter
+func WithModel(model string) MistralAdapterOpts {
+	return func(a *MistralAdapter) {
+		a.model = model
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for API calls, e.g. to add a proxy or tracing transport
+func WithHTTPClient(client *http.Client) MistralAdapterOpts {
+	return func(a *MistralAdapter) {
+		a.httpClient = client
+	}
+}
+
+// WithBaseURL points the adapter at a different endpoint, e.g. a self-hosted Mistral-compatible server
+func WithBaseURL(url string) MistralAdapterOpts {
+	return func(a *MistralAdapter) {
+		a.baseURL = url
+	}
+}
+
+// NewMistralAdapter creates a new Mistral adapter with the given API key and options
+func NewMistralAdapter(apiKey string, opts ...MistralAdapterOpts) (*MistralAdapter, error) {
+	adapter := &MistralAdapter{
+		apiKey:     apiKey,
+		model:      "mistral-large-latest", // default model
+		httpClient: http.DefaultClient,
+		baseURL:    "https://api.mistral.ai/v1",
+	}
+
+	for _, opt := range opts {
+		opt(adapter)
+	}
+
+	return adapter, nil
+}
+
+// chatRequest is the body of a chat completions request
+type chatRequest struct {
+	Model             string        `json:"model"`
+	Messages          []chatMessage `json:"messages"`
+	Tools             []chatTool    `json:"tools,omitempty"`
+	ToolChoice        string        `json:"tool_choice,omitempty"`
+	ParallelToolCalls *bool         `json:"parallel_tool_calls,omitempty"`
+	Temperature       *float64      `json:"temperature,omitempty"`
+	TopP              *float64      `json:"top_p,omitempty"`
+	MaxTokens         int           `json:"max_tokens,omitempty"`
+	Stop              []string      `json:"stop,omitempty"`
+	RandomSeed        *int64        `json:"random_seed,omitempty"`
+}
+
+type chatMessage struct {
+	Role       string         `json:"role"`
+	Content    string         `json:"content"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	Name       string         `json:"name,omitempty"`
+}
+
+type chatToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function chatFunctionCall `json:"function"`
+}
+
+type chatFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+type chatFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// chatResponse is the body of a chat completions response
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   json.RawMessage `json:"content"`
+			ToolCalls []chatToolCall  `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// Invoke implements the LLM interface by calling Mistral's chat completions endpoint
+func (a *MistralAdapter) Invoke(ctx context.Context, request *llm.LLMRequest) (*llm.LLMResponse, error) {
+	chatReq, err := a.newChatRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(a.baseURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+a.apiKey)
+
+	httpResp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, providerError(0, err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, providerError(httpResp.StatusCode, err)
+	}
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, providerError(httpResp.StatusCode, apiError(httpResp.StatusCode, respBody))
+	}
+
+	var resp chatResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return convertResponse(&resp)
+}
+
+// newChatRequest translates our request into a Mistral chat completions request
+func (a *MistralAdapter) newChatRequest(request *llm.LLMRequest) (*chatRequest, error) {
+	history := append(llm.ExampleMessages(request.Examples, func(content string) llm.Message {
+		return llm.NewSystemMessage(content)
+	}), request.History...)
+	if request.ToolResultDelivery == llm.ToolResultDeliveryText {
+		history = llm.ToolMessagesAsText(history)
+	}
+
+	chatReq := &chatRequest{
+		Model:             a.model,
+		Messages:          convertMessages(request.System, llm.DropOrphanedToolResults(llm.ExpandToolCalls(history))),
+		Temperature:       request.Temperature,
+		TopP:              request.TopP,
+		MaxTokens:         request.MaxCompletionTokens,
+		Stop:              request.Stop,
+		RandomSeed:        request.Seed,
+		ParallelToolCalls: request.ParallelToolCalls,
+	}
+
+	if request.ToolUsage != nil && len(request.Tools) > 0 {
+		toolChoice, tools, err := convertToolUsage(request.ToolUsage, request.Tools)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert tool usage: %w", err)
+		}
+
+		chatReq.ToolChoice = toolChoice
+		chatReq.Tools = convertTools(tools)
+	} else {
+		chatReq.ParallelToolCalls = nil
+	}
+
+	return chatReq, nil
+}
+
+// convertMessages converts our history to Mistral messages. Mistral rejects a system message between
+// an assistant's tool calls and their results, so instructions arriving there are held back until
+// the results have been delivered.
+func convertMessages(system string, history llm.History) []chatMessage {
+	var messages []chatMessage
+	if strings.TrimSpace(system) != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: system})
+	}
+
+	var deferred []chatMessage
+	instruct := func(content string) {
+		instruction := chatMessage{Role: "system", Content: content}
+		if awaitingToolResults(messages) {
+			deferred = append(deferred, instruction)
+			return
+		}
+		messages = append(messages, instruction)
+	}
+
+	for _, msg := range history {
+		// Held back instructions go right before the next message that isn't part of the tool exchange
+		switch msg.(type) {
+		case *llm.ToolResultMessage, *llm.ToolErrorMessage, *llm.SystemMessage, *llm.DeveloperMessage:
+		default:
+			messages = append(messages, deferred...)
+			deferred = nil
+		}
+
+		switch m := msg.(type) {
+		case *llm.SystemMessage:
+			instruct(m.Content)
+		case *llm.DeveloperMessage:
+			instruct(m.Content)
+
+		case *llm.UserMessage:
+			messages = append(messages, chatMessage{Role: "user", Content: m.Content})
+
+		case *llm.AssistantMessage:
+			if m.Content == "" {
+				continue
+			}
+			messages = append(messages, chatMessage{Role: "assistant", Content: m.Content})
+
+		case *llm.ToolCallMessage:
+			toolCall := chatToolCall{
+				ID:       toolCallID(m.ToolCall.ID),
+				Type:     "function",
+				Function: chatFunctionCall{Name: m.ToolCall.Name, Arguments: string(m.ToolCall.Args)},
+			}
+
+			// Parallel calls of one turn share a single assistant message along with its text
+			if n := len(messages); n > 0 && messages[n-1].Role == "assistant" {
+				messages[n-1].ToolCalls = append(messages[n-1].ToolCalls, toolCall)
+				continue
+			}
+			messages = append(messages, chatMessage{Role: "assistant", ToolCalls: []chatToolCall{toolCall}})
+
+		case *llm.ToolResultMessage:
+			messages = append(messages, chatMessage{
+				Role:       "tool",
+				Content:    string(m.Result),
+				ToolCallID: toolCallID(m.ToolCall.ID),
+				Name:       m.ToolCall.Name,
+			})
+
+		case *llm.ToolErrorMessage:
+			continue
+		}
+	}
+
+	return append(messages, deferred...)
+}
+
+// awaitingToolResults reports whether the conversation is between tool calls and their results
+func awaitingToolResults(messages []chatMessage) bool {
+	if len(messages) == 0 {
+		return false
+	}
+
+	last := messages[len(messages)-1]
+	return last.Role == "tool" || (last.Role == "assistant" && len(last.ToolCalls) > 0)
+}
+
+var mistralToolCallID = regexp.MustCompile(`^[a-zA-Z0-9]{9}$`)
+
+// toolCallID returns the ID in the form Mistral requires, nine alphanumeric characters. IDs of calls
+// made by Mistral pass through, others, e.g. from a conversation started with another provider,
+// are mapped to a stable substitute so that calls and their results still match.
+func toolCallID(id string) string {
+	if mistralToolCallID.MatchString(id) {
+		return id
+	}
+
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])[:9]
+}
+
+// convertTools converts our Tool interface to Mistral function tools
+func convertTools(tools []llm.Tool) []chatTool {
+	converted := make([]chatTool, 0, len(tools))
+
+	for _, tool := range tools {
+		converted = append(converted, chatTool{
+			Type: "function",
+			Function: chatFunction{
+				Name:        tool.Name(),
+				Description: tool.Description(),
+				Parameters:  tool.InputSchemaRaw(),
+			},
+		})
+	}
+
+	return converted
+}
+
+// convertResponse translates Mistral's first choice into our response
+func convertResponse(resp *chatResponse) (*llm.LLMResponse, error) {
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	response := llm.NewLLMResponse()
+	if usage := resp.Usage; usage != nil {
+		response.Usage = &llm.Usage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.TotalTokens,
+		}
+	}
+
+	choice := resp.Choices[0]
+	response.FinishReason = choice.FinishReason
+
+	content, err := messageContent(choice.Message.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode message content: %w", err)
+	}
+	if content != "" {
+		response.AddMessage(&llm.AssistantMessage{Content: content})
+	}
+
+	for _, toolCall := range choice.Message.ToolCalls {
+		args := toolCall.Function.Arguments
+		if strings.TrimSpace(args) == "" {
+			args = "{}"
+		}
+
+		response.AddToolCall(&llm.ToolCall{ID: toolCall.ID, Name: toolCall.Function.Name, Args: json.RawMessage(args)})
+	}
+
+	return response, nil
+}
+
+// messageContent returns the text of a message. Content is usually a string, but reasoning models
+// return a list of chunks, of which only the text ones are kept.
+func messageContent(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+
+	var chunks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &chunks); err != nil {
+		return "", err
+	}
+
+	var content strings.Builder
+	for _, chunk := range chunks {
+		if chunk.Type == "text" {
+			content.WriteString(chunk.Text)
+		}
+	}
+
+	return content.String(), nil
+}
+
+// Close is a no-op, the adapter holds no resources to release
+func (a *MistralAdapter) Close() error {
+	return nil
+}
+
+// Capabilities reports the features supported by the Mistral adapter
+func (a *MistralAdapter) Capabilities() llm.Capabilities {
+	return llm.Capabilities{
+		ForcedTools:       true,
+		ParallelToolCalls: true,
+	}
+}
@@ -0,0 +1,184 @@
+package mistral
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func newRecordingAdapter(t *testing.T, response string) (*MistralAdapter, *llmtest.RecordingTransport) {
+	transport := llmtest.NewRecordingTransport(response)
+
+	adapter, err := NewMistralAdapter("test-key", WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	return adapter, transport
+}
+
+const chatResponseBody = `{
+	"choices": [{
+		"message": {"role": "assistant", "content": "Let me calculate.", "tool_calls": [
+			{"id": "D681PevKs", "type": "function", "function": {"name": "calculator", "arguments": "{\"a\": 2}"}}
+		]},
+		"finish_reason": "tool_calls"
+	}],
+	"usage": {"prompt_tokens": 20, "completion_tokens": 10, "total_tokens": 30}
+}`
+
+func TestInvoke(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, chatResponseBody)
+
+	first := &llm.ToolCall{ID: "call_abc123", Name: "calculator", Args: json.RawMessage(`{"a": 1}`)}
+	second := &llm.ToolCall{ID: "Xy12Ab34C", Name: "calculator", Args: json.RawMessage(`{"a": 2}`)}
+
+	request := llm.NewLLMRequest(
+		llm.NewHistory(
+			llm.NewUserMessage("Add things"),
+			&llm.AssistantMessage{Content: "Calculating.", ToolCalls: []*llm.ToolCall{first, second}},
+			llm.NewDeveloperMessage("Answer in digits"),
+			llm.NewToolResultMessage(first, json.RawMessage(`{"result": 1}`)),
+			llm.NewToolResultMessage(second, json.RawMessage(`{"result": 2}`)),
+			llm.NewUserMessage("And now?"),
+		),
+		llm.WithSystem("You are a calculator."),
+		llm.WithTools(llmtest.NewMockTool("calculator"), llmtest.NewMockTool("weather")),
+		llm.WithToolUsage(llm.ForceTool("calculator")),
+		llm.WithSeed(7),
+	)
+
+	response, err := adapter.Invoke(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if auth := transport.Headers[0].Get("Authorization"); auth != "Bearer test-key" {
+		t.Errorf("Expected the bearer token, got %q", auth)
+	}
+
+	body := transport.Requests[0]
+
+	// The developer message between the tool calls and their results moves after the results
+	messages := body["messages"].([]any)
+	expectedRoles := []string{"system", "user", "assistant", "tool", "tool", "system", "user"}
+	if len(messages) != len(expectedRoles) {
+		t.Fatalf("Expected %d messages, got %d: %v", len(expectedRoles), len(messages), messages)
+	}
+	for i, raw := range messages {
+		if role := raw.(map[string]any)["role"]; role != expectedRoles[i] {
+			t.Errorf("Expected message %d to have role %s, got %v", i, expectedRoles[i], role)
+		}
+	}
+
+	assistant := messages[2].(map[string]any)
+	toolCalls := assistant["tool_calls"].([]any)
+	if assistant["content"] != "Calculating." || len(toolCalls) != 2 {
+		t.Fatalf("Expected one assistant turn with text and both calls, got %v", assistant)
+	}
+
+	// Foreign IDs are mapped to Mistral's format, consistently for calls and results
+	mapped := toolCalls[0].(map[string]any)["id"].(string)
+	if len(mapped) != 9 || mapped == first.ID || messages[3].(map[string]any)["tool_call_id"] != mapped {
+		t.Errorf("Expected a 9 character ID shared by call and result, got %q and %v", mapped, messages[3].(map[string]any)["tool_call_id"])
+	}
+	if id := toolCalls[1].(map[string]any)["id"]; id != second.ID || messages[4].(map[string]any)["tool_call_id"] != second.ID {
+		t.Errorf("Expected the Mistral ID to pass through, got %v", id)
+	}
+
+	// Forcing a tool declares only that tool with tool_choice "any"
+	if body["tool_choice"] != "any" {
+		t.Errorf("Expected tool_choice any, got %v", body["tool_choice"])
+	}
+	if tools := body["tools"].([]any); len(tools) != 1 || tools[0].(map[string]any)["function"].(map[string]any)["name"] != "calculator" {
+		t.Errorf("Expected only the calculator definition, got %v", tools)
+	}
+
+	if body["random_seed"] != 7.0 {
+		t.Errorf("Expected random_seed 7, got %v", body["random_seed"])
+	}
+
+	if content := response.Messages[0].(*llm.AssistantMessage).Content; content != "Let me calculate." {
+		t.Errorf("Expected the assistant text, got %q", content)
+	}
+
+	calls := response.ToolCalls()
+	if len(calls) != 1 || calls[0].ID != "D681PevKs" || string(calls[0].Args) != `{"a": 2}` {
+		t.Errorf("Expected the tool call, got %+v", calls)
+	}
+
+	if response.FinishReason != "tool_calls" || response.Usage.TotalTokens != 30 {
+		t.Errorf("Expected finish reason and usage, got %q %+v", response.FinishReason, response.Usage)
+	}
+}
+
+func TestInvokeFewShotExamples(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, chatResponseBody)
+
+	example := llm.NewHistory(llm.NewUserMessage("What is 1 plus 2?"), &llm.AssistantMessage{Content: "3"})
+	request := llm.NewLLMRequest(
+		llm.NewHistory(llm.NewUserMessage("What is 15 plus 27?")),
+		llm.WithFewShotExamples([]llm.History{example}),
+	)
+
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// System messages delimit the examples from the real conversation
+	messages := transport.Requests[0]["messages"].([]any)
+	expected := [][2]string{
+		{"system", "Example 1 (for illustration only, not part of the conversation):"},
+		{"user", "What is 1 plus 2?"},
+		{"assistant", "3"},
+		{"system", "End of examples. The actual conversation follows."},
+		{"user", "What is 15 plus 27?"},
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d messages, got %d: %v", len(expected), len(messages), messages)
+	}
+
+	for i, raw := range messages {
+		message := raw.(map[string]any)
+		if message["role"] != expected[i][0] || message["content"] != expected[i][1] {
+			t.Errorf("Expected message %d to be %v, got %v", i, expected[i], message)
+		}
+	}
+}
+
+func TestInvokeChunkedContent(t *testing.T) {
+	adapter, _ := newRecordingAdapter(t, `{"choices": [{"message": {"role": "assistant", "content": [
+		{"type": "thinking", "thinking": [{"type": "text", "text": "Hmm."}]},
+		{"type": "text", "text": "Four."}
+	]}, "finish_reason": "stop"}]}`)
+
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("2 + 2?"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if content := response.Messages[0].(*llm.AssistantMessage).Content; content != "Four." {
+		t.Errorf("Expected only the text chunks, got %q", content)
+	}
+}
+
+func TestInvokeProviderError(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, `{"object": "error", "message": "Unauthorized"}`)
+	transport.Status = http.StatusUnauthorized
+
+	_, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+
+	var providerErr *llm.LLMProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("Expected an LLMProviderError, got %v", err)
+	}
+	if providerErr.Provider != "Mistral" || providerErr.StatusCode != http.StatusUnauthorized || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("Expected a Mistral error with status 401, got %v", err)
+	}
+}
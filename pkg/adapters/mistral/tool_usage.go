@@ -0,0 +1,28 @@
+package mistral
+
+import (
+	"fmt"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// convertToolUsage converts our ToolUsage interface to Mistral's tool_choice and the tools to declare.
+// Mistral's "any" forces a call of one of the declared tools, so forcing a tool declares only that one.
+func convertToolUsage(toolUsage llm.ToolUsage, tools []llm.Tool) (string, []llm.Tool, error) {
+	switch toolUsage.Type() {
+	case llm.ToolUsageForced:
+		if forced, ok := toolUsage.(*llm.ForcedToolUsage); ok {
+			tool, err := llm.FindTool(forced.ToolName, tools)
+			if err != nil {
+				return "", nil, fmt.Errorf("forced tool %s not available", forced.ToolName)
+			}
+
+			return "any", []llm.Tool{tool}, nil
+		}
+
+	case llm.ToolUsageNone:
+		return "none", tools, nil
+	}
+
+	return "auto", tools, nil
+}
@@ -0,0 +1,63 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+
+	openai "github.com/openai/openai-go/v2"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// maxEmbeddingInputs is the most inputs OpenAI accepts in a single embeddings request
+const maxEmbeddingInputs = 2048
+
+var _ llm.Embedder = (*OpenAIAdapter)(nil)
+
+// WithEmbeddingModel sets the model used by Embed
+func WithEmbeddingModel(model string) OpenAIAdapterOpts {
+	return func(a *OpenAIAdapter) {
+		a.embeddingModel = model
+	}
+}
+
+// Embed implements the llm.Embedder interface using OpenAI's embeddings endpoint.
+// Large inputs are sent in batches, the vectors are returned in the order of texts.
+func (a *OpenAIAdapter) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+
+	for start := 0; start < len(texts); start += maxEmbeddingInputs {
+		end := min(start+maxEmbeddingInputs, len(texts))
+
+		resp, err := a.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+			Model: openai.EmbeddingModel(a.embeddingModel),
+			Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts[start:end]},
+		})
+		if err != nil {
+			return nil, providerError(err)
+		}
+
+		if len(resp.Data) != end-start {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", end-start, len(resp.Data))
+		}
+
+		// The API reports each vector's position in the batch, so don't rely on the response order
+		for _, data := range resp.Data {
+			if data.Index < 0 || int(data.Index) >= end-start {
+				return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+			}
+			embeddings[start+int(data.Index)] = toFloat32(data.Embedding)
+		}
+	}
+
+	return embeddings, nil
+}
+
+func toFloat32(values []float64) []float32 {
+	vector := make([]float32, len(values))
+	for i, v := range values {
+		vector[i] = float32(v)
+	}
+
+	return vector
+}
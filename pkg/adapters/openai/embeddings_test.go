@@ -0,0 +1,107 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/openai/openai-go/v2/option"
+)
+
+// embeddingTransport answers embeddings requests with one vector per input holding the input's
+// number, listing the vectors in reverse order
+type embeddingTransport struct {
+	batches [][]string
+	models  []string
+}
+
+func (e *embeddingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body struct {
+		Input []string `json:"input"`
+		Model string   `json:"model"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	e.batches = append(e.batches, body.Input)
+	e.models = append(e.models, body.Model)
+
+	data := make([]map[string]any, 0, len(body.Input))
+	for i := len(body.Input) - 1; i >= 0; i-- {
+		n, err := strconv.Atoi(body.Input[i])
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, map[string]any{"object": "embedding", "index": i, "embedding": []float64{float64(n)}})
+	}
+
+	reply, err := json.Marshal(map[string]any{"object": "list", "model": body.Model, "data": data})
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(reply)),
+		Request:    req,
+	}, nil
+}
+
+func TestEmbedBatchesAndPreservesOrder(t *testing.T) {
+	transport := &embeddingTransport{}
+	adapter, err := NewOpenAIAdapter("test-key",
+		WithEmbeddingModel("text-embedding-3-large"),
+		WithClientOptions(option.WithHTTPClient(&http.Client{Transport: transport}), option.WithMaxRetries(0)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	texts := make([]string, maxEmbeddingInputs+3)
+	for i := range texts {
+		texts[i] = fmt.Sprint(i)
+	}
+
+	embeddings, err := adapter.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(transport.batches) != 2 || len(transport.batches[0]) != maxEmbeddingInputs || len(transport.batches[1]) != 3 {
+		t.Fatalf("Expected batches of %d and 3 inputs, got %d batches", maxEmbeddingInputs, len(transport.batches))
+	}
+
+	if transport.models[0] != "text-embedding-3-large" {
+		t.Errorf("Expected embedding model text-embedding-3-large, got %s", transport.models[0])
+	}
+
+	if len(embeddings) != len(texts) {
+		t.Fatalf("Expected %d embeddings, got %d", len(texts), len(embeddings))
+	}
+
+	for i, embedding := range embeddings {
+		if len(embedding) != 1 || embedding[0] != float32(i) {
+			t.Fatalf("Expected embedding %d to be [%d], got %v", i, i, embedding)
+		}
+	}
+}
+
+func TestEmbedEmptyInput(t *testing.T) {
+	transport := &embeddingTransport{}
+	adapter, _ := NewOpenAIAdapter("test-key", WithClientOptions(option.WithHTTPClient(&http.Client{Transport: transport})))
+
+	embeddings, err := adapter.Embed(context.Background(), nil)
+	if err != nil || len(embeddings) != 0 {
+		t.Errorf("Expected no embeddings, got %v, %v", embeddings, err)
+	}
+
+	if len(transport.batches) != 0 {
+		t.Errorf("Expected no API call, got %d", len(transport.batches))
+	}
+}
@@ -0,0 +1,21 @@
+package openai
+
+import (
+	"errors"
+
+	openai "github.com/openai/openai-go/v2"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// providerError wraps a failed API call as an llm.LLMProviderError carrying the HTTP status, if any
+func providerError(err error) error {
+	providerErr := &llm.LLMProviderError{Provider: "OpenAI", Err: err}
+
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		providerErr.StatusCode = apiErr.StatusCode
+	}
+
+	return providerErr
+}
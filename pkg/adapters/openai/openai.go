@@ -2,8 +2,14 @@ package openai
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	openai "github.com/openai/openai-go/v2"
 	"github.com/openai/openai-go/v2/option"
@@ -14,8 +20,25 @@ import (
 
 // OpenAIAdapter implements the LLM interface using OpenAI's API
 type OpenAIAdapter struct {
-	client *openai.Client
-	model  string
+	client         *openai.Client
+	model          string
+	embeddingModel string
+
+	toolResultDelivery llm.ToolResultDelivery
+	clientOptions      []option.RequestOption
+	profiles           map[string]ModelProfile
+	responseSchema     *responseSchema
+	captureRaw         bool
+
+	apiRetries      int
+	apiRetryDelay   time.Duration
+	apiRetryBackoff float64
+}
+
+// responseSchema is the JSON schema the model's output must follow
+type responseSchema struct {
+	name   string
+	schema json.RawMessage
 }
 
 // OpenAIAdapterOpts represents options for configuring the OpenAI adapter
@@ -28,37 +51,176 @@ func WithModel(model string) OpenAIAdapterOpts {
 	}
 }
 
+// WithDefaultToolResultDelivery sets how tool results are delivered when the request doesn't say,
+// e.g. llm.ToolResultDeliveryText for OpenAI-compatible servers whose models lack tool-result support
+func WithDefaultToolResultDelivery(delivery llm.ToolResultDelivery) OpenAIAdapterOpts {
+	return func(a *OpenAIAdapter) {
+		a.toolResultDelivery = delivery
+	}
+}
+
+// WithClientOptions passes additional request options to the underlying OpenAI client,
+// e.g. option.WithHTTPClient or option.WithHeader
+func WithClientOptions(opts ...option.RequestOption) OpenAIAdapterOpts {
+	return func(a *OpenAIAdapter) {
+		a.clientOptions = append(a.clientOptions, opts...)
+	}
+}
+
+// WithResponseSchema asks the model to reply with JSON following the schema, using OpenAI's native
+// structured outputs in strict mode. The JSON is returned as the content of an AssistantMessage.
+// Requests fail for models that don't support structured outputs.
+func WithResponseSchema(name string, schema json.RawMessage) OpenAIAdapterOpts {
+	return func(a *OpenAIAdapter) {
+		a.responseSchema = &responseSchema{name: name, schema: schema}
+	}
+}
+
+// WithCaptureRaw keeps the raw JSON of every chat completion in LLMResponse.Raw, including all
+// choices, logprobs and refusals, e.g. to debug odd model behavior. Off by default to save memory.
+func WithCaptureRaw() OpenAIAdapterOpts {
+	return func(a *OpenAIAdapter) {
+		a.captureRaw = true
+	}
+}
+
+// WithBaseURL points the adapter at an OpenAI-compatible server, e.g. a local vLLM instance
+func WithBaseURL(url string) OpenAIAdapterOpts {
+	return WithClientOptions(option.WithBaseURL(url))
+}
+
+// WithOrganization sets the organization used for API requests with org-scoped keys
+func WithOrganization(id string) OpenAIAdapterOpts {
+	return WithClientOptions(option.WithOrganization(id))
+}
+
+// WithHTTPClient sets the HTTP client used for API calls, e.g. to configure a proxy or TLS
+func WithHTTPClient(client *http.Client) OpenAIAdapterOpts {
+	return WithClientOptions(option.WithHTTPClient(client))
+}
+
+// WithRequestTimeout limits how long each attempt of an API call may take, retries get a fresh timeout
+func WithRequestTimeout(timeout time.Duration) OpenAIAdapterOpts {
+	return WithClientOptions(option.WithRequestTimeout(timeout))
+}
+
+// WithHeader adds a static header to every API call, e.g. the token of an API gateway
+func WithHeader(key, value string) OpenAIAdapterOpts {
+	return WithClientOptions(option.WithHeader(key, value))
+}
+
 // NewOpenAIAdapter creates a new OpenAI adapter with the given API key and options
 func NewOpenAIAdapter(apiKey string, opts ...OpenAIAdapterOpts) (*OpenAIAdapter, error) {
-	client := openai.NewClient(option.WithAPIKey(apiKey))
-
 	adapter := &OpenAIAdapter{
-		client: &client,
-		model:  "gpt-4o", // default model
+		model:           "gpt-4o",                 // default model
+		embeddingModel:  "text-embedding-3-small", // default embedding model
+		apiRetryDelay:   500 * time.Millisecond,   // default first retry delay
+		apiRetryBackoff: 2.0,                      // default retry backoff
 	}
 
 	for _, opt := range opts {
 		opt(adapter)
 	}
 
+	client := openai.NewClient(append([]option.RequestOption{option.WithAPIKey(apiKey)}, adapter.clientOptions...)...)
+	adapter.client = &client
+
 	return adapter, nil
 }
 
 // Invoke implements the LLM interface by calling OpenAI's API
 func (a *OpenAIAdapter) Invoke(ctx context.Context, request *llm.LLMRequest) (*llm.LLMResponse, error) {
-	history := append(llm.NewHistory(llm.NewSystemMessage(request.System)), request.History...)
+	request, err := a.applyProfile(request)
+	if err != nil {
+		return nil, err
+	}
+
+	chatReq, err := a.newChatParams(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.newChatCompletion(ctx, request.RequestID, chatReq, requestOptions(request)...)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	return a.convertResponse(resp, request)
+}
+
+// requestOptions passes the request's correlation ID and idempotency key as headers along with its
+// pass-through parameters
+func requestOptions(request *llm.LLMRequest) []option.RequestOption {
+	opts := modelParamOptions(request.ModelParams)
+
+	if request.RequestID != "" {
+		opts = append(opts, option.WithHeader("X-Client-Request-Id", request.RequestID))
+	}
+
+	if request.IdempotencyKey != "" {
+		opts = append(opts, option.WithHeader("Idempotency-Key", request.IdempotencyKey))
+	}
 
+	return opts
+}
+
+// newChatParams translates our request into OpenAI's chat completion parameters
+func (a *OpenAIAdapter) newChatParams(request *llm.LLMRequest) (openai.ChatCompletionNewParams, error) {
 	chatReq := openai.ChatCompletionNewParams{
 		Model:    shared.ChatModel(a.model),
-		Messages: a.convertMessages(history),
+		Messages: a.buildMessages(request),
 	}
 
 	if request.MaxCompletionTokens > 0 {
 		chatReq.MaxCompletionTokens = openai.Int(int64(request.MaxCompletionTokens))
 	}
 
-	if request.Temperature > 0 {
-		chatReq.Temperature = openai.Float(request.Temperature)
+	if request.Temperature != nil {
+		chatReq.Temperature = openai.Float(*request.Temperature)
+	}
+
+	if request.TopP != nil {
+		chatReq.TopP = openai.Float(*request.TopP)
+	}
+
+	if request.Seed != nil {
+		chatReq.Seed = openai.Int(*request.Seed)
+	}
+
+	if request.FrequencyPenalty != 0 {
+		chatReq.FrequencyPenalty = openai.Float(request.FrequencyPenalty)
+	}
+
+	if request.PresencePenalty != 0 {
+		chatReq.PresencePenalty = openai.Float(request.PresencePenalty)
+	}
+
+	if request.TopLogprobs != nil {
+		chatReq.Logprobs = openai.Bool(true)
+		if *request.TopLogprobs > 0 {
+			chatReq.TopLogprobs = openai.Int(int64(*request.TopLogprobs))
+		}
+	}
+
+	// A single sequence goes out in the string form, which every compatible server accepts
+	switch len(request.Stop) {
+	case 0:
+	case 1:
+		chatReq.Stop = openai.ChatCompletionNewParamsStopUnion{OfString: openai.String(request.Stop[0])}
+	default:
+		chatReq.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: request.Stop}
+	}
+
+	if err := a.applyModalities(&chatReq, request); err != nil {
+		return chatReq, err
+	}
+
+	if err := applyJSONMode(&chatReq, request); err != nil {
+		return chatReq, err
+	}
+
+	if err := a.applyResponseSchema(&chatReq); err != nil {
+		return chatReq, err
 	}
 
 	// Handle tool usage based on the ToolUsage strategy
@@ -69,55 +231,280 @@ func (a *OpenAIAdapter) Invoke(ctx context.Context, request *llm.LLMRequest) (*l
 		// Convert tool usage to OpenAI format
 		toolChoice, err := convertToolUsage(request.ToolUsage, request.Tools)
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert tool usage: %w", err)
+			return chatReq, fmt.Errorf("failed to convert tool usage: %w", err)
 		}
 
 		if toolChoice != nil {
 			chatReq.ToolChoice = *toolChoice
 		}
+
+		if request.ParallelToolCalls != nil {
+			chatReq.ParallelToolCalls = openai.Bool(*request.ParallelToolCalls)
+		}
 	}
 
-	// Pretty print the chat request for debugging
-	// reqJSON, err := json.MarshalIndent(chatReq, "", "  ")
-	// if err != nil {
-	// 	return nil, fmt.Errorf("failed to marshal chat request: %w", err)
-	// }
-	// fmt.Println(string(reqJSON))
+	return chatReq, nil
+}
 
-	resp, err := a.client.Chat.Completions.New(ctx, chatReq)
-	if err != nil {
-		return nil, fmt.Errorf("OpenAI API call failed: %w", err)
+// applyModalities sets the output modalities and audio parameters, rejecting them for text-only models
+func (a *OpenAIAdapter) applyModalities(chatReq *openai.ChatCompletionNewParams, request *llm.LLMRequest) error {
+	wantsAudio := request.AudioOutput != nil || slices.Contains(request.Modalities, "audio")
+	if wantsAudio && !supportsAudio(a.model) {
+		return fmt.Errorf("model %s does not support audio output", a.model)
+	}
+
+	chatReq.Modalities = request.Modalities
+
+	if request.AudioOutput != nil {
+		if len(chatReq.Modalities) == 0 {
+			chatReq.Modalities = []string{"text", "audio"}
+		}
+
+		chatReq.Audio = openai.ChatCompletionAudioParam{
+			Voice:  openai.ChatCompletionAudioParamVoice(request.AudioOutput.Voice),
+			Format: openai.ChatCompletionAudioParamFormat(request.AudioOutput.Format),
+		}
+	}
+
+	return nil
+}
+
+// supportsAudio reports whether the model can produce audio output
+func supportsAudio(model string) bool {
+	return strings.Contains(model, "audio")
+}
+
+// applyJSONMode sets the json_object response format when the request asks for JSON mode. OpenAI
+// rejects such requests unless "json" appears in the messages, so that is checked upfront.
+func applyJSONMode(chatReq *openai.ChatCompletionNewParams, request *llm.LLMRequest) error {
+	if !request.JSONMode {
+		return nil
+	}
+
+	if !mentionsJSON(request) {
+		return errors.New(`JSON mode requires the word "json" in the system prompt or messages, e.g. "Reply in JSON"`)
 	}
 
+	chatReq.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONObject: &shared.ResponseFormatJSONObjectParam{},
+	}
+
+	return nil
+}
+
+// mentionsJSON reports whether the instructions or the text messages of the request mention JSON
+func mentionsJSON(request *llm.LLMRequest) bool {
+	texts := []string{request.System}
+	for _, history := range append(slices.Clone(request.Examples), request.History) {
+		for _, msg := range history {
+			switch m := msg.(type) {
+			case *llm.SystemMessage:
+				texts = append(texts, m.Content)
+			case *llm.DeveloperMessage:
+				texts = append(texts, m.Content)
+			case *llm.UserMessage:
+				texts = append(texts, m.Content)
+			case *llm.AssistantMessage:
+				texts = append(texts, m.Content)
+			}
+		}
+	}
+
+	for _, text := range texts {
+		if strings.Contains(strings.ToLower(text), "json") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyResponseSchema sets the strict json_schema response format when a response schema is configured
+func (a *OpenAIAdapter) applyResponseSchema(chatReq *openai.ChatCompletionNewParams) error {
+	if a.responseSchema == nil {
+		return nil
+	}
+
+	if !supportsStructuredOutputs(a.model) {
+		return fmt.Errorf("model %s does not support structured outputs", a.model)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(a.responseSchema.schema, &schema); err != nil {
+		return fmt.Errorf("invalid response schema: %w", err)
+	}
+
+	chatReq.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+			JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   a.responseSchema.name,
+				Strict: openai.Bool(true),
+				Schema: schema,
+			},
+		},
+	}
+
+	return nil
+}
+
+// supportsStructuredOutputs reports whether the model accepts the json_schema response format,
+// which models released before gpt-4o-2024-08-06 reject
+func supportsStructuredOutputs(model string) bool {
+	for _, prefix := range []string{"gpt-3.5", "gpt-4-", "gpt-4o-2024-05-13"} {
+		if strings.HasPrefix(model, prefix) {
+			return false
+		}
+	}
+	return model != "gpt-4" && model != "o1-preview" && model != "o1-mini"
+}
+
+// convertResponse translates OpenAI's chat completion into our response
+func (a *OpenAIAdapter) convertResponse(resp *openai.ChatCompletion, request *llm.LLMRequest) (*llm.LLMResponse, error) {
 	response := llm.NewLLMResponse()
+	response.SystemFingerprint = resp.SystemFingerprint
+
+	if a.captureRaw {
+		response.Raw = json.RawMessage(resp.RawJSON())
+	}
+
+	if resp.Usage.TotalTokens > 0 {
+		response.Usage = &llm.Usage{
+			PromptTokens:     int(resp.Usage.PromptTokens),
+			CompletionTokens: int(resp.Usage.CompletionTokens),
+			TotalTokens:      int(resp.Usage.TotalTokens),
+		}
+	}
 
 	if len(resp.Choices) > 0 {
 		choice := resp.Choices[0]
+		response.FinishReason = choice.FinishReason
+		if choice.FinishReason == "content_filter" {
+			response.Blocked = &llm.SafetyBlock{Reason: choice.FinishReason}
+		}
+
+		// A refusal replaces the structured output
+		if choice.Message.Refusal != "" {
+			response.Blocked = &llm.SafetyBlock{Reason: llm.SafetyBlockRefusal, Message: choice.Message.Refusal}
+		}
+
+		response.Logprobs = convertLogprobs(choice.Logprobs.Content)
+
+		var toolCalls []*llm.ToolCall
+		for _, toolCall := range choice.Message.ToolCalls {
+			toolCalls = append(toolCalls, &llm.ToolCall{
+				ID:   toolCall.ID,
+				Name: toolCall.Function.Name,
+				Args: json.RawMessage(toolCall.Function.Arguments),
+			})
+		}
+
+		// Text and tool calls of one turn stay together in a single message
 		if choice.Message.Content != "" {
-			textMsg := &llm.AssistantMessage{Content: choice.Message.Content}
-			response.AddMessage(textMsg)
-		}
-
-		if choice.Message.ToolCalls != nil {
-			for _, toolCall := range choice.Message.ToolCalls {
-				ourToolCall := &llm.ToolCall{
-					ID:   toolCall.ID,
-					Name: toolCall.Function.Name,
-					Args: json.RawMessage(toolCall.Function.Arguments),
-				}
-				response.AddToolCall(ourToolCall)
+			response.AddMessage(&llm.AssistantMessage{Content: choice.Message.Content, ToolCalls: toolCalls})
+			toolCalls = nil
+		}
+
+		if choice.Message.Audio.Data != "" {
+			data, err := base64.StdEncoding.DecodeString(choice.Message.Audio.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode audio: %w", err)
+			}
+
+			audioMsg := &llm.AudioMessage{
+				ID:         choice.Message.Audio.ID,
+				Data:       data,
+				Transcript: choice.Message.Audio.Transcript,
+			}
+			if request.AudioOutput != nil {
+				audioMsg.Format = request.AudioOutput.Format
 			}
+			response.AddMessage(audioMsg)
+		}
+
+		for _, toolCall := range toolCalls {
+			response.AddToolCall(toolCall)
 		}
 	}
 
 	return response, nil
 }
 
-// convertMessages converts our Message interface to OpenAI's format
-func (a *OpenAIAdapter) convertMessages(messages []llm.Message) []openai.ChatCompletionMessageParamUnion {
+// convertLogprobs translates the log probabilities of the message content tokens
+func convertLogprobs(tokens []openai.ChatCompletionTokenLogprob) []llm.TokenLogprob {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	logprobs := make([]llm.TokenLogprob, len(tokens))
+	for i, token := range tokens {
+		logprobs[i] = llm.TokenLogprob{Token: token.Token, Logprob: token.Logprob}
+		for _, alternative := range token.TopLogprobs {
+			logprobs[i].Alternatives = append(logprobs[i].Alternatives, llm.TokenLogprob{Token: alternative.Token, Logprob: alternative.Logprob})
+		}
+	}
+
+	return logprobs
+}
+
+// buildMessages assembles the conversation sent to OpenAI for the given request: the system prompt,
+// the delimited few-shot examples and the history, dropping tool results that no longer have a matching tool call
+func (a *OpenAIAdapter) buildMessages(request *llm.LLMRequest) []openai.ChatCompletionMessageParamUnion {
+	var history llm.History
+	if prependSystemPrompt(request) {
+		history = history.Append(llm.NewSystemMessage(request.System))
+	}
+
+	history = history.Append(llm.ExampleMessages(request.Examples, func(content string) llm.Message {
+		return llm.NewSystemMessage(content)
+	})...)
+
+	history = history.Append(llm.ExpandToolCalls(request.History)...)
+	history = llm.DropOrphanedToolResults(history)
+
+	delivery := request.ToolResultDelivery
+	if delivery == "" {
+		delivery = a.toolResultDelivery
+	}
+
+	if delivery == llm.ToolResultDeliveryText {
+		history = llm.ToolMessagesAsText(history)
+	}
+
+	return a.convertMessages(history, delivery)
+}
+
+// prependSystemPrompt reports whether the request's system prompt has to be sent ahead of the history,
+// it is left out when the history already carries it, e.g. in a resumed conversation
+func prependSystemPrompt(request *llm.LLMRequest) bool {
+	for _, msg := range request.History {
+		if system, ok := msg.(*llm.SystemMessage); ok && system.Content == request.System {
+			return false
+		}
+	}
+
+	return true
+}
+
+// convertMessages converts our Message interface to OpenAI's format,
+// delivering tool calls and results according to the given delivery mode
+func (a *OpenAIAdapter) convertMessages(messages []llm.Message, delivery llm.ToolResultDelivery) []openai.ChatCompletionMessageParamUnion {
 	var openaiMessages []openai.ChatCompletionMessageParamUnion
 
 	for _, msg := range messages {
+		if delivery == llm.ToolResultDeliveryUserRole {
+			switch m := msg.(type) {
+			case *llm.ToolCallMessage:
+				openaiMessages = append(openaiMessages, openai.AssistantMessage(
+					fmt.Sprintf("Calling tool %s with arguments: %s", m.ToolCall.Name, string(m.ToolCall.Args))))
+				continue
+
+			case *llm.ToolResultMessage:
+				openaiMessages = append(openaiMessages, openai.UserMessage(
+					fmt.Sprintf("Result of tool %s: %s", m.ToolCall.Name, string(m.Result))))
+				continue
+			}
+		}
+
 		switch m := msg.(type) {
 		case *llm.UserMessage:
 			openaiMessages = append(openaiMessages, openai.UserMessage(m.Content))
@@ -125,24 +512,42 @@ func (a *OpenAIAdapter) convertMessages(messages []llm.Message) []openai.ChatCom
 			openaiMessages = append(openaiMessages, openai.AssistantMessage(m.Content))
 		case *llm.SystemMessage:
 			openaiMessages = append(openaiMessages, openai.SystemMessage(m.Content))
+		case *llm.DeveloperMessage:
+			openaiMessages = append(openaiMessages, openai.DeveloperMessage(m.Content))
 
-		case *llm.ToolCallMessage:
-			// Convert tool call to assistant message with tool_calls
+		case *llm.AudioMessage:
+			// Previous audio responses are referenced by their ID
+			if m.ID == "" {
+				continue
+			}
 			asst := openai.ChatCompletionAssistantMessageParam{
-				Role: "assistant",
-				ToolCalls: []openai.ChatCompletionMessageToolCallUnionParam{
-					{
-						OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
-							ID: m.ToolCall.ID,
-							Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
-								Name:      m.ToolCall.Name,
-								Arguments: string(m.ToolCall.Args),
-							},
-							Type: "function",
-						},
+				Audio: openai.ChatCompletionAssistantMessageParamAudio{ID: m.ID},
+			}
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessageParamUnion{OfAssistant: &asst})
+
+		case *llm.ToolCallMessage:
+			toolCall := openai.ChatCompletionMessageToolCallUnionParam{
+				OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+					ID: m.ToolCall.ID,
+					Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+						Name:      m.ToolCall.Name,
+						Arguments: string(m.ToolCall.Args),
 					},
+					Type: "function",
 				},
 			}
+
+			// Parallel calls of one turn share a single assistant message along with the text the model
+			// wrote before calling them, OpenAI expects the tool messages answering them right after it
+			if last := lastAssistantTurn(openaiMessages); last != nil {
+				last.ToolCalls = append(last.ToolCalls, toolCall)
+				continue
+			}
+
+			asst := openai.ChatCompletionAssistantMessageParam{
+				Role:      "assistant",
+				ToolCalls: []openai.ChatCompletionMessageToolCallUnionParam{toolCall},
+			}
 			openaiMessages = append(openaiMessages, openai.ChatCompletionMessageParamUnion{OfAssistant: &asst})
 
 		case *llm.ToolResultMessage:
@@ -160,6 +565,20 @@ func (a *OpenAIAdapter) convertMessages(messages []llm.Message) []openai.ChatCom
 	return openaiMessages
 }
 
+// lastAssistantTurn returns the last message when it is an assistant message carrying tool calls or text
+func lastAssistantTurn(messages []openai.ChatCompletionMessageParamUnion) *openai.ChatCompletionAssistantMessageParam {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	last := messages[len(messages)-1].OfAssistant
+	if last == nil || (len(last.ToolCalls) == 0 && !last.Content.OfString.Valid()) {
+		return nil
+	}
+
+	return last
+}
+
 // convertTools converts our Tool interface to OpenAI's format
 func (a *OpenAIAdapter) convertTools(tools []llm.Tool) []openai.ChatCompletionToolUnionParam {
 	var openaiTools []openai.ChatCompletionToolUnionParam
@@ -184,3 +603,19 @@ func (a *OpenAIAdapter) convertTools(tools []llm.Tool) []openai.ChatCompletionTo
 
 	return openaiTools
 }
+
+// Close is a no-op, the OpenAI client holds no resources to release
+func (a *OpenAIAdapter) Close() error {
+	return nil
+}
+
+// Capabilities reports the features supported by the OpenAI adapter
+func (a *OpenAIAdapter) Capabilities() llm.Capabilities {
+	return llm.Capabilities{
+		Streaming:         true,
+		ForcedTools:       true,
+		JSONMode:          true,
+		StructuredOutput:  true,
+		ParallelToolCalls: true,
+	}
+}
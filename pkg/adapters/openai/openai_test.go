@@ -0,0 +1,663 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v2/option"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func TestBuildMessagesDropsOrphanedToolResults(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	orphan := &llm.ToolCall{ID: "call_trimmed", Name: "calculator", Args: json.RawMessage(`{}`)}
+	request := llm.NewLLMRequest(llm.NewHistory(
+		llm.NewToolResultMessage(orphan, json.RawMessage(`{"result": 4}`)),
+		llm.NewUserMessage("And now?"),
+	))
+
+	messages := adapter.buildMessages(request)
+
+	for _, msg := range messages {
+		if msg.OfTool != nil {
+			t.Errorf("Expected orphaned tool result not to be sent, got tool message for %s", msg.OfTool.ToolCallID)
+		}
+	}
+
+	// System prompt followed by the user message
+	if len(messages) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(messages))
+	}
+}
+
+func TestConvertMessagesToolResultDelivery(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	call := &llm.ToolCall{ID: "call_1", Name: "calculator", Args: json.RawMessage(`{"a":1}`)}
+	history := llm.NewHistory(
+		llm.NewToolCallMessage(call),
+		llm.NewToolResultMessage(call, json.RawMessage(`{"result":1}`)),
+	)
+
+	t.Run("Tool role", func(t *testing.T) {
+		messages := adapter.convertMessages(history, llm.ToolResultDeliveryToolRole)
+		if len(messages) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(messages))
+		}
+
+		if messages[0].OfAssistant == nil || len(messages[0].OfAssistant.ToolCalls) != 1 {
+			t.Errorf("Expected assistant message with a tool call")
+		}
+
+		if messages[1].OfTool == nil || messages[1].OfTool.ToolCallID != "call_1" {
+			t.Errorf("Expected tool message answering call_1")
+		}
+	})
+
+	t.Run("User role", func(t *testing.T) {
+		messages := adapter.convertMessages(history, llm.ToolResultDeliveryUserRole)
+		if len(messages) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(messages))
+		}
+
+		if messages[0].OfAssistant == nil || len(messages[0].OfAssistant.ToolCalls) != 0 {
+			t.Errorf("Expected plain assistant message without tool calls")
+		}
+
+		if messages[1].OfUser == nil {
+			t.Fatalf("Expected tool result to be delivered as a user message")
+		}
+
+		content := messages[1].OfUser.Content.OfString.Value
+		if content != `Result of tool calculator: {"result":1}` {
+			t.Errorf("Unexpected user message content: %s", content)
+		}
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	capabilities := llm.CapabilitiesOf(adapter)
+
+	if !capabilities.ForcedTools {
+		t.Error("Expected OpenAI adapter to support forced tools")
+	}
+
+	if !capabilities.ParallelToolCalls {
+		t.Error("Expected OpenAI adapter to support parallel tool calls")
+	}
+
+	if !capabilities.JSONMode {
+		t.Error("Expected OpenAI adapter to support JSON mode")
+	}
+
+	if !capabilities.StructuredOutput {
+		t.Error("Expected OpenAI adapter to support structured output")
+	}
+}
+
+func TestNewChatParamsAudioOutput(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key", WithModel("gpt-4o-audio-preview"))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	request := llm.NewLLMRequest(
+		llm.NewHistory(llm.NewUserMessage("Say hello")),
+		llm.WithAudioOutput("alloy", "wav"),
+	)
+
+	params, err := adapter.newChatParams(request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Failed to marshal params: %v", err)
+	}
+
+	var payload struct {
+		Modalities []string `json:"modalities"`
+		Audio      struct {
+			Voice  string `json:"voice"`
+			Format string `json:"format"`
+		} `json:"audio"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Failed to unmarshal params: %v", err)
+	}
+
+	if len(payload.Modalities) != 2 || payload.Modalities[0] != "text" || payload.Modalities[1] != "audio" {
+		t.Errorf("Expected text and audio modalities, got %v", payload.Modalities)
+	}
+
+	if payload.Audio.Voice != "alloy" || payload.Audio.Format != "wav" {
+		t.Errorf("Expected alloy/wav audio, got %+v", payload.Audio)
+	}
+}
+
+func TestNewChatParamsParallelToolCalls(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []llm.LLMRequestOpts
+		expected any
+	}{
+		{name: "provider default", opts: nil, expected: nil},
+		{name: "disabled", opts: []llm.LLMRequestOpts{llm.WithParallelToolCalls(false)}, expected: false},
+		{name: "enabled", opts: []llm.LLMRequestOpts{llm.WithParallelToolCalls(true)}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]llm.LLMRequestOpts{llm.WithTools(llmtest.NewMockTool("calculator"))}, tt.opts...)
+			params, err := adapter.newChatParams(llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")), opts...))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			body, err := json.Marshal(params)
+			if err != nil {
+				t.Fatalf("Failed to marshal params: %v", err)
+			}
+
+			var payload map[string]any
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatalf("Failed to unmarshal params: %v", err)
+			}
+
+			if payload["parallel_tool_calls"] != tt.expected {
+				t.Errorf("Expected parallel_tool_calls %v, got %v", tt.expected, payload["parallel_tool_calls"])
+			}
+		})
+	}
+}
+
+func TestNewChatParamsNoToolUsage(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Summarize")),
+		llm.WithTools(llmtest.NewMockTool("calculator")),
+		llm.WithToolUsage(llm.ForceNoTools()),
+	)
+	params, err := adapter.newChatParams(request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Failed to marshal params: %v", err)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Failed to unmarshal params: %v", err)
+	}
+
+	if payload["tool_choice"] != "none" {
+		t.Errorf("Expected tool_choice none, got %v", payload["tool_choice"])
+	}
+	if tools, _ := payload["tools"].([]any); len(tools) != 1 {
+		t.Errorf("Expected the tools to stay declared, got %v", payload["tools"])
+	}
+}
+
+func TestNewChatParamsJSONMode(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		request     *llm.LLMRequest
+		expectedErr bool
+	}{
+		{
+			name:    "mentioned in the system prompt",
+			request: llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("List three colors")), llm.WithSystem("Reply in JSON"), llm.WithJSONMode()),
+		},
+		{
+			name:    "mentioned in a message",
+			request: llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("List three colors as a json object")), llm.WithJSONMode()),
+		},
+		{
+			name:        "not mentioned",
+			request:     llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("List three colors")), llm.WithJSONMode()),
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := adapter.newChatParams(tt.request)
+			if tt.expectedErr {
+				if err == nil || !strings.Contains(err.Error(), `requires the word "json"`) {
+					t.Errorf("Expected a missing json mention to be rejected, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			body, err := json.Marshal(params)
+			if err != nil {
+				t.Fatalf("Failed to marshal params: %v", err)
+			}
+
+			var payload struct {
+				ResponseFormat map[string]any `json:"response_format"`
+			}
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatalf("Failed to unmarshal params: %v", err)
+			}
+
+			if payload.ResponseFormat["type"] != "json_object" {
+				t.Errorf("Expected response format json_object, got %v", payload.ResponseFormat)
+			}
+		})
+	}
+}
+
+func TestNewChatParamsAudioOutputTextOnlyModel(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key", WithModel("gpt-4o"))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	request := llm.NewLLMRequest(
+		llm.NewHistory(llm.NewUserMessage("Say hello")),
+		llm.WithModalities([]string{"text", "audio"}),
+	)
+
+	if _, err := adapter.newChatParams(request); err == nil {
+		t.Error("Expected audio output to be rejected for a text-only model")
+	}
+}
+
+func TestBuildMessagesFewShotExamples(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	call := &llm.ToolCall{ID: "example_call", Name: "calculator", Args: json.RawMessage(`{"operation": "add", "a": 1, "b": 2}`)}
+	example := llm.NewHistory(
+		llm.NewUserMessage("What is 1 plus 2?"),
+		llm.NewToolCallMessage(call),
+		llm.NewToolResultMessage(call, json.RawMessage(`{"result": 3}`)),
+	)
+
+	request := llm.NewLLMRequest(
+		llm.NewHistory(llm.NewUserMessage("What is 15 plus 27?")),
+		llm.WithSystem("You are a calculator"),
+		llm.WithFewShotExamples([]llm.History{example}),
+	)
+
+	messages := adapter.buildMessages(request)
+
+	// System, example opening, 3 example messages, example closing, real user message
+	if len(messages) != 7 {
+		t.Fatalf("Expected 7 messages, got %d", len(messages))
+	}
+
+	if messages[1].OfSystem == nil || messages[5].OfSystem == nil {
+		t.Errorf("Expected examples to be delimited by system messages")
+	}
+
+	if messages[2].OfUser == nil || messages[2].OfUser.Content.OfString.Value != "What is 1 plus 2?" {
+		t.Errorf("Expected example user message before the history")
+	}
+
+	if messages[4].OfTool == nil || messages[4].OfTool.ToolCallID != "example_call" {
+		t.Errorf("Expected example tool result before the history")
+	}
+
+	if messages[6].OfUser == nil || messages[6].OfUser.Content.OfString.Value != "What is 15 plus 27?" {
+		t.Errorf("Expected real history after the examples")
+	}
+
+	if len(request.History) != 1 {
+		t.Errorf("Expected examples not to be added to the history, got %d messages", len(request.History))
+	}
+}
+
+func TestBuildMessagesTextDelivery(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key", WithDefaultToolResultDelivery(llm.ToolResultDeliveryText))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	call := &llm.ToolCall{ID: "call_1", Name: "calculator", Args: json.RawMessage(`{"a":1}`)}
+	history := llm.NewHistory(
+		llm.NewToolCallMessage(call),
+		llm.NewToolResultMessage(call, json.RawMessage(`{"result":1}`)),
+	)
+
+	messages := adapter.buildMessages(llm.NewLLMRequest(history))
+
+	// System prompt and the rendered tool exchange
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+
+	asst := messages[1].OfAssistant
+	if asst == nil || len(asst.ToolCalls) != 0 {
+		t.Fatalf("Expected a plain assistant message")
+	}
+
+	if content := asst.Content.OfString.Value; content != `I called calculator with {"a":1} and got {"result":1}.` {
+		t.Errorf("Unexpected rendering: %s", content)
+	}
+
+	// The request can still ask for native tool messages
+	messages = adapter.buildMessages(llm.NewLLMRequest(history, llm.WithToolResultDelivery(llm.ToolResultDeliveryToolRole)))
+	if len(messages) != 3 || messages[2].OfTool == nil {
+		t.Errorf("Expected the request delivery to override the adapter default")
+	}
+}
+
+func TestAdapterClose(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	var closer io.Closer = adapter
+	if err := closer.Close(); err != nil {
+		t.Errorf("Expected Close to succeed, got %v", err)
+	}
+}
+
+func TestBaseURLAndOrganization(t *testing.T) {
+	var path, organization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		organization = r.Header.Get("OpenAI-Organization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionResponse))
+	}))
+	defer server.Close()
+
+	adapter, err := NewOpenAIAdapter("test-key",
+		WithBaseURL(server.URL+"/v1/"),
+		WithOrganization("org-123"),
+		WithClientOptions(option.WithMaxRetries(0)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	if _, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if path != "/v1/chat/completions" {
+		t.Errorf("Expected the request to go to the base URL, got %s", path)
+	}
+	if organization != "org-123" {
+		t.Errorf("Expected organization header org-123, got %q", organization)
+	}
+}
+
+func TestInvokeCaptureRaw(t *testing.T) {
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")))
+
+	adapter, _ := newRecordingAdapter(t)
+	response, err := adapter.Invoke(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.Raw != nil {
+		t.Errorf("Expected no raw response by default, got %s", response.Raw)
+	}
+
+	adapter, _ = newRecordingAdapter(t, WithCaptureRaw())
+	response, err = adapter.Invoke(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var raw struct {
+		ID      string `json:"id"`
+		Choices []any  `json:"choices"`
+	}
+	if err := json.Unmarshal(response.Raw, &raw); err != nil {
+		t.Fatalf("Expected the raw response to be JSON, got %s: %v", response.Raw, err)
+	}
+	if raw.ID == "" || len(raw.Choices) == 0 {
+		t.Errorf("Expected the provider's response body, got %s", response.Raw)
+	}
+}
+
+func TestHTTPClientHeaderAndTimeout(t *testing.T) {
+	var gateway string
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gateway = r.Header.Get("Helicone-Auth")
+		calls++
+		// The second call is too slow to finish in time
+		if calls == 2 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionResponse))
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	adapter, err := NewOpenAIAdapter("test-key",
+		WithBaseURL(server.URL),
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithHeader("Helicone-Auth", "Bearer gateway-key"),
+		WithRequestTimeout(50*time.Millisecond),
+		WithClientOptions(option.WithMaxRetries(0)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")))
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if transport.requests != 1 {
+		t.Errorf("Expected the request to go through the custom client, got %d requests", transport.requests)
+	}
+	if gateway != "Bearer gateway-key" {
+		t.Errorf("Expected the gateway header, got %q", gateway)
+	}
+
+	if _, err := adapter.Invoke(context.Background(), request); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a slow request to time out, got %v", err)
+	}
+}
+
+// countingTransport counts the requests passing through it
+type countingTransport struct {
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestConvertMessagesToolCallIDsThroughAgent(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	model := llmtest.NewScriptedLLM(
+		llmtest.CallTools(
+			&llm.ToolCall{ID: "call_a", Name: "calculator", Args: json.RawMessage(`{"a":1}`)},
+			&llm.ToolCall{ID: "call_b", Name: "calculator", Args: json.RawMessage(`{"a":2}`)},
+		),
+		llmtest.Answer("Done"),
+	)
+	tool := llm.NewGenericTool("calculator", "Calculates",
+		func(ctx context.Context, input calculatorInput) (json.RawMessage, error) {
+			return json.RawMessage(fmt.Sprintf(`{"result":%d}`, input.A)), nil
+		})
+
+	agent := llm.NewAgent(model, []llm.Tool{tool})
+	if _, err := agent.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Go")), llm.WithToolUsage(llm.AutoToolSelection()))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	messages := adapter.convertMessages(model.Requests()[1].History, llm.ToolResultDeliveryToolRole)
+	if len(messages) != 4 {
+		t.Fatalf("Expected user, assistant and two tool messages, got %d", len(messages))
+	}
+
+	asst := messages[1].OfAssistant
+	if asst == nil || len(asst.ToolCalls) != 2 {
+		t.Fatalf("Expected one assistant message with both tool calls")
+	}
+
+	for i, id := range []string{"call_a", "call_b"} {
+		if asst.ToolCalls[i].OfFunction.ID != id {
+			t.Errorf("Expected tool call %d to keep ID %s, got %s", i, id, asst.ToolCalls[i].OfFunction.ID)
+		}
+
+		toolMessage := messages[2+i].OfTool
+		if toolMessage == nil || toolMessage.ToolCallID != id {
+			t.Errorf("Expected tool message %d to answer %s, got %+v", i, id, toolMessage)
+		}
+	}
+}
+
+func TestInvokeProviderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": {"message": "Rate limit reached", "type": "requests", "code": "rate_limit_exceeded"}}`))
+	}))
+	defer server.Close()
+
+	adapter, err := NewOpenAIAdapter("test-key", WithBaseURL(server.URL+"/v1/"), WithClientOptions(option.WithMaxRetries(0)))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	_, err = adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+
+	var providerErr *llm.LLMProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("Expected LLMProviderError, got %v", err)
+	}
+	if providerErr.Provider != "OpenAI" || providerErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected OpenAI error with status 429, got %s %d", providerErr.Provider, providerErr.StatusCode)
+	}
+}
+
+func TestToolCallTurnReplaysAsOneAssistantMessage(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t)
+	transport.Responses = []string{
+		`{"id":"chatcmpl_1","object":"chat.completion","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"Let me check.",` +
+			`"tool_calls":[{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Prague\"}"}}]}}]}`,
+		chatCompletionResponse,
+	}
+
+	history := llm.NewHistory(llm.NewUserMessage("Weather in Prague?"))
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(history))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The text and the tool calls come back as one assistant message
+	if len(response.Messages) != 1 {
+		t.Fatalf("Expected a single message, got %v", response.Messages)
+	}
+	if turn, ok := response.Messages[0].(*llm.AssistantMessage); !ok || turn.Content != "Let me check." || len(turn.ToolCalls) != 1 {
+		t.Fatalf("Expected the text with its tool call, got %+v", response.Messages[0])
+	}
+	if toolCalls := response.ToolCalls(); len(toolCalls) != 1 || toolCalls[0].ID != "call_1" {
+		t.Errorf("Expected ToolCalls to include the assistant's calls, got %+v", toolCalls)
+	}
+
+	history = append(history, response.Messages...)
+	history = append(history, llm.NewToolResultMessage(response.ToolCalls()[0], json.RawMessage(`{"temp": 21}`)))
+
+	if _, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(history)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var messages []any
+	for _, message := range transport.Requests[1]["messages"].([]any) {
+		if message.(map[string]any)["role"] != "system" {
+			messages = append(messages, message)
+		}
+	}
+	if len(messages) != 3 {
+		t.Fatalf("Expected user, assistant and tool messages, got %v", messages)
+	}
+
+	assistant := messages[1].(map[string]any)
+	if assistant["content"] != "Let me check." || len(assistant["tool_calls"].([]any)) != 1 {
+		t.Errorf("Expected the text and tool call in one assistant message, got %v", assistant)
+	}
+}
+
+func TestBuildMessagesSystemAndDeveloperMessages(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	// A resumed conversation already carries the request's system prompt
+	request := llm.NewLLMRequest(llm.NewHistory(
+		llm.NewSystemMessage("You are a travel agent."),
+		llm.NewDeveloperMessage("Prefer direct flights."),
+		llm.NewSystemMessage("The user is based in Prague."),
+		llm.NewUserMessage("Find me a flight"),
+	), llm.WithSystem("You are a travel agent."))
+
+	messages := adapter.buildMessages(request)
+	if len(messages) != 4 {
+		t.Fatalf("Expected the system prompt not to be duplicated, got %d messages", len(messages))
+	}
+
+	if messages[0].OfSystem == nil || messages[0].OfSystem.Content.OfString.Value != "You are a travel agent." {
+		t.Errorf("Expected the system prompt first, got %+v", messages[0])
+	}
+	if messages[1].OfDeveloper == nil || messages[1].OfDeveloper.Content.OfString.Value != "Prefer direct flights." {
+		t.Errorf("Expected a developer message second, got %+v", messages[1])
+	}
+	if messages[2].OfSystem == nil || messages[2].OfSystem.Content.OfString.Value != "The user is based in Prague." {
+		t.Errorf("Expected the second system message third, got %+v", messages[2])
+	}
+}
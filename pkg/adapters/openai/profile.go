@@ -0,0 +1,175 @@
+package openai
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go/v2/option"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// ModelProfile describes the request parameters a model does not accept, so they can be
+// caught before the API answers with an opaque 400
+type ModelProfile struct {
+	// Unsupported lists the rejected parameters by API name, e.g. "temperature"
+	Unsupported []string
+
+	// Strip drops unsupported parameters with a warning instead of failing the request
+	Strip bool
+}
+
+// reasoningProfile covers the o-series reasoning models, which reject sampling parameters
+var reasoningProfile = ModelProfile{
+	Unsupported: []string{"temperature", "top_p", "presence_penalty", "frequency_penalty", "logprobs", "top_logprobs", "logit_bias", "stop"},
+}
+
+// defaultModelProfiles are matched by model name or name prefix, e.g. "o3" covers "o3-mini"
+var defaultModelProfiles = map[string]ModelProfile{
+	"o1": reasoningProfile,
+	"o3": reasoningProfile,
+	"o4": reasoningProfile,
+}
+
+// WithModelProfile registers the profile of a model, matched by name or name prefix like the
+// built-in profiles, which it takes precedence over
+func WithModelProfile(model string, profile ModelProfile) OpenAIAdapterOpts {
+	return func(a *OpenAIAdapter) {
+		if a.profiles == nil {
+			a.profiles = make(map[string]ModelProfile)
+		}
+		a.profiles[model] = profile
+	}
+}
+
+// profile returns the profile of the adapter's model, if any
+func (a *OpenAIAdapter) profile() (ModelProfile, bool) {
+	if profile, ok := matchProfile(a.profiles, a.model); ok {
+		return profile, true
+	}
+
+	return matchProfile(defaultModelProfiles, a.model)
+}
+
+// matchProfile finds the profile registered for the model or its longest matching prefix
+func matchProfile(profiles map[string]ModelProfile, model string) (ModelProfile, bool) {
+	best := ""
+	for name := range profiles {
+		if (model == name || strings.HasPrefix(model, name+"-")) && len(name) > len(best) {
+			best = name
+		}
+	}
+
+	profile, ok := profiles[best]
+	return profile, ok && best != ""
+}
+
+// applyProfile checks the request against the model's profile, returning a copy without the
+// unsupported parameters when the profile strips them
+func (a *OpenAIAdapter) applyProfile(request *llm.LLMRequest) (*llm.LLMRequest, error) {
+	profile, ok := a.profile()
+	if !ok {
+		return request, nil
+	}
+
+	var unsupported []string
+	for _, param := range requestParams(request) {
+		if slices.Contains(profile.Unsupported, param) {
+			unsupported = append(unsupported, param)
+		}
+	}
+
+	if len(unsupported) == 0 {
+		return request, nil
+	}
+
+	if !profile.Strip {
+		return nil, fmt.Errorf("model %s does not support %s", a.model, strings.Join(unsupported, ", "))
+	}
+
+	slog.Warn("Dropping parameters not supported by the model", "model", a.model, "params", unsupported)
+
+	stripped := request.Clone()
+	stripped.ModelParams = maps.Clone(request.ModelParams)
+	for _, param := range unsupported {
+		switch param {
+		case "temperature":
+			stripped.Temperature = nil
+		case "top_p":
+			stripped.TopP = nil
+		case "max_completion_tokens":
+			stripped.MaxCompletionTokens = 0
+		case "seed":
+			stripped.Seed = nil
+		case "stop":
+			stripped.Stop = nil
+		case "frequency_penalty":
+			stripped.FrequencyPenalty = 0
+		case "presence_penalty":
+			stripped.PresencePenalty = 0
+		case "logprobs", "top_logprobs":
+			stripped.TopLogprobs = nil
+		}
+		delete(stripped.ModelParams, param)
+	}
+
+	return stripped, nil
+}
+
+// requestParams lists the API names of the parameters set on the request
+func requestParams(request *llm.LLMRequest) []string {
+	var params []string
+
+	if request.Temperature != nil {
+		params = append(params, "temperature")
+	}
+	if request.TopP != nil {
+		params = append(params, "top_p")
+	}
+	if request.MaxCompletionTokens > 0 {
+		params = append(params, "max_completion_tokens")
+	}
+	if request.Seed != nil {
+		params = append(params, "seed")
+	}
+	if len(request.Stop) > 0 {
+		params = append(params, "stop")
+	}
+	if request.FrequencyPenalty != 0 {
+		params = append(params, "frequency_penalty")
+	}
+	if request.PresencePenalty != 0 {
+		params = append(params, "presence_penalty")
+	}
+	if request.TopLogprobs != nil {
+		params = append(params, "logprobs")
+		if *request.TopLogprobs > 0 {
+			params = append(params, "top_logprobs")
+		}
+	}
+
+	for param := range request.ModelParams {
+		if !slices.Contains(params, param) {
+			params = append(params, param)
+		}
+	}
+	sort.Strings(params)
+
+	return params
+}
+
+// modelParamOptions passes the request's pass-through parameters into the request body
+func modelParamOptions(params map[string]any) []option.RequestOption {
+	keys := slices.Sorted(maps.Keys(params))
+
+	opts := make([]option.RequestOption, len(keys))
+	for i, key := range keys {
+		opts[i] = option.WithJSONSet(key, params[key])
+	}
+
+	return opts
+}
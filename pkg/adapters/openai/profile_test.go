@@ -0,0 +1,426 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v2/option"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+const chatCompletionResponse = `{"id":"chatcmpl_1","object":"chat.completion","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Hi"}}],"usage":{"prompt_tokens":9,"completion_tokens":3,"total_tokens":12}}`
+
+func newRecordingAdapter(t *testing.T, opts ...OpenAIAdapterOpts) (*OpenAIAdapter, *llmtest.RecordingTransport) {
+	transport := llmtest.NewRecordingTransport(chatCompletionResponse)
+
+	opts = append(opts, WithClientOptions(
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+		option.WithMaxRetries(0),
+	))
+
+	adapter, err := NewOpenAIAdapter("test-key", opts...)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	return adapter, transport
+}
+
+func TestReasoningModelProfileRejectsTemperature(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, WithModel("o3-mini"))
+
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")), llm.WithTemperature(0.7))
+
+	_, err := adapter.Invoke(context.Background(), request)
+	if err == nil || !strings.Contains(err.Error(), "model o3-mini does not support temperature") {
+		t.Fatalf("Expected temperature to be rejected, got %v", err)
+	}
+
+	if len(transport.Requests) != 0 {
+		t.Errorf("Expected no API call for a rejected request")
+	}
+}
+
+func TestModelProfileStripsUnsupportedParams(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t,
+		WithModel("my-local-model"),
+		WithModelProfile("my-local-model", ModelProfile{Unsupported: []string{"temperature", "seed"}, Strip: true}),
+	)
+
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")),
+		llm.WithTemperature(0.7),
+		llm.WithModelParams(map[string]any{"seed": 42, "reasoning_effort": "low"}),
+	)
+
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body := transport.Requests[0]
+	if _, ok := body["temperature"]; ok {
+		t.Errorf("Expected temperature to be stripped, got %v", body["temperature"])
+	}
+	if _, ok := body["seed"]; ok {
+		t.Errorf("Expected seed to be stripped, got %v", body["seed"])
+	}
+	if body["reasoning_effort"] != "low" {
+		t.Errorf("Expected supported params to pass through, got %v", body["reasoning_effort"])
+	}
+
+	// The caller's request is left untouched
+	if request.Temperature == nil || *request.Temperature != 0.7 || request.ModelParams["seed"] != 42 {
+		t.Errorf("Expected the original request to keep its params")
+	}
+}
+
+func TestModelProfileMatching(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key", WithModel("o1-preview"))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	if _, ok := adapter.profile(); !ok {
+		t.Error("Expected o1-preview to match the o1 profile")
+	}
+
+	adapter.model = "o100"
+	if _, ok := adapter.profile(); ok {
+		t.Error("Expected o100 not to match the o1 profile")
+	}
+
+	adapter.model = "gpt-4o"
+	if _, ok := adapter.profile(); ok {
+		t.Error("Expected gpt-4o to have no profile")
+	}
+}
+
+func TestInvokeContentFilteredResponse(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t)
+	transport.Responses = []string{`{"id":"chatcmpl_2","object":"chat.completion","choices":[{"index":0,"finish_reason":"content_filter","message":{"role":"assistant","content":""}}]}`}
+
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.Blocked == nil || response.Blocked.Reason != "content_filter" {
+		t.Errorf("Expected a content filter block, got %+v", response.Blocked)
+	}
+
+	if len(response.Messages) != 0 {
+		t.Errorf("Expected no messages for a blocked response, got %d", len(response.Messages))
+	}
+}
+
+func TestInvokeSendsSamplingParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []llm.LLMRequestOpts
+		temperature any
+		maxTokens   any
+	}{
+		{
+			name:        "explicit zero temperature and token limit",
+			opts:        []llm.LLMRequestOpts{llm.WithTemperature(0.0), llm.WithMaxCompletionTokens(1000)},
+			temperature: 0.0,
+			maxTokens:   1000.0,
+		},
+		{
+			name: "unset values are omitted",
+			opts: []llm.LLMRequestOpts{llm.WithMaxCompletionTokens(0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, transport := newRecordingAdapter(t)
+
+			request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Plan my trip")), tt.opts...)
+			if _, err := adapter.Invoke(context.Background(), request); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			body := transport.Requests[0]
+			if temperature, ok := body["temperature"]; temperature != tt.temperature || ok != (tt.temperature != nil) {
+				t.Errorf("Expected temperature %v, got %v", tt.temperature, temperature)
+			}
+			if maxTokens, ok := body["max_completion_tokens"]; maxTokens != tt.maxTokens || ok != (tt.maxTokens != nil) {
+				t.Errorf("Expected max_completion_tokens %v, got %v", tt.maxTokens, maxTokens)
+			}
+		})
+	}
+}
+
+func TestInvokeSendsStopSequences(t *testing.T) {
+	tests := []struct {
+		name     string
+		stop     []string
+		expected any
+	}{
+		{"single sequence", []string{"###"}, "###"},
+		{"several sequences", []string{"###", "END"}, []any{"###", "END"}},
+		{"no sequences", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, transport := newRecordingAdapter(t)
+
+			request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Extract the names")), llm.WithStop(tt.stop...))
+			if _, err := adapter.Invoke(context.Background(), request); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			stop, ok := transport.Requests[0]["stop"]
+			if !reflect.DeepEqual(stop, tt.expected) || ok != (tt.expected != nil) {
+				t.Errorf("Expected stop %v, got %v", tt.expected, stop)
+			}
+		})
+	}
+}
+
+func TestInvokeSendsSeed(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t)
+	transport.Responses = []string{`{"id":"chatcmpl_1","object":"chat.completion","system_fingerprint":"fp_44709d6fcb","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Hi"}}]}`}
+
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")), llm.WithSeed(42)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if seed := transport.Requests[0]["seed"]; seed != 42.0 {
+		t.Errorf("Expected seed 42, got %v", seed)
+	}
+	if response.SystemFingerprint != "fp_44709d6fcb" {
+		t.Errorf("Expected system fingerprint fp_44709d6fcb, got %q", response.SystemFingerprint)
+	}
+}
+
+func TestInvokeSendsPenalties(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []llm.LLMRequestOpts
+		frequency any
+		presence  any
+	}{
+		{"both penalties", []llm.LLMRequestOpts{llm.WithFrequencyPenalty(0.5), llm.WithPresencePenalty(-0.3)}, 0.5, -0.3},
+		{"frequency only", []llm.LLMRequestOpts{llm.WithFrequencyPenalty(1.2)}, 1.2, nil},
+		{"unset", nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, transport := newRecordingAdapter(t)
+
+			request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Write a poem")), tt.opts...)
+			if _, err := adapter.Invoke(context.Background(), request); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			frequency, ok := transport.Requests[0]["frequency_penalty"]
+			if frequency != tt.frequency || ok != (tt.frequency != nil) {
+				t.Errorf("Expected frequency penalty %v, got %v", tt.frequency, frequency)
+			}
+
+			presence, ok := transport.Requests[0]["presence_penalty"]
+			if presence != tt.presence || ok != (tt.presence != nil) {
+				t.Errorf("Expected presence penalty %v, got %v", tt.presence, presence)
+			}
+		})
+	}
+}
+
+func TestInvokeLogprobs(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t)
+	transport.Responses = []string{`{"id":"chatcmpl_1","object":"chat.completion","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Paris"},"logprobs":{"content":[{"token":"Par","logprob":-0.01,"bytes":null,"top_logprobs":[{"token":"Par","logprob":-0.01,"bytes":null},{"token":"Lyon","logprob":-4.6,"bytes":null}]},{"token":"is","logprob":-0.002,"bytes":null,"top_logprobs":[]}],"refusal":null}}]}`}
+
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Capital of France?")), llm.WithLogprobs(2)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body := transport.Requests[0]
+	if body["logprobs"] != true || body["top_logprobs"] != 2.0 {
+		t.Errorf("Expected logprobs with 2 alternatives, got %v and %v", body["logprobs"], body["top_logprobs"])
+	}
+
+	expected := []llm.TokenLogprob{
+		{Token: "Par", Logprob: -0.01, Alternatives: []llm.TokenLogprob{{Token: "Par", Logprob: -0.01}, {Token: "Lyon", Logprob: -4.6}}},
+		{Token: "is", Logprob: -0.002},
+	}
+	if !reflect.DeepEqual(response.Logprobs, expected) {
+		t.Errorf("Expected logprobs %v, got %v", expected, response.Logprobs)
+	}
+}
+
+func TestInvokeLogprobsUnset(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []llm.LLMRequestOpts
+		logprobs    any
+		topLogprobs any
+	}{
+		{"unset", nil, nil, nil},
+		{"without alternatives", []llm.LLMRequestOpts{llm.WithLogprobs(0)}, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, transport := newRecordingAdapter(t)
+
+			response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")), tt.opts...))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if logprobs := transport.Requests[0]["logprobs"]; logprobs != tt.logprobs {
+				t.Errorf("Expected logprobs %v, got %v", tt.logprobs, logprobs)
+			}
+			if topLogprobs, ok := transport.Requests[0]["top_logprobs"]; ok {
+				t.Errorf("Expected no top_logprobs, got %v", topLogprobs)
+			}
+			if response.Logprobs != nil {
+				t.Errorf("Expected no logprobs in the response, got %v", response.Logprobs)
+			}
+		})
+	}
+}
+
+func TestReasoningModelProfileRejectsPenalties(t *testing.T) {
+	adapter, _ := newRecordingAdapter(t, WithModel("o3"))
+
+	_, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")), llm.WithPresencePenalty(0.5)))
+	if err == nil || !strings.Contains(err.Error(), "model o3 does not support presence_penalty") {
+		t.Errorf("Expected unsupported presence_penalty error, got %v", err)
+	}
+}
+
+func TestModelProfileStripsSeed(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t,
+		WithModel("my-local-model"),
+		WithModelProfile("my-local-model", ModelProfile{Unsupported: []string{"seed"}, Strip: true}),
+	)
+
+	if _, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")), llm.WithSeed(42))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if seed, ok := transport.Requests[0]["seed"]; ok {
+		t.Errorf("Expected seed to be stripped, got %v", seed)
+	}
+}
+
+func TestReasoningModelProfileRejectsStop(t *testing.T) {
+	adapter, _ := newRecordingAdapter(t, WithModel("o3"))
+
+	_, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")), llm.WithStop("###")))
+	if err == nil || !strings.Contains(err.Error(), "model o3 does not support stop") {
+		t.Errorf("Expected stop to be rejected, got %v", err)
+	}
+}
+
+func TestInvokeReportsUsage(t *testing.T) {
+	adapter, _ := newRecordingAdapter(t)
+
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := llm.Usage{PromptTokens: 9, CompletionTokens: 3, TotalTokens: 12}
+	if response.Usage == nil || *response.Usage != expected {
+		t.Errorf("Expected usage %+v, got %+v", expected, response.Usage)
+	}
+
+	if response.FinishReason != "stop" {
+		t.Errorf("Expected finish reason stop, got %q", response.FinishReason)
+	}
+}
+
+func TestInvokeWithResponseSchema(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"],"additionalProperties":false}`)
+	adapter, transport := newRecordingAdapter(t, WithResponseSchema("destination", schema))
+	transport.Responses = []string{`{"id":"chatcmpl_1","object":"chat.completion","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"{\"city\":\"Prague\"}"}}]}`}
+
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Where to?"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	format, _ := transport.Requests[0]["response_format"].(map[string]any)
+	if format["type"] != "json_schema" {
+		t.Fatalf("Expected a json_schema response format, got %v", transport.Requests[0]["response_format"])
+	}
+	jsonSchema, _ := format["json_schema"].(map[string]any)
+	if jsonSchema["name"] != "destination" || jsonSchema["strict"] != true {
+		t.Errorf("Expected a strict schema named destination, got %v", jsonSchema)
+	}
+	if _, ok := jsonSchema["schema"].(map[string]any)["properties"]; !ok {
+		t.Errorf("Expected the schema to be sent, got %v", jsonSchema["schema"])
+	}
+
+	if _, ok := transport.Requests[0]["tools"]; ok {
+		t.Errorf("Expected no tools to be sent, got %v", transport.Requests[0]["tools"])
+	}
+
+	msg, ok := response.Messages[0].(*llm.AssistantMessage)
+	if !ok || msg.Content != `{"city":"Prague"}` {
+		t.Errorf("Expected the JSON as an assistant message, got %v", response.Messages)
+	}
+}
+
+func TestResponseSchemaRejectedForUnsupportedModel(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object"}`)
+	adapter, transport := newRecordingAdapter(t, WithModel("gpt-4-turbo"), WithResponseSchema("out", schema))
+
+	_, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+	if err == nil || !strings.Contains(err.Error(), "model gpt-4-turbo does not support structured outputs") {
+		t.Fatalf("Expected structured outputs to be rejected, got %v", err)
+	}
+
+	if len(transport.Requests) != 0 {
+		t.Errorf("Expected no API call for a rejected request")
+	}
+}
+
+func TestInvokeReportsRefusal(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t, WithResponseSchema("out", json.RawMessage(`{"type":"object"}`)))
+	transport.Responses = []string{`{"id":"chatcmpl_1","object":"chat.completion","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"","refusal":"I can't help with that"}}]}`}
+
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.Blocked == nil || response.Blocked.Reason != "refusal" {
+		t.Errorf("Expected the refusal to be reported as blocked, got %+v", response.Blocked)
+	}
+	if response.Blocked != nil && response.Blocked.Message != "I can't help with that" {
+		t.Errorf("Expected the refusal text, got %q", response.Blocked.Message)
+	}
+}
+
+func TestAgentReturnsRefusalError(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t)
+	transport.Responses = []string{`{"id":"chatcmpl_1","object":"chat.completion","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"","refusal":"I can't help with that"}}]}`}
+
+	agent := llm.NewAgent(adapter, nil, llm.WithRetryOnEmptyResponse(2))
+	_, err := agent.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+
+	var refusal *llm.RefusalError
+	if !errors.As(err, &refusal) || refusal.Refusal != "I can't help with that" {
+		t.Fatalf("Expected a RefusalError, got %v", err)
+	}
+
+	// A refusal is final, it isn't retried like an empty response
+	if len(transport.Requests) != 1 {
+		t.Errorf("Expected a single call, got %d", len(transport.Requests))
+	}
+}
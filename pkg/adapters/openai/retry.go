@@ -0,0 +1,102 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	openai "github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/option"
+)
+
+// WithMaxAPIRetries retries chat completions failing with a rate limit or server error up to the given
+// number of times, replacing the client's own retries. Unlike the agent's retries, which correct failed
+// tool calls, these repeat the same request.
+func WithMaxAPIRetries(maxRetries int) OpenAIAdapterOpts {
+	return func(a *OpenAIAdapter) {
+		a.apiRetries = maxRetries
+	}
+}
+
+// WithAPIRetryBackoff sets the delay before the first API retry and the exponential backoff multiplier
+// between retries. A Retry-After header sent by the API takes precedence.
+func WithAPIRetryBackoff(delay time.Duration, backoff float64) OpenAIAdapterOpts {
+	return func(a *OpenAIAdapter) {
+		a.apiRetryDelay = delay
+		a.apiRetryBackoff = backoff
+	}
+}
+
+// newChatCompletion creates the chat completion, retrying transient API errors when configured
+func (a *OpenAIAdapter) newChatCompletion(ctx context.Context, requestID string, params openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error) {
+	if a.apiRetries <= 0 {
+		return a.client.Chat.Completions.New(ctx, params, opts...)
+	}
+
+	// Retries are ours, the client must not retry on its own
+	opts = append(opts, option.WithMaxRetries(0))
+
+	delay := a.apiRetryDelay
+	for attempt := 0; ; attempt++ {
+		resp, err := a.client.Chat.Completions.New(ctx, params, opts...)
+		if err == nil || attempt == a.apiRetries || !isTransientAPIError(err) {
+			return resp, err
+		}
+
+		wait := delay
+		if retryAfter, ok := retryAfter(err); ok {
+			wait = retryAfter
+		}
+
+		slog.Warn("OpenAI API call failed, retrying",
+			"request_id", requestID,
+			"attempt", attempt+1,
+			"max_retries", a.apiRetries,
+			"delay", wait,
+			"error", err.Error(),
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+			delay = time.Duration(float64(delay) * a.apiRetryBackoff)
+		}
+	}
+}
+
+// isTransientAPIError reports whether the API call failed with a rate limit or server error
+func isTransientAPIError(err error) bool {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+}
+
+// retryAfter returns how long the API asked to wait before retrying, given in seconds or as an HTTP date
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return 0, false
+	}
+
+	value := apiErr.Response.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,168 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// flakyServer fails the first calls with the given statuses before answering normally
+func flakyServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= len(statuses) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statuses[calls-1])
+			w.Write([]byte(`{"error":{"message":"try again","type":"server_error"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionResponse))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func TestInvokeRetriesTransientAPIErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		statuses      []int
+		maxRetries    int
+		expectedCalls int
+		expectedErr   bool
+	}{
+		{"rate limit", []int{http.StatusTooManyRequests}, 2, 2, false},
+		{"server errors", []int{http.StatusInternalServerError, http.StatusServiceUnavailable}, 2, 3, false},
+		{"retries exhausted", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, 2, 3, true},
+		{"bad request", []int{http.StatusBadRequest}, 2, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := flakyServer(t, nil, tt.statuses...)
+
+			adapter, err := NewOpenAIAdapter("test-key",
+				WithBaseURL(server.URL),
+				WithMaxAPIRetries(tt.maxRetries),
+				WithAPIRetryBackoff(time.Millisecond, 2),
+			)
+			if err != nil {
+				t.Fatalf("Failed to create adapter: %v", err)
+			}
+
+			_, err = adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if *calls != tt.expectedCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectedCalls, *calls)
+			}
+		})
+	}
+}
+
+func TestInvokeHonorsRetryAfter(t *testing.T) {
+	server, calls := flakyServer(t, http.Header{"Retry-After": []string{"1"}}, http.StatusTooManyRequests)
+
+	adapter, err := NewOpenAIAdapter("test-key",
+		WithBaseURL(server.URL),
+		WithMaxAPIRetries(1),
+		WithAPIRetryBackoff(time.Millisecond, 2),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected to wait for Retry-After, retried after %v", elapsed)
+	}
+	if *calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", *calls)
+	}
+}
+
+func TestInvokeAPIRetryCancellation(t *testing.T) {
+	server, calls := flakyServer(t, nil, http.StatusServiceUnavailable)
+
+	adapter, err := NewOpenAIAdapter("test-key",
+		WithBaseURL(server.URL),
+		WithMaxAPIRetries(3),
+		WithAPIRetryBackoff(time.Minute, 2),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = adapter.Invoke(ctx, llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("Expected a single call, got %d", *calls)
+	}
+}
+
+func TestInvokeSendsRequestIDAndIdempotencyKey(t *testing.T) {
+	var requestIDs, keys []string
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		requestIDs = append(requestIDs, r.Header.Get("X-Client-Request-Id"))
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"message":"try again","type":"server_error"}}`))
+			return
+		}
+		w.Write([]byte(chatCompletionResponse))
+	}))
+	defer server.Close()
+
+	adapter, err := NewOpenAIAdapter("test-key",
+		WithBaseURL(server.URL),
+		WithMaxAPIRetries(1),
+		WithAPIRetryBackoff(time.Millisecond, 2),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")), llm.WithRequestID("req-42"), llm.WithIdempotencyKey("job-7-1"))
+	if _, err := adapter.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The retry repeats the same call, so it keeps the key
+	for i := range calls {
+		if requestIDs[i] != "req-42" || keys[i] != "job-7-1" {
+			t.Errorf("Expected attempt %d to send request ID req-42 and key job-7-1, got %q and %q", i+1, requestIDs[i], keys[i])
+		}
+	}
+
+	if _, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requestIDs[2] != "" || keys[2] != "" {
+		t.Errorf("Expected no headers when unset, got %q and %q", requestIDs[2], keys[2])
+	}
+}
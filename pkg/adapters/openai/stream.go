@@ -0,0 +1,181 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	openai "github.com/openai/openai-go/v2"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// InvokeStream implements the StreamingLLM interface using OpenAI's streamed chat completions.
+// Tool call fragments are forwarded as they arrive, keyed by their index, so that
+// llm.StreamAccumulator (or llm.CollectStream) can assemble the arguments. Each call is also
+// assembled here and emitted whole once its arguments are complete; a call whose arguments
+// never become valid JSON fails the stream instead.
+func (a *OpenAIAdapter) InvokeStream(ctx context.Context, request *llm.LLMRequest) (<-chan llm.StreamEvent, error) {
+	request, err := a.applyProfile(request)
+	if err != nil {
+		return nil, err
+	}
+
+	chatReq, err := a.newChatParams(request)
+	if err != nil {
+		return nil, err
+	}
+	chatReq.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+	stream := a.client.Chat.Completions.NewStreaming(ctx, chatReq, requestOptions(request)...)
+
+	events := make(chan llm.StreamEvent)
+	go func() {
+		defer close(events)
+		defer stream.Close()
+
+		send := func(event llm.StreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var finishReason string
+		var usage *llm.Usage
+		calls := &toolCallAssembler{}
+
+		// emit sends the assembled tool calls as complete events, failing the stream on broken arguments
+		emit := func(toolCalls []*llm.ToolCall, err error) bool {
+			if err != nil {
+				send(llm.StreamEvent{Type: llm.StreamEventError, Err: err})
+				return false
+			}
+			for _, toolCall := range toolCalls {
+				if !send(llm.StreamEvent{Type: llm.StreamEventToolCall, ToolCall: toolCall}) {
+					return false
+				}
+			}
+			return true
+		}
+
+		for stream.Next() {
+			chunk := stream.Current()
+
+			if chunk.Usage.TotalTokens > 0 {
+				usage = &llm.Usage{
+					PromptTokens:     int(chunk.Usage.PromptTokens),
+					CompletionTokens: int(chunk.Usage.CompletionTokens),
+					TotalTokens:      int(chunk.Usage.TotalTokens),
+				}
+			}
+
+			for _, choice := range chunk.Choices {
+				if choice.Delta.Content != "" {
+					if !send(llm.StreamEvent{Type: llm.StreamEventTextDelta, Text: choice.Delta.Content}) {
+						return
+					}
+				}
+
+				for _, toolCall := range choice.Delta.ToolCalls {
+					delta := &llm.ToolCallDelta{
+						Index:     int(toolCall.Index),
+						ID:        toolCall.ID,
+						Name:      toolCall.Function.Name,
+						ArgsDelta: toolCall.Function.Arguments,
+					}
+					// Calls are streamed one after another, a new index completes the previous call
+					if !emit(calls.add(delta)) {
+						return
+					}
+					if !send(llm.StreamEvent{Type: llm.StreamEventToolCallDelta, ToolCallDelta: delta}) {
+						return
+					}
+				}
+
+				if choice.FinishReason != "" {
+					finishReason = choice.FinishReason
+					if !emit(calls.flush()) {
+						return
+					}
+				}
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := stream.Err(); err != nil {
+			send(llm.StreamEvent{Type: llm.StreamEventError, Err: providerError(err)})
+			return
+		}
+
+		// A stream closed before any choice finished was cut off
+		if finishReason == "" {
+			send(llm.StreamEvent{Type: llm.StreamEventError, Err: fmt.Errorf("OpenAI stream ended early: %w", io.ErrUnexpectedEOF)})
+			return
+		}
+
+		send(llm.StreamEvent{Type: llm.StreamEventDone, FinishReason: finishReason, Usage: usage})
+	}()
+
+	return events, nil
+}
+
+// toolCallAssembler concatenates streamed tool call fragments by index into complete tool calls
+type toolCallAssembler struct {
+	current *llm.ToolCallDelta
+	args    strings.Builder
+}
+
+// add merges the fragment into its call, returning the previous call if the fragment starts a new one
+func (a *toolCallAssembler) add(delta *llm.ToolCallDelta) ([]*llm.ToolCall, error) {
+	var completed []*llm.ToolCall
+	if a.current != nil && a.current.Index != delta.Index {
+		var err error
+		if completed, err = a.flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.current == nil {
+		a.current = &llm.ToolCallDelta{Index: delta.Index}
+	}
+	if delta.ID != "" {
+		a.current.ID = delta.ID
+	}
+	if delta.Name != "" {
+		a.current.Name = delta.Name
+	}
+	a.args.WriteString(delta.ArgsDelta)
+
+	return completed, nil
+}
+
+// flush completes the call being assembled, if any. Arguments that don't parse as JSON are an error,
+// e.g. when the completion ran out of tokens in the middle of them.
+func (a *toolCallAssembler) flush() ([]*llm.ToolCall, error) {
+	if a.current == nil {
+		return nil, nil
+	}
+
+	args := a.args.String()
+	if strings.TrimSpace(args) == "" {
+		args = "{}"
+	}
+
+	toolCall := &llm.ToolCall{ID: a.current.ID, Name: a.current.Name, Args: json.RawMessage(args)}
+	a.current = nil
+	a.args.Reset()
+
+	if !json.Valid(toolCall.Args) {
+		return nil, fmt.Errorf("OpenAI stream ended with incomplete arguments of tool call %s: %s", toolCall.Name, toolCall.Args)
+	}
+
+	return []*llm.ToolCall{toolCall}, nil
+}
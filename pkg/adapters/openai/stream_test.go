@@ -0,0 +1,218 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v2/option"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// sseBody renders chunks as a server-sent event stream
+func sseBody(chunks ...string) string {
+	var body strings.Builder
+	for _, chunk := range chunks {
+		body.WriteString("data: " + chunk + "\n\n")
+	}
+	return body.String()
+}
+
+func TestInvokeStream(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t)
+	transport.Responses = []string{sseBody(
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"Checking"}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":"}}]}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Prague\"}"}}]}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+		`[DONE]`,
+	)}
+
+	response, err := llm.CollectStream(context.Background(), adapter, llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Weather?"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if transport.Requests[0]["stream"] != true {
+		t.Errorf("Expected a streamed request, got %v", transport.Requests[0]["stream"])
+	}
+
+	if content := response.Messages[0].(*llm.AssistantMessage).Content; content != "Checking" {
+		t.Errorf("Expected streamed text, got %q", content)
+	}
+
+	toolCalls := response.ToolCalls()
+	if len(toolCalls) != 1 || toolCalls[0].ID != "call_1" || string(toolCalls[0].Args) != `{"city":"Prague"}` {
+		t.Fatalf("Expected the assembled tool call, got %+v", toolCalls)
+	}
+
+	if response.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %q", response.FinishReason)
+	}
+
+	if response.Usage == nil || response.Usage.TotalTokens != 15 {
+		t.Errorf("Expected usage of 15 tokens, got %+v", response.Usage)
+	}
+}
+
+func TestInvokeStreamAssemblesFragmentedToolCalls(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t)
+	transport.Responses = []string{sseBody(
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"weather","arguments":""}}]}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"ci"}}]}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"ty\": \"Pra"}}]}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"gue\"}"}}]}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"time","arguments":"{\"zone\""}}]}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"function":{"arguments":": \"CET\"}"}}]}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`[DONE]`,
+	)}
+
+	events, err := adapter.InvokeStream(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Weather and time?"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var types []llm.StreamEventType
+	var toolCalls []*llm.ToolCall
+	accumulator := llm.NewStreamAccumulator()
+	for event := range events {
+		types = append(types, event.Type)
+		accumulator.Add(event)
+		if event.Type == llm.StreamEventToolCall {
+			toolCalls = append(toolCalls, event.ToolCall)
+		}
+	}
+
+	if len(toolCalls) != 2 {
+		t.Fatalf("Expected 2 complete tool calls, got %+v", toolCalls)
+	}
+	if toolCalls[0].ID != "call_1" || toolCalls[0].Name != "weather" || string(toolCalls[0].Args) != `{"city": "Prague"}` {
+		t.Errorf("Expected the assembled weather call, got %+v", toolCalls[0])
+	}
+	if toolCalls[1].ID != "call_2" || toolCalls[1].Name != "time" || string(toolCalls[1].Args) != `{"zone": "CET"}` {
+		t.Errorf("Expected the assembled time call, got %+v", toolCalls[1])
+	}
+
+	// The first call is complete as soon as the second one starts
+	expected := []llm.StreamEventType{
+		llm.StreamEventToolCallDelta, llm.StreamEventToolCallDelta, llm.StreamEventToolCallDelta, llm.StreamEventToolCallDelta,
+		llm.StreamEventToolCall, llm.StreamEventToolCallDelta, llm.StreamEventToolCallDelta,
+		llm.StreamEventToolCall, llm.StreamEventDone,
+	}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected events %v, got %v", expected, types)
+	}
+
+	// Accumulating the deltas yields the same calls
+	assembled := accumulator.Result().ToolCalls()
+	if len(assembled) != 2 || string(assembled[0].Args) != string(toolCalls[0].Args) || string(assembled[1].Args) != string(toolCalls[1].Args) {
+		t.Errorf("Expected the accumulated calls to match, got %+v", assembled)
+	}
+}
+
+func TestInvokeStreamIncompleteToolCallArguments(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t)
+	transport.Responses = []string{sseBody(
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\": \"Pra"}}]}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"length"}]}`,
+		`[DONE]`,
+	)}
+
+	_, err := llm.CollectStream(context.Background(), adapter, llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Weather?"))))
+	if err == nil || !strings.Contains(err.Error(), "incomplete arguments of tool call weather") {
+		t.Errorf("Expected truncated arguments to fail the stream, got %v", err)
+	}
+}
+
+func TestInvokeStreamMidStreamError(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t)
+	transport.Responses = []string{sseBody(
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+		`{"error":{"message":"server overloaded","type":"server_error"}}`,
+	)}
+
+	events, err := adapter.InvokeStream(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var last llm.StreamEvent
+	for event := range events {
+		last = event
+	}
+
+	if last.Type != llm.StreamEventError || !strings.Contains(last.Err.Error(), "server overloaded") {
+		t.Errorf("Expected a terminal error event, got %+v", last)
+	}
+}
+
+func TestInvokeStreamCutOff(t *testing.T) {
+	adapter, transport := newRecordingAdapter(t)
+	transport.Responses = []string{sseBody(
+		`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+	)}
+
+	_, err := llm.CollectStream(context.Background(), adapter, llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected a cut off stream to fail with io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+// stallingTransport sends a first chunk and then keeps the stream open until the request is cancelled
+type stallingTransport struct{}
+
+func (stallingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.Write([]byte(sseBody(`{"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"Hel"}}]}`)))
+		<-req.Context().Done()
+		writer.CloseWithError(req.Context().Err())
+	}()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       reader,
+		Request:    req,
+	}, nil
+}
+
+func TestInvokeStreamCancellation(t *testing.T) {
+	adapter, err := NewOpenAIAdapter("test-key", WithClientOptions(
+		option.WithHTTPClient(&http.Client{Transport: stallingTransport{}}),
+		option.WithMaxRetries(0),
+	))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := adapter.InvokeStream(ctx, llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if event := <-events; event.Text != "Hel" {
+		t.Fatalf("Expected the first text delta, got %+v", event)
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected no further events after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the channel to close after cancellation")
+	}
+}
@@ -0,0 +1,273 @@
+package openai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	openai "github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/responses"
+	"github.com/openai/openai-go/v2/shared"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// ThreadAdapter keeps the conversation state on OpenAI's servers using the Responses API.
+// Instead of resending the full history, each call sends only the messages added since the
+// previous response and links to it by ID, which keeps payloads small for long conversations.
+//
+// This mode is specific to OpenAI: it requires responses to be stored server-side and tracks
+// a single conversation, so use one ThreadAdapter per conversation. When the history no longer
+// extends the one already sent (e.g. it was trimmed or edited), the thread is restarted with the
+// full history.
+type ThreadAdapter struct {
+	adapter *OpenAIAdapter
+
+	mu         sync.Mutex
+	responseID string
+	sent       int
+	sentHash   [sha256.Size]byte
+}
+
+// NewThreadAdapter creates an adapter maintaining a server-side thread, accepting the same options as NewOpenAIAdapter
+func NewThreadAdapter(apiKey string, opts ...OpenAIAdapterOpts) (*ThreadAdapter, error) {
+	adapter, err := NewOpenAIAdapter(apiKey, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ThreadAdapter{adapter: adapter}, nil
+}
+
+// ThreadID returns the ID of the last response in the thread, empty before the first call
+func (t *ThreadAdapter) ThreadID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.responseID
+}
+
+// Reset forgets the server-side thread, the next call starts a new one with the full history
+func (t *ThreadAdapter) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.responseID = ""
+	t.sent = 0
+	t.sentHash = [sha256.Size]byte{}
+}
+
+// Invoke sends the messages added since the last call and continues the server-side thread
+func (t *ThreadAdapter) Invoke(ctx context.Context, request *llm.LLMRequest) (*llm.LLMResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	request, err := t.adapter.applyProfile(request)
+	if err != nil {
+		return nil, err
+	}
+
+	history := llm.DropOrphanedToolResults(request.History)
+	if !t.continues(history) {
+		t.responseID = ""
+		t.sent = 0
+	}
+
+	params, err := t.newResponseParams(request, history[t.sent:])
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.adapter.client.Responses.New(ctx, params, requestOptions(request)...)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	response := convertResponseOutput(resp)
+
+	// The server holds the response messages too, the caller appends them to the history
+	sent := append(slices.Clone(history), response.Messages...)
+	t.sentHash, err = historyHash(sent)
+	if err != nil {
+		// Without a fingerprint the next call can't tell whether it continues the thread
+		t.responseID = ""
+		t.sent = 0
+		return response, nil
+	}
+
+	t.responseID = resp.ID
+	t.sent = len(sent)
+
+	return response, nil
+}
+
+// continues reports whether the history starts with exactly the messages already in the thread
+func (t *ThreadAdapter) continues(history llm.History) bool {
+	if t.sent == 0 {
+		return true
+	}
+	if len(history) < t.sent {
+		return false
+	}
+
+	hash, err := historyHash(history[:t.sent])
+	return err == nil && hash == t.sentHash
+}
+
+// historyHash fingerprints the history, catching edits that keep its length
+func historyHash(history llm.History) ([sha256.Size]byte, error) {
+	data, err := llm.MarshalHistory(history)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	return sha256.Sum256(data), nil
+}
+
+// newResponseParams translates the request into Responses API parameters carrying only the new messages
+func (t *ThreadAdapter) newResponseParams(request *llm.LLMRequest, messages llm.History) (responses.ResponseNewParams, error) {
+	params := responses.ResponseNewParams{
+		Model: shared.ResponsesModel(t.adapter.model),
+		Store: openai.Bool(true),
+		Input: responses.ResponseNewParamsInputUnion{OfInputItemList: convertInputItems(messages)},
+	}
+
+	// Instructions are not carried over from the previous response and must be sent on every call
+	if request.System != "" {
+		params.Instructions = openai.String(request.System)
+	}
+
+	if t.responseID != "" {
+		params.PreviousResponseID = openai.String(t.responseID)
+	}
+
+	if request.MaxCompletionTokens > 0 {
+		params.MaxOutputTokens = openai.Int(int64(request.MaxCompletionTokens))
+	}
+
+	if request.Temperature != nil {
+		params.Temperature = openai.Float(*request.Temperature)
+	}
+
+	if request.TopP != nil {
+		params.TopP = openai.Float(*request.TopP)
+	}
+
+	if request.ToolUsage != nil && len(request.Tools) > 0 {
+		params.Tools = convertResponseTools(request.Tools)
+
+		if forced, ok := request.ToolUsage.(*llm.ForcedToolUsage); ok {
+			tool, err := llm.FindTool(forced.ToolName, request.Tools)
+			if err != nil {
+				return params, fmt.Errorf("failed to convert tool usage: forced tool %s not available", forced.ToolName)
+			}
+
+			params.ToolChoice = responses.ResponseNewParamsToolChoiceUnion{
+				OfFunctionTool: &responses.ToolChoiceFunctionParam{Name: tool.Name()},
+			}
+		}
+
+		if request.ToolUsage.Type() == llm.ToolUsageNone {
+			params.ToolChoice = responses.ResponseNewParamsToolChoiceUnion{
+				OfToolChoiceMode: openai.Opt(responses.ToolChoiceOptionsNone),
+			}
+		}
+
+		if request.ParallelToolCalls != nil {
+			params.ParallelToolCalls = openai.Bool(*request.ParallelToolCalls)
+		}
+	}
+
+	return params, nil
+}
+
+// convertInputItems converts our messages to Responses API input items
+func convertInputItems(messages llm.History) responses.ResponseInputParam {
+	var items responses.ResponseInputParam
+
+	for _, msg := range llm.ExpandToolCalls(messages) {
+		switch m := msg.(type) {
+		case *llm.UserMessage:
+			items = append(items, responses.ResponseInputItemParamOfMessage(m.Content, responses.EasyInputMessageRoleUser))
+		case *llm.AssistantMessage:
+			items = append(items, responses.ResponseInputItemParamOfMessage(m.Content, responses.EasyInputMessageRoleAssistant))
+		case *llm.SystemMessage:
+			items = append(items, responses.ResponseInputItemParamOfMessage(m.Content, responses.EasyInputMessageRoleSystem))
+		case *llm.DeveloperMessage:
+			items = append(items, responses.ResponseInputItemParamOfMessage(m.Content, responses.EasyInputMessageRoleDeveloper))
+		case *llm.ToolCallMessage:
+			items = append(items, responses.ResponseInputItemParamOfFunctionCall(string(m.ToolCall.Args), m.ToolCall.ID, m.ToolCall.Name))
+		case *llm.ToolResultMessage:
+			items = append(items, responses.ResponseInputItemParamOfFunctionCallOutput(m.ToolCall.ID, string(m.Result)))
+		}
+	}
+
+	return items
+}
+
+// convertResponseTools converts our Tool interface to Responses API function tools
+func convertResponseTools(tools []llm.Tool) []responses.ToolUnionParam {
+	var responseTools []responses.ToolUnionParam
+
+	for _, tool := range tools {
+		var params map[string]any
+		if err := json.Unmarshal(tool.InputSchemaRaw(), &params); err != nil {
+			params = make(map[string]any)
+		}
+
+		responseTool := responses.ToolParamOfFunction(tool.Name(), params, false)
+		responseTool.OfFunction.Description = openai.String(tool.Description())
+		responseTools = append(responseTools, responseTool)
+	}
+
+	return responseTools
+}
+
+// convertResponseOutput reconstructs our response from the output items of a Responses API call
+func convertResponseOutput(resp *responses.Response) *llm.LLMResponse {
+	response := llm.NewLLMResponse()
+
+	if resp.Usage.TotalTokens > 0 {
+		response.Usage = &llm.Usage{
+			PromptTokens:     int(resp.Usage.InputTokens),
+			CompletionTokens: int(resp.Usage.OutputTokens),
+			TotalTokens:      int(resp.Usage.TotalTokens),
+		}
+	}
+
+	for _, item := range resp.Output {
+		switch item.Type {
+		case "message":
+			for _, content := range item.Content {
+				if content.Type == "output_text" && content.Text != "" {
+					response.AddMessage(&llm.AssistantMessage{Content: content.Text})
+				}
+			}
+
+		case "function_call":
+			response.AddToolCall(&llm.ToolCall{
+				ID:   item.CallID,
+				Name: item.Name,
+				Args: json.RawMessage(item.Arguments),
+			})
+		}
+	}
+
+	return response
+}
+
+// Capabilities reports the features supported by the underlying OpenAI adapter, threads are not streamed
+func (t *ThreadAdapter) Capabilities() llm.Capabilities {
+	capabilities := t.adapter.Capabilities()
+	capabilities.Streaming = false
+
+	return capabilities
+}
+
+// Close releases resources held by the adapter, see OpenAIAdapter.Close
+func (t *ThreadAdapter) Close() error {
+	return t.adapter.Close()
+}
@@ -0,0 +1,163 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/openai/openai-go/v2/option"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+type calculatorInput struct {
+	A int `json:"a"`
+}
+
+func newRecordingThreadAdapter(t *testing.T, responses ...string) (*ThreadAdapter, *llmtest.RecordingTransport) {
+	transport := llmtest.NewRecordingTransport(responses...)
+
+	adapter, err := NewThreadAdapter("test-key", WithClientOptions(
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+		option.WithMaxRetries(0),
+	))
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	return adapter, transport
+}
+
+func TestThreadAdapterSendsOnlyNewMessages(t *testing.T) {
+	adapter, transport := newRecordingThreadAdapter(t,
+		`{"id":"resp_1","object":"response","output":[{"type":"function_call","id":"fc_1","call_id":"call_1","name":"calculator","arguments":"{\"a\":1}"}]}`,
+		`{"id":"resp_2","object":"response","output":[{"type":"message","id":"msg_1","role":"assistant","content":[{"type":"output_text","text":"The answer is 1."}]}]}`,
+	)
+
+	tool := llm.NewGenericTool("calculator", "Adds numbers",
+		func(ctx context.Context, input calculatorInput) (json.RawMessage, error) { return nil, nil })
+
+	history := llm.NewHistory(llm.NewUserMessage("What is 1?"))
+	request := llm.NewLLMRequest(history, llm.WithSystem("Be brief"), llm.WithTools(tool), llm.WithToolUsage(llm.AutoToolSelection()))
+
+	response, err := adapter.Invoke(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	toolCalls := response.ToolCalls()
+	if len(toolCalls) != 1 || toolCalls[0].ID != "call_1" || toolCalls[0].Name != "calculator" {
+		t.Fatalf("Expected the calculator tool call, got %+v", toolCalls)
+	}
+
+	history = history.Append(response.Messages...)
+	history = history.Append(llm.NewToolResultMessage(toolCalls[0], json.RawMessage(`{"result":1}`)))
+
+	response, err = adapter.Invoke(context.Background(), request.Clone(llm.WithHistory(history)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if content := response.Messages[0].(*llm.AssistantMessage).Content; content != "The answer is 1." {
+		t.Errorf("Expected final answer, got %q", content)
+	}
+
+	if adapter.ThreadID() != "resp_2" {
+		t.Errorf("Expected thread to point at resp_2, got %s", adapter.ThreadID())
+	}
+
+	first, second := transport.Requests[0], transport.Requests[1]
+
+	if _, ok := first["previous_response_id"]; ok {
+		t.Errorf("Expected the first call to start a new thread")
+	}
+	if first["instructions"] != "Be brief" {
+		t.Errorf("Expected system prompt as instructions, got %v", first["instructions"])
+	}
+
+	if second["previous_response_id"] != "resp_1" {
+		t.Errorf("Expected the second call to continue resp_1, got %v", second["previous_response_id"])
+	}
+
+	// Only the tool result is new, the user message and tool call are already on the server
+	input := second["input"].([]any)
+	if len(input) != 1 {
+		t.Fatalf("Expected 1 new input item, got %d", len(input))
+	}
+
+	item := input[0].(map[string]any)
+	if item["type"] != "function_call_output" || item["call_id"] != "call_1" {
+		t.Errorf("Expected the tool result as function call output, got %v", item)
+	}
+}
+
+func TestThreadAdapterRestartsOnDivergedHistory(t *testing.T) {
+	adapter, transport := newRecordingThreadAdapter(t,
+		`{"id":"resp_1","object":"response","output":[{"type":"message","id":"msg_1","role":"assistant","content":[{"type":"output_text","text":"Hi"}]}]}`,
+		`{"id":"resp_2","object":"response","output":[]}`,
+	)
+
+	history := llm.NewHistory(llm.NewUserMessage("Hello"), llm.NewUserMessage("Are you there?"))
+	if _, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(history)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A trimmed history cannot continue the thread
+	trimmed := llm.NewHistory(llm.NewUserMessage("Start over"))
+	if _, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(trimmed)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	second := transport.Requests[1]
+	if _, ok := second["previous_response_id"]; ok {
+		t.Errorf("Expected a new thread after the history diverged")
+	}
+	if input := second["input"].([]any); len(input) != 1 {
+		t.Errorf("Expected the full trimmed history to be sent, got %d items", len(input))
+	}
+}
+
+func TestThreadAdapterRestartsOnEditedHistory(t *testing.T) {
+	adapter, transport := newRecordingThreadAdapter(t,
+		`{"id":"resp_1","object":"response","output":[{"type":"message","id":"msg_1","role":"assistant","content":[{"type":"output_text","text":"Hi"}]}]}`,
+		`{"id":"resp_2","object":"response","output":[]}`,
+	)
+
+	history := llm.NewHistory(llm.NewUserMessage("Hello"))
+	response, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(history))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The history grew, but the message the thread started with was edited
+	edited := llm.NewHistory(llm.NewUserMessage("Hello, I need help"))
+	edited = edited.Append(response.Messages...)
+	edited = edited.Append(llm.NewUserMessage("Are you there?"))
+	if _, err := adapter.Invoke(context.Background(), llm.NewLLMRequest(edited)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	second := transport.Requests[1]
+	if _, ok := second["previous_response_id"]; ok {
+		t.Errorf("Expected a new thread after the history was edited")
+	}
+	if input := second["input"].([]any); len(input) != 3 {
+		t.Errorf("Expected the full edited history to be sent, got %d items", len(input))
+	}
+}
+
+func TestThreadAdapterCapabilities(t *testing.T) {
+	adapter, err := NewThreadAdapter("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	if _, ok := llm.LLM(adapter).(llm.StreamingLLM); ok {
+		t.Fatal("Expected ThreadAdapter not to stream")
+	}
+	if llm.CapabilitiesOf(adapter).Streaming {
+		t.Error("Expected ThreadAdapter not to report streaming")
+	}
+}
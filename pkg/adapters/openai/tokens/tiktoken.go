@@ -0,0 +1,88 @@
+package tokens
+
+import (
+	"fmt"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+func init() {
+	// Load the encodings from embedded files instead of downloading them on first use
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// Tokens OpenAI's chat format adds around each message and to prime the reply, see
+// https://cookbook.openai.com/examples/how_to_count_tokens_with_tiktoken
+const (
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+)
+
+// TiktokenCounter counts tokens exactly as OpenAI models do, using the tiktoken encoding of the model
+type TiktokenCounter struct {
+	encoding *tiktoken.Tiktoken
+}
+
+// NewTiktokenCounter creates a counter for the given OpenAI model, falling back to the o200k_base
+// encoding of current models when tiktoken doesn't know the model
+func NewTiktokenCounter(model string) (*TiktokenCounter, error) {
+	encoding, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		encoding, err = tiktoken.GetEncoding(tiktoken.MODEL_O200K_BASE)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tiktoken encoding: %w", err)
+	}
+
+	return &TiktokenCounter{encoding: encoding}, nil
+}
+
+// Count implements llm.TokenCounter
+func (c *TiktokenCounter) Count(text string) int {
+	return len(c.encoding.EncodeOrdinary(text))
+}
+
+// CountMessages implements llm.TokenCounter, adding the chat format overhead of each message
+func (c *TiktokenCounter) CountMessages(messages []llm.Message) int {
+	if len(messages) == 0 {
+		return 0
+	}
+
+	tokens := tokensPerReply
+	for _, msg := range messages {
+		tokens += tokensPerMessage + c.Count(string(msg.Role())) + c.Count(messageText(msg))
+	}
+
+	return tokens
+}
+
+// messageText returns the text of a message as it is sent to OpenAI
+func messageText(msg llm.Message) string {
+	switch m := msg.(type) {
+	case *llm.UserMessage:
+		return m.Content
+	case *llm.AssistantMessage:
+		text := m.Content
+		for _, toolCall := range m.ToolCalls {
+			text += toolCall.Name + string(toolCall.Args)
+		}
+		return text
+	case *llm.SystemMessage:
+		return m.Content
+	case *llm.DeveloperMessage:
+		return m.Content
+	case *llm.AudioMessage:
+		return m.Transcript
+	case *llm.ToolCallMessage:
+		return m.ToolCall.Name + string(m.ToolCall.Args)
+	case *llm.ToolResultMessage:
+		return string(m.Result)
+	case *llm.ToolErrorMessage:
+		return m.Error
+	}
+
+	return ""
+}
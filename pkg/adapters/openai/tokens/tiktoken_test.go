@@ -0,0 +1,42 @@
+package tokens
+
+import (
+	"testing"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+func TestTiktokenCounter(t *testing.T) {
+	counter, err := NewTiktokenCounter("gpt-4o")
+	if err != nil {
+		t.Fatalf("Failed to create counter: %v", err)
+	}
+
+	if got := counter.Count("hello world"); got != 2 {
+		t.Errorf("Expected 2 tokens for 'hello world', got %d", got)
+	}
+
+	// Each message costs its content, its role and the format overhead, plus the reply priming
+	messages := []llm.Message{llm.NewSystemMessage("hello world"), llm.NewUserMessage("hello world")}
+	expected := tokensPerReply + 2*(tokensPerMessage+2) + counter.Count("system") + counter.Count("user")
+	if got := counter.CountMessages(messages); got != expected {
+		t.Errorf("Expected %d tokens, got %d", expected, got)
+	}
+
+	if got := counter.CountMessages(nil); got != 0 {
+		t.Errorf("Expected no tokens for no messages, got %d", got)
+	}
+}
+
+func TestTiktokenCounterUnknownModel(t *testing.T) {
+	counter, err := NewTiktokenCounter("my-local-model")
+	if err != nil {
+		t.Fatalf("Expected a fallback encoding, got %v", err)
+	}
+
+	if got := counter.Count("hello world"); got != 2 {
+		t.Errorf("Expected 2 tokens for 'hello world', got %d", got)
+	}
+}
+
+var _ llm.TokenCounter = (*TiktokenCounter)(nil)
@@ -17,9 +17,12 @@ func convertToolUsage(toolUsage llm.ToolUsage, tools []llm.Tool) (*openai.ChatCo
 	case llm.ToolUsageAuto:
 		return nil, nil
 
+	case llm.ToolUsageNone:
+		return &openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String("none")}, nil
+
 	case llm.ToolUsageForced:
 		if forced, ok := toolUsage.(*llm.ForcedToolUsage); ok {
-			tool, err := findTool(forced.ToolName, tools)
+			tool, err := llm.FindTool(forced.ToolName, tools)
 			if err != nil {
 				return nil, fmt.Errorf("forced tool %s not available", forced.ToolName)
 			}
@@ -33,12 +36,3 @@ func convertToolUsage(toolUsage llm.ToolUsage, tools []llm.Tool) (*openai.ChatCo
 		return nil, nil
 	}
 }
-
-func findTool(name string, tools []llm.Tool) (llm.Tool, error) {
-	for _, tool := range tools {
-		if tool.Name() == name {
-			return tool, nil
-		}
-	}
-	return nil, fmt.Errorf("tool %s not found", name)
-}
@@ -3,29 +3,94 @@ package llm
 import (
 	"bytes"
 	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	_ "embed"
 )
 
-// Agent represents an agent that can use tools and interact with an LLM
+// Agent represents an agent that can use tools and interact with an LLM. An agent holds no
+// conversation state: each call works on a copy of the request's history, so one agent can be
+// reused across conversations and called concurrently.
 type Agent struct {
-	llm   LLM
-	tools []Tool
+	llm      LLM
+	tools    []Tool
+	registry *ToolRegistry
 
 	maxRetries   int
 	retryDelay   time.Duration
 	retryBackoff float64
+	retryPrompt  RetryPromptTemplate
+
+	retryableClassifier RetryableClassifier
+
+	retryJitter   float64
+	maxRetryDelay time.Duration
+	jitterRand    *lockedRand
+
+	iterationTimeout time.Duration
+	toolTimeout      time.Duration
+	requestContext   func(ctx context.Context) context.Context
+	maxIterations    int
+
+	summarizer          LLM
+	summarizerThreshold int
+
+	historyTrimmer HistoryTrimmer
+	tokenCounter   TokenCounter
 
 	outputSchema *json.RawMessage
+
+	toolCallLimits      map[string]int
+	concurrentToolCalls int
+
+	structuredToolErrors bool
+
+	requireToolUse bool
+
+	emptyResponseRetries int
+
+	stopOnToolResult func(*ToolCall, json.RawMessage) bool
+
+	toolOutputsInPrompt bool
+
+	resultEncoder ToolResultEncoder
+
+	totalRetryBudget int
+
+	validateToolArgs  bool
+	groundFinalAnswer bool
+
+	observer AgentObserver
+
+	toolApprover ToolApprover
+
+	toolCache Cache
+
+	run *agentRun
 }
 
 // AgentOpts represents options for configuring an agent
 type AgentOpts = func(*Agent)
 
+// WithToolRegistry makes the registry's tools available to the agent next to its static tools.
+// The registry is snapshotted when Invoke starts, so a run sees a consistent set of tools
+// while changes made meanwhile are picked up by the next run.
+func WithToolRegistry(registry *ToolRegistry) AgentOpts {
+	return func(a *Agent) {
+		a.registry = registry
+	}
+}
+
 // WithMaxRetries sets the maximum number of retries for tool calls
 func WithMaxRetries(maxRetries int) AgentOpts {
 	return func(a *Agent) {
@@ -40,6 +105,14 @@ func WithRetryDelay(delay time.Duration) AgentOpts {
 	}
 }
 
+// WithTotalRetryBudget caps the retries of all tool calls within a single run, on top of the
+// per-call WithMaxRetries. Once the budget is spent, failing tool calls are no longer retried.
+func WithTotalRetryBudget(budget int) AgentOpts {
+	return func(a *Agent) {
+		a.totalRetryBudget = budget
+	}
+}
+
 // WithRetryBackoff sets the exponential backoff multiplier for retries
 func WithRetryBackoff(backoff float64) AgentOpts {
 	return func(a *Agent) {
@@ -47,12 +120,217 @@ func WithRetryBackoff(backoff float64) AgentOpts {
 	}
 }
 
+// WithRetryJitter randomizes each retry delay by ±fraction (0 to 1), so agents failing at the same time
+// don't all retry at the same instant
+func WithRetryJitter(fraction float64) AgentOpts {
+	return func(a *Agent) {
+		a.retryJitter = min(max(fraction, 0), 1)
+	}
+}
+
+// WithMaxRetryDelay caps the delay between retries, including jitter
+func WithMaxRetryDelay(delay time.Duration) AgentOpts {
+	return func(a *Agent) {
+		a.maxRetryDelay = delay
+	}
+}
+
+// RetryableClassifier decides whether a failed tool call is worth retrying
+type RetryableClassifier = func(err error) bool
+
+// WithRetryableClassifier narrows which tool errors are retried, the others fail the call right away.
+// By default every error is retried except a PermanentError, which is never retried regardless of the classifier.
+func WithRetryableClassifier(classifier RetryableClassifier) AgentOpts {
+	return func(a *Agent) {
+		a.retryableClassifier = classifier
+	}
+}
+
+// RetryValidationAndTransientErrors is a RetryableClassifier retrying only invalid arguments the model
+// can correct (SchemaValidationError) and transient failures (RetryableError, ToolTimeoutError),
+// so deterministic logic errors such as a division by zero fail without wasting retries
+func RetryValidationAndTransientErrors(err error) bool {
+	var validationErr *SchemaValidationError
+	var timeoutErr *ToolTimeoutError
+	return errors.As(err, &validationErr) || errors.As(err, &timeoutErr) || IsRetryable(err)
+}
+
+// RetryPromptTemplate builds the message asking the LLM to correct a failed tool call
+type RetryPromptTemplate = func(toolName, errMsg, args string) string
+
+// WithRetryPromptTemplate sets the template used to ask the LLM for corrected tool call parameters,
+// e.g. to localize the message or tune it for a domain
+func WithRetryPromptTemplate(template RetryPromptTemplate) AgentOpts {
+	return func(a *Agent) {
+		a.retryPrompt = template
+	}
+}
+
+// WithToolTimeout bounds each tool call attempt, a call running longer fails with a retryable
+// ToolTimeoutError handled like any other tool error. Tools honoring ctx are cancelled; a tool
+// ignoring ctx keeps running in the background after the agent has moved on.
+func WithToolTimeout(timeout time.Duration) AgentOpts {
+	return func(a *Agent) {
+		a.toolTimeout = timeout
+	}
+}
+
+// WithRequestContext derives the context every tool runs with from the context of the call, e.g. to
+// attach the caller's user ID and auth token with WithToolContext. It runs for each tool attempt,
+// before the tool timeout applies, so it should be cheap.
+func WithRequestContext(derive func(ctx context.Context) context.Context) AgentOpts {
+	return func(a *Agent) {
+		a.requestContext = derive
+	}
+}
+
+// ToolApprover decides whether a tool call may run, e.g. by asking a human before spending money
+type ToolApprover = func(ctx context.Context, toolCall *ToolCall) (bool, error)
+
+// WithToolApprover consults the approver before each tool call. A denied call is not run and the model
+// is told so it can take another path; an approver error aborts the run with a ToolApprovalError.
+func WithToolApprover(approver ToolApprover) AgentOpts {
+	return func(a *Agent) {
+		a.toolApprover = approver
+	}
+}
+
+// WithIterationTimeout bounds each underlying LLM call of the agent loop with its own deadline,
+// so a single slow turn cannot consume the whole budget of a multi-step run
+func WithIterationTimeout(timeout time.Duration) AgentOpts {
+	return func(a *Agent) {
+		a.iterationTimeout = timeout
+	}
+}
+
+// WithMaxIterations caps the LLM calls of a single run (10 by default) so a model that keeps calling
+// tools cannot loop forever. Past the cap Invoke returns a *MaxIterationsError; zero disables the cap.
+func WithMaxIterations(n int) AgentOpts {
+	return func(a *Agent) {
+		a.maxIterations = n
+	}
+}
+
+// WithHistoryTrimmer trims the history sent with each LLM call of the loop, e.g. KeepLastTokens to stay
+// within the model's context window. The run keeps the full history, only the requests are trimmed.
+func WithHistoryTrimmer(trimmer HistoryTrimmer) AgentOpts {
+	return func(a *Agent) {
+		a.historyTrimmer = trimmer
+	}
+}
+
+// WithTokenCounter sets the counter used to estimate the prompt size of each LLM call, which is logged
+// at debug level before the call is made
+func WithTokenCounter(counter TokenCounter) AgentOpts {
+	return func(a *Agent) {
+		a.tokenCounter = counter
+	}
+}
+
+// WithToolResultSummarizer condenses tool results larger than threshold bytes using the given LLM,
+// guided by the user's request. The summary replaces the result in history while the full result
+// is kept on the ToolResultMessage's FullResult.
+func WithToolResultSummarizer(summarizer LLM, threshold int) AgentOpts {
+	return func(a *Agent) {
+		a.summarizer = summarizer
+		a.summarizerThreshold = threshold
+	}
+}
+
+// WithConcurrentToolCalls runs up to n tool calls of the same turn in parallel. Results keep the order
+// of the calls regardless of completion order, and a failing call doesn't stop the others.
+func WithConcurrentToolCalls(n int) AgentOpts {
+	return func(a *Agent) {
+		a.concurrentToolCalls = n
+	}
+}
+
+// WithPerToolCallLimit caps how many times each named tool can be invoked in a single run,
+// e.g. {"search": 5}. Calls beyond the limit are not executed, the model is told the tool is exhausted instead.
+func WithPerToolCallLimit(limits map[string]int) AgentOpts {
+	return func(a *Agent) {
+		a.toolCallLimits = limits
+	}
+}
+
+// WithStructuredToolErrors delivers failed tool calls to the model as a JSON ToolError,
+// {"error": true, "message": "...", "retryable": bool}, instead of the bare error text
+func WithStructuredToolErrors() AgentOpts {
+	return func(a *Agent) {
+		a.structuredToolErrors = true
+	}
+}
+
+// WithRequireToolUse requires at least one successful tool call before a final answer is accepted.
+// A model answering without one is re-prompted to use the available tools, and after
+// three attempts the run fails with ErrToolUseRequired.
+func WithRequireToolUse(require bool) AgentOpts {
+	return func(a *Agent) {
+		a.requireToolUse = require
+	}
+}
+
+// WithRetryOnEmptyResponse re-invokes the LLM up to n times when it answers with neither content
+// nor tool calls, e.g. after a degenerate generation. Once the retries run out ErrEmptyResponse is returned.
+func WithRetryOnEmptyResponse(n int) AgentOpts {
+	return func(a *Agent) {
+		a.emptyResponseRetries = n
+	}
+}
+
+// WithStopOnToolResult ends the run once a tool result satisfies the condition, e.g. a tool
+// returning {"done": true}. The condition is evaluated after each successful tool execution; the
+// remaining calls of the same turn still run and the response carrying the results is returned
+// without another LLM call (apart from formatting with WithOutputSchema).
+func WithStopOnToolResult(condition func(toolCall *ToolCall, result json.RawMessage) bool) AgentOpts {
+	return func(a *Agent) {
+		a.stopOnToolResult = condition
+	}
+}
+
+// WithToolOutputSchemaInPrompt describes what each tool returns in the system prompt, so the model
+// can plan multi-step calls where one tool's output feeds the next. Only tools implementing
+// ToolWithOutputSchema are described.
+func WithToolOutputSchemaInPrompt() AgentOpts {
+	return func(a *Agent) {
+		a.toolOutputsInPrompt = true
+	}
+}
+
+// ToolResultEncoder renders a tool result into the text placed in the tool message
+type ToolResultEncoder = func(toolCall *ToolCall, result json.RawMessage) (string, error)
+
+// WithToolResultEncoder transforms tool results before they are sent to the model, e.g. into a
+// text table or YAML. The raw JSON is kept on the ToolResultMessage's FullResult; results that
+// fail to encode are sent as raw JSON. Defaults to sending the raw JSON.
+func WithToolResultEncoder(encoder ToolResultEncoder) AgentOpts {
+	return func(a *Agent) {
+		a.resultEncoder = encoder
+	}
+}
+
+// WithValidateToolArgs validates tool call arguments against the tool's input schema before running it.
+// Violations never reach the tool, they feed the retry loop so the LLM can correct the arguments.
+func WithValidateToolArgs(validate bool) AgentOpts {
+	return func(a *Agent) {
+		a.validateToolArgs = validate
+	}
+}
+
 func WithOutputSchema(schema json.RawMessage) AgentOpts {
 	return func(a *Agent) {
 		a.outputSchema = &schema
 	}
 }
 
+// WithGroundFinalAnswer injects the tool results collected during the run into the prompt formatting
+// the final structured answer, asking the model to draw only from them. It applies with WithOutputSchema.
+func WithGroundFinalAnswer(ground bool) AgentOpts {
+	return func(a *Agent) {
+		a.groundFinalAnswer = ground
+	}
+}
+
 // NewAgent creates a new agent with the given LLM and tools
 func NewAgent(llm LLM, tools []Tool, opts ...AgentOpts) LLM {
 	a := &Agent{
@@ -61,6 +339,14 @@ func NewAgent(llm LLM, tools []Tool, opts ...AgentOpts) LLM {
 		maxRetries:   3,                      // Default: 3 retries
 		retryDelay:   100 * time.Millisecond, // Default: 100ms initial delay
 		retryBackoff: 2.0,                    // Default: 2x backoff
+		retryPrompt:  defaultRetryPrompt,
+		jitterRand:   newLockedRand(),
+
+		maxIterations: 10, // Default: 10 LLM calls per run
+
+		totalRetryBudget: -1, // Default: no budget across tool calls
+
+		observer: NoopAgentObserver{},
 	}
 
 	for _, opt := range opts {
@@ -70,73 +356,485 @@ func NewAgent(llm LLM, tools []Tool, opts ...AgentOpts) LLM {
 	return a
 }
 
-// Loop processes the conversation loop, handling tool calls and LLM responses
+// Invoke runs the conversation loop, executing tool calls until the LLM produces a final response
 func (a *Agent) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	a = a.startRun()
+
+	response, err := a.invoke(ctx, request)
+	a.observer.OnFinish(ctx, response, err)
+
+	return response, err
+}
+
+// invoke runs the conversation loop of a started run
+func (a *Agent) invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
 	req := request.Clone(
+		WithHistory(append(NewHistory(), request.History...)),
 		WithTools(a.tools...),
 		WithToolUsage(AutoToolSelection()),
 	)
 
-	response, err := a.llm.Invoke(ctx, req)
-	if err != nil {
-		return nil, err
+	if req.RequestID == "" {
+		req.RequestID = newRequestID()
+	}
+	a.run.requestID = req.RequestID
+
+	if guidance := a.toolOutputGuidance(); guidance != "" {
+		req = req.Clone(WithSystem(strings.TrimSpace(req.System + "\n\n" + guidance)))
 	}
 
-	toolCalls := response.ToolCalls()
-	if len(toolCalls) > 0 {
-		for _, toolCall := range toolCalls {
-			message, err := a.CallTool(ctx, toolCall)
-			if err != nil {
-				response.AddMessage(NewToolResultErrorMessage(toolCall, err.Error()))
-			} else {
-				response.AddMessage(message)
+	toolCallCounts := make(map[string]int)
+	usedTools := false
+	toolUseReprompts := 0
+	emptyRetries := 0
+
+	for iteration := 1; ; iteration++ {
+		if a.maxIterations > 0 && iteration > a.maxIterations {
+			a.logger().Warn("Agent reached the iteration cap", "iterations", a.maxIterations)
+			return nil, &MaxIterationsError{
+				Iterations:           a.maxIterations,
+				LastAssistantMessage: lastAssistantMessage(req.History),
+				History:              req.History,
+			}
+		}
+
+		response, streamed, err := a.invokeIteration(ctx, req, iteration)
+		if err != nil {
+			return nil, err
+		}
+		a.run.addUsage(response.Usage)
+
+		toolCalls := response.ToolCalls()
+		if len(toolCalls) == 0 {
+			if block := response.Blocked; block != nil && block.Reason == SafetyBlockRefusal {
+				return nil, &RefusalError{Refusal: block.Message}
+			}
+
+			if a.emptyResponseRetries > 0 && isEmptyResponse(response) {
+				if emptyRetries == a.emptyResponseRetries {
+					return nil, ErrEmptyResponse
+				}
+				emptyRetries++
+
+				a.logger().Warn("Model returned an empty response, retrying", "attempt", emptyRetries)
+				continue
+			}
+
+			if a.requireToolUse && !usedTools && len(a.tools) > 0 {
+				if toolUseReprompts == maxToolUseReprompts {
+					return nil, ErrToolUseRequired
+				}
+				toolUseReprompts++
+
+				a.logger().Info("Model answered without using tools, re-prompting", "attempt", toolUseReprompts)
+				req = req.Clone(
+					WithHistory(req.History.Append(response.Messages...).Append(NewUserMessage(a.requireToolUsePrompt()))),
+				)
+				continue
 			}
+
+			return a.finalize(ctx, req, response, iteration)
+		}
+
+		ensureUniqueToolCallIDs(a.logger(), toolCalls)
+
+		// Limits are applied in call order before anything runs, so they don't depend on completion order
+		outcomes := make([]toolCallOutcome, len(toolCalls))
+		var pending []int
+		for i, toolCall := range toolCalls {
+			toolCallCounts[toolCall.Name]++
+			if limit, ok := a.toolCallLimits[toolCall.Name]; ok && toolCallCounts[toolCall.Name] > limit {
+				a.logger().Warn("Tool call limit reached", "tool", toolCall.Name, "limit", limit)
+				outcomes[i] = toolCallOutcome{message: NewToolResultErrorMessage(toolCall, toolExhaustedMessage(toolCall.Name, limit)), limited: true}
+				continue
+			}
+
+			// Streamed calls were reported to the observer when they started
+			if outcome, ok := streamed[toolCall]; ok {
+				a.observer.OnToolResult(ctx, toolCall, outcome.message, outcome.err)
+				outcomes[i] = outcome
+				continue
+			}
+
+			pending = append(pending, i)
+		}
+
+		a.runToolCalls(ctx, toolCalls, pending, outcomes)
+
+		stop := false
+		for i, toolCall := range toolCalls {
+			outcome := outcomes[i]
+			switch {
+			case outcome.limited:
+				response.AddMessage(outcome.message)
+				continue
+			case outcome.err != nil:
+				var approvalErr *ToolApprovalError
+				if errors.As(outcome.err, &approvalErr) {
+					return nil, outcome.err
+				}
+				response.AddMessage(a.toolErrorMessage(toolCall, outcome.err))
+				continue
+			}
+
+			usedTools = true
+			stop = stop || a.stopsOnToolResult(toolCall, outcome.message)
+			response.AddMessage(a.encodeToolResult(a.summarizeToolResult(ctx, req, outcome.message)))
 		}
 
 		req = req.Clone(
-			WithHistory(request.History.Append(response.Messages...)),
+			WithHistory(req.History.Append(response.Messages...)),
 		)
 
-		return a.Invoke(ctx, req)
+		if stop {
+			a.logger().Info("Tool result triggered the stop condition, finalizing", "iteration", iteration)
+			return a.finalize(ctx, req, response, iteration)
+		}
+	}
+}
+
+// stopsOnToolResult evaluates the stop condition against a successful tool result
+func (a *Agent) stopsOnToolResult(toolCall *ToolCall, message Message) bool {
+	if a.stopOnToolResult == nil {
+		return false
+	}
+
+	result, ok := message.(*ToolResultMessage)
+	return ok && a.stopOnToolResult(toolCall, result.Result)
+}
+
+// lastAssistantMessage returns the latest assistant text of the history, or nil when there is none
+func lastAssistantMessage(history History) *AssistantMessage {
+	for i := len(history) - 1; i >= 0; i-- {
+		if msg, ok := history[i].(*AssistantMessage); ok {
+			return msg
+		}
+	}
+
+	return nil
+}
+
+// toolCallOutcome is the result of a single tool call of a turn
+type toolCallOutcome struct {
+	message Message
+	err     error
+	limited bool // the call was not run, message tells the model the tool is exhausted
+}
+
+// runToolCalls executes the pending tool calls, up to the configured number at a time, storing each
+// outcome at the call's index. Calls that haven't started when the context is cancelled are skipped.
+func (a *Agent) runToolCalls(ctx context.Context, toolCalls []*ToolCall, pending []int, outcomes []toolCallOutcome) {
+	slots := make(chan struct{}, max(a.concurrentToolCalls, 1))
+	var wg sync.WaitGroup
+
+	for _, i := range pending {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			outcomes[i].err = ctx.Err()
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			<-slots
+			outcomes[i].err = err
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			outcomes[i].message, outcomes[i].err = a.CallTool(ctx, toolCalls[i])
+		}()
+	}
+
+	wg.Wait()
+}
+
+// isEmptyResponse reports whether the response carries no content at all
+func isEmptyResponse(response *LLMResponse) bool {
+	for _, msg := range response.Messages {
+		assistant, ok := msg.(*AssistantMessage)
+		if !ok || strings.TrimSpace(assistant.Content) != "" || len(assistant.ToolCalls) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// maxToolUseReprompts bounds how many times a model answering without tools is re-prompted
+const maxToolUseReprompts = 3
+
+//go:embed prompts/require_tool_use.txt
+var requireToolUsePromptFormat string
+
+// requireToolUsePrompt asks the model to call one of the agent's tools before answering
+func (a *Agent) requireToolUsePrompt() string {
+	names := make([]string, len(a.tools))
+	for i, t := range a.tools {
+		names[i] = t.Name()
 	}
 
+	return fmt.Sprintf(requireToolUsePromptFormat, strings.Join(names, ", "))
+}
+
+//go:embed prompts/tool_outputs.txt
+var toolOutputsPromptFormat string
+
+// toolOutputGuidance renders the system prompt section describing the tools' outputs
+func (a *Agent) toolOutputGuidance() string {
+	if !a.toolOutputsInPrompt {
+		return ""
+	}
+
+	var lines []string
+	for _, t := range a.tools {
+		withOutput, ok := t.(ToolWithOutputSchema)
+		if !ok {
+			continue
+		}
+
+		var schema map[string]any
+		if err := json.Unmarshal(withOutput.OutputSchemaRaw(), &schema); err != nil {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("- %s returns %s", t.Name(), describeSchemaShape(schema)))
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(toolOutputsPromptFormat, strings.Join(lines, "\n"))
+}
+
+// toolExhaustedMessage tells the model it used up its calls of a tool
+func toolExhaustedMessage(toolName string, limit int) string {
+	return fmt.Sprintf("Tool %s has been exhausted: it can be called at most %d times in this run. Do not call it again, answer with the information you already have or use another tool.", toolName, limit)
+}
+
+// toolDeniedMessage tells the model its call was not approved
+func toolDeniedMessage(toolName string) string {
+	return fmt.Sprintf("The call to tool %s was denied and the tool was not run. Do not call it again with the same arguments, continue another way or explain what you would need.", toolName)
+}
+
+// finalize turns the last LLM response into the agent's result, formatting it when an output schema is set
+// The result reports the usage of all LLM calls of the run.
+func (a *Agent) finalize(ctx context.Context, req *LLMRequest, response *LLMResponse, iteration int) (*LLMResponse, error) {
 	if a.outputSchema != nil {
-		formatted := NewBaseLLMWithStructuredOutput(*a.outputSchema, a.llm)
-		formattedResponse, err := formatted.Invoke(ctx, req)
+		if grounding := a.groundingPrompt(req.History); grounding != "" {
+			req = req.Clone(WithHistory(slices.Clone(req.History).Append(NewUserMessage(grounding))))
+		}
+
+		formatted, err := a.invokeLLM(ctx, NewBaseLLMWithStructuredOutput(*a.outputSchema, a.llm), req, iteration+1)
 		if err != nil {
 			return nil, err
 		}
-
-		return formattedResponse, nil
+		a.run.addUsage(formatted.Usage)
+		response = formatted
 	}
 
+	response.Usage = a.run.totalUsage()
 	return response, nil
 }
 
+//go:embed prompts/ground_final_answer.txt
+var groundFinalAnswerPromptFormat string
+
+// groundingPrompt lists the tool results of the history for the final formatting call
+func (a *Agent) groundingPrompt(history History) string {
+	if !a.groundFinalAnswer {
+		return ""
+	}
+
+	var lines []string
+	for _, msg := range history {
+		if result, ok := msg.(*ToolResultMessage); ok && result.ToolCall != nil {
+			lines = append(lines, fmt.Sprintf("- %s(%s) returned %s", result.ToolCall.Name, compactJSON(result.ToolCall.Args), compactJSON(result.Result)))
+		}
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(groundFinalAnswerPromptFormat, strings.Join(lines, "\n"))
+}
+
+// invokeLLM performs a single iteration's LLM call, bounded by the iteration timeout when configured
+func (a *Agent) invokeLLM(ctx context.Context, model LLM, req *LLMRequest, iteration int) (*LLMResponse, error) {
+	sent := a.sentRequest(req, iteration)
+	return a.withIterationTimeout(ctx, req, iteration, func(iterationCtx context.Context) (*LLMResponse, error) {
+		return model.Invoke(iterationCtx, sent)
+	})
+}
+
+// sentRequest returns the request as sent to the model in the iteration, with the history trimmed when a
+// trimmer is set and an idempotency key of its own, as each iteration makes a different call
+func (a *Agent) sentRequest(req *LLMRequest, iteration int) *LLMRequest {
+	if a.historyTrimmer != nil {
+		req = req.Clone(WithHistory(a.historyTrimmer(req.History)))
+	}
+
+	if req.IdempotencyKey != "" {
+		req = req.Clone(WithIdempotencyKey(fmt.Sprintf("%s-%d", req.IdempotencyKey, iteration)))
+	}
+
+	return req
+}
+
+// withIterationTimeout runs an iteration's call with its own deadline when an iteration timeout is configured
+func (a *Agent) withIterationTimeout(ctx context.Context, req *LLMRequest, iteration int, call func(context.Context) (*LLMResponse, error)) (*LLMResponse, error) {
+	if a.iterationTimeout <= 0 {
+		return call(ctx)
+	}
+
+	iterationCtx, cancel := context.WithTimeout(ctx, a.iterationTimeout)
+	defer cancel()
+
+	response, err := call(iterationCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(iterationCtx.Err(), context.DeadlineExceeded) {
+		return nil, &IterationTimeoutError{
+			Iteration: iteration,
+			Timeout:   a.iterationTimeout,
+			History:   req.History,
+			Err:       err,
+		}
+	}
+
+	return response, err
+}
+
+// agentRun holds the state shared by everything happening within a single run
+type agentRun struct {
+	mu          sync.Mutex
+	requestID   string // correlates the log lines and LLM calls of the run
+	retriesLeft int    // negative when the run has no retry budget
+	usage       *Usage // nil until an LLM call of the run reports usage
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	var id [16]byte
+	_, _ = cryptorand.Read(id[:])
+
+	return hex.EncodeToString(id[:])
+}
+
+// logger returns the logger of the run, tagging its lines with its request ID
+func (a *Agent) logger() *slog.Logger {
+	if a.run == nil || a.run.requestID == "" {
+		return slog.Default()
+	}
+
+	return slog.With("request_id", a.run.requestID)
+}
+
+// addUsage accumulates the usage reported by one of the run's LLM calls
+func (r *agentRun) addUsage(usage *Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.usage = addUsage(r.usage, usage)
+}
+
+// totalUsage returns the usage accumulated over the run so far
+func (r *agentRun) totalUsage() *Usage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.usage == nil {
+		return nil
+	}
+
+	usage := *r.usage
+	return &usage
+}
+
+// takeRetry consumes one retry from the run's budget, reporting whether one was available
+func (r *agentRun) takeRetry() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.retriesLeft < 0 {
+		return true
+	}
+	if r.retriesLeft == 0 {
+		return false
+	}
+
+	r.retriesLeft--
+	return true
+}
+
+// startRun returns the agent to use for a single run, with its own run state and the registry's
+// current tools fixed. Within a run it returns the agent itself.
+func (a *Agent) startRun() *Agent {
+	if a.run != nil {
+		return a
+	}
+
+	run := *a
+	run.run = &agentRun{retriesLeft: a.totalRetryBudget}
+
+	if a.registry != nil {
+		run.tools = append(slices.Clone(a.tools), a.registry.Tools()...)
+		run.registry = nil
+	}
+
+	return &run
+}
+
 // CallTool executes a tool call with retry logic using a formatter approach
 func (a *Agent) CallTool(ctx context.Context, toolCall *ToolCall) (Message, error) {
+	a = a.startRun()
+	a.observer.OnToolCall(ctx, toolCall)
+
+	result, err := a.callTool(ctx, toolCall)
+	a.observer.OnToolResult(ctx, toolCall, result, err)
+
+	return result, err
+}
+
+// callTool finds the called tool and runs it with retries once approved
+func (a *Agent) callTool(ctx context.Context, toolCall *ToolCall) (Message, error) {
 	// Find the tool to get its input schema
 	targetTool, err := a.findTool(toolCall.Name)
 	if err != nil {
 		return nil, err
 	}
 
-	return a.executeToolWithRetry(ctx, toolCall, targetTool)
+	if a.toolApprover != nil {
+		approved, err := a.toolApprover(ctx, toolCall)
+		if err != nil {
+			return nil, &ToolApprovalError{Tool: toolCall.Name, Err: err}
+		}
+		if !approved {
+			a.logger().Info("Tool call denied by the approver", "tool", toolCall.Name)
+			return NewToolResultErrorMessage(toolCall, toolDeniedMessage(toolCall.Name)), nil
+		}
+	}
+
+	return a.cachedToolCall(ctx, toolCall, targetTool)
 }
 
 // findTool finds a tool by name from the agent's tool list
 func (a *Agent) findTool(name string) (Tool, error) {
-	for _, t := range a.tools {
-		if t.Name() == name {
-			return t, nil
-		}
+	tool, err := FindTool(name, a.tools)
+	if err != nil {
+		return nil, NewPermanentError(err)
 	}
-	return nil, fmt.Errorf("tool not found: %s", name)
+	return tool, nil
 }
 
 // executeToolWithRetry manages the retry loop for tool execution
 func (a *Agent) executeToolWithRetry(ctx context.Context, toolCall *ToolCall, targetTool Tool) (Message, error) {
 	var lastErr error
+	var attempts int
 	delay := a.retryDelay
 	currentToolCall := toolCall // Create a local copy
 
@@ -146,12 +844,13 @@ func (a *Agent) executeToolWithRetry(ctx context.Context, toolCall *ToolCall, ta
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
-			case <-time.After(delay):
-				delay = time.Duration(float64(delay) * a.retryBackoff)
+			case <-time.After(a.retryWait(delay)):
+				delay = a.nextRetryDelay(delay)
 			}
 		}
 
 		// Try to execute the tool
+		attempts++
 		result, err := a.executeToolAttempt(ctx, currentToolCall, targetTool)
 		if err == nil {
 			return result, nil
@@ -159,11 +858,28 @@ func (a *Agent) executeToolWithRetry(ctx context.Context, toolCall *ToolCall, ta
 
 		lastErr = err
 
+		if isPermanent(err) {
+			return nil, fmt.Errorf("tool call failed permanently: %w", &ToolExecutionError{Tool: toolCall.Name, Attempts: attempts, Err: err})
+		}
+
+		if a.retryableClassifier != nil && !a.retryableClassifier(err) {
+			a.logger().Info("Tool call failed with a non-retryable error, not retrying", "tool", toolCall.Name, "error", err.Error())
+			return nil, fmt.Errorf("tool call failed with a non-retryable error: %w", &ToolExecutionError{Tool: toolCall.Name, Attempts: attempts, Err: err})
+		}
+
 		// If this is the last attempt, don't retry
 		if attempt == a.maxRetries {
 			break
 		}
 
+		if !a.run.takeRetry() {
+			a.logger().Warn("Retry budget of the run exhausted, not retrying", "tool", toolCall.Name)
+			return nil, fmt.Errorf("tool call failed after %d attempts, retry budget exhausted: %w", attempt+1,
+				&ToolExecutionError{Tool: toolCall.Name, Attempts: attempts, Err: lastErr})
+		}
+
+		a.observer.OnRetry(ctx, currentToolCall, attempt+1, err)
+
 		// Handle tool failure and get corrected parameters
 		correctedToolCall, shouldContinue := a.handleToolFailure(ctx, currentToolCall, targetTool, attempt, err)
 		if !shouldContinue {
@@ -173,12 +889,78 @@ func (a *Agent) executeToolWithRetry(ctx context.Context, toolCall *ToolCall, ta
 		currentToolCall = correctedToolCall
 	}
 
-	return nil, fmt.Errorf("tool call failed after %d retries: %w", a.maxRetries+1, lastErr)
+	return nil, fmt.Errorf("tool call failed after %d retries: %w", a.maxRetries+1,
+		&ToolExecutionError{Tool: toolCall.Name, Attempts: attempts, Err: lastErr})
+}
+
+// retryWait returns how long to wait for a retry with the given delay, randomized by the jitter and capped
+func (a *Agent) retryWait(delay time.Duration) time.Duration {
+	if a.retryJitter > 0 {
+		delay = time.Duration(float64(delay) * (1 + a.retryJitter*(2*a.jitterRand.Float64()-1)))
+	}
+
+	if a.maxRetryDelay > 0 && delay > a.maxRetryDelay {
+		delay = a.maxRetryDelay
+	}
+
+	return delay
+}
+
+// nextRetryDelay applies the backoff to the delay, keeping it within the cap
+func (a *Agent) nextRetryDelay(delay time.Duration) time.Duration {
+	delay = time.Duration(float64(delay) * a.retryBackoff)
+	if a.maxRetryDelay > 0 && delay > a.maxRetryDelay {
+		delay = a.maxRetryDelay
+	}
+
+	return delay
+}
+
+// lockedRand is a random source shared by the agent's concurrent tool calls
+type lockedRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func newLockedRand() *lockedRand {
+	return &lockedRand{rng: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+}
+
+// Float64 returns a random number in [0, 1)
+func (r *lockedRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rng.Float64()
+}
+
+// toolErrorMessage converts a failed tool call into the tool result delivered to the model
+func (a *Agent) toolErrorMessage(toolCall *ToolCall, err error) *ToolResultMessage {
+	if a.structuredToolErrors {
+		return NewStructuredToolErrorMessage(toolCall, err)
+	}
+
+	return NewToolResultErrorMessage(toolCall, err.Error())
 }
 
 // executeToolAttempt executes a single tool attempt
 func (a *Agent) executeToolAttempt(ctx context.Context, toolCall *ToolCall, targetTool Tool) (Message, error) {
-	result, err := targetTool.Run(ctx, toolCall.Args)
+	// A call without arguments runs, and is validated, as a call with an empty object
+	if args := normalizeArgs(toolCall.Args); !bytes.Equal(args, toolCall.Args) {
+		toolCall = &ToolCall{ID: toolCall.ID, Name: toolCall.Name, Args: args}
+	}
+
+	if a.validateToolArgs {
+		fieldErrs, err := ValidateSchema(targetTool.InputSchemaRaw(), toolCall.Args)
+		if err != nil {
+			return nil, err
+		}
+		if len(fieldErrs) > 0 {
+			return nil, fmt.Errorf("invalid arguments, the tool was not run: %w", &SchemaValidationError{Tool: targetTool.Name(), Errors: fieldErrs})
+		}
+	}
+
+	result, err := a.runTool(ctx, toolCall, targetTool)
 	if err != nil {
 		return nil, err
 	}
@@ -189,10 +971,50 @@ func (a *Agent) executeToolAttempt(ctx context.Context, toolCall *ToolCall, targ
 	}, nil
 }
 
+// runTool runs the tool, giving up on it once the tool timeout passes
+func (a *Agent) runTool(ctx context.Context, toolCall *ToolCall, targetTool Tool) (json.RawMessage, error) {
+	if a.requestContext != nil {
+		ctx = a.requestContext(ctx)
+	}
+
+	if a.toolTimeout <= 0 {
+		return targetTool.Run(ctx, toolCall.Args)
+	}
+
+	toolCtx, cancel := context.WithTimeout(ctx, a.toolTimeout)
+	defer cancel()
+
+	type outcome struct {
+		result json.RawMessage
+		err    error
+	}
+
+	// Buffered so a tool finishing after the timeout doesn't block forever
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := targetTool.Run(toolCtx, toolCall.Args)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err == nil || toolCtx.Err() == nil || ctx.Err() != nil {
+			return o.result, o.err
+		}
+	case <-toolCtx.Done():
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	a.logger().Warn("Tool call timed out", "tool", toolCall.Name, "timeout", a.toolTimeout)
+	return nil, NewRetryableError(&ToolTimeoutError{Tool: toolCall.Name, Timeout: a.toolTimeout})
+}
+
 // handleToolFailure handles tool failure and attempts to get corrected parameters
 func (a *Agent) handleToolFailure(ctx context.Context, toolCall *ToolCall, targetTool Tool, attempt int, err error) (*ToolCall, bool) {
 	// Log the retry attempt for debugging
-	slog.Info("Tool call failed, asking LLM to correct parameters",
+	a.logger().Info("Tool call failed, asking LLM to correct parameters",
 		"tool", toolCall.Name,
 		"attempt", attempt+1,
 		"max_attempts", a.maxRetries,
@@ -202,7 +1024,7 @@ func (a *Agent) handleToolFailure(ctx context.Context, toolCall *ToolCall, targe
 	// Get corrected parameters from the LLM
 	correctedArgs, err := a.correctToolCall(ctx, toolCall, targetTool, err)
 	if err != nil {
-		slog.Warn("Failed to get corrected parameters from LLM, continuing to next retry attempt",
+		a.logger().Warn("Failed to get corrected parameters from LLM, continuing to next retry attempt",
 			"tool", toolCall.Name,
 			"attempt", attempt+1,
 			"error", err.Error(),
@@ -213,7 +1035,7 @@ func (a *Agent) handleToolFailure(ctx context.Context, toolCall *ToolCall, targe
 	// Update the tool call with corrected parameters
 	updatedToolCall := a.updateToolCallArgs(toolCall, correctedArgs)
 
-	slog.Info("LLM provided corrected tool call parameters",
+	a.logger().Info("LLM provided corrected tool call parameters",
 		"tool", updatedToolCall.Name,
 		"corrected_params", prettyJSON(correctedArgs),
 	)
@@ -224,16 +1046,20 @@ func (a *Agent) handleToolFailure(ctx context.Context, toolCall *ToolCall, targe
 //go:embed prompts/tool_call_correction.txt
 var correctionPromptFormat string
 
+// defaultRetryPrompt renders the embedded correction prompt
+func defaultRetryPrompt(toolName, errMsg, args string) string {
+	return fmt.Sprintf(correctionPromptFormat, toolName, errMsg, args)
+}
+
 // correctToolCall uses the LLM to get corrected parameters for a failed tool call
 func (a *Agent) correctToolCall(ctx context.Context, toolCall *ToolCall, targetTool Tool, originalErr error) (json.RawMessage, error) {
-	errorMessage := NewUserMessage(fmt.Sprintf(
-		correctionPromptFormat,
+	errorMessage := NewUserMessage(a.retryPrompt(
 		toolCall.Name,
 		originalErr.Error(),
 		prettyJSON(toolCall.Args),
 	))
 
-	retryRequest := NewLLMRequest(NewHistory(errorMessage))
+	retryRequest := NewLLMRequest(NewHistory(errorMessage), WithRequestID(a.run.requestID))
 	formatter := NewBaseLLMWithStructuredOutput(targetTool.InputSchemaRaw(), a.llm)
 
 	// Get corrected parameters from the LLM
@@ -241,11 +1067,12 @@ func (a *Agent) correctToolCall(ctx context.Context, toolCall *ToolCall, targetT
 	if retryErr != nil {
 		return nil, fmt.Errorf("failed to get corrected parameters: %w", retryErr)
 	}
+	a.run.addUsage(retryResponse.Usage)
 
 	// Extract the corrected parameters from the LLM response
 	if len(retryResponse.Messages) > 0 {
 		if userMessage, ok := retryResponse.Messages[0].(*UserMessage); ok {
-			slog.Info("Retry response", "response", userMessage.Content)
+			a.logger().Info("Retry response", "response", userMessage.Content)
 			return []byte(userMessage.Content), nil
 		}
 	}
@@ -253,7 +1080,148 @@ func (a *Agent) correctToolCall(ctx context.Context, toolCall *ToolCall, targetT
 	return nil, fmt.Errorf("LLM did not provide corrected parameters")
 }
 
+//go:embed prompts/tool_result_summary.txt
+var summaryPromptFormat string
+
+// summarizeToolResult replaces a tool result exceeding the summarizer threshold with its summary.
+// The original message is returned unchanged when no summarizer is set or summarization fails.
+func (a *Agent) summarizeToolResult(ctx context.Context, req *LLMRequest, message Message) Message {
+	result, ok := message.(*ToolResultMessage)
+	if !ok || a.summarizer == nil || len(result.Result) <= a.summarizerThreshold {
+		return message
+	}
+
+	summaryRequest := NewLLMRequest(NewHistory(NewUserMessage(fmt.Sprintf(
+		summaryPromptFormat,
+		result.ToolCall.Name,
+		lastUserQuery(req.History),
+		a.summarizerThreshold,
+		string(result.Result),
+	))), WithRequestID(a.run.requestID))
+
+	summaryResponse, err := a.summarizer.Invoke(ctx, summaryRequest)
+	if err != nil {
+		a.logger().Warn("Failed to summarize tool result, keeping the full result",
+			"tool", result.ToolCall.Name,
+			"error", err.Error(),
+		)
+		return message
+	}
+	a.run.addUsage(summaryResponse.Usage)
+
+	summary, err := json.Marshal(lastAssistantContent(summaryResponse.Messages))
+	if err != nil {
+		return message
+	}
+
+	a.logger().Info("Summarized tool result",
+		"tool", result.ToolCall.Name,
+		"original_size", len(result.Result),
+		"summary_size", len(summary),
+	)
+
+	return &ToolResultMessage{
+		ToolCall:   result.ToolCall,
+		Result:     summary,
+		FullResult: result.Result,
+	}
+}
+
+// encodeToolResult applies the result encoder to results not already condensed by the summarizer
+func (a *Agent) encodeToolResult(message Message) Message {
+	result, ok := message.(*ToolResultMessage)
+	if !ok || a.resultEncoder == nil || result.FullResult != nil {
+		return message
+	}
+
+	encoded, err := a.resultEncoder(result.ToolCall, result.Result)
+	if err != nil {
+		a.logger().Warn("Failed to encode tool result, sending raw JSON",
+			"tool", result.ToolCall.Name,
+			"error", err.Error(),
+		)
+		return message
+	}
+
+	return &ToolResultMessage{
+		ToolCall:   result.ToolCall,
+		Result:     json.RawMessage(encoded),
+		FullResult: result.Result,
+	}
+}
+
 // HELPERS
+
+// ensureUniqueToolCallIDs reassigns the IDs of tool calls repeating an earlier ID of the same response,
+// and assigns one to calls the model returned without, so each result correlates with exactly one call.
+// The new-to-original mapping is logged to logger and returned.
+func ensureUniqueToolCallIDs(logger *slog.Logger, toolCalls []*ToolCall) map[string]string {
+	seen := make(map[string]bool, len(toolCalls))
+	for _, toolCall := range toolCalls {
+		if toolCall.ID != "" {
+			seen[toolCall.ID] = false
+		}
+	}
+
+	reassigned := make(map[string]string)
+	for _, toolCall := range toolCalls {
+		if toolCall.ID != "" && !seen[toolCall.ID] {
+			seen[toolCall.ID] = true
+			continue
+		}
+
+		base, first := toolCall.ID, 2
+		if base == "" {
+			base, first = "call", 1
+		}
+
+		var newID string
+		for n := first; ; n++ {
+			newID = fmt.Sprintf("%s_%d", base, n)
+			if _, taken := seen[newID]; !taken {
+				break
+			}
+		}
+
+		if toolCall.ID == "" {
+			logger.Warn("Model returned tool call without an ID, assigning one",
+				"tool", toolCall.Name,
+				"new_id", newID,
+			)
+		} else {
+			logger.Warn("Model returned duplicate tool call ID, reassigning",
+				"tool", toolCall.Name,
+				"original_id", toolCall.ID,
+				"new_id", newID,
+			)
+		}
+
+		reassigned[newID] = toolCall.ID
+		seen[newID] = true
+		toolCall.ID = newID
+	}
+
+	return reassigned
+}
+
+func lastUserQuery(history History) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if userMessage, ok := history[i].(*UserMessage); ok {
+			return userMessage.Content
+		}
+	}
+	return ""
+}
+
+func lastAssistantContent(messages History) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if assistantMessage, ok := messages[i].(*AssistantMessage); ok {
+			return assistantMessage.Content
+		}
+	}
+	return ""
+}
+
 func (a *Agent) updateToolCallArgs(originalToolCall *ToolCall, newArgs json.RawMessage) *ToolCall {
 	return &ToolCall{
 		ID:   originalToolCall.ID,
@@ -0,0 +1,103 @@
+package llm
+
+import "context"
+
+// AgentEventType represents the type of an agent event
+type AgentEventType string
+
+const (
+	// AgentEventAssistantText carries text the model wrote in an iteration, including its final answer
+	AgentEventAssistantText AgentEventType = "assistant_text"
+
+	// AgentEventToolCall is emitted when the agent starts calling a tool
+	AgentEventToolCall AgentEventType = "tool_call"
+
+	// AgentEventToolResult is emitted when a tool call is done, Err is set when it failed after all retries
+	AgentEventToolResult AgentEventType = "tool_result"
+
+	// AgentEventRetry is emitted before a failed tool call is retried
+	AgentEventRetry AgentEventType = "retry"
+
+	// AgentEventDone is the last event of a run, carrying its final response or error
+	AgentEventDone AgentEventType = "done"
+)
+
+// AgentEvent is a turn-level progress event of an agent run, e.g. to show live progress in a UI.
+// Unlike StreamEvent it doesn't carry tokens, only what the agent did.
+type AgentEvent struct {
+	Type      AgentEventType
+	Iteration int
+	Text      string
+	ToolCall  *ToolCall
+	Result    Message
+	Attempt   int
+	Response  *LLMResponse
+	Err       error
+}
+
+// InvokeEvents runs the conversation loop like Invoke, emitting its progress as events. The final
+// response, or the error the run failed with, comes with the AgentEventDone event, after which the
+// channel is closed. Cancelling the context ends the run and closes the channel, possibly without
+// a done event when nobody is receiving anymore. Tool events may arrive in any order when the agent
+// runs tool calls concurrently. The agent's observer is still notified.
+func (a *Agent) InvokeEvents(ctx context.Context, request *LLMRequest) <-chan AgentEvent {
+	events := make(chan AgentEvent)
+
+	send := func(event AgentEvent) {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	}
+
+	run := a.startRun()
+	run.observer = &eventObserver{AgentObserver: run.observer, send: send}
+
+	go func() {
+		defer close(events)
+
+		response, err := run.invoke(ctx, request)
+		run.observer.OnFinish(ctx, response, err)
+	}()
+
+	return events
+}
+
+// eventObserver forwards the agent's lifecycle to the wrapped observer and emits it as agent events
+type eventObserver struct {
+	AgentObserver
+	send func(AgentEvent)
+}
+
+func (o *eventObserver) OnLLMResponse(ctx context.Context, iteration int, response *LLMResponse, err error) {
+	o.AgentObserver.OnLLMResponse(ctx, iteration, response, err)
+	if response == nil {
+		return
+	}
+
+	for _, msg := range response.Messages {
+		if assistant, ok := msg.(*AssistantMessage); ok && assistant.Content != "" {
+			o.send(AgentEvent{Type: AgentEventAssistantText, Iteration: iteration, Text: assistant.Content})
+		}
+	}
+}
+
+func (o *eventObserver) OnToolCall(ctx context.Context, toolCall *ToolCall) {
+	o.AgentObserver.OnToolCall(ctx, toolCall)
+	o.send(AgentEvent{Type: AgentEventToolCall, ToolCall: toolCall})
+}
+
+func (o *eventObserver) OnToolResult(ctx context.Context, toolCall *ToolCall, result Message, err error) {
+	o.AgentObserver.OnToolResult(ctx, toolCall, result, err)
+	o.send(AgentEvent{Type: AgentEventToolResult, ToolCall: toolCall, Result: result, Err: err})
+}
+
+func (o *eventObserver) OnRetry(ctx context.Context, toolCall *ToolCall, attempt int, err error) {
+	o.AgentObserver.OnRetry(ctx, toolCall, attempt, err)
+	o.send(AgentEvent{Type: AgentEventRetry, ToolCall: toolCall, Attempt: attempt, Err: err})
+}
+
+func (o *eventObserver) OnFinish(ctx context.Context, response *LLMResponse, err error) {
+	o.AgentObserver.OnFinish(ctx, response, err)
+	o.send(AgentEvent{Type: AgentEventDone, Response: response, Err: err})
+}
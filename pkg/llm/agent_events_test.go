@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAgentInvokeEvents(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		// The correction of the failed call
+		if forced, ok := request.ToolUsage.(*ForcedToolUsage); ok {
+			return toolCallResponse("", forced.ToolName, `{"value": "good"}`), nil
+		}
+
+		calls++
+		if calls == 1 {
+			response := toolCallResponse("call_1", "mock", `{"value": "bad"}`)
+			response.Messages = append([]Message{&AssistantMessage{Content: "Let me check."}}, response.Messages...)
+			return response, nil
+		}
+		return textResponse("Done"), nil
+	})
+
+	tool := &mockTool{name: "mock", shouldFail: true, correctArgs: json.RawMessage(`{"value": "good"}`)}
+	observer := &recordingObserver{}
+	agent := NewAgent(model, []Tool{tool}, WithObserver(observer), WithRetryDelay(0)).(*Agent)
+
+	var events []string
+	var last AgentEvent
+	for event := range agent.InvokeEvents(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Go")))) {
+		last = event
+		switch event.Type {
+		case AgentEventAssistantText:
+			events = append(events, fmt.Sprintf("text %d %s", event.Iteration, event.Text))
+		case AgentEventToolCall, AgentEventToolResult:
+			events = append(events, fmt.Sprintf("%s %s %v", event.Type, event.ToolCall.ID, event.Err))
+		case AgentEventRetry:
+			events = append(events, fmt.Sprintf("retry %s %d", event.ToolCall.ID, event.Attempt))
+		case AgentEventDone:
+			events = append(events, fmt.Sprintf("done %v", event.Err))
+		}
+	}
+
+	expected := []string{
+		"text 1 Let me check.",
+		"tool_call call_1 <nil>",
+		"retry call_1 1",
+		"tool_result call_1 <nil>",
+		"text 2 Done",
+		"done <nil>",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected events %v, got %v", expected, events)
+	}
+
+	// The final response comes with the last event
+	if last.Response == nil || last.Response.Messages[0].(*AssistantMessage).Content != "Done" {
+		t.Errorf("Expected the final response with the done event, got %+v", last.Response)
+	}
+
+	// The agent's own observer still sees the run
+	if n := len(observer.events); n == 0 || observer.events[n-1] != "finish <nil>" {
+		t.Errorf("Expected the observer to be notified, got %v", observer.events)
+	}
+}
+
+func TestAgentInvokeEventsCancelled(t *testing.T) {
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		return textResponse("Done"), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := NewAgent(model, nil).(*Agent).InvokeEvents(ctx, NewLLMRequest(NewHistory(NewUserMessage("Go"))))
+
+	// Nobody receives anymore, the run must still end and close the channel
+	cancel()
+	for range events {
+	}
+}
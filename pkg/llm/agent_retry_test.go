@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -13,16 +16,30 @@ type mockLLM struct {
 	invokeCount int
 	shouldFail  bool
 	correctArgs json.RawMessage
+	requests    []*LLMRequest
 }
 
 func (m *mockLLM) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
 	m.invokeCount++
+	m.requests = append(m.requests, request)
 
 	if m.shouldFail && m.invokeCount == 1 {
 		// First call fails, return error
 		return nil, errors.New("mock LLM failure")
 	}
 
+	// Follow forced tool usage the way the formatter expects
+	if forced, ok := request.ToolUsage.(*ForcedToolUsage); ok {
+		return &LLMResponse{
+			Messages: []Message{
+				NewToolCallMessage(&ToolCall{
+					Name: forced.ToolName,
+					Args: m.correctArgs,
+				}),
+			},
+		}, nil
+	}
+
 	// Return corrected parameters in an assistant message (simplified formatter behavior)
 	return &LLMResponse{
 		Messages: []Message{
@@ -46,7 +63,7 @@ func (m *mockTool) InputSchemaRaw() json.RawMessage {
 	return json.RawMessage(`{"type": "object"}`)
 }
 func (m *mockTool) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
-	if m.shouldFail && string(args) != string(m.correctArgs) {
+	if m.shouldFail && compactJSON(args) != compactJSON(m.correctArgs) {
 		return nil, errors.New("invalid arguments")
 	}
 	return json.RawMessage(`{"result": "success"}`), nil
@@ -212,3 +229,384 @@ func TestAgentRetryMechanism(t *testing.T) {
 		}
 	})
 }
+
+func TestAgentRetryPromptTemplate(t *testing.T) {
+	ctx := context.Background()
+	correctArgs := json.RawMessage(`{"param": "correct"}`)
+
+	mockTool := &mockTool{
+		name:        "test_tool",
+		shouldFail:  true,
+		correctArgs: correctArgs,
+	}
+
+	mockLLM := &mockLLM{
+		correctArgs: correctArgs,
+	}
+
+	agent := NewAgent(mockLLM, []Tool{mockTool},
+		WithMaxRetries(1),
+		WithRetryDelay(time.Millisecond),
+		WithRetryPromptTemplate(func(toolName, errMsg, args string) string {
+			return "Nástroj " + toolName + " selhal: " + errMsg + "\n" + args
+		}),
+	)
+
+	_, err := agent.(*Agent).CallTool(ctx, &ToolCall{
+		Name: "test_tool",
+		Args: json.RawMessage(`{"param": "wrong"}`),
+	})
+	if err != nil {
+		t.Fatalf("Expected tool to succeed after retry, got error: %v", err)
+	}
+
+	if len(mockLLM.requests) != 1 {
+		t.Fatalf("Expected 1 correction request, got %d", len(mockLLM.requests))
+	}
+
+	history := mockLLM.requests[0].History
+	if len(history) != 1 {
+		t.Fatalf("Expected correction request with 1 message, got %d", len(history))
+	}
+
+	content := history[0].(*UserMessage).Content
+	if !strings.HasPrefix(content, "Nástroj test_tool selhal: invalid arguments") {
+		t.Errorf("Expected custom retry prompt to be used, got %q", content)
+	}
+}
+
+// failingTool always fails, counting its runs
+type failingTool struct {
+	mockTool
+	runs map[string]int
+}
+
+func (f *failingTool) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	f.runs[f.name]++
+	return nil, errors.New("backend unavailable")
+}
+
+func TestAgentTotalRetryBudget(t *testing.T) {
+	runs := make(map[string]int)
+	failing := func(name string) Tool {
+		return &failingTool{mockTool: mockTool{name: name}, runs: runs}
+	}
+
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		// Correction requests get unchanged arguments back
+		if forced, ok := request.ToolUsage.(*ForcedToolUsage); ok {
+			return toolCallResponse("", forced.ToolName, `{}`), nil
+		}
+
+		calls++
+		if calls == 1 {
+			response := toolCallResponse("call_1", "first", `{}`)
+			response.AddToolCall(&ToolCall{ID: "call_2", Name: "second", Args: json.RawMessage(`{}`)})
+			return response, nil
+		}
+		return textResponse("Both tools are down."), nil
+	})
+
+	agent := NewAgent(model, []Tool{failing("first"), failing("second")},
+		WithMaxRetries(3),
+		WithRetryDelay(time.Millisecond),
+		WithTotalRetryBudget(2),
+	)
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("go")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The first tool spends the whole budget, the second one is not retried at all
+	if runs["first"] != 3 {
+		t.Errorf("Expected first tool to run 3 times, got %d", runs["first"])
+	}
+	if runs["second"] != 1 {
+		t.Errorf("Expected second tool to run once, got %d", runs["second"])
+	}
+
+	// Each run gets a fresh budget
+	calls = 0
+	clear(runs)
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("go")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if runs["first"] != 3 {
+		t.Errorf("Expected budget to reset for the next run, got %d runs of first tool", runs["first"])
+	}
+}
+
+// strictTool requires an integer quantity, counting its runs
+type strictTool struct {
+	runs int
+}
+
+func (s *strictTool) Name() string        { return "order_item" }
+func (s *strictTool) Description() string { return "Orders an item" }
+func (s *strictTool) InputSchemaRaw() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"item":{"type":"string"},"quantity":{"type":"integer"}},"required":["item","quantity"]}`)
+}
+func (s *strictTool) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	s.runs++
+	return json.RawMessage(`{"ordered":true}`), nil
+}
+
+func TestAgentValidateToolArgs(t *testing.T) {
+	tool := &strictTool{}
+	model := &mockLLM{correctArgs: json.RawMessage(`{"item":"pen","quantity":2}`)}
+
+	agent := NewAgent(model, []Tool{tool}, WithValidateToolArgs(true), WithRetryDelay(time.Millisecond)).(*Agent)
+
+	message, err := agent.CallTool(context.Background(), &ToolCall{
+		ID:   "call_1",
+		Name: "order_item",
+		Args: json.RawMessage(`{"item":"pen","quantity":"two"}`),
+	})
+	if err != nil {
+		t.Fatalf("Expected corrected call to succeed, got %v", err)
+	}
+
+	if tool.runs != 1 {
+		t.Errorf("Expected the tool to run only with valid arguments, got %d runs", tool.runs)
+	}
+
+	if result := message.(*ToolResultMessage); compactJSON(result.ToolCall.Args) != `{"item":"pen","quantity":2}` {
+		t.Errorf("Expected corrected arguments, got %s", result.ToolCall.Args)
+	}
+
+	// The correction prompt explains the violation
+	prompt := model.requests[0].History[0].(*UserMessage).Content
+	if !strings.Contains(prompt, "field 'quantity' must be an integer") {
+		t.Errorf("Expected the schema violation in the correction prompt, got %q", prompt)
+	}
+}
+
+func TestAgentValidateToolArgsNeverRunsInvalidCalls(t *testing.T) {
+	tool := &strictTool{}
+	model := &mockLLM{correctArgs: json.RawMessage(`{"item":"pen"}`)}
+
+	agent := NewAgent(model, []Tool{tool}, WithValidateToolArgs(true), WithMaxRetries(2), WithRetryDelay(time.Millisecond)).(*Agent)
+
+	_, err := agent.CallTool(context.Background(), &ToolCall{Name: "order_item", Args: json.RawMessage(`{}`)})
+	if err == nil {
+		t.Fatal("Expected validation to fail")
+	}
+
+	var fieldErrs FieldErrors
+	if !errors.As(err, &fieldErrs) {
+		t.Errorf("Expected FieldErrors, got %v", err)
+	}
+
+	if tool.runs != 0 {
+		t.Errorf("Expected the tool never to run, got %d runs", tool.runs)
+	}
+}
+
+func TestAgentEmptyToolArgs(t *testing.T) {
+	var got json.RawMessage
+	currentTime := &argsRecordingTool{name: "current_time", args: &got}
+
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		if len(request.History) == 1 {
+			return toolCallResponse("call_1", "current_time", ""), nil
+		}
+		return textResponse("It is noon."), nil
+	})
+
+	observer := &recordingObserver{}
+	agent := NewAgent(model, []Tool{currentTime}, WithValidateToolArgs(true), WithObserver(observer))
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("What time is it?")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(got) != "{}" {
+		t.Errorf("Expected the tool to run with an empty object, got %q", got)
+	}
+	for _, event := range observer.events {
+		if strings.HasPrefix(event, "retry") {
+			t.Errorf("Expected no retries, got %v", observer.events)
+		}
+	}
+}
+
+// argsRecordingTool is a tool without parameters that records the arguments it runs with
+type argsRecordingTool struct {
+	name string
+	args *json.RawMessage
+}
+
+func (m *argsRecordingTool) Name() string        { return m.name }
+func (m *argsRecordingTool) Description() string { return "A tool without parameters" }
+func (m *argsRecordingTool) InputSchemaRaw() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{},"additionalProperties":false}`)
+}
+func (m *argsRecordingTool) OutputSchemaRaw() json.RawMessage { return nil }
+
+func (m *argsRecordingTool) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	*m.args = args
+	var input struct{}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(`"12:00"`), nil
+}
+
+func TestAgentRetryJitterBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []AgentOpts
+		delay    time.Duration
+		min, max time.Duration
+	}{
+		{"no jitter", nil, 100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"jitter", []AgentOpts{WithRetryJitter(0.2)}, 100 * time.Millisecond, 80 * time.Millisecond, 120 * time.Millisecond},
+		{"jitter clamped to 1", []AgentOpts{WithRetryJitter(3)}, 100 * time.Millisecond, 0, 200 * time.Millisecond},
+		{"capped", []AgentOpts{WithRetryJitter(0.5), WithMaxRetryDelay(110 * time.Millisecond)}, 100 * time.Millisecond, 50 * time.Millisecond, 110 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := NewAgent(&mockLLM{}, nil, tt.opts...).(*Agent)
+
+			seen := make(map[time.Duration]bool)
+			for range 1000 {
+				wait := agent.retryWait(tt.delay)
+				if wait < tt.min || wait > tt.max {
+					t.Fatalf("Expected wait within [%v, %v], got %v", tt.min, tt.max, wait)
+				}
+				seen[wait] = true
+			}
+
+			if tt.min != tt.max && len(seen) < 2 {
+				t.Errorf("Expected randomized waits, got %v", seen)
+			}
+		})
+	}
+}
+
+func TestAgentMaxRetryDelayCapsBackoff(t *testing.T) {
+	agent := NewAgent(&mockLLM{}, nil, WithRetryBackoff(10), WithMaxRetryDelay(time.Second)).(*Agent)
+
+	delay := 100 * time.Millisecond
+	for range 5 {
+		delay = agent.nextRetryDelay(delay)
+	}
+
+	if delay != time.Second {
+		t.Errorf("Expected the delay to be capped at 1s, got %v", delay)
+	}
+}
+
+// slowTool sleeps on its first calls, ignoring ctx when told to
+type slowTool struct {
+	sleep     time.Duration
+	slowCalls int
+	ignoreCtx bool
+	calls     atomic.Int32
+}
+
+func (s *slowTool) Name() string                    { return "slow" }
+func (s *slowTool) Description() string             { return "Slow tool for testing" }
+func (s *slowTool) InputSchemaRaw() json.RawMessage { return json.RawMessage(`{"type": "object"}`) }
+func (s *slowTool) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	if int(s.calls.Add(1)) > s.slowCalls {
+		return json.RawMessage(`{"result": "fast"}`), nil
+	}
+
+	if s.ignoreCtx {
+		time.Sleep(s.sleep)
+		return json.RawMessage(`{"result": "late"}`), nil
+	}
+
+	select {
+	case <-time.After(s.sleep):
+		return json.RawMessage(`{"result": "late"}`), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestAgentToolTimeout(t *testing.T) {
+	for _, ignoreCtx := range []bool{false, true} {
+		tool := &slowTool{sleep: 500 * time.Millisecond, slowCalls: 1, ignoreCtx: ignoreCtx}
+		agent := NewAgent(&mockLLM{}, []Tool{tool}, WithToolTimeout(20*time.Millisecond), WithMaxRetries(0)).(*Agent)
+
+		start := time.Now()
+		_, err := agent.CallTool(context.Background(), &ToolCall{ID: "call_1", Name: "slow", Args: json.RawMessage(`{}`)})
+		elapsed := time.Since(start)
+
+		var timeoutErr *ToolTimeoutError
+		if !errors.As(err, &timeoutErr) || timeoutErr.Tool != "slow" {
+			t.Fatalf("Expected a tool timeout error (ignoreCtx=%v), got %v", ignoreCtx, err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) || !IsRetryable(err) {
+			t.Errorf("Expected a retryable deadline error, got %v", err)
+		}
+		if elapsed > 250*time.Millisecond {
+			t.Errorf("Expected the agent not to wait for the tool (ignoreCtx=%v), took %v", ignoreCtx, elapsed)
+		}
+	}
+}
+
+func TestAgentToolTimeoutIsRetried(t *testing.T) {
+	tool := &slowTool{sleep: 500 * time.Millisecond, slowCalls: 1}
+	agent := NewAgent(&mockLLM{correctArgs: json.RawMessage(`{}`)}, []Tool{tool},
+		WithToolTimeout(20*time.Millisecond), WithMaxRetries(1), WithRetryDelay(time.Millisecond))
+
+	result, err := agent.(*Agent).CallTool(context.Background(), &ToolCall{ID: "call_1", Name: "slow", Args: json.RawMessage(`{}`)})
+	if err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+
+	if string(result.(*ToolResultMessage).Result) != `{"result": "fast"}` || tool.calls.Load() != 2 {
+		t.Errorf("Expected the second attempt to succeed, got %s after %d calls", result.(*ToolResultMessage).Result, tool.calls.Load())
+	}
+}
+
+func TestAgentRetryableClassifierSkipsRetries(t *testing.T) {
+	tool := &mockTool{name: "test_tool", shouldFail: true, correctArgs: json.RawMessage(`{"param": "correct"}`)}
+	model := &mockLLM{correctArgs: json.RawMessage(`{"param": "correct"}`)}
+
+	agent := NewAgent(model, []Tool{tool},
+		WithMaxRetries(2),
+		WithRetryDelay(time.Millisecond),
+		WithRetryableClassifier(RetryValidationAndTransientErrors),
+	).(*Agent)
+
+	_, err := agent.CallTool(context.Background(), &ToolCall{ID: "call_1", Name: "test_tool", Args: json.RawMessage(`{"param": "wrong"}`)})
+	if err == nil || !strings.Contains(err.Error(), "non-retryable") {
+		t.Fatalf("Expected a non-retryable failure, got %v", err)
+	}
+
+	if model.invokeCount != 0 {
+		t.Errorf("Expected no correction to be requested, got %d LLM calls", model.invokeCount)
+	}
+
+	var execErr *ToolExecutionError
+	if !errors.As(err, &execErr) || execErr.Attempts != 1 {
+		t.Errorf("Expected a single attempt, got %v", err)
+	}
+}
+
+func TestRetryValidationAndTransientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"logic error", errors.New("division by zero"), false},
+		{"schema validation", fmt.Errorf("invalid arguments: %w", &SchemaValidationError{Errors: FieldErrors{{Path: "a", Message: "is required"}}}), true},
+		{"timeout", NewRetryableError(&ToolTimeoutError{Tool: "slow", Timeout: time.Second}), true},
+		{"retryable", NewRetryableError(errors.New("rate limited")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetryValidationAndTransientErrors(tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// invokeIteration performs the LLM call of a single iteration. When both the LLM and some of the tools
+// support streaming, the call is streamed and those tools start working on their arguments as they
+// arrive; their outcomes are returned keyed by tool call. Otherwise the call is buffered and all tools
+// run once the response is complete.
+func (a *Agent) invokeIteration(ctx context.Context, req *LLMRequest, iteration int) (*LLMResponse, map[*ToolCall]toolCallOutcome, error) {
+	sent := a.sentRequest(req, iteration)
+	if a.tokenCounter != nil {
+		a.logger().Debug("Estimated prompt size", "iteration", iteration, "tokens", EstimatePromptTokens(a.tokenCounter, sent))
+	}
+	a.observer.OnLLMRequest(ctx, iteration, sent)
+
+	response, streamed, err := a.callIteration(ctx, req, sent, iteration)
+	a.observer.OnLLMResponse(ctx, iteration, response, err)
+
+	return response, streamed, err
+}
+
+// callIteration streams or buffers the iteration's LLM call sending the given request, see invokeIteration
+func (a *Agent) callIteration(ctx context.Context, req, sent *LLMRequest, iteration int) (*LLMResponse, map[*ToolCall]toolCallOutcome, error) {
+	streamer, ok := a.llm.(StreamingLLM)
+	if !ok || !a.hasStreamingTools() {
+		response, err := a.withIterationTimeout(ctx, req, iteration, func(iterationCtx context.Context) (*LLMResponse, error) {
+			return a.llm.Invoke(iterationCtx, sent)
+		})
+		return response, nil, err
+	}
+
+	var streamed map[*ToolCall]toolCallOutcome
+	response, err := a.withIterationTimeout(ctx, req, iteration, func(iterationCtx context.Context) (*LLMResponse, error) {
+		var err error
+		var response *LLMResponse
+		response, streamed, err = a.streamIteration(ctx, iterationCtx, streamer, sent)
+		return response, err
+	})
+
+	return response, streamed, err
+}
+
+// streamingToolRun tracks a streaming tool fed while its arguments are being generated
+type streamingToolRun struct {
+	toolCall *ToolCall // the call as the observer saw it start, before its arguments arrived
+
+	args   chan json.RawMessage
+	done   chan struct{}
+	cancel context.CancelFunc
+	result json.RawMessage
+	err    error
+}
+
+// streamIteration streams the LLM response, feeding argument fragments to streaming tools as they arrive.
+// Tools run with toolCtx while the stream itself is bound to streamCtx.
+func (a *Agent) streamIteration(toolCtx, streamCtx context.Context, streamer StreamingLLM, req *LLMRequest) (*LLMResponse, map[*ToolCall]toolCallOutcome, error) {
+	events, err := streamer.InvokeStream(streamCtx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	observerCtx := toolCtx
+	toolCtx, cancelTools := context.WithCancel(toolCtx)
+	defer cancelTools()
+
+	// Abandoned runs report why to the observer, completed ones are reported by the agent
+	runs := make(map[int]*streamingToolRun)
+	stopRuns := func(reason error) {
+		for _, run := range runs {
+			close(run.args)
+			<-run.done
+			if reason != nil {
+				a.observer.OnToolResult(observerCtx, run.toolCall, nil, reason)
+			}
+		}
+	}
+
+	accumulator := NewStreamAccumulator()
+	done := false
+
+	for event := range events {
+		if event.Type == StreamEventError {
+			err := fmt.Errorf("stream failed: %w", event.Err)
+			cancelTools()
+			stopRuns(err)
+			return nil, nil, err
+		}
+
+		accumulator.Add(event)
+
+		// Streaming tools fed from the abandoned attempt start over as well
+		if event.Type == StreamEventRestart {
+			for _, run := range runs {
+				run.cancel()
+			}
+			stopRuns(errStreamRestarted)
+			runs = make(map[int]*streamingToolRun)
+			continue
+		}
+
+		if event.Type == StreamEventDone {
+			done = true
+		}
+
+		delta := event.ToolCallDelta
+		if event.Type != StreamEventToolCallDelta || delta == nil {
+			continue
+		}
+
+		run, ok := runs[delta.Index]
+		if !ok && delta.Name != "" {
+			if tool, ok := a.findStreamingTool(delta.Name); ok {
+				run = a.startStreamingTool(toolCtx, tool, &ToolCall{ID: delta.ID, Name: delta.Name})
+				a.observer.OnToolCall(observerCtx, run.toolCall)
+				runs[delta.Index] = run
+			}
+		}
+
+		if run != nil && delta.ArgsDelta != "" {
+			select {
+			case run.args <- json.RawMessage(delta.ArgsDelta):
+			case <-streamCtx.Done():
+			}
+		}
+	}
+
+	if !done {
+		err := streamCtx.Err()
+		if err == nil {
+			err = errors.New("stream ended without completing")
+		}
+		cancelTools()
+		stopRuns(err)
+		return nil, nil, err
+	}
+
+	stopRuns(nil)
+
+	response := accumulator.Result()
+	toolCalls := response.ToolCalls()
+
+	streamed := make(map[*ToolCall]toolCallOutcome)
+	for i, index := range accumulator.toolCallIndexes() {
+		run, ok := runs[index]
+		if !ok {
+			continue
+		}
+
+		if run.err != nil {
+			streamed[toolCalls[i]] = toolCallOutcome{err: run.err}
+		} else {
+			streamed[toolCalls[i]] = toolCallOutcome{message: NewToolResultMessage(toolCalls[i], run.result)}
+		}
+	}
+
+	return response, streamed, nil
+}
+
+// errStreamRestarted is reported for the streaming tool runs abandoned when the stream starts over
+var errStreamRestarted = errors.New("stream restarted")
+
+// startStreamingTool runs the tool in the background, consuming argument fragments from the returned run.
+// Like any tool call it runs with the request context and within the tool timeout.
+func (a *Agent) startStreamingTool(ctx context.Context, tool StreamingTool, toolCall *ToolCall) *streamingToolRun {
+	if a.requestContext != nil {
+		ctx = a.requestContext(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	if a.toolTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, a.toolTimeout)
+	}
+
+	run := &streamingToolRun{
+		toolCall: toolCall,
+		args:     make(chan json.RawMessage),
+		done:     make(chan struct{}),
+		cancel:   cancel,
+	}
+
+	go func() {
+		defer close(run.done)
+		defer cancel()
+		run.result, run.err = tool.RunStream(ctx, run.args)
+		if run.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			run.err = &ToolTimeoutError{Tool: toolCall.Name, Timeout: a.toolTimeout}
+		}
+
+		// Keep draining so the stream never blocks on a tool that returned early
+		for range run.args {
+		}
+	}()
+
+	return run
+}
+
+// hasStreamingTools reports whether any of the agent's tools supports streamed arguments
+func (a *Agent) hasStreamingTools() bool {
+	for _, tool := range a.tools {
+		if _, ok := tool.(StreamingTool); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// findStreamingTool finds a streaming tool by name from the agent's tool list that may start before its
+// call is complete. Calls that must be approved, validated, limited or served from the cache are left to
+// run once the response is complete, like any other tool call.
+func (a *Agent) findStreamingTool(name string) (StreamingTool, bool) {
+	if a.toolApprover != nil || a.validateToolArgs {
+		return nil, false
+	}
+	if _, limited := a.toolCallLimits[name]; limited {
+		return nil, false
+	}
+
+	tool, err := a.findTool(name)
+	if err != nil {
+		return nil, false
+	}
+	if cacheable, ok := tool.(CacheableTool); ok && a.toolCache != nil && cacheable.Cacheable() {
+		return nil, false
+	}
+
+	streamingTool, ok := tool.(StreamingTool)
+	return streamingTool, ok
+}
@@ -0,0 +1,302 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streamingMockLLM streams the scripted events on the first call and answers with text afterwards
+type streamingMockLLM struct {
+	calls    int
+	requests []*LLMRequest
+	events   func(ch chan<- StreamEvent)
+}
+
+func (s *streamingMockLLM) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	return nil, errors.New("buffered invoke should not be used")
+}
+
+func (s *streamingMockLLM) InvokeStream(ctx context.Context, request *LLMRequest) (<-chan StreamEvent, error) {
+	s.calls++
+	s.requests = append(s.requests, request)
+	ch := make(chan StreamEvent)
+
+	if s.calls > 1 {
+		go func() {
+			defer close(ch)
+			ch <- StreamEvent{Type: StreamEventTextDelta, Text: "File written."}
+			ch <- StreamEvent{Type: StreamEventDone, FinishReason: "stop"}
+		}()
+		return ch, nil
+	}
+
+	go func() {
+		defer close(ch)
+		s.events(ch)
+	}()
+	return ch, nil
+}
+
+// streamingWriter is a streaming tool recording the fragments it receives, failing with err when set
+type streamingWriter struct {
+	firstFragment chan struct{}
+	fragments     []string
+	err           error
+}
+
+func (w *streamingWriter) Name() string        { return "write_file" }
+func (w *streamingWriter) Description() string { return "Writes a file" }
+func (w *streamingWriter) InputSchemaRaw() json.RawMessage {
+	return json.RawMessage(`{"type": "object"}`)
+}
+func (w *streamingWriter) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	return nil, errors.New("buffered run should not be used")
+}
+func (w *streamingWriter) RunStream(ctx context.Context, argStream <-chan json.RawMessage) (json.RawMessage, error) {
+	for fragment := range argStream {
+		if len(w.fragments) == 0 {
+			close(w.firstFragment)
+		}
+		w.fragments = append(w.fragments, string(fragment))
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	return json.RawMessage(`{"written": true}`), nil
+}
+
+func TestAgentStreamingToolReceivesArgumentFragments(t *testing.T) {
+	writer := &streamingWriter{firstFragment: make(chan struct{})}
+
+	model := &streamingMockLLM{events: func(ch chan<- StreamEvent) {
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ID: "call_1", Name: "write_file"}}
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ArgsDelta: `{"path": "a.txt", `}}
+
+		// The tool gets to work before the arguments are complete
+		select {
+		case <-writer.firstFragment:
+		case <-time.After(time.Second):
+			ch <- StreamEvent{Type: StreamEventError, Err: errors.New("tool did not receive the first fragment")}
+			return
+		}
+
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ArgsDelta: `"content": "hello"`}}
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ArgsDelta: `}`}}
+		ch <- StreamEvent{Type: StreamEventDone, FinishReason: "tool_calls"}
+	}}
+
+	agent := NewAgent(model, []Tool{writer})
+
+	response, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Write hello to a.txt"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(writer.fragments) != 3 {
+		t.Errorf("Expected 3 argument fragments, got %d", len(writer.fragments))
+	}
+
+	if args := strings.Join(writer.fragments, ""); args != `{"path": "a.txt", "content": "hello"}` {
+		t.Errorf("Unexpected assembled arguments: %s", args)
+	}
+
+	if content := response.Messages[0].(*AssistantMessage).Content; content != "File written." {
+		t.Errorf("Unexpected final answer: %s", content)
+	}
+}
+
+func TestAgentStreamErrorAbortsIteration(t *testing.T) {
+	writer := &streamingWriter{firstFragment: make(chan struct{})}
+
+	model := &streamingMockLLM{events: func(ch chan<- StreamEvent) {
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ID: "call_1", Name: "write_file"}}
+		ch <- StreamEvent{Type: StreamEventError, Err: errors.New("connection reset")}
+	}}
+
+	agent := NewAgent(model, []Tool{writer})
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("go")))); err == nil {
+		t.Error("Expected stream error to fail the run")
+	}
+}
+
+func TestAgentDeniedStreamingToolNeverRuns(t *testing.T) {
+	writer := &streamingWriter{firstFragment: make(chan struct{})}
+
+	model := &streamingMockLLM{events: func(ch chan<- StreamEvent) {
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ID: "call_1", Name: "write_file"}}
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ArgsDelta: `{"path": "a.txt"}`}}
+		ch <- StreamEvent{Type: StreamEventDone, FinishReason: "tool_calls"}
+	}}
+
+	var approved []string
+	agent := NewAgent(model, []Tool{writer}, WithToolApprover(func(ctx context.Context, toolCall *ToolCall) (bool, error) {
+		approved = append(approved, string(toolCall.Args))
+		return false, nil
+	}))
+
+	response, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Write a.txt"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(writer.fragments) != 0 {
+		t.Errorf("Expected the denied tool not to receive arguments, got %v", writer.fragments)
+	}
+	if len(approved) != 1 || approved[0] != `{"path": "a.txt"}` {
+		t.Errorf("Expected the approver to see the complete call, got %v", approved)
+	}
+
+	for _, msg := range response.Messages {
+		if result, ok := msg.(*ToolResultMessage); ok && !strings.Contains(string(result.Result), "denied") {
+			t.Errorf("Expected the model to be told the call was denied, got %s", result.Result)
+		}
+	}
+}
+
+func TestAgentObservesStreamingToolFromItsStart(t *testing.T) {
+	writer := &streamingWriter{firstFragment: make(chan struct{})}
+	observer := &recordingObserver{}
+
+	var startEvents []string
+	model := &streamingMockLLM{events: func(ch chan<- StreamEvent) {
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ID: "call_1", Name: "write_file"}}
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ArgsDelta: `{"path": "a.txt"}`}}
+		<-writer.firstFragment
+
+		observer.mu.Lock()
+		startEvents = slices.Clone(observer.events)
+		observer.mu.Unlock()
+
+		ch <- StreamEvent{Type: StreamEventDone, FinishReason: "tool_calls"}
+	}}
+
+	agent := NewAgent(model, []Tool{writer}, WithObserver(observer))
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Write a.txt")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !slices.Contains(startEvents, "tool_call call_1") {
+		t.Errorf("Expected the tool call to be observed while it runs, got %v", startEvents)
+	}
+	if calls := slices.DeleteFunc(slices.Clone(observer.events), func(event string) bool {
+		return !strings.HasPrefix(event, "tool_")
+	}); !slices.Equal(calls, []string{"tool_call call_1", `tool_result call_1 {"written": true}`}) {
+		t.Errorf("Expected the call to be observed once, got %v", calls)
+	}
+}
+
+// toolCallCapture keeps the calls handed to OnToolCall and OnToolResult
+type toolCallCapture struct {
+	NoopAgentObserver
+	started, completed *ToolCall
+}
+
+func (c *toolCallCapture) OnToolCall(ctx context.Context, toolCall *ToolCall) {
+	c.started = toolCall
+}
+
+func (c *toolCallCapture) OnToolResult(ctx context.Context, toolCall *ToolCall, result Message, err error) {
+	c.completed = toolCall
+}
+
+func TestAgentKeepsTheObservedStreamingToolCall(t *testing.T) {
+	writer := &streamingWriter{firstFragment: make(chan struct{})}
+	observer := &toolCallCapture{}
+
+	model := &streamingMockLLM{events: func(ch chan<- StreamEvent) {
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ID: "call_1", Name: "write_file"}}
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ArgsDelta: `{"path": "a.txt"}`}}
+		ch <- StreamEvent{Type: StreamEventDone, FinishReason: "tool_calls"}
+	}}
+
+	agent := NewAgent(model, []Tool{writer}, WithObserver(observer))
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Write a.txt")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if observer.started.ID != "call_1" || len(observer.started.Args) != 0 {
+		t.Errorf("Expected the started call to be left as observed, got %+v", observer.started)
+	}
+	if observer.completed.ID != "call_1" || string(observer.completed.Args) != `{"path": "a.txt"}` {
+		t.Errorf("Expected the result to carry the completed call, got %+v", observer.completed)
+	}
+}
+
+func TestAgentFailedStreamingToolIsAToolError(t *testing.T) {
+	writer := &streamingWriter{firstFragment: make(chan struct{}), err: errors.New("disk full")}
+	observer := &recordingObserver{}
+
+	model := &streamingMockLLM{events: func(ch chan<- StreamEvent) {
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ID: "call_1", Name: "write_file"}}
+		ch <- StreamEvent{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ArgsDelta: `{"path": "a.txt"}`}}
+		ch <- StreamEvent{Type: StreamEventDone, FinishReason: "tool_calls"}
+	}}
+
+	stopped := false
+	agent := NewAgent(model, []Tool{writer},
+		WithObserver(observer),
+		WithStructuredToolErrors(),
+		WithStopOnToolResult(func(toolCall *ToolCall, result json.RawMessage) bool {
+			stopped = true
+			return true
+		}),
+	)
+
+	response, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Write a.txt"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if stopped {
+		t.Error("Expected a failed call not to be checked against the stop condition")
+	}
+	if model.calls != 2 {
+		t.Errorf("Expected the model to be called again after the failure, got %d calls", model.calls)
+	}
+	if !slices.Contains(observer.events, "tool_result call_1 disk full") {
+		t.Errorf("Expected the failure to be observed, got %v", observer.events)
+	}
+
+	var toolResult *ToolResultMessage
+	for _, msg := range model.requests[1].History {
+		if result, ok := msg.(*ToolResultMessage); ok {
+			toolResult = result
+		}
+	}
+	var toolErr ToolError
+	if toolResult == nil || json.Unmarshal([]byte(toolResult.Result), &toolErr) != nil || !toolErr.Error || toolErr.Message != "disk full" {
+		t.Errorf("Expected a structured tool error to be sent to the model, got %v", toolResult)
+	}
+
+	if content := response.Messages[0].(*AssistantMessage).Content; content != "File written." {
+		t.Errorf("Unexpected final answer: %s", content)
+	}
+}
+
+func TestAgentFallsBackToBufferedRunWithoutStreamingTools(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		if calls == 1 {
+			return toolCallResponse("call_1", "test_tool", `{}`), nil
+		}
+		return textResponse("done"), nil
+	})
+
+	agent := NewAgent(model, []Tool{&mockTool{name: "test_tool"}})
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("go")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected 2 buffered calls, got %d", calls)
+	}
+}
@@ -0,0 +1,1083 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// invokeFunc adapts a function to the LLM interface
+type invokeFunc func(ctx context.Context, request *LLMRequest) (*LLMResponse, error)
+
+func (f invokeFunc) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	return f(ctx, request)
+}
+
+// toolCallResponse builds a response calling the given tool
+func toolCallResponse(id, name string, args string) *LLMResponse {
+	response := NewLLMResponse()
+	response.AddToolCall(&ToolCall{ID: id, Name: name, Args: json.RawMessage(args)})
+	return response
+}
+
+// textResponse builds a final assistant response
+func textResponse(content string) *LLMResponse {
+	response := NewLLMResponse()
+	response.AddMessage(&AssistantMessage{Content: content})
+	return response
+}
+
+func TestAgentIterationTimeout(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		if calls == 1 {
+			return toolCallResponse("call_1", "test_tool", `{}`), nil
+		}
+
+		// Hang until the iteration deadline hits
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	agent := NewAgent(model, []Tool{&mockTool{name: "test_tool"}}, WithIterationTimeout(20*time.Millisecond))
+
+	_, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("go"))))
+
+	var timeoutErr *IterationTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("Expected IterationTimeoutError, got %v", err)
+	}
+
+	if timeoutErr.Iteration != 2 {
+		t.Errorf("Expected timeout on iteration 2, got %d", timeoutErr.Iteration)
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to wrap context.DeadlineExceeded")
+	}
+
+	// User message, tool call and tool result from the first iteration are preserved
+	if len(timeoutErr.History) != 3 {
+		t.Fatalf("Expected 3 messages of partial history, got %d", len(timeoutErr.History))
+	}
+
+	if timeoutErr.History[2].Kind() != MessageKindToolResult {
+		t.Errorf("Expected partial history to end with the tool result, got %s", timeoutErr.History[2].Kind())
+	}
+}
+
+func TestAgentIterationTimeoutAppliesPerIteration(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		select {
+		case <-time.After(15 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if calls < 3 {
+			return toolCallResponse("call", "test_tool", `{}`), nil
+		}
+		return textResponse("done"), nil
+	})
+
+	// Each iteration fits its own deadline, even though the run as a whole takes longer
+	agent := NewAgent(model, []Tool{&mockTool{name: "test_tool"}}, WithIterationTimeout(30*time.Millisecond))
+
+	response, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("go"))))
+	if err != nil {
+		t.Fatalf("Expected run to succeed, got %v", err)
+	}
+
+	if response.Messages[0].(*AssistantMessage).Content != "done" {
+		t.Errorf("Expected final answer, got %+v", response.Messages[0])
+	}
+}
+
+func TestAgentToolResultSummarizer(t *testing.T) {
+	largeResult := json.RawMessage(`{"document":"` + strings.Repeat("lorem ipsum ", 100) + `"}`)
+	largeTool := NewGenericTool("fetch_document", "Fetches a document",
+		func(ctx context.Context, input struct{}) (json.RawMessage, error) {
+			return largeResult, nil
+		})
+
+	var finalRequest *LLMRequest
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		if calls == 1 {
+			return toolCallResponse("call_1", "fetch_document", `{}`), nil
+		}
+		finalRequest = request
+		return textResponse("The document is about lorem ipsum."), nil
+	})
+
+	var summaryPrompt string
+	summarizer := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		summaryPrompt = request.History[0].(*UserMessage).Content
+		return textResponse("Placeholder text."), nil
+	})
+
+	agent := NewAgent(model, []Tool{largeTool}, WithToolResultSummarizer(summarizer, 200))
+
+	_, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("What is the document about?"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(summaryPrompt, "What is the document about?") {
+		t.Errorf("Expected summary to be guided by the user's query, got prompt %q", summaryPrompt)
+	}
+
+	result, ok := finalRequest.History[2].(*ToolResultMessage)
+	if !ok {
+		t.Fatalf("Expected tool result in history, got %T", finalRequest.History[2])
+	}
+
+	if string(result.Result) != `"Placeholder text."` {
+		t.Errorf("Expected summary in history, got %s", string(result.Result))
+	}
+
+	if string(result.FullResult) != string(largeResult) {
+		t.Errorf("Expected full result to be preserved")
+	}
+}
+
+func TestAgentToolResultSummarizerSkipsSmallResults(t *testing.T) {
+	summarized := false
+	summarizer := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		summarized = true
+		return textResponse("summary"), nil
+	})
+
+	agent := NewAgent(nil, nil, WithToolResultSummarizer(summarizer, 200)).(*Agent)
+	message := NewToolResultMessage(&ToolCall{Name: "small"}, json.RawMessage(`{"ok": true}`))
+
+	if got := agent.summarizeToolResult(context.Background(), NewLLMRequest(NewHistory()), message); got != message {
+		t.Errorf("Expected small result to be kept as is")
+	}
+
+	if summarized {
+		t.Error("Expected summarizer not to be called for small results")
+	}
+}
+
+func TestAgentDuplicateToolCallIDs(t *testing.T) {
+	echo := NewGenericTool("echo", "Echoes the input",
+		func(ctx context.Context, input struct {
+			Value string `json:"value"`
+		}) (string, error) {
+			return input.Value, nil
+		})
+
+	var finalRequest *LLMRequest
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		if calls == 1 {
+			response := NewLLMResponse()
+			response.AddToolCall(&ToolCall{ID: "call_1", Name: "echo", Args: json.RawMessage(`{"value": "first"}`)})
+			response.AddToolCall(&ToolCall{ID: "call_1", Name: "echo", Args: json.RawMessage(`{"value": "second"}`)})
+			return response, nil
+		}
+		finalRequest = request
+		return textResponse("done"), nil
+	})
+
+	agent := NewAgent(model, []Tool{echo})
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("echo twice")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	history := finalRequest.History
+	if len(history) != 5 {
+		t.Fatalf("Expected 5 messages, got %d", len(history))
+	}
+
+	first := history[1].(*ToolCallMessage).ToolCall
+	second := history[2].(*ToolCallMessage).ToolCall
+	if first.ID == second.ID {
+		t.Fatalf("Expected tool call IDs to be unique, both are %s", first.ID)
+	}
+
+	expected := map[string]string{first.ID: `"first"`, second.ID: `"second"`}
+	for _, msg := range history[3:] {
+		result := msg.(*ToolResultMessage)
+		if string(result.Result) != expected[result.ToolCall.ID] {
+			t.Errorf("Expected result %s for %s, got %s", expected[result.ToolCall.ID], result.ToolCall.ID, string(result.Result))
+		}
+	}
+}
+
+func TestEnsureUniqueToolCallIDs(t *testing.T) {
+	toolCalls := []*ToolCall{{ID: "a"}, {ID: "a"}, {ID: "a_2"}, {ID: "b"}}
+
+	reassigned := ensureUniqueToolCallIDs(slog.Default(), toolCalls)
+
+	ids := []string{toolCalls[0].ID, toolCalls[1].ID, toolCalls[2].ID, toolCalls[3].ID}
+	expected := []string{"a", "a_3", "a_2", "b"}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Errorf("Expected ID %s at %d, got %s", expected[i], i, ids[i])
+		}
+	}
+
+	if reassigned["a_3"] != "a" || len(reassigned) != 1 {
+		t.Errorf("Expected mapping a_3 -> a, got %v", reassigned)
+	}
+}
+
+func TestAgentLogsToolCallsWithRequestID(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		if calls == 1 {
+			response := toolCallResponse("call_1", "lookup", `{"a": 1}`)
+			response.AddToolCall(&ToolCall{ID: "call_1", Name: "lookup", Args: json.RawMessage(`{"a": 1}`)})
+			return response, nil
+		}
+		return textResponse("Done"), nil
+	})
+
+	agent := NewAgent(model, []Tool{&lookupTool{cacheable: true}}, WithToolCache(NewLRUCache(10)))
+
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(previous)
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Go")), WithRequestID("req-1"))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, message := range []string{"Model returned duplicate tool call ID, reassigning", "Tool result served from cache"} {
+		found := false
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("Failed to decode log line %q: %v", line, err)
+			}
+			if entry["msg"] == message {
+				found = entry["request_id"] == "req-1"
+			}
+		}
+		if !found {
+			t.Errorf("Expected %q to be logged with the request ID, got %s", message, logs.String())
+		}
+	}
+}
+
+func TestEnsureUniqueToolCallIDsAssignsMissing(t *testing.T) {
+	toolCalls := []*ToolCall{{ID: ""}, {ID: "call_1"}, {ID: ""}}
+
+	reassigned := ensureUniqueToolCallIDs(slog.Default(), toolCalls)
+
+	expected := []string{"call_2", "call_1", "call_3"}
+	for i := range expected {
+		if toolCalls[i].ID != expected[i] {
+			t.Errorf("Expected ID %s at %d, got %s", expected[i], i, toolCalls[i].ID)
+		}
+	}
+
+	if len(reassigned) != 2 {
+		t.Errorf("Expected 2 assigned IDs, got %v", reassigned)
+	}
+}
+
+func TestAgentPerToolCallLimit(t *testing.T) {
+	searches := 0
+	search := NewGenericTool("search", "Searches the web",
+		func(ctx context.Context, input struct{}) (json.RawMessage, error) {
+			searches++
+			return json.RawMessage(`{"results":[]}`), nil
+		})
+
+	var lastRequest *LLMRequest
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		lastRequest = request
+
+		// Keep searching until told the tool is exhausted
+		if last, ok := request.History[len(request.History)-1].(*ToolResultMessage); ok && strings.Contains(string(last.Result), "exhausted") {
+			return textResponse("I could not find anything."), nil
+		}
+		return toolCallResponse("call", "search", `{}`), nil
+	})
+
+	agent := NewAgent(model, []Tool{search}, WithPerToolCallLimit(map[string]int{"search": 2}))
+
+	response, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Find it"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if searches != 2 {
+		t.Errorf("Expected search to run 2 times, got %d", searches)
+	}
+
+	if response.Messages[0].(*AssistantMessage).Content != "I could not find anything." {
+		t.Errorf("Expected the agent to give up on the exhausted tool, got %+v", response.Messages[0])
+	}
+
+	// User message plus three call/result pairs, the last one reporting the exhausted tool
+	if len(lastRequest.History) != 7 {
+		t.Fatalf("Expected 7 messages in history, got %d", len(lastRequest.History))
+	}
+}
+
+func TestAgentRequireToolUse(t *testing.T) {
+	var requests []*LLMRequest
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		requests = append(requests, request)
+
+		switch len(requests) {
+		case 1:
+			return textResponse("Paris has 2 million inhabitants."), nil
+		case 2:
+			return toolCallResponse("call_1", "test_tool", `{}`), nil
+		default:
+			return textResponse("According to the tool, 2.1 million."), nil
+		}
+	})
+
+	agent := NewAgent(model, []Tool{&mockTool{name: "test_tool"}}, WithRequireToolUse(true))
+
+	response, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("How many people live in Paris?"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 LLM calls, got %d", len(requests))
+	}
+
+	// The ungrounded answer is followed by a nudge to use the tools
+	nudge, ok := requests[1].History[2].(*UserMessage)
+	if !ok || !strings.Contains(nudge.Content, "test_tool") {
+		t.Errorf("Expected a re-prompt naming the tools, got %+v", requests[1].History[2])
+	}
+
+	if response.Messages[0].(*AssistantMessage).Content != "According to the tool, 2.1 million." {
+		t.Errorf("Expected the grounded answer, got %+v", response.Messages[0])
+	}
+}
+
+func TestAgentRequireToolUseGivesUp(t *testing.T) {
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		return textResponse("I just know."), nil
+	})
+
+	agent := NewAgent(model, []Tool{&mockTool{name: "test_tool"}}, WithRequireToolUse(true))
+
+	_, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("go"))))
+	if !errors.Is(err, ErrToolUseRequired) {
+		t.Errorf("Expected ErrToolUseRequired, got %v", err)
+	}
+}
+
+func TestAgentStopOnToolResult(t *testing.T) {
+	finish := NewGenericTool("finish_task", "Marks the task as done",
+		func(ctx context.Context, input struct{}) (json.RawMessage, error) {
+			return json.RawMessage(`{"done":true}`), nil
+		})
+
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		// The model would keep going if asked again
+		return toolCallResponse("call", "finish_task", `{}`), nil
+	})
+
+	agent := NewAgent(model, []Tool{finish}, WithStopOnToolResult(func(toolCall *ToolCall, result json.RawMessage) bool {
+		var status struct {
+			Done bool `json:"done"`
+		}
+		return json.Unmarshal(result, &status) == nil && status.Done
+	}))
+
+	response, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Do the task"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected the run to stop after the first tool result, got %d LLM calls", calls)
+	}
+
+	if len(response.Messages) != 2 {
+		t.Fatalf("Expected tool call and result in the response, got %d messages", len(response.Messages))
+	}
+
+	result, ok := response.Messages[1].(*ToolResultMessage)
+	if !ok || string(result.Result) != `{"done":true}` {
+		t.Errorf("Expected the terminal tool result, got %+v", response.Messages[1])
+	}
+}
+
+type weatherReport struct {
+	Temperature float64 `json:"temperature"`
+	Conditions  string  `json:"conditions"`
+}
+
+type cityLookup struct {
+	City string `json:"city"`
+}
+
+func TestAgentToolOutputSchemaInPrompt(t *testing.T) {
+	weather := NewGenericTool("get_weather", "Gets the weather for a city",
+		func(ctx context.Context, input cityLookup) (weatherReport, error) {
+			return weatherReport{}, nil
+		})
+	locate := NewGenericTool("locate_user", "Finds the user's city",
+		func(ctx context.Context, input struct{}) (cityLookup, error) {
+			return cityLookup{}, nil
+		})
+
+	var system string
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		system = request.System
+		return textResponse("done"), nil
+	})
+
+	agent := NewAgent(model, []Tool{weather, locate, &mockTool{name: "test_tool"}}, WithToolOutputSchemaInPrompt())
+
+	_, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("What's the weather here?")), WithSystem("You are a weather assistant.")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(system, "You are a weather assistant.") {
+		t.Errorf("Expected the original system prompt to be kept, got %q", system)
+	}
+
+	for _, expected := range []string{
+		"- get_weather returns {conditions: string, temperature: number}",
+		"- locate_user returns {city: string}",
+	} {
+		if !strings.Contains(system, expected) {
+			t.Errorf("Expected system prompt to contain %q, got %q", expected, system)
+		}
+	}
+
+	if strings.Contains(system, "test_tool") {
+		t.Errorf("Expected tools without an output schema to be skipped, got %q", system)
+	}
+}
+
+func TestAgentToolResultEncoder(t *testing.T) {
+	listUsers := NewGenericTool("list_users", "Lists users",
+		func(ctx context.Context, input struct{}) (json.RawMessage, error) {
+			return json.RawMessage(`[{"name":"Ada","age":36},{"name":"Alan","age":41}]`), nil
+		})
+
+	tableEncoder := func(toolCall *ToolCall, result json.RawMessage) (string, error) {
+		var rows []struct {
+			Name string `json:"name"`
+			Age  int    `json:"age"`
+		}
+		if err := json.Unmarshal(result, &rows); err != nil {
+			return "", err
+		}
+
+		var table strings.Builder
+		table.WriteString("name | age\n")
+		for _, row := range rows {
+			table.WriteString(fmt.Sprintf("%s | %d\n", row.Name, row.Age))
+		}
+		return table.String(), nil
+	}
+
+	var lastRequest *LLMRequest
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		lastRequest = request
+		if len(request.History) == 1 {
+			return toolCallResponse("call_1", "list_users", `{}`), nil
+		}
+		return textResponse("There are 2 users."), nil
+	})
+
+	agent := NewAgent(model, []Tool{listUsers}, WithToolResultEncoder(tableEncoder))
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Who are the users?")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result := lastRequest.History[2].(*ToolResultMessage)
+
+	expected := "name | age\nAda | 36\nAlan | 41\n"
+	if string(result.Result) != expected {
+		t.Errorf("Expected encoded table %q, got %q", expected, result.Result)
+	}
+
+	if !strings.Contains(string(result.FullResult), `"name":"Ada"`) {
+		t.Errorf("Expected raw JSON to be kept as the full result, got %s", result.FullResult)
+	}
+}
+
+func TestAgentGroundFinalAnswer(t *testing.T) {
+	weather := NewGenericTool("get_weather", "Gets the weather for a city",
+		func(ctx context.Context, input cityLookup) (weatherReport, error) {
+			return weatherReport{Temperature: 21, Conditions: "sunny"}, nil
+		})
+
+	var formatterPrompt string
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		switch calls {
+		case 1:
+			return toolCallResponse("call_1", "get_weather", `{"city":"Prague"}`), nil
+		case 2:
+			return textResponse("It is warm and sunny in Prague."), nil
+		}
+
+		// The formatter copies the grounded tool result into its structured answer
+		formatterPrompt = request.History[len(request.History)-1].(*UserMessage).Content
+		_, result, _ := strings.Cut(formatterPrompt, " returned ")
+		return toolCallResponse("call_2", "formatter", result), nil
+	})
+
+	schema := json.RawMessage(`{"type":"object","properties":{"temperature":{"type":"number"},"conditions":{"type":"string"}},"required":["temperature","conditions"]}`)
+	agent := NewAgent(model, []Tool{weather}, WithOutputSchema(schema), WithGroundFinalAnswer(true))
+
+	response, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Weather in Prague?"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(formatterPrompt, `- get_weather({"city":"Prague"}) returned {"temperature":21,"conditions":"sunny"}`) {
+		t.Errorf("Expected the tool result in the formatting prompt, got %q", formatterPrompt)
+	}
+
+	var report weatherReport
+	if err := json.Unmarshal([]byte(response.Messages[0].(*UserMessage).Content), &report); err != nil {
+		t.Fatalf("Expected structured output, got %v", err)
+	}
+
+	if report.Temperature != 21 || report.Conditions != "sunny" {
+		t.Errorf("Expected the structured output to reflect the tool result, got %+v", report)
+	}
+}
+
+func TestAgentRetryOnEmptyResponse(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		if calls == 1 {
+			return textResponse("  "), nil
+		}
+		return textResponse("Here is the answer."), nil
+	})
+
+	agent := NewAgent(model, nil, WithRetryOnEmptyResponse(2))
+
+	response, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Question?"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected 2 LLM calls, got %d", calls)
+	}
+
+	if content := response.Messages[0].(*AssistantMessage).Content; content != "Here is the answer." {
+		t.Errorf("Expected the non-empty answer, got %q", content)
+	}
+}
+
+func TestAgentRetryOnEmptyResponseGivesUp(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		return NewLLMResponse(), nil
+	})
+
+	agent := NewAgent(model, nil, WithRetryOnEmptyResponse(2))
+
+	_, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Question?"))))
+	if !errors.Is(err, ErrEmptyResponse) {
+		t.Fatalf("Expected ErrEmptyResponse, got %v", err)
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected the initial call and 2 retries, got %d calls", calls)
+	}
+}
+
+// blockingTool waits on its release channel before returning, recording when it started
+type blockingTool struct {
+	name    string
+	started chan string
+	release chan struct{}
+	fail    bool
+}
+
+func (b *blockingTool) Name() string                    { return b.name }
+func (b *blockingTool) Description() string             { return "Blocks until released" }
+func (b *blockingTool) InputSchemaRaw() json.RawMessage { return json.RawMessage(`{"type": "object"}`) }
+func (b *blockingTool) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	b.started <- b.name
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if b.fail {
+		return nil, NewPermanentError(errors.New("no rooms left"))
+	}
+	return json.RawMessage(fmt.Sprintf(`{"booked": %q}`, b.name)), nil
+}
+
+// parallelCallsResponse builds a response calling the given tools in one turn
+func parallelCallsResponse(names ...string) *LLMResponse {
+	response := NewLLMResponse()
+	for i, name := range names {
+		response.AddToolCall(&ToolCall{ID: fmt.Sprintf("call_%d", i+1), Name: name, Args: json.RawMessage(`{}`)})
+	}
+	return response
+}
+
+func TestAgentConcurrentToolCalls(t *testing.T) {
+	started := make(chan string, 2)
+	flightRelease := make(chan struct{})
+	hotelRelease := make(chan struct{})
+	flight := &blockingTool{name: "book_flight", started: started, release: flightRelease}
+	hotel := &blockingTool{name: "book_hotel", started: started, release: hotelRelease, fail: true}
+
+	var history History
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		if calls == 1 {
+			return parallelCallsResponse("book_flight", "book_hotel"), nil
+		}
+		history = request.History
+		return textResponse("done"), nil
+	})
+
+	agent := NewAgent(model, []Tool{flight, hotel}, WithConcurrentToolCalls(2))
+
+	// Both calls have to start before either is released, then the second one completes first
+	go func() {
+		for range 2 {
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				close(flightRelease)
+				close(hotelRelease)
+				return
+			}
+		}
+		close(hotelRelease)
+		time.Sleep(10 * time.Millisecond)
+		close(flightRelease)
+	}()
+
+	start := time.Now()
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Book my trip")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if time.Since(start) >= time.Second {
+		t.Fatal("Expected the tool calls to run concurrently")
+	}
+
+	// User message, two tool calls and their results in call order
+	if len(history) != 5 {
+		t.Fatalf("Expected 5 messages, got %d", len(history))
+	}
+
+	flightResult, ok := history[3].(*ToolResultMessage)
+	if !ok || flightResult.ToolCall.Name != "book_flight" || !strings.Contains(string(flightResult.Result), "booked") {
+		t.Errorf("Expected the flight result first, got %s", describeMessage(history[3]))
+	}
+
+	// The failing hotel booking doesn't prevent the flight from finishing
+	hotelResult, ok := history[4].(*ToolResultMessage)
+	if !ok || hotelResult.ToolCall.Name != "book_hotel" || !strings.Contains(string(hotelResult.Result), "no rooms left") {
+		t.Errorf("Expected the failed hotel result second, got %s", describeMessage(history[4]))
+	}
+}
+
+func TestAgentConcurrentToolCallsCancellation(t *testing.T) {
+	started := make(chan string, 3)
+	release := make(chan struct{})
+	tools := []Tool{
+		&blockingTool{name: "first", started: started, release: release},
+		&blockingTool{name: "second", started: started, release: release},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return parallelCallsResponse("first", "second", "first"), nil
+	})
+
+	agent := NewAgent(model, tools, WithConcurrentToolCalls(2), WithMaxRetries(0))
+
+	go func() {
+		<-started
+		<-started
+		cancel()
+	}()
+
+	_, err := agent.Invoke(ctx, NewLLMRequest(NewHistory(NewUserMessage("go"))))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the run to be cancelled, got %v", err)
+	}
+
+	// The third call was still waiting for a slot and never started
+	if len(started) != 0 {
+		t.Errorf("Expected pending tool calls not to start after cancellation, %d did", len(started))
+	}
+}
+
+func TestAgentMaxIterations(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		response := toolCallResponse(fmt.Sprintf("call_%d", calls), "test_tool", `{}`)
+		response.Messages = append(History{&AssistantMessage{Content: fmt.Sprintf("Trying again (%d)", calls)}}, response.Messages...)
+		return response, nil
+	})
+
+	agent := NewAgent(model, []Tool{&mockTool{name: "test_tool"}}, WithMaxIterations(3))
+
+	_, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("loop"))))
+	if !errors.Is(err, ErrMaxIterationsExceeded) {
+		t.Fatalf("Expected ErrMaxIterationsExceeded, got %v", err)
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected 3 LLM calls, got %d", calls)
+	}
+
+	var iterationsErr *MaxIterationsError
+	if !errors.As(err, &iterationsErr) {
+		t.Fatalf("Expected MaxIterationsError, got %T", err)
+	}
+
+	if iterationsErr.Iterations != 3 {
+		t.Errorf("Expected 3 iterations, got %d", iterationsErr.Iterations)
+	}
+
+	if iterationsErr.LastAssistantMessage == nil || iterationsErr.LastAssistantMessage.Content != "Trying again (3)" {
+		t.Errorf("Expected the last assistant message, got %+v", iterationsErr.LastAssistantMessage)
+	}
+}
+
+func TestAgentMaxIterationsDefault(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		return toolCallResponse(fmt.Sprintf("call_%d", calls), "test_tool", `{}`), nil
+	})
+
+	agent := NewAgent(model, []Tool{&mockTool{name: "test_tool"}})
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("loop")))); !errors.Is(err, ErrMaxIterationsExceeded) {
+		t.Fatalf("Expected ErrMaxIterationsExceeded, got %v", err)
+	}
+
+	if calls != 10 {
+		t.Errorf("Expected the default cap of 10 LLM calls, got %d", calls)
+	}
+}
+
+func TestAgentAccumulatesUsage(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+
+		var response *LLMResponse
+		if calls == 1 {
+			response = toolCallResponse("call_1", "test_tool", `{}`)
+		} else {
+			response = textResponse("done")
+		}
+		response.Usage = &Usage{PromptTokens: 10 * calls, CompletionTokens: calls, TotalTokens: 11 * calls}
+		return response, nil
+	})
+
+	agent := NewAgent(model, []Tool{&mockTool{name: "test_tool"}})
+
+	response, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("go"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := Usage{PromptTokens: 30, CompletionTokens: 3, TotalTokens: 33}
+	if response.Usage == nil || *response.Usage != expected {
+		t.Errorf("Expected usage %+v across both turns, got %+v", expected, response.Usage)
+	}
+}
+
+func TestAgentToolApproverDenies(t *testing.T) {
+	var requests []*LLMRequest
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		requests = append(requests, request)
+		if len(requests) == 1 {
+			return toolCallResponse("call_1", "book_flight", `{}`), nil
+		}
+		return textResponse("I could not book the flight"), nil
+	})
+
+	booked := false
+	bookFlight := NewGenericTool("book_flight", "Books a flight",
+		func(ctx context.Context, input weatherReport) (json.RawMessage, error) {
+			booked = true
+			return json.RawMessage(`{"booked": true}`), nil
+		})
+
+	var approved []string
+	agent := NewAgent(model, []Tool{bookFlight}, WithToolApprover(func(ctx context.Context, toolCall *ToolCall) (bool, error) {
+		approved = append(approved, toolCall.ID)
+		return false, nil
+	}))
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Book me a flight")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if booked {
+		t.Errorf("Expected the denied tool not to run")
+	}
+	if len(approved) != 1 || approved[0] != "call_1" {
+		t.Errorf("Expected the approver to be consulted for call_1, got %v", approved)
+	}
+
+	result, ok := requests[1].History[len(requests[1].History)-1].(*ToolResultMessage)
+	if !ok || !strings.Contains(string(result.Result), "denied") {
+		t.Errorf("Expected the model to be told the call was denied, got %v", requests[1].History)
+	}
+}
+
+func TestAgentToolApproverErrorAbortsRun(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		return toolCallResponse("call_1", "book_flight", `{}`), nil
+	})
+
+	bookFlight := NewGenericTool("book_flight", "Books a flight",
+		func(ctx context.Context, input weatherReport) (json.RawMessage, error) {
+			return json.RawMessage(`{"booked": true}`), nil
+		})
+
+	approverErr := errors.New("approval service unavailable")
+	agent := NewAgent(model, []Tool{bookFlight}, WithToolApprover(func(ctx context.Context, toolCall *ToolCall) (bool, error) {
+		return false, approverErr
+	}))
+
+	_, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Book me a flight"))))
+
+	var approvalErr *ToolApprovalError
+	if !errors.As(err, &approvalErr) || approvalErr.Tool != "book_flight" || !errors.Is(err, approverErr) {
+		t.Fatalf("Expected a tool approval error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the run to stop after the first iteration, got %d LLM calls", calls)
+	}
+}
+
+func TestAgentRunsToolCallsOfAssistantMessage(t *testing.T) {
+	runs := 0
+	lookup := NewGenericTool("lookup", "Looks up a record",
+		func(ctx context.Context, input struct{}) (string, error) {
+			runs++
+			return "found", nil
+		})
+
+	var lastRequest *LLMRequest
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		lastRequest = request
+		if len(request.History) == 1 {
+			response := NewLLMResponse()
+			response.AddMessage(&AssistantMessage{
+				Content:   "Let me look that up.",
+				ToolCalls: []*ToolCall{{ID: "call_1", Name: "lookup", Args: json.RawMessage(`{}`)}},
+			})
+			return response, nil
+		}
+		return textResponse("The record was found."), nil
+	})
+
+	agent := NewAgent(model, []Tool{lookup})
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Find record 7")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if runs != 1 {
+		t.Errorf("Expected the tool to run once, got %d runs", runs)
+	}
+
+	if len(lastRequest.History) != 3 {
+		t.Fatalf("Expected user, assistant turn and tool result, got %d messages", len(lastRequest.History))
+	}
+	if result, ok := lastRequest.History[2].(*ToolResultMessage); !ok || result.ToolCall.ID != "call_1" {
+		t.Errorf("Expected the tool result for call_1, got %+v", lastRequest.History[2])
+	}
+}
+
+func TestAgentInvocationsAreIsolated(t *testing.T) {
+	// The model calls a tool first and then answers with every user message it was sent
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		var users []string
+		toolResults := 0
+		for _, msg := range request.History {
+			switch m := msg.(type) {
+			case *UserMessage:
+				users = append(users, m.Content)
+			case *ToolResultMessage:
+				toolResults++
+			}
+		}
+
+		if toolResults == 0 {
+			return toolCallResponse("call_1", "test_tool", `{}`), nil
+		}
+
+		response := textResponse(fmt.Sprintf("%s (%d tool results)", strings.Join(users, ", "), toolResults))
+		response.Usage = &Usage{TotalTokens: 10}
+		return response, nil
+	})
+
+	agent := NewAgent(model, []Tool{&mockTool{name: "test_tool"}})
+
+	invoke := func(input string) (string, error) {
+		request := NewLLMRequest(NewHistory(NewUserMessage(input)))
+
+		response, err := agent.Invoke(context.Background(), request)
+		if err != nil {
+			return "", err
+		}
+		if len(request.History) != 1 {
+			return "", fmt.Errorf("the request history was changed to %d messages", len(request.History))
+		}
+		if response.Usage == nil || response.Usage.TotalTokens != 10 {
+			return "", fmt.Errorf("usage of another run leaked in: %+v", response.Usage)
+		}
+
+		return lastAssistantMessage(response.Messages).Content, nil
+	}
+
+	// Reusing the agent starts every conversation from scratch
+	for _, input := range []string{"alice", "bob"} {
+		answer, err := invoke(input)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if expected := input + " (1 tool results)"; answer != expected {
+			t.Errorf("Expected answer %q, got %q", expected, answer)
+		}
+	}
+
+	// So does calling it concurrently
+	errs := make(chan error, 10)
+	for i := range 10 {
+		input := fmt.Sprintf("user-%d", i)
+		go func() {
+			answer, err := invoke(input)
+			if err == nil && answer != input+" (1 tool results)" {
+				err = fmt.Errorf("expected only %s in the conversation, got %q", input, answer)
+			}
+			errs <- err
+		}()
+	}
+	for range 10 {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestAgentRequestID(t *testing.T) {
+	var requests []*LLMRequest
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		requests = append(requests, request)
+		if forced, ok := request.ToolUsage.(*ForcedToolUsage); ok {
+			return toolCallResponse("fix_1", forced.ToolName, `{"param": "correct"}`), nil
+		}
+		if len(request.History) == 1 {
+			return toolCallResponse("call_1", "test_tool", `{"param": "wrong"}`), nil
+		}
+		return textResponse("Done"), nil
+	})
+
+	tool := &mockTool{name: "test_tool", shouldFail: true, correctArgs: json.RawMessage(`{"param": "correct"}`)}
+	agent := NewAgent(model, []Tool{tool}, WithMaxRetries(1), WithRetryDelay(time.Millisecond))
+
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	defer slog.SetDefault(previous)
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Go")), WithIdempotencyKey("job-7"))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("Expected 2 iterations and a correction call, got %d calls", len(requests))
+	}
+
+	requestID := requests[0].RequestID
+	if requestID == "" {
+		t.Fatal("Expected the agent to generate a request ID")
+	}
+	for i, request := range requests {
+		if request.RequestID != requestID {
+			t.Errorf("Expected call %d to carry request ID %s, got %q", i, requestID, request.RequestID)
+		}
+	}
+
+	// Each LLM call of the run has a key of its own, the correction call none
+	if requests[0].IdempotencyKey != "job-7-1" || requests[1].IdempotencyKey != "" || requests[2].IdempotencyKey != "job-7-2" {
+		t.Errorf("Expected per-iteration idempotency keys, got %q, %q and %q",
+			requests[0].IdempotencyKey, requests[1].IdempotencyKey, requests[2].IdempotencyKey)
+	}
+
+	var retryLines int
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", line, err)
+		}
+		if entry["request_id"] != requestID {
+			t.Errorf("Expected log line %q to carry the request ID", line)
+		}
+		if strings.Contains(line, "correct parameters") {
+			retryLines++
+		}
+	}
+	if retryLines == 0 {
+		t.Error("Expected the retry to be logged")
+	}
+
+	// The next run is another interaction, unless the caller passes its own ID
+	requests = nil
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Go")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requests[0].RequestID == "" || requests[0].RequestID == requestID {
+		t.Errorf("Expected a new request ID for the next run, got %q", requests[0].RequestID)
+	}
+
+	requests = nil
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Go")), WithRequestID("req-42"))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requests[0].RequestID != "req-42" {
+		t.Errorf("Expected the caller's request ID, got %q", requests[0].RequestID)
+	}
+}
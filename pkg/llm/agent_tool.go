@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// agentTool adapts an LLM, typically a specialized Agent, into a tool another agent can call
+type agentTool struct {
+	name        string
+	description string
+	inputSchema json.RawMessage
+	agent       LLM
+}
+
+// AgentTool turns the agent into a tool, so that a planning agent can delegate to it. Each run starts
+// a fresh conversation with the arguments as its single user message: a JSON string argument is sent
+// as its text, any other arguments as their JSON. The final assistant text is returned as the result.
+//
+// The sub-agent is invoked with the context of the tool call, so cancellation, deadlines and data set
+// with WithToolContext reach its own tools. Nothing limits the depth of delegation besides each agent's
+// WithMaxIterations, so avoid giving an agent a tool that leads back to itself.
+func AgentTool(name, description string, inputSchema json.RawMessage, agent LLM) Tool {
+	return &agentTool{
+		name:        name,
+		description: description,
+		inputSchema: inputSchema,
+		agent:       agent,
+	}
+}
+
+// Name returns the name of the tool
+func (t *agentTool) Name() string {
+	return t.name
+}
+
+// Description returns the description of the tool
+func (t *agentTool) Description() string {
+	return t.description
+}
+
+// InputSchemaRaw returns the JSON schema for the tool's input
+func (t *agentTool) InputSchemaRaw() json.RawMessage {
+	return t.inputSchema
+}
+
+// Run invokes the sub-agent with the arguments and returns its final answer as a JSON string
+func (t *agentTool) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	prompt := string(args)
+
+	var text string
+	if err := json.Unmarshal(args, &text); err == nil {
+		prompt = text
+	}
+
+	response, err := t.agent.Invoke(ctx, NewLLMRequest(NewHistory(NewUserMessage(prompt))))
+	if err != nil {
+		return nil, fmt.Errorf("sub-agent %s failed: %w", t.name, err)
+	}
+
+	answer := lastAssistantMessage(response.Messages)
+	if answer == nil {
+		return nil, fmt.Errorf("sub-agent %s gave no answer", t.name)
+	}
+
+	return json.Marshal(answer.Content)
+}
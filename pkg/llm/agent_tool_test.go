@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type agentToolContextKey struct{}
+
+func TestAgentTool(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           string
+		expectedPrompt string
+	}{
+		{"string arguments", `"Summarize the report"`, "Summarize the report"},
+		{"object arguments", `{"topic":"report"}`, `{"topic":"report"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompt string
+			var value any
+			inner := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+				prompt = request.History[0].(*UserMessage).Content
+				value = ctx.Value(agentToolContextKey{})
+				return textResponse("The report is fine"), nil
+			})
+
+			tool := AgentTool("researcher", "Researches a topic", json.RawMessage(`{"type":"object"}`), NewAgent(inner, nil))
+			if tool.Name() != "researcher" || tool.Description() != "Researches a topic" || string(tool.InputSchemaRaw()) != `{"type":"object"}` {
+				t.Errorf("Expected the tool to describe itself as given, got %s %q %s", tool.Name(), tool.Description(), tool.InputSchemaRaw())
+			}
+
+			ctx := context.WithValue(context.Background(), agentToolContextKey{}, "request-1")
+			result, err := tool.Run(ctx, json.RawMessage(tt.args))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if prompt != tt.expectedPrompt {
+				t.Errorf("Expected prompt %q, got %q", tt.expectedPrompt, prompt)
+			}
+			if value != "request-1" {
+				t.Errorf("Expected the context to reach the sub-agent, got %v", value)
+			}
+			if string(result) != `"The report is fine"` {
+				t.Errorf("Expected the final answer as the result, got %s", result)
+			}
+		})
+	}
+}
+
+func TestAgentToolThroughPlanner(t *testing.T) {
+	inner := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		return textResponse("42"), nil
+	})
+	researcher := AgentTool("researcher", "Researches a topic", json.RawMessage(`{"type":"string"}`), NewAgent(inner, nil))
+
+	calls := 0
+	var delivered string
+	planner := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		if calls == 1 {
+			return toolCallResponse("call_1", "researcher", `"What is the answer?"`), nil
+		}
+		for _, msg := range request.History {
+			if result, ok := msg.(*ToolResultMessage); ok {
+				delivered = string(result.Result)
+			}
+		}
+		return textResponse("The answer is 42"), nil
+	})
+
+	if _, err := NewAgent(planner, []Tool{researcher}).Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Go")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if delivered != `"42"` {
+		t.Errorf("Expected the sub-agent's answer as the tool result, got %s", delivered)
+	}
+}
+
+func TestAgentToolErrors(t *testing.T) {
+	failure := errors.New("model unavailable")
+	tests := []struct {
+		name     string
+		response *LLMResponse
+		err      error
+	}{
+		{"failed sub-agent", nil, failure},
+		{"no answer", NewLLMResponse(), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+				return tt.response, tt.err
+			})
+
+			_, err := AgentTool("researcher", "", nil, inner).Run(context.Background(), json.RawMessage(`"Go"`))
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("Expected the sub-agent's error to be wrapped, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ScoredOutput is an output of a task along with its score
+type ScoredOutput[O any] struct {
+	Output O
+	Score  float64
+}
+
+// BestOfNResult is the highest scoring output of the runs and every run's scored output, in run order
+type BestOfNResult[O any] struct {
+	Best       ScoredOutput[O]
+	Candidates []ScoredOutput[O]
+}
+
+// BestOfN runs the task n times concurrently with the same input, scores each output with the eval
+// and returns the highest scoring one, the earliest run on a tie. Sampling several answers and keeping
+// the best improves quality when the eval is more reliable than a single run, e.g. tests of generated
+// code. Any failing run or score fails the whole call.
+func BestOfN[I, O any](ctx context.Context, task Task[I, O], eval Eval[O], input I, n int) (*BestOfNResult[O], error) {
+	if n < 1 {
+		return nil, fmt.Errorf("best of n needs at least one run, got %d", n)
+	}
+
+	candidates := make([]ScoredOutput[O], n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			output, err := task.Run(ctx, input)
+			if err != nil {
+				errs[i] = fmt.Errorf("run %d failed: %w", i, err)
+				return
+			}
+
+			score, err := eval.Score(ctx, output)
+			if err != nil {
+				errs[i] = fmt.Errorf("scoring run %d failed: %w", i, err)
+				return
+			}
+
+			candidates[i] = ScoredOutput[O]{Output: output, Score: score}
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	result := &BestOfNResult[O]{Best: candidates[0], Candidates: candidates}
+	for _, candidate := range candidates[1:] {
+		if candidate.Score > result.Best.Score {
+			result.Best = candidate
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// draftTask answers with a numbered draft on each run
+type draftTask struct {
+	mu   sync.Mutex
+	runs int
+}
+
+func (d *draftTask) Run(ctx context.Context, topic string) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.runs++
+	return fmt.Sprintf("%s draft %s", topic, strings.Repeat("!", d.runs)), nil
+}
+
+// lengthEval scores outputs by their length, failing for outputs containing fail
+type lengthEval struct{}
+
+func (lengthEval) Score(ctx context.Context, output string) (float64, error) {
+	if strings.Contains(output, "fail") {
+		return 0, errors.New("cannot score")
+	}
+
+	return float64(len(output)), nil
+}
+
+func TestBestOfN(t *testing.T) {
+	task := &draftTask{}
+
+	result, err := BestOfN[string, string](context.Background(), task, lengthEval{}, "essay", 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if task.runs != 3 {
+		t.Errorf("Expected 3 runs, got %d", task.runs)
+	}
+	if len(result.Candidates) != 3 {
+		t.Fatalf("Expected 3 scored candidates, got %d", len(result.Candidates))
+	}
+	for _, candidate := range result.Candidates {
+		if candidate.Score != float64(len(candidate.Output)) {
+			t.Errorf("Expected %q to be scored %d, got %v", candidate.Output, len(candidate.Output), candidate.Score)
+		}
+	}
+
+	if result.Best.Output != "essay draft !!!" || result.Best.Score != 15 {
+		t.Errorf("Expected the longest draft to win, got %+v", result.Best)
+	}
+}
+
+func TestBestOfNFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		topic    string
+		n        int
+		expected string
+	}{
+		{name: "No runs", topic: "essay", n: 0, expected: "best of n needs at least one run, got 0"},
+		{name: "Failing eval", topic: "fail", n: 1, expected: "scoring run 0 failed: cannot score"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BestOfN[string, string](context.Background(), &draftTask{}, lengthEval{}, tt.topic, tt.n)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestBestOfNWithLLMTask(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls++
+		if calls == 2 {
+			return textResponse("A thorough answer"), nil
+		}
+		return textResponse("Short"), nil
+	})
+
+	result, err := BestOfN(context.Background(), LLMTask(model), answerLengthEval{}, NewLLMRequest(NewHistory(NewUserMessage("Explain"))), 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if answer := lastAssistantMessage(result.Best.Output.Messages); answer == nil || answer.Content != "A thorough answer" {
+		t.Errorf("Expected the thorough answer to win, got %v", result.Best.Output.Messages)
+	}
+}
+
+// answerLengthEval scores responses by the length of their final answer
+type answerLengthEval struct{}
+
+func (answerLengthEval) Score(ctx context.Context, response *LLMResponse) (float64, error) {
+	answer := lastAssistantMessage(response.Messages)
+	if answer == nil {
+		return 0, nil
+	}
+
+	return float64(len(answer.Content)), nil
+}
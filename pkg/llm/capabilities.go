@@ -0,0 +1,26 @@
+package llm
+
+// Capabilities describes which features an LLM provider supports, so higher-level code
+// can pick a strategy accordingly (e.g. native structured output vs. forced tools)
+type Capabilities struct {
+	Streaming         bool
+	ForcedTools       bool
+	JSONMode          bool
+	StructuredOutput  bool
+	Vision            bool
+	ParallelToolCalls bool
+}
+
+// CapabilityProvider is implemented by LLMs that can describe their capabilities
+type CapabilityProvider interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities reported by the LLM, or no capabilities when it doesn't describe them
+func CapabilitiesOf(model LLM) Capabilities {
+	if provider, ok := model.(CapabilityProvider); ok {
+		return provider.Capabilities()
+	}
+
+	return Capabilities{}
+}
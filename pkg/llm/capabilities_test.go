@@ -0,0 +1,38 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+type capableLLM struct {
+	capabilities Capabilities
+}
+
+func (c *capableLLM) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	return NewLLMResponse(), nil
+}
+
+func (c *capableLLM) Capabilities() Capabilities {
+	return c.capabilities
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	capable := &capableLLM{capabilities: Capabilities{ForcedTools: true, Streaming: true}}
+
+	if got := CapabilitiesOf(capable); got != capable.capabilities {
+		t.Errorf("Expected %+v, got %+v", capable.capabilities, got)
+	}
+
+	if got := CapabilitiesOf(&flakyLLM{}); got != (Capabilities{}) {
+		t.Errorf("Expected no capabilities for an LLM not describing them, got %+v", got)
+	}
+
+	if got := CapabilitiesOf(NewCircuitBreakerLLM(capable)); got != (Capabilities{ForcedTools: true}) {
+		t.Errorf("Expected circuit breaker to report wrapped capabilities without streaming, got %+v", got)
+	}
+
+	if got := CapabilitiesOf(NewRateLimitedLLM(capable, 1, 1)); got != (Capabilities{ForcedTools: true}) {
+		t.Errorf("Expected rate limiter to report wrapped capabilities without streaming, got %+v", got)
+	}
+}
@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned while the circuit breaker is open and calls fail fast
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState represents the state of a circuit breaker
+type CircuitState string
+
+const (
+	// CircuitClosed lets calls through and counts consecutive failures
+	CircuitClosed CircuitState = "closed"
+
+	// CircuitOpen fails all calls fast until the cooldown elapses
+	CircuitOpen CircuitState = "open"
+
+	// CircuitHalfOpen lets a single probe call through to test recovery
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreakerLLM wraps an LLM and stops calling it after repeated failures
+type CircuitBreakerLLM struct {
+	inner LLM
+
+	failureThreshold int
+	failureWindow    time.Duration
+	cooldown         time.Duration
+	now              func() time.Time
+
+	mu             sync.Mutex
+	state          CircuitState
+	failures       int
+	firstFailureAt time.Time
+	openedAt       time.Time
+	probing        bool
+}
+
+// CircuitBreakerOpts represents options for configuring a circuit breaker
+type CircuitBreakerOpts = func(*CircuitBreakerLLM)
+
+// WithFailureThreshold sets the number of consecutive failures that open the circuit
+func WithFailureThreshold(threshold int) CircuitBreakerOpts {
+	return func(c *CircuitBreakerLLM) {
+		c.failureThreshold = threshold
+	}
+}
+
+// WithFailureWindow only counts consecutive failures happening within the window, a streak
+// older than that starts over. Without a window all consecutive failures count.
+func WithFailureWindow(window time.Duration) CircuitBreakerOpts {
+	return func(c *CircuitBreakerLLM) {
+		c.failureWindow = window
+	}
+}
+
+// WithCooldown sets how long the circuit stays open before a probe call is allowed
+func WithCooldown(cooldown time.Duration) CircuitBreakerOpts {
+	return func(c *CircuitBreakerLLM) {
+		c.cooldown = cooldown
+	}
+}
+
+// NewCircuitBreakerLLM creates a circuit breaker around the given LLM
+func NewCircuitBreakerLLM(inner LLM, opts ...CircuitBreakerOpts) *CircuitBreakerLLM {
+	c := &CircuitBreakerLLM{
+		inner:            inner,
+		failureThreshold: 5,                // Default: open after 5 consecutive failures
+		cooldown:         30 * time.Second, // Default: 30s cooldown
+		now:              time.Now,
+		state:            CircuitClosed,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Invoke implements the LLM interface, failing fast with ErrCircuitOpen while the circuit is open
+func (c *CircuitBreakerLLM) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	if err := c.allow(); err != nil {
+		return nil, err
+	}
+
+	response, err := c.inner.Invoke(ctx, request)
+	c.record(ctx, err)
+
+	return response, err
+}
+
+// allow decides whether a call may proceed, moving an expired open circuit to half-open
+func (c *CircuitBreakerLLM) allow() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case CircuitOpen:
+		if c.now().Sub(c.openedAt) < c.cooldown {
+			return ErrCircuitOpen
+		}
+
+		c.state = CircuitHalfOpen
+		c.probing = true
+		return nil
+
+	case CircuitHalfOpen:
+		// Only a single probe is allowed while testing recovery
+		if c.probing {
+			return ErrCircuitOpen
+		}
+
+		c.probing = true
+		return nil
+	}
+
+	return nil
+}
+
+// record updates the circuit state with the outcome of a call
+func (c *CircuitBreakerLLM) record(ctx context.Context, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.probing = false
+
+	if err == nil {
+		c.state = CircuitClosed
+		c.failures = 0
+		return
+	}
+
+	// Calls cancelled by the caller say nothing about the provider's health
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := c.now()
+	if c.failures > 0 && c.failureWindow > 0 && now.Sub(c.firstFailureAt) > c.failureWindow {
+		c.failures = 0
+	}
+	if c.failures == 0 {
+		c.firstFailureAt = now
+	}
+
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= c.failureThreshold {
+		c.state = CircuitOpen
+		c.openedAt = now
+	}
+}
+
+// State reports the current state of the circuit, e.g. for metrics. An open circuit whose
+// cooldown has elapsed reports half-open, the next call probes the provider.
+func (c *CircuitBreakerLLM) State() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == CircuitOpen && c.now().Sub(c.openedAt) >= c.cooldown {
+		return CircuitHalfOpen
+	}
+
+	return c.state
+}
+
+// Capabilities reports the capabilities of the wrapped LLM, except streaming as the breaker only guards Invoke
+func (c *CircuitBreakerLLM) Capabilities() Capabilities {
+	capabilities := CapabilitiesOf(c.inner)
+	capabilities.Streaming = false
+
+	return capabilities
+}
@@ -0,0 +1,144 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyLLM fails while fail is set
+type flakyLLM struct {
+	fail  bool
+	calls int
+}
+
+func (f *flakyLLM) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	f.calls++
+	if f.fail {
+		return nil, errors.New("provider unavailable")
+	}
+	return NewLLMResponse(), nil
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	inner := &flakyLLM{fail: true}
+	breaker := NewCircuitBreakerLLM(inner, WithFailureThreshold(2), WithCooldown(time.Minute))
+	breaker.now = func() time.Time { return now }
+
+	request := NewLLMRequest(NewHistory())
+
+	// Closed: failures pass through until the threshold
+	for i := 0; i < 2; i++ {
+		if _, err := breaker.Invoke(ctx, request); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected provider error on call %d, got %v", i+1, err)
+		}
+	}
+
+	if breaker.state != CircuitOpen {
+		t.Fatalf("Expected circuit to be open, got %s", breaker.state)
+	}
+
+	// Open: fail fast without calling the provider
+	if _, err := breaker.Invoke(ctx, request); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	if inner.calls != 2 {
+		t.Errorf("Expected provider not to be called while open, got %d calls", inner.calls)
+	}
+
+	// Half-open: a failed probe reopens the circuit
+	now = now.Add(time.Minute)
+	if _, err := breaker.Invoke(ctx, request); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected probe to reach the provider, got %v", err)
+	}
+
+	if breaker.state != CircuitOpen {
+		t.Fatalf("Expected failed probe to reopen the circuit, got %s", breaker.state)
+	}
+
+	// Half-open: a successful probe closes the circuit
+	now = now.Add(time.Minute)
+	inner.fail = false
+	if _, err := breaker.Invoke(ctx, request); err != nil {
+		t.Errorf("Expected probe to succeed, got %v", err)
+	}
+
+	if breaker.state != CircuitClosed {
+		t.Errorf("Expected circuit to be closed after recovery, got %s", breaker.state)
+	}
+
+	if inner.calls != 4 {
+		t.Errorf("Expected 4 provider calls, got %d", inner.calls)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	ctx := context.Background()
+	inner := &flakyLLM{}
+	breaker := NewCircuitBreakerLLM(inner, WithFailureThreshold(2))
+	request := NewLLMRequest(NewHistory())
+
+	inner.fail = true
+	breaker.Invoke(ctx, request)
+	inner.fail = false
+	breaker.Invoke(ctx, request)
+	inner.fail = true
+	breaker.Invoke(ctx, request)
+
+	if breaker.state != CircuitClosed {
+		t.Errorf("Expected non-consecutive failures to keep the circuit closed, got %s", breaker.state)
+	}
+}
+
+func TestCircuitBreakerFailureWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	inner := &flakyLLM{fail: true}
+	breaker := NewCircuitBreakerLLM(inner, WithFailureThreshold(2), WithFailureWindow(time.Minute))
+	breaker.now = func() time.Time { return now }
+	request := NewLLMRequest(NewHistory())
+
+	// Failures further apart than the window don't add up
+	breaker.Invoke(ctx, request)
+	now = now.Add(2 * time.Minute)
+	breaker.Invoke(ctx, request)
+
+	if breaker.State() != CircuitClosed {
+		t.Fatalf("Expected failures outside the window to keep the circuit closed, got %s", breaker.State())
+	}
+
+	now = now.Add(30 * time.Second)
+	breaker.Invoke(ctx, request)
+
+	if breaker.State() != CircuitOpen {
+		t.Errorf("Expected failures within the window to open the circuit, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreakerState(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	breaker := NewCircuitBreakerLLM(&flakyLLM{fail: true}, WithFailureThreshold(1), WithCooldown(time.Minute))
+	breaker.now = func() time.Time { return now }
+
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected a new circuit to be closed, got %s", breaker.State())
+	}
+
+	breaker.Invoke(ctx, NewLLMRequest(NewHistory()))
+	if breaker.State() != CircuitOpen {
+		t.Errorf("Expected the circuit to be open, got %s", breaker.State())
+	}
+
+	now = now.Add(time.Minute)
+	if breaker.State() != CircuitHalfOpen {
+		t.Errorf("Expected the circuit to be half-open after the cooldown, got %s", breaker.State())
+	}
+}
@@ -0,0 +1,9 @@
+package llm
+
+import "context"
+
+// Embedder turns texts into embedding vectors, e.g. for semantic search over documents.
+// The returned slice holds one vector per text, in the order of the input.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
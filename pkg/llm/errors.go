@@ -0,0 +1,237 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IterationTimeoutError is returned when a single agent iteration exceeds its timeout.
+// It carries the history accumulated before the timed out call so partial progress is not lost.
+type IterationTimeoutError struct {
+	Iteration int
+	Timeout   time.Duration
+	History   History
+	Err       error
+}
+
+func (e *IterationTimeoutError) Error() string {
+	return fmt.Sprintf("agent iteration %d timed out after %s: %v", e.Iteration, e.Timeout, e.Err)
+}
+
+func (e *IterationTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// ToolTimeoutError is returned when a tool call runs longer than the agent's tool timeout
+type ToolTimeoutError struct {
+	Tool    string
+	Timeout time.Duration
+}
+
+func (e *ToolTimeoutError) Error() string {
+	return fmt.Sprintf("tool %s timed out after %s", e.Tool, e.Timeout)
+}
+
+func (e *ToolTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// ToolApprovalError is returned when the tool approver fails, it aborts the agent run
+type ToolApprovalError struct {
+	Tool string
+	Err  error
+}
+
+func (e *ToolApprovalError) Error() string {
+	return fmt.Sprintf("failed to approve call to tool %s: %v", e.Tool, e.Err)
+}
+
+func (e *ToolApprovalError) Unwrap() error {
+	return e.Err
+}
+
+// ToolNotFoundError is returned when the model calls a tool the agent doesn't have
+type ToolNotFoundError struct {
+	Tool string
+}
+
+func (e *ToolNotFoundError) Error() string {
+	return fmt.Sprintf("tool not found: %s", e.Tool)
+}
+
+// PipelineError is returned when a pipeline stage fails, Stage names the failed stage
+type PipelineError struct {
+	Stage string
+	Err   error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("pipeline stage %s failed: %v", e.Stage, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// ToolExecutionError is returned when a tool call still fails after its retries, Err is the last failure
+type ToolExecutionError struct {
+	Tool     string
+	Attempts int
+	Err      error
+}
+
+func (e *ToolExecutionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ToolExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaValidationError is returned when tool arguments or structured output don't match the schema.
+// It unwraps to the FieldErrors listing each violation.
+type SchemaValidationError struct {
+	Tool   string
+	Errors FieldErrors
+}
+
+func (e *SchemaValidationError) Error() string {
+	return e.Errors.Error()
+}
+
+func (e *SchemaValidationError) Unwrap() error {
+	return e.Errors
+}
+
+// RefusalError is returned by the agent when the model declined to answer for policy reasons,
+// Refusal is the model's explanation
+type RefusalError struct {
+	Refusal string
+}
+
+func (e *RefusalError) Error() string {
+	if e.Refusal == "" {
+		return "model refused to answer"
+	}
+	return fmt.Sprintf("model refused to answer: %s", e.Refusal)
+}
+
+// LLMProviderError is returned by adapters when the provider's API call fails. StatusCode is the
+// HTTP status of the failed call, zero when no response was received, e.g. on a network error.
+type LLMProviderError struct {
+	Provider   string
+	StatusCode int
+	Err        error
+}
+
+func (e *LLMProviderError) Error() string {
+	return fmt.Sprintf("%s API call failed: %v", e.Provider, e.Err)
+}
+
+func (e *LLMProviderError) Unwrap() error {
+	return e.Err
+}
+
+// ErrMaxIterationsExceeded is matched by errors.Is when an agent run hits its iteration cap
+var ErrMaxIterationsExceeded = errors.New("agent exceeded the maximum number of iterations")
+
+// MaxIterationsError is returned when an agent run keeps calling tools past WithMaxIterations.
+// It carries the last assistant message and the history so callers can fall back gracefully.
+type MaxIterationsError struct {
+	Iterations           int
+	LastAssistantMessage *AssistantMessage
+	History              History
+}
+
+func (e *MaxIterationsError) Error() string {
+	return fmt.Sprintf("%v: stopped after %d iterations", ErrMaxIterationsExceeded, e.Iterations)
+}
+
+func (e *MaxIterationsError) Is(target error) bool {
+	return target == ErrMaxIterationsExceeded
+}
+
+// PermanentError marks a tool error that will not go away by retrying, e.g. a missing record.
+// The agent does not retry such calls and reports them to the model as not retryable.
+type PermanentError struct {
+	Err error
+}
+
+// NewPermanentError wraps err as a permanent failure
+func NewPermanentError(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// RetryableError marks a transient tool error, e.g. a timeout or rate limit, which the model
+// may resolve by calling the tool again
+type RetryableError struct {
+	Err error
+}
+
+// NewRetryableError wraps err as a transient failure
+func NewRetryableError(err error) error {
+	return &RetryableError{Err: err}
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether err is marked as retryable. Permanent errors and errors
+// without a marker are not retryable.
+func IsRetryable(err error) bool {
+	var permanent *PermanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+
+	var retryable *RetryableError
+	return errors.As(err, &retryable)
+}
+
+func isPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// ToolError is the structured form of a tool failure delivered to the model as the tool result
+type ToolError struct {
+	Error     bool   `json:"error"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+}
+
+// NewStructuredToolErrorMessage creates a tool result carrying err as a JSON ToolError
+func NewStructuredToolErrorMessage(toolCall *ToolCall, err error) *ToolResultMessage {
+	result, _ := json.Marshal(ToolError{
+		Error:     true,
+		Message:   err.Error(),
+		Retryable: IsRetryable(err),
+	})
+
+	return &ToolResultMessage{
+		ToolCall: toolCall,
+		Result:   result,
+	}
+}
+
+// ErrToolUseRequired is returned when tool use is required but the model keeps answering without calling a tool
+var ErrToolUseRequired = errors.New("model answered without using the required tools")
+
+// ErrEmptyResponse is returned when the model keeps answering with an empty response (see WithRetryOnEmptyResponse)
+var ErrEmptyResponse = errors.New("model returned an empty response")
@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"plain", errors.New("boom"), false},
+		{"retryable", NewRetryableError(errors.New("rate limited")), true},
+		{"wrapped retryable", fmt.Errorf("search: %w", NewRetryableError(errors.New("timeout"))), true},
+		{"permanent", NewPermanentError(errors.New("not found")), false},
+		{"permanent wrapping retryable", NewPermanentError(NewRetryableError(errors.New("gone"))), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAgentStructuredToolErrors(t *testing.T) {
+	runs := 0
+	lookup := NewGenericTool("lookup", "Looks up a record",
+		func(ctx context.Context, input struct{}) (json.RawMessage, error) {
+			runs++
+			return nil, NewPermanentError(errors.New("record not found"))
+		})
+
+	var lastRequest *LLMRequest
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		lastRequest = request
+		if len(request.History) == 1 {
+			return toolCallResponse("call_1", "lookup", `{}`), nil
+		}
+		return textResponse("No such record."), nil
+	})
+
+	agent := NewAgent(model, []Tool{lookup}, WithStructuredToolErrors())
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Find record 7")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if runs != 1 {
+		t.Errorf("Expected permanent error not to be retried, got %d runs", runs)
+	}
+
+	result, ok := lastRequest.History[2].(*ToolResultMessage)
+	if !ok {
+		t.Fatalf("Expected tool result in history, got %T", lastRequest.History[2])
+	}
+
+	var toolErr map[string]any
+	if err := json.Unmarshal(result.Result, &toolErr); err != nil {
+		t.Fatalf("Expected JSON tool error, got %s", result.Result)
+	}
+
+	expected := map[string]any{
+		"error":     true,
+		"message":   "tool call failed permanently: tool execution failed: record not found",
+		"retryable": false,
+	}
+	if len(toolErr) != len(expected) {
+		t.Errorf("Expected fields %v, got %v", expected, toolErr)
+	}
+	for key, value := range expected {
+		if toolErr[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, toolErr[key])
+		}
+	}
+}
+
+func TestTypedToolErrors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ToolNotFound", func(t *testing.T) {
+		agent := NewAgent(&mockLLM{}, nil).(*Agent)
+
+		_, err := agent.CallTool(ctx, &ToolCall{ID: "call_1", Name: "missing", Args: json.RawMessage(`{}`)})
+
+		var notFound *ToolNotFoundError
+		if !errors.As(err, &notFound) || notFound.Tool != "missing" {
+			t.Errorf("Expected ToolNotFoundError for missing, got %v", err)
+		}
+	})
+
+	t.Run("ToolExecution", func(t *testing.T) {
+		tool := &mockTool{name: "test_tool", shouldFail: true, correctArgs: json.RawMessage(`{"param": "correct"}`)}
+		agent := NewAgent(&mockLLM{correctArgs: json.RawMessage(`{"param": "wrong"}`)}, []Tool{tool},
+			WithMaxRetries(1), WithRetryDelay(0)).(*Agent)
+
+		_, err := agent.CallTool(ctx, &ToolCall{ID: "call_1", Name: "test_tool", Args: json.RawMessage(`{"param": "wrong"}`)})
+
+		var execErr *ToolExecutionError
+		if !errors.As(err, &execErr) {
+			t.Fatalf("Expected ToolExecutionError, got %v", err)
+		}
+		if execErr.Tool != "test_tool" || execErr.Attempts != 2 {
+			t.Errorf("Expected 2 attempts of test_tool, got %d attempts of %s", execErr.Attempts, execErr.Tool)
+		}
+	})
+
+	t.Run("SchemaValidation", func(t *testing.T) {
+		agent := NewAgent(&mockLLM{}, []Tool{&strictTool{}}, WithValidateToolArgs(true), WithMaxRetries(0)).(*Agent)
+
+		_, err := agent.CallTool(ctx, &ToolCall{ID: "call_1", Name: "order_item", Args: json.RawMessage(`{}`)})
+
+		var validationErr *SchemaValidationError
+		if !errors.As(err, &validationErr) || validationErr.Tool != "order_item" || len(validationErr.Errors) == 0 {
+			t.Errorf("Expected SchemaValidationError for order_item, got %v", err)
+		}
+	})
+}
+
+func TestStructuredOutputValidationError(t *testing.T) {
+	formatter := NewBaseLLMWithStructuredOutput(
+		json.RawMessage(`{"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}`), nil)
+
+	err := formatter.ValidateInput(json.RawMessage(`{}`))
+
+	var validationErr *SchemaValidationError
+	if !errors.As(err, &validationErr) || validationErr.Tool != "formatter" {
+		t.Errorf("Expected SchemaValidationError from the formatter, got %v", err)
+	}
+
+	var fieldErrs FieldErrors
+	if !errors.As(err, &fieldErrs) || len(fieldErrs) != 1 {
+		t.Errorf("Expected the FieldErrors to be unwrapped, got %v", err)
+	}
+}
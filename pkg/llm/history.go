@@ -0,0 +1,281 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// DropOrphanedToolResults removes tool results whose tool call ID does not match
+// any tool call issued earlier in the history. Providers such as OpenAI reject
+// requests containing such results, which typically appear after naive trimming
+// removed the assistant turn that issued the call.
+func DropOrphanedToolResults(history History) History {
+	issued := make(map[string]bool)
+	repaired := make(History, 0, len(history))
+
+	for i, msg := range history {
+		for _, toolCall := range toolCallsOf(msg) {
+			issued[toolCall.ID] = true
+		}
+
+		if result, ok := msg.(*ToolResultMessage); ok {
+			if result.ToolCall == nil || !issued[result.ToolCall.ID] {
+				slog.Warn("Dropping tool result without a matching tool call",
+					"index", i,
+					"tool_call_id", toolCallID(result.ToolCall),
+				)
+				continue
+			}
+		}
+
+		repaired = append(repaired, msg)
+	}
+
+	return repaired
+}
+
+// SplitPreservingToolPairs returns the tail of the history starting at keepFromIndex, moving the
+// boundary earlier when needed so that no kept tool result loses the tool call that produced it.
+// Parallel calls answered after the boundary are kept together with all of their results.
+// Trimming paths should use it instead of slicing the history directly.
+func SplitPreservingToolPairs(history History, keepFromIndex int) History {
+	start := min(max(keepFromIndex, 0), len(history))
+
+	callIndex := make(map[string]int)
+	for i, msg := range history {
+		for _, toolCall := range toolCallsOf(msg) {
+			if _, ok := callIndex[toolCall.ID]; !ok {
+				callIndex[toolCall.ID] = i
+			}
+		}
+	}
+
+	// Pulling in an earlier call can pull in results of even earlier calls, repeat until stable
+	for moved := true; moved; {
+		moved = false
+		for _, msg := range history[start:] {
+			toolCall := resultToolCallOf(msg)
+			if toolCall == nil {
+				continue
+			}
+
+			if i, ok := callIndex[toolCall.ID]; ok && i < start {
+				start = i
+				moved = true
+			}
+		}
+	}
+
+	return append(History{}, history[start:]...)
+}
+
+// resultToolCallOf returns the tool call a tool result or error message answers, if any
+func resultToolCallOf(msg Message) *ToolCall {
+	switch m := msg.(type) {
+	case *ToolResultMessage:
+		return m.ToolCall
+	case *ToolErrorMessage:
+		return m.ToolCall
+	}
+
+	return nil
+}
+
+// toolCallsOf returns the tool calls carried by the given message, if any
+func toolCallsOf(msg Message) []*ToolCall {
+	switch m := msg.(type) {
+	case *ToolCallMessage:
+		if m.ToolCall != nil {
+			return []*ToolCall{m.ToolCall}
+		}
+	case *AssistantMessage:
+		return m.ToolCalls
+	}
+
+	return nil
+}
+
+// ExpandToolCalls splits assistant messages carrying tool calls into their text and a ToolCallMessage
+// per call, for code handling tool calls one message at a time. Other messages are kept as they are.
+func ExpandToolCalls(history History) History {
+	expanded := make(History, 0, len(history))
+
+	for _, msg := range history {
+		assistant, ok := msg.(*AssistantMessage)
+		if !ok || len(assistant.ToolCalls) == 0 {
+			expanded = append(expanded, msg)
+			continue
+		}
+
+		if assistant.Content != "" {
+			expanded = append(expanded, &AssistantMessage{Content: assistant.Content})
+		}
+		for _, toolCall := range assistant.ToolCalls {
+			expanded = append(expanded, NewToolCallMessage(toolCall))
+		}
+	}
+
+	return expanded
+}
+
+// ExampleMessages lays out few-shot examples to send before the history, introducing each one and closing
+// the set with messages built by delimiter, so the model doesn't take them for part of the conversation.
+// Providers that move system messages out of the turns delimit with user messages instead.
+func ExampleMessages(examples []History, delimiter func(content string) Message) History {
+	if len(examples) == 0 {
+		return nil
+	}
+
+	var messages History
+	for i, example := range examples {
+		messages = append(messages, delimiter(fmt.Sprintf("Example %d (for illustration only, not part of the conversation):", i+1)))
+		messages = append(messages, example...)
+	}
+
+	return append(messages, delimiter("End of examples. The actual conversation follows."))
+}
+
+func toolCallID(toolCall *ToolCall) string {
+	if toolCall == nil {
+		return ""
+	}
+
+	return toolCall.ID
+}
+
+// HistoryDiffType represents the kind of difference between two histories
+type HistoryDiffType string
+
+const (
+	HistoryDiffAdded   HistoryDiffType = "added"
+	HistoryDiffRemoved HistoryDiffType = "removed"
+	HistoryDiffChanged HistoryDiffType = "changed"
+)
+
+// HistoryDiff describes a single difference between a baseline history A and a history B.
+// IndexA is -1 for added messages and IndexB is -1 for removed ones.
+type HistoryDiff struct {
+	Type   HistoryDiffType
+	IndexA int
+	IndexB int
+	A      Message
+	B      Message
+}
+
+func (d HistoryDiff) String() string {
+	switch d.Type {
+	case HistoryDiffAdded:
+		return fmt.Sprintf("+ [%d] %s", d.IndexB, describeMessage(d.B))
+	case HistoryDiffRemoved:
+		return fmt.Sprintf("- [%d] %s", d.IndexA, describeMessage(d.A))
+	default:
+		return fmt.Sprintf("~ [%d -> %d] %s => %s", d.IndexA, d.IndexB, describeMessage(d.A), describeMessage(d.B))
+	}
+}
+
+// DiffHistory aligns two histories and reports how b diverged from the baseline a.
+// Messages are matched on role, kind and content; unmatched messages of the same role and kind
+// at the same place are reported as changed, the rest as added or removed.
+func DiffHistory(a, b History) []HistoryDiff {
+	keysA := make([]string, len(a))
+	for i, msg := range a {
+		keysA[i] = messageKey(msg)
+	}
+	keysB := make([]string, len(b))
+	for i, msg := range b {
+		keysB[i] = messageKey(msg)
+	}
+
+	// Longest common subsequence table, lcs[i][j] covers a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if keysA[i] == keysB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diffs []HistoryDiff
+	var removed, added []int
+
+	flush := func() {
+		for len(removed) > 0 && len(added) > 0 && sameShape(a[removed[0]], b[added[0]]) {
+			diffs = append(diffs, HistoryDiff{Type: HistoryDiffChanged, IndexA: removed[0], IndexB: added[0], A: a[removed[0]], B: b[added[0]]})
+			removed, added = removed[1:], added[1:]
+		}
+		for _, i := range removed {
+			diffs = append(diffs, HistoryDiff{Type: HistoryDiffRemoved, IndexA: i, IndexB: -1, A: a[i]})
+		}
+		for _, j := range added {
+			diffs = append(diffs, HistoryDiff{Type: HistoryDiffAdded, IndexA: -1, IndexB: j, B: b[j]})
+		}
+		removed, added = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && keysA[i] == keysB[j]:
+			flush()
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, j)
+			j++
+		default:
+			removed = append(removed, i)
+			i++
+		}
+	}
+	flush()
+
+	return diffs
+}
+
+func sameShape(a, b Message) bool {
+	return a.Role() == b.Role() && a.Kind() == b.Kind()
+}
+
+func messageKey(msg Message) string {
+	return string(msg.Role()) + "/" + string(msg.Kind()) + "/" + describeMessage(msg)
+}
+
+// describeMessage renders the content of a message for comparison and display
+func describeMessage(msg Message) string {
+	switch m := msg.(type) {
+	case *UserMessage:
+		return "user: " + m.Content
+	case *AssistantMessage:
+		return "assistant: " + m.Content
+	case *SystemMessage:
+		return "system: " + m.Content
+	case *DeveloperMessage:
+		return "developer: " + m.Content
+	case *AudioMessage:
+		return "audio: " + m.Transcript
+	case *ToolCallMessage:
+		return fmt.Sprintf("tool call %s(%s)", m.ToolCall.Name, compactJSON(m.ToolCall.Args))
+	case *ToolResultMessage:
+		return fmt.Sprintf("tool result %s: %s", m.ToolCall.Name, compactJSON(m.Result))
+	case *ToolErrorMessage:
+		return fmt.Sprintf("tool error %s: %s", m.ToolCall.Name, m.Error)
+	}
+
+	return fmt.Sprintf("%s: %+v", msg.Kind(), msg)
+}
+
+func compactJSON(data json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
@@ -0,0 +1,176 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// historyEntry is the JSON form of a single message, tagged with its kind and role
+type historyEntry struct {
+	Kind MessageKind `json:"kind"`
+	Role MessageRole `json:"role"`
+
+	Content string `json:"content,omitempty"`
+
+	ToolCall   *toolCallEntry   `json:"tool_call,omitempty"`
+	ToolCalls  []*toolCallEntry `json:"tool_calls,omitempty"`
+	Result     *string          `json:"result,omitempty"`
+	FullResult *string          `json:"full_result,omitempty"`
+	Error      string           `json:"error,omitempty"`
+
+	AudioID    string `json:"audio_id,omitempty"`
+	AudioData  []byte `json:"audio_data,omitempty"`
+	Format     string `json:"format,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// toolCallEntry is the JSON form of a tool call. Args are kept as a string since the model
+// doesn't always produce valid JSON, and they should round-trip byte for byte.
+type toolCallEntry struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Args string `json:"args"`
+}
+
+// MarshalHistory encodes the history as JSON, e.g. to checkpoint a conversation and resume it later
+func MarshalHistory(history History) ([]byte, error) {
+	entries := make([]historyEntry, 0, len(history))
+
+	for i, msg := range history {
+		entry := historyEntry{Kind: msg.Kind(), Role: msg.Role()}
+
+		switch m := msg.(type) {
+		case *UserMessage:
+			entry.Content = m.Content
+		case *AssistantMessage:
+			entry.Content = m.Content
+			for _, toolCall := range m.ToolCalls {
+				entry.ToolCalls = append(entry.ToolCalls, newToolCallEntry(toolCall))
+			}
+		case *SystemMessage:
+			entry.Content = m.Content
+		case *DeveloperMessage:
+			entry.Content = m.Content
+
+		case *AudioMessage:
+			entry.AudioID = m.ID
+			entry.AudioData = m.Data
+			entry.Format = m.Format
+			entry.Transcript = m.Transcript
+
+		case *ToolCallMessage:
+			entry.ToolCall = newToolCallEntry(m.ToolCall)
+
+		case *ToolResultMessage:
+			entry.ToolCall = newToolCallEntry(m.ToolCall)
+			result := string(m.Result)
+			entry.Result = &result
+			if m.FullResult != nil {
+				fullResult := string(m.FullResult)
+				entry.FullResult = &fullResult
+			}
+
+		case *ToolErrorMessage:
+			entry.ToolCall = newToolCallEntry(m.ToolCall)
+			entry.Error = m.Error
+
+		default:
+			return nil, fmt.Errorf("cannot marshal message %d of type %T", i, msg)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return json.Marshal(entries)
+}
+
+// UnmarshalHistory decodes a history encoded by MarshalHistory. Tool results share the *ToolCall
+// of the tool call message with the same ID, as they do in a live conversation.
+func UnmarshalHistory(data []byte) (History, error) {
+	var entries []historyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode history: %w", err)
+	}
+
+	history := make(History, 0, len(entries))
+	calls := make(map[string]*ToolCall)
+
+	for i, entry := range entries {
+		var msg Message
+
+		switch {
+		case entry.Kind == MessageKindText && entry.Role == MessageRoleUser:
+			msg = &UserMessage{Content: entry.Content}
+		case entry.Kind == MessageKindText && entry.Role == MessageRoleAssistant:
+			assistant := &AssistantMessage{Content: entry.Content}
+			for _, toolCallEntry := range entry.ToolCalls {
+				toolCall := toolCallEntry.toolCall()
+				calls[toolCall.ID] = toolCall
+				assistant.ToolCalls = append(assistant.ToolCalls, toolCall)
+			}
+			msg = assistant
+		case entry.Kind == MessageKindText && entry.Role == MessageRoleSystem:
+			msg = &SystemMessage{Content: entry.Content}
+		case entry.Kind == MessageKindText && entry.Role == MessageRoleDeveloper:
+			msg = &DeveloperMessage{Content: entry.Content}
+
+		case entry.Kind == MessageKindAudio:
+			msg = &AudioMessage{
+				ID:         entry.AudioID,
+				Data:       entry.AudioData,
+				Format:     entry.Format,
+				Transcript: entry.Transcript,
+			}
+
+		case entry.Kind == MessageKindToolCall:
+			if entry.ToolCall == nil {
+				return nil, fmt.Errorf("message %d: tool call is missing", i)
+			}
+			toolCall := entry.ToolCall.toolCall()
+			calls[toolCall.ID] = toolCall
+			msg = &ToolCallMessage{ToolCall: toolCall}
+
+		case entry.Kind == MessageKindToolResult:
+			if entry.ToolCall == nil {
+				return nil, fmt.Errorf("message %d: tool call is missing", i)
+			}
+			toolCall, ok := calls[entry.ToolCall.ID]
+			if !ok {
+				toolCall = entry.ToolCall.toolCall()
+			}
+
+			if entry.Role == MessageRoleAssistant {
+				msg = &ToolErrorMessage{ToolCall: toolCall, Error: entry.Error}
+				break
+			}
+
+			result := &ToolResultMessage{ToolCall: toolCall}
+			if entry.Result != nil {
+				result.Result = json.RawMessage(*entry.Result)
+			}
+			if entry.FullResult != nil {
+				result.FullResult = json.RawMessage(*entry.FullResult)
+			}
+			msg = result
+
+		default:
+			return nil, fmt.Errorf("message %d: unknown message kind %q with role %q", i, entry.Kind, entry.Role)
+		}
+
+		history = append(history, msg)
+	}
+
+	return history, nil
+}
+
+func newToolCallEntry(toolCall *ToolCall) *toolCallEntry {
+	if toolCall == nil {
+		return &toolCallEntry{}
+	}
+
+	return &toolCallEntry{ID: toolCall.ID, Name: toolCall.Name, Args: string(toolCall.Args)}
+}
+
+func (e *toolCallEntry) toolCall() *ToolCall {
+	return &ToolCall{ID: e.ID, Name: e.Name, Args: json.RawMessage(e.Args)}
+}
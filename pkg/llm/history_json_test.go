@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalHistoryRoundTrip(t *testing.T) {
+	call := &ToolCall{ID: "call_1", Name: "calculator", Args: json.RawMessage(`{"a": 2, "b": 2}`)}
+	failed := &ToolCall{ID: "call_2", Name: "calculator", Args: json.RawMessage(`{"a": `)}
+
+	history := NewHistory(
+		NewSystemMessage("You are a calculator"),
+		NewUserMessage("What is 2 + 2?"),
+		NewToolCallMessage(call),
+		&ToolResultMessage{ToolCall: call, Result: json.RawMessage(`{"result": 4}`), FullResult: json.RawMessage(`{"result": 4, "steps": []}`)},
+		NewToolCallMessage(failed),
+		NewToolResultErrorMessage(failed, "invalid arguments"),
+		NewToolErrorMessage(failed, "unexpected end of JSON input"),
+		&AssistantMessage{Content: "2 + 2 = 4"},
+		&AudioMessage{ID: "audio_1", Data: []byte{1, 2, 3}, Format: "wav", Transcript: "four"},
+		NewDeveloperMessage("Answer in digits"),
+	)
+
+	data, err := MarshalHistory(history)
+	if err != nil {
+		t.Fatalf("Failed to marshal history: %v", err)
+	}
+
+	restored, err := UnmarshalHistory(data)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal history: %v", err)
+	}
+
+	if len(restored) != len(history) {
+		t.Fatalf("Expected %d messages, got %d", len(history), len(restored))
+	}
+
+	for i := range history {
+		if !reflect.DeepEqual(restored[i], history[i]) {
+			t.Errorf("Expected message %d to be %#v, got %#v", i, history[i], restored[i])
+		}
+	}
+
+	// Results point at the same tool call as the call message, like in a live conversation
+	restoredCall := restored[2].(*ToolCallMessage).ToolCall
+	if restored[3].(*ToolResultMessage).ToolCall != restoredCall {
+		t.Errorf("Expected the tool result to share the restored tool call")
+	}
+}
+
+func TestMarshalHistoryTagsMessages(t *testing.T) {
+	data, err := MarshalHistory(NewHistory(NewUserMessage("Hi")))
+	if err != nil {
+		t.Fatalf("Failed to marshal history: %v", err)
+	}
+
+	if string(data) != `[{"kind":"text","role":"user","content":"Hi"}]` {
+		t.Errorf("Expected tagged message, got %s", data)
+	}
+}
+
+type customMessage struct{}
+
+func (customMessage) Kind() MessageKind { return "custom" }
+func (customMessage) Role() MessageRole { return MessageRoleUser }
+
+func TestMarshalHistoryErrors(t *testing.T) {
+	if _, err := MarshalHistory(NewHistory(customMessage{})); err == nil || !strings.Contains(err.Error(), "cannot marshal message 0") {
+		t.Errorf("Expected unsupported message to fail, got %v", err)
+	}
+
+	if _, err := UnmarshalHistory([]byte(`[{"kind":"custom","role":"user"}]`)); err == nil || !strings.Contains(err.Error(), "unknown message kind") {
+		t.Errorf("Expected unknown kind to fail, got %v", err)
+	}
+
+	if _, err := UnmarshalHistory([]byte(`{`)); err == nil {
+		t.Errorf("Expected invalid JSON to fail")
+	}
+}
+
+func TestMarshalHistoryAssistantToolCalls(t *testing.T) {
+	call := &ToolCall{ID: "call_1", Name: "weather", Args: json.RawMessage(`{"city": "Prague"}`)}
+	history := NewHistory(
+		&AssistantMessage{Content: "Let me check.", ToolCalls: []*ToolCall{call}},
+		NewToolResultMessage(call, json.RawMessage(`{"temp": 21}`)),
+	)
+
+	data, err := MarshalHistory(history)
+	if err != nil {
+		t.Fatalf("Failed to marshal history: %v", err)
+	}
+
+	restored, err := UnmarshalHistory(data)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal history: %v", err)
+	}
+
+	if !reflect.DeepEqual(restored, history) {
+		t.Fatalf("Expected %#v, got %#v", history, restored)
+	}
+
+	if restored[1].(*ToolResultMessage).ToolCall != restored[0].(*AssistantMessage).ToolCalls[0] {
+		t.Errorf("Expected the tool result to share the assistant's tool call")
+	}
+}
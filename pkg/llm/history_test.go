@@ -0,0 +1,202 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDropOrphanedToolResults(t *testing.T) {
+	call := &ToolCall{ID: "call_1", Name: "calculator", Args: json.RawMessage(`{}`)}
+	orphan := &ToolCall{ID: "call_trimmed", Name: "calculator", Args: json.RawMessage(`{}`)}
+
+	history := NewHistory(
+		NewUserMessage("What is 2 + 2?"),
+		NewToolResultMessage(orphan, json.RawMessage(`{"result": 3}`)),
+		NewToolCallMessage(call),
+		NewToolResultMessage(call, json.RawMessage(`{"result": 4}`)),
+	)
+
+	repaired := DropOrphanedToolResults(history)
+
+	if len(repaired) != 3 {
+		t.Fatalf("Expected 3 messages after repair, got %d", len(repaired))
+	}
+
+	for _, msg := range repaired {
+		if result, ok := msg.(*ToolResultMessage); ok && result.ToolCall.ID == orphan.ID {
+			t.Errorf("Expected orphaned tool result %s to be dropped", orphan.ID)
+		}
+	}
+
+	if result, ok := repaired[2].(*ToolResultMessage); !ok || result.ToolCall.ID != call.ID {
+		t.Errorf("Expected matched tool result for %s to be kept", call.ID)
+	}
+}
+
+func TestDropOrphanedToolResultsResultBeforeCall(t *testing.T) {
+	call := &ToolCall{ID: "call_1", Name: "calculator"}
+
+	// A result is only valid after the call that produced it
+	history := NewHistory(
+		NewToolResultMessage(call, json.RawMessage(`{}`)),
+		NewToolCallMessage(call),
+	)
+
+	repaired := DropOrphanedToolResults(history)
+
+	if len(repaired) != 1 {
+		t.Fatalf("Expected 1 message after repair, got %d", len(repaired))
+	}
+
+	if repaired[0].Kind() != MessageKindToolCall {
+		t.Errorf("Expected the tool call to be kept, got %s", repaired[0].Kind())
+	}
+}
+
+func TestDiffHistory(t *testing.T) {
+	call := &ToolCall{ID: "call_1", Name: "search", Args: json.RawMessage(`{"q": "flights"}`)}
+	changedCall := &ToolCall{ID: "call_1", Name: "search", Args: json.RawMessage(`{"q": "cheap flights"}`)}
+
+	baseline := NewHistory(
+		NewSystemMessage("You are a travel agent"),
+		NewUserMessage("Find me a flight"),
+		NewToolCallMessage(call),
+		NewToolResultMessage(call, json.RawMessage(`{"flights": 3}`)),
+		&AssistantMessage{Content: "I found 3 flights"},
+	)
+
+	current := NewHistory(
+		NewSystemMessage("You are a travel agent"),
+		NewUserMessage("Find me a flight"),
+		NewToolCallMessage(changedCall),
+		NewToolResultMessage(changedCall, json.RawMessage(`{"flights": 3}`)),
+		&AssistantMessage{Content: "I found 3 flights"},
+		NewUserMessage("Book the first one"),
+	)
+
+	diffs := DiffHistory(baseline, current)
+
+	expected := []struct {
+		diffType HistoryDiffType
+		indexA   int
+		indexB   int
+	}{
+		{HistoryDiffChanged, 2, 2},
+		{HistoryDiffAdded, -1, 5},
+	}
+
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d diffs, got %d: %v", len(expected), len(diffs), diffs)
+	}
+
+	for i, e := range expected {
+		if diffs[i].Type != e.diffType || diffs[i].IndexA != e.indexA || diffs[i].IndexB != e.indexB {
+			t.Errorf("Expected diff %d to be %s [%d -> %d], got %s", i, e.diffType, e.indexA, e.indexB, diffs[i])
+		}
+	}
+
+	if diffs[0].String() != `~ [2 -> 2] tool call search({"q":"flights"}) => tool call search({"q":"cheap flights"})` {
+		t.Errorf("Unexpected diff rendering: %s", diffs[0])
+	}
+}
+
+func TestDiffHistoryRemoved(t *testing.T) {
+	baseline := NewHistory(NewUserMessage("a"), NewSystemMessage("b"), NewUserMessage("c"))
+	current := NewHistory(NewUserMessage("a"), NewUserMessage("c"))
+
+	diffs := DiffHistory(baseline, current)
+
+	if len(diffs) != 1 || diffs[0].Type != HistoryDiffRemoved || diffs[0].IndexA != 1 {
+		t.Errorf("Expected the system message to be reported as removed, got %v", diffs)
+	}
+
+	if len(DiffHistory(current, current)) != 0 {
+		t.Error("Expected identical histories to have no diffs")
+	}
+}
+
+func TestSplitPreservingToolPairs(t *testing.T) {
+	first := &ToolCall{ID: "call_1", Name: "search"}
+	second := &ToolCall{ID: "call_2", Name: "search"}
+	third := &ToolCall{ID: "call_3", Name: "weather"}
+
+	history := NewHistory(
+		NewUserMessage("Find things"),                                       // 0
+		NewToolCallMessage(first),                                           // 1
+		NewToolResultMessage(first, json.RawMessage(`{"hits": 1}`)),         // 2
+		NewUserMessage("And the weather?"),                                  // 3
+		NewToolCallMessage(second),                                          // 4
+		NewToolCallMessage(third),                                           // 5
+		NewToolResultMessage(second, json.RawMessage(`{"hits": 2}`)),        // 6
+		NewToolResultMessage(third, json.RawMessage(`{"temperature": 21}`)), // 7
+		&AssistantMessage{Content: "Done"},                                  // 8
+	)
+
+	tests := []struct {
+		name          string
+		keepFromIndex int
+		expectedStart int
+	}{
+		{name: "boundary outside any pair", keepFromIndex: 3, expectedStart: 3},
+		{name: "boundary between call and result", keepFromIndex: 2, expectedStart: 1},
+		{name: "boundary between parallel calls", keepFromIndex: 5, expectedStart: 4},
+		{name: "boundary between parallel results", keepFromIndex: 7, expectedStart: 4},
+		{name: "boundary after all pairs", keepFromIndex: 8, expectedStart: 8},
+		{name: "boundary past the end", keepFromIndex: 20, expectedStart: 9},
+		{name: "negative boundary", keepFromIndex: -1, expectedStart: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept := SplitPreservingToolPairs(history, tt.keepFromIndex)
+
+			if len(kept) != len(history)-tt.expectedStart {
+				t.Fatalf("Expected %d messages kept, got %d", len(history)-tt.expectedStart, len(kept))
+			}
+
+			if len(kept) > 0 && kept[0] != history[tt.expectedStart] {
+				t.Errorf("Expected the kept history to start at message %d, got %s", tt.expectedStart, describeMessage(kept[0]))
+			}
+
+			if repaired := DropOrphanedToolResults(kept); len(repaired) != len(kept) {
+				t.Errorf("Expected no orphaned tool results, %d were dropped", len(kept)-len(repaired))
+			}
+		})
+	}
+}
+
+func TestExpandToolCalls(t *testing.T) {
+	first := &ToolCall{ID: "call_1", Name: "weather", Args: json.RawMessage(`{"city": "Prague"}`)}
+	second := &ToolCall{ID: "call_2", Name: "weather", Args: json.RawMessage(`{"city": "Brno"}`)}
+
+	history := NewHistory(
+		NewUserMessage("Weather in Prague and Brno?"),
+		&AssistantMessage{Content: "Let me check.", ToolCalls: []*ToolCall{first, second}},
+		NewToolResultMessage(first, json.RawMessage(`{"temp": 21}`)),
+		NewToolResultMessage(second, json.RawMessage(`{"temp": 19}`)),
+	)
+
+	// Results of calls carried by an assistant message are not orphaned
+	if repaired := DropOrphanedToolResults(history); len(repaired) != len(history) {
+		t.Fatalf("Expected all %d messages to be kept, got %d", len(history), len(repaired))
+	}
+
+	expanded := ExpandToolCalls(history)
+	if len(expanded) != 6 {
+		t.Fatalf("Expected 6 messages, got %d", len(expanded))
+	}
+
+	if text, ok := expanded[1].(*AssistantMessage); !ok || text.Content != "Let me check." || len(text.ToolCalls) != 0 {
+		t.Errorf("Expected the assistant text without tool calls, got %+v", expanded[1])
+	}
+
+	for i, expected := range []*ToolCall{first, second} {
+		if call, ok := expanded[2+i].(*ToolCallMessage); !ok || call.ToolCall != expected {
+			t.Errorf("Expected tool call %s at %d, got %+v", expected.ID, 2+i, expanded[2+i])
+		}
+	}
+
+	if history[1].(*AssistantMessage).ToolCalls == nil {
+		t.Errorf("Expected the original history to be left untouched")
+	}
+}
@@ -0,0 +1,63 @@
+package llm
+
+// HistoryTrimmer shortens the history sent to the model, e.g. to stay within its context window
+type HistoryTrimmer = func(history History) History
+
+// KeepLastMessages keeps the system and developer messages and the last n messages of the history.
+// A tool call is never split from its results, so slightly more messages may be kept.
+func KeepLastMessages(n int) HistoryTrimmer {
+	return func(history History) History {
+		return trimHistory(history, len(history)-n)
+	}
+}
+
+// KeepLastTokens keeps the system and developer messages and as many of the latest messages as fit within
+// maxTokens as measured by counter, ApproximateTokenCounter when nil. The last message is always kept,
+// and a tool call is never split from its results, which may exceed the budget.
+func KeepLastTokens(maxTokens int, counter TokenCounter) HistoryTrimmer {
+	if counter == nil {
+		counter = ApproximateTokenCounter{}
+	}
+	cost := func(msg Message) int {
+		return counter.CountMessages([]Message{msg})
+	}
+
+	return func(history History) History {
+		// System and developer messages are always kept, so they are paid for up front
+		budget := maxTokens
+		for _, msg := range history {
+			if isInstruction(msg) {
+				budget -= cost(msg)
+			}
+		}
+
+		keepFrom := len(history)
+		for i := len(history) - 1; i >= 0; i-- {
+			if !isInstruction(history[i]) {
+				budget -= cost(history[i])
+			}
+			if budget < 0 && keepFrom < len(history) {
+				break
+			}
+			keepFrom = i
+		}
+
+		return trimHistory(history, keepFrom)
+	}
+}
+
+// trimHistory keeps the history from keepFrom on, preserving tool pairs, along with the system
+// and developer messages before it
+func trimHistory(history History, keepFrom int) History {
+	tail := SplitPreservingToolPairs(history, keepFrom)
+	start := len(history) - len(tail)
+
+	var trimmed History
+	for _, msg := range history[:start] {
+		if isInstruction(msg) {
+			trimmed = append(trimmed, msg)
+		}
+	}
+
+	return append(trimmed, tail...)
+}
@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// perMessageCounter counts every message as a single token
+type perMessageCounter struct{}
+
+func (perMessageCounter) Count(text string) int                { return 1 }
+func (perMessageCounter) CountMessages(messages []Message) int { return len(messages) }
+
+func TestHistoryTrimmers(t *testing.T) {
+	system := NewSystemMessage("You are a travel agent")
+	developer := NewDeveloperMessage("Prefer direct flights")
+	call := &ToolCall{ID: "call_1", Name: "search", Args: json.RawMessage(`{}`)}
+	first := &ToolCall{ID: "call_2", Name: "search", Args: json.RawMessage(`{}`)}
+	second := &ToolCall{ID: "call_3", Name: "search", Args: json.RawMessage(`{}`)}
+
+	single := NewHistory(
+		system,
+		NewUserMessage("Find a flight"),
+		&AssistantMessage{Content: "Where to?"},
+		NewUserMessage("Prague"),
+		NewToolCallMessage(call),
+		NewToolResultMessage(call, json.RawMessage(`{"flights": 3}`)),
+		&AssistantMessage{Content: "I found 3 flights"},
+	)
+
+	parallel := NewHistory(
+		system,
+		NewUserMessage("Find flights and hotels"),
+		NewToolCallMessage(first),
+		NewToolCallMessage(second),
+		NewToolResultMessage(first, json.RawMessage(`{"flights": 3}`)),
+		NewToolResultMessage(second, json.RawMessage(`{"hotels": 2}`)),
+	)
+
+	perMessage := perMessageCounter{}
+
+	tests := []struct {
+		name     string
+		trimmer  HistoryTrimmer
+		history  History
+		expected History
+	}{
+		{
+			name:     "keeps the last messages and the system message",
+			trimmer:  KeepLastMessages(2),
+			history:  single,
+			expected: NewHistory(system, single[4], single[5], single[6]),
+		},
+		{
+			name:     "keeps parallel calls with their results",
+			trimmer:  KeepLastMessages(1),
+			history:  parallel,
+			expected: NewHistory(system, parallel[2], parallel[3], parallel[4], parallel[5]),
+		},
+		{
+			name:     "keeps everything when it fits",
+			trimmer:  KeepLastMessages(10),
+			history:  single,
+			expected: single,
+		},
+		{
+			name:     "keeps the latest messages within the token budget",
+			trimmer:  KeepLastTokens(3, perMessage),
+			history:  single[:4],
+			expected: NewHistory(system, single[2], single[3]),
+		},
+		{
+			name:     "pulls in the call of a kept result",
+			trimmer:  KeepLastTokens(2, perMessage),
+			history:  single[:6],
+			expected: NewHistory(system, single[4], single[5]),
+		},
+		{
+			name:     "keeps developer messages like system messages",
+			trimmer:  KeepLastMessages(1),
+			history:  NewHistory(system, developer, single[1], single[2]),
+			expected: NewHistory(system, developer, single[2]),
+		},
+		{
+			name:     "always keeps the last message",
+			trimmer:  KeepLastTokens(0, perMessage),
+			history:  single,
+			expected: NewHistory(system, single[6]),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trimmed := tt.trimmer(tt.history)
+
+			if len(trimmed) != len(tt.expected) {
+				t.Fatalf("Expected %d messages, got %d: %v", len(tt.expected), len(trimmed), trimmed)
+			}
+			for i := range tt.expected {
+				if trimmed[i] != tt.expected[i] {
+					t.Errorf("Expected %s at %d, got %s", describeMessage(tt.expected[i]), i, describeMessage(trimmed[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestAgentHistoryTrimmer(t *testing.T) {
+	var sent []History
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		sent = append(sent, request.History)
+		if len(sent) == 1 {
+			return toolCallResponse("call_1", "test_tool", `{}`), nil
+		}
+		return textResponse("Done"), nil
+	})
+
+	tool := &mockTool{name: "test_tool"}
+	agent := NewAgent(model, []Tool{tool}, WithHistoryTrimmer(KeepLastMessages(1)))
+
+	history := NewHistory(NewSystemMessage("Be brief"), NewUserMessage("Earlier question"), NewUserMessage("Go"))
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(history)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(sent[0]) != 2 {
+		t.Errorf("Expected the system message and the last message to be sent, got %v", sent[0])
+	}
+
+	// The tool call is kept with its result
+	if len(sent[1]) != 3 {
+		t.Fatalf("Expected the system message and the tool pair to be sent, got %v", sent[1])
+	}
+	if _, ok := sent[1][1].(*ToolCallMessage); !ok {
+		t.Errorf("Expected the tool call to be kept, got %s", describeMessage(sent[1][1]))
+	}
+}
@@ -0,0 +1,47 @@
+package llmtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// MockLLM returns the same response (or error) for every call and records the requests it receives
+type MockLLM struct {
+	Response *llm.LLMResponse
+	Err      error
+
+	mu       sync.Mutex
+	requests []*llm.LLMRequest
+}
+
+// NewMockLLM creates a mock LLM answering every request with the given text
+func NewMockLLM(text string) *MockLLM {
+	response := llm.NewLLMResponse()
+	response.AddMessage(&llm.AssistantMessage{Content: text})
+
+	return &MockLLM{Response: response}
+}
+
+// Invoke implements the LLM interface
+func (m *MockLLM) Invoke(ctx context.Context, request *llm.LLMRequest) (*llm.LLMResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, request)
+
+	if m.Err != nil {
+		return nil, m.Err
+	}
+
+	return m.Response, nil
+}
+
+// Requests returns the requests received so far
+func (m *MockLLM) Requests() []*llm.LLMRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*llm.LLMRequest(nil), m.requests...)
+}
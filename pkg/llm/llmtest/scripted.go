@@ -0,0 +1,99 @@
+// Package llmtest provides LLM implementations for testing agent flows without a real model
+package llmtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// ErrScriptExhausted is returned when a ScriptedLLM is invoked after its last step
+var ErrScriptExhausted = errors.New("scripted LLM has no steps left")
+
+// Step is a single scripted model turn, either a set of tool calls or a final text answer
+type Step struct {
+	ToolCalls []*llm.ToolCall
+	Text      string
+}
+
+// CallTool creates a step in which the model calls the named tool with the given JSON arguments
+func CallTool(name string, args string) Step {
+	return Step{ToolCalls: []*llm.ToolCall{{Name: name, Args: json.RawMessage(args)}}}
+}
+
+// CallTools creates a step in which the model issues several tool calls in one turn
+func CallTools(toolCalls ...*llm.ToolCall) Step {
+	return Step{ToolCalls: toolCalls}
+}
+
+// Answer creates a step in which the model replies with the given text
+func Answer(text string) Step {
+	return Step{Text: text}
+}
+
+// ScriptedLLM replays a fixed sequence of steps, one per Invoke, driving an agent
+// predictably through multiple iterations. It records every request it receives.
+type ScriptedLLM struct {
+	mu       sync.Mutex
+	steps    []Step
+	next     int
+	calls    int
+	requests []*llm.LLMRequest
+}
+
+// NewScriptedLLM creates a scripted LLM playing the given steps in order
+func NewScriptedLLM(steps ...Step) *ScriptedLLM {
+	return &ScriptedLLM{steps: steps}
+}
+
+// Invoke implements the LLM interface by returning the next scripted step
+func (s *ScriptedLLM) Invoke(ctx context.Context, request *llm.LLMRequest) (*llm.LLMResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, request)
+
+	if s.next >= len(s.steps) {
+		return nil, ErrScriptExhausted
+	}
+
+	step := s.steps[s.next]
+	s.next++
+
+	response := llm.NewLLMResponse()
+	if step.Text != "" {
+		response.AddMessage(&llm.AssistantMessage{Content: step.Text})
+	}
+
+	for _, toolCall := range step.ToolCalls {
+		s.calls++
+		id := toolCall.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", s.calls)
+		}
+
+		response.AddToolCall(&llm.ToolCall{ID: id, Name: toolCall.Name, Args: toolCall.Args})
+	}
+
+	return response, nil
+}
+
+// Requests returns the requests received so far
+func (s *ScriptedLLM) Requests() []*llm.LLMRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*llm.LLMRequest(nil), s.requests...)
+}
+
+// Remaining returns the number of steps not yet played
+func (s *ScriptedLLM) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.steps) - s.next
+}
@@ -0,0 +1,87 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+type cityInput struct {
+	City string `json:"city" jsonschema:"required"`
+}
+
+type weather struct {
+	Forecast string `json:"forecast"`
+}
+
+type packing struct {
+	Items []string `json:"items"`
+}
+
+func TestScriptedAgentTwoToolSequence(t *testing.T) {
+	var executed []string
+
+	weatherTool := llm.NewGenericTool("get_weather", "Gets the weather for a city",
+		func(ctx context.Context, input cityInput) (weather, error) {
+			executed = append(executed, "get_weather:"+input.City)
+			return weather{Forecast: "rain"}, nil
+		})
+
+	packingTool := llm.NewGenericTool("packing_list", "Suggests what to pack",
+		func(ctx context.Context, input cityInput) (packing, error) {
+			executed = append(executed, "packing_list:"+input.City)
+			return packing{Items: []string{"umbrella"}}, nil
+		})
+
+	model := NewScriptedLLM(
+		CallTool("get_weather", `{"city": "Prague"}`),
+		CallTool("packing_list", `{"city": "Prague"}`),
+		Answer("Pack an umbrella, it will rain in Prague."),
+	)
+
+	agent := llm.NewAgent(model, []llm.Tool{weatherTool, packingTool})
+
+	response, err := agent.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(
+		llm.NewUserMessage("What should I pack for Prague?"),
+	)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(executed) != 2 || executed[0] != "get_weather:Prague" || executed[1] != "packing_list:Prague" {
+		t.Errorf("Expected both tools to run in order, got %v", executed)
+	}
+
+	if answer := response.Messages[0].(*llm.AssistantMessage).Content; answer != "Pack an umbrella, it will rain in Prague." {
+		t.Errorf("Unexpected final answer: %s", answer)
+	}
+
+	if model.Remaining() != 0 {
+		t.Errorf("Expected the whole script to be played, %d steps left", model.Remaining())
+	}
+
+	// The last request carries both tool calls and their results
+	requests := model.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(requests))
+	}
+
+	if history := requests[2].History; len(history) != 5 {
+		t.Errorf("Expected 5 messages in the final request, got %d", len(history))
+	}
+}
+
+func TestScriptedLLMExhausted(t *testing.T) {
+	model := NewScriptedLLM(Answer("only once"))
+	request := llm.NewLLMRequest(llm.NewHistory())
+
+	if _, err := model.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := model.Invoke(context.Background(), request); !errors.Is(err, ErrScriptExhausted) {
+		t.Errorf("Expected ErrScriptExhausted, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package llmtest
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// MockTool is a tool taking a single number argument a and always answering with the same result
+type MockTool struct {
+	name string
+}
+
+// NewMockTool creates a mock tool with the given name
+func NewMockTool(name string) *MockTool {
+	return &MockTool{name: name}
+}
+
+func (m *MockTool) Name() string        { return m.name }
+func (m *MockTool) Description() string { return "Mock tool for testing" }
+func (m *MockTool) InputSchemaRaw() json.RawMessage {
+	return json.RawMessage(`{"type": "object", "properties": {"a": {"type": "number"}}, "required": ["a"], "additionalProperties": false}`)
+}
+func (m *MockTool) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	return json.RawMessage(`{"result": "mock"}`), nil
+}
@@ -0,0 +1,60 @@
+package llmtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// RecordingTransport is an http.RoundTripper recording the JSON requests it receives and replying with
+// canned responses, for testing adapters without calling the provider
+type RecordingTransport struct {
+	// Status of the replies, 200 when zero
+	Status int
+
+	// Responses are replied in order, the last one is repeated once the others are used up
+	Responses []string
+
+	Requests []map[string]any
+	Paths    []string
+	Headers  []http.Header
+}
+
+// NewRecordingTransport creates a transport replying with the given responses in order
+func NewRecordingTransport(responses ...string) *RecordingTransport {
+	return &RecordingTransport{Responses: responses}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (r *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, err
+	}
+	r.Requests = append(r.Requests, decoded)
+	r.Paths = append(r.Paths, req.URL.Path)
+	r.Headers = append(r.Headers, req.Header)
+
+	reply := r.Responses[0]
+	if len(r.Responses) > 1 {
+		r.Responses = r.Responses[1:]
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(reply)),
+		Request:    req,
+	}, nil
+}
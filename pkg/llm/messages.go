@@ -17,6 +17,7 @@ const (
 	MessageKindText       MessageKind = "text"
 	MessageKindToolCall   MessageKind = "tool_call"
 	MessageKindToolResult MessageKind = "tool_result"
+	MessageKindAudio      MessageKind = "audio"
 )
 
 // MessageRole represents the role of the message sender
@@ -26,6 +27,7 @@ const (
 	MessageRoleUser      MessageRole = "user"
 	MessageRoleAssistant MessageRole = "assistant"
 	MessageRoleSystem    MessageRole = "system"
+	MessageRoleDeveloper MessageRole = "developer"
 	MessageRoleTool      MessageRole = "tool"
 )
 
@@ -51,6 +53,9 @@ func (m *UserMessage) Role() MessageRole {
 // AssistantMessage represents a message from the assistant
 type AssistantMessage struct {
 	Content string
+
+	// ToolCalls are the tool calls the assistant made in the same turn as the text, if any
+	ToolCalls []*ToolCall
 }
 
 func (m *AssistantMessage) Kind() MessageKind {
@@ -61,6 +66,22 @@ func (m *AssistantMessage) Role() MessageRole {
 	return MessageRoleAssistant
 }
 
+// AudioMessage represents audio produced by the assistant
+type AudioMessage struct {
+	ID         string
+	Data       []byte
+	Format     string
+	Transcript string
+}
+
+func (m *AudioMessage) Kind() MessageKind {
+	return MessageKindAudio
+}
+
+func (m *AudioMessage) Role() MessageRole {
+	return MessageRoleAssistant
+}
+
 // SystemMessage represents a system message
 type SystemMessage struct {
 	Content string
@@ -80,6 +101,35 @@ func (m *SystemMessage) Role() MessageRole {
 	return MessageRoleSystem
 }
 
+// DeveloperMessage carries instructions from the application developer. Models distinguishing the
+// developer role weigh it below system messages, other providers treat it as a system message.
+type DeveloperMessage struct {
+	Content string
+}
+
+func NewDeveloperMessage(content string) *DeveloperMessage {
+	return &DeveloperMessage{
+		Content: content,
+	}
+}
+
+func (m *DeveloperMessage) Kind() MessageKind {
+	return MessageKindText
+}
+
+func (m *DeveloperMessage) Role() MessageRole {
+	return MessageRoleDeveloper
+}
+
+// isInstruction reports whether the message instructs the model rather than takes part in the conversation
+func isInstruction(msg Message) bool {
+	switch msg.(type) {
+	case *SystemMessage, *DeveloperMessage:
+		return true
+	}
+	return false
+}
+
 type ToolCallMessage struct {
 	ToolCall *ToolCall
 }
@@ -102,6 +152,10 @@ func (m *ToolCallMessage) Role() MessageRole {
 type ToolResultMessage struct {
 	ToolCall *ToolCall
 	Result   json.RawMessage
+
+	// FullResult holds the original result when Result was condensed (e.g. summarized),
+	// it is kept for the transcript and never sent to the provider
+	FullResult json.RawMessage
 }
 
 func NewToolResultMessage(toolCall *ToolCall, result json.RawMessage) *ToolResultMessage {
@@ -131,9 +185,15 @@ type ToolCall struct {
 	ID   string
 	Name string
 	Args json.RawMessage
+
+	// Decoded Args cached by ArgsMap, along with the arguments they were decoded from
+	argsMap    map[string]any
+	argsMapOf  json.RawMessage
+	argsMapErr error
 }
 
-// ToolErrorMessage represents an error that occurred during tool execution
+// ToolErrorMessage represents an error that occurred during tool execution. Adapters don't send it,
+// the agent delivers tool errors as tool results.
 type ToolErrorMessage struct {
 	ToolCall *ToolCall
 	Error    string
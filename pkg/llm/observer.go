@@ -0,0 +1,56 @@
+package llm
+
+import "context"
+
+// AgentObserver is notified of the agent's lifecycle events, e.g. to emit tracing spans or metrics.
+// Tool events may arrive concurrently when the agent runs tool calls in parallel (see WithConcurrentToolCalls).
+// Embed NoopAgentObserver to implement only the events of interest.
+type AgentObserver interface {
+	// OnLLMRequest is called before each iteration's LLM call
+	OnLLMRequest(ctx context.Context, iteration int, request *LLMRequest)
+
+	// OnLLMResponse is called once the iteration's LLM call returns, err is set when it failed
+	OnLLMResponse(ctx context.Context, iteration int, response *LLMResponse, err error)
+
+	// OnToolCall is called before a tool call starts, including its retries. A streamed call is
+	// reported as soon as it starts, before its arguments are complete; OnToolResult then receives
+	// the completed call, with the same ID.
+	OnToolCall(ctx context.Context, toolCall *ToolCall)
+
+	// OnToolResult is called when a tool call is done, err is set when it failed after all retries
+	OnToolResult(ctx context.Context, toolCall *ToolCall, result Message, err error)
+
+	// OnRetry is called before a failed tool call is retried, attempt counts from 1
+	OnRetry(ctx context.Context, toolCall *ToolCall, attempt int, err error)
+
+	// OnFinish is called when the run ends, with its final response or error
+	OnFinish(ctx context.Context, response *LLMResponse, err error)
+}
+
+// NoopAgentObserver ignores all events, it is the agent's default observer
+type NoopAgentObserver struct{}
+
+func (NoopAgentObserver) OnLLMRequest(ctx context.Context, iteration int, request *LLMRequest) {}
+
+func (NoopAgentObserver) OnLLMResponse(ctx context.Context, iteration int, response *LLMResponse, err error) {
+}
+
+func (NoopAgentObserver) OnToolCall(ctx context.Context, toolCall *ToolCall) {}
+
+func (NoopAgentObserver) OnToolResult(ctx context.Context, toolCall *ToolCall, result Message, err error) {
+}
+
+func (NoopAgentObserver) OnRetry(ctx context.Context, toolCall *ToolCall, attempt int, err error) {}
+
+func (NoopAgentObserver) OnFinish(ctx context.Context, response *LLMResponse, err error) {}
+
+// WithObserver registers an observer notified of the agent's LLM calls, tool calls, retries and the end of a run.
+// A nil observer restores the default, NoopAgentObserver.
+func WithObserver(observer AgentObserver) AgentOpts {
+	return func(a *Agent) {
+		if observer == nil {
+			observer = NoopAgentObserver{}
+		}
+		a.observer = observer
+	}
+}
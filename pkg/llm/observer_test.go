@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// recordingObserver records the events it receives as strings
+type recordingObserver struct {
+	NoopAgentObserver
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recordingObserver) record(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingObserver) OnLLMRequest(ctx context.Context, iteration int, request *LLMRequest) {
+	r.record("llm_request %d", iteration)
+}
+
+func (r *recordingObserver) OnLLMResponse(ctx context.Context, iteration int, response *LLMResponse, err error) {
+	r.record("llm_response %d %d", iteration, len(response.Messages))
+}
+
+func (r *recordingObserver) OnToolCall(ctx context.Context, toolCall *ToolCall) {
+	r.record("tool_call %s", toolCall.ID)
+}
+
+func (r *recordingObserver) OnToolResult(ctx context.Context, toolCall *ToolCall, result Message, err error) {
+	if err != nil {
+		r.record("tool_result %s %v", toolCall.ID, err)
+		return
+	}
+	r.record("tool_result %s %s", toolCall.ID, result.(*ToolResultMessage).Result)
+}
+
+func (r *recordingObserver) OnRetry(ctx context.Context, toolCall *ToolCall, attempt int, err error) {
+	r.record("retry %s %d %v", toolCall.ID, attempt, err)
+}
+
+func (r *recordingObserver) OnFinish(ctx context.Context, response *LLMResponse, err error) {
+	r.record("finish %v", err)
+}
+
+func TestAgentObserver(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		// The correction of the failed call
+		if forced, ok := request.ToolUsage.(*ForcedToolUsage); ok {
+			return toolCallResponse("", forced.ToolName, `{"value": "good"}`), nil
+		}
+
+		calls++
+		if calls == 1 {
+			return toolCallResponse("call_1", "mock", `{"value": "bad"}`), nil
+		}
+		return textResponse("Done"), nil
+	})
+
+	tool := &mockTool{name: "mock", shouldFail: true, correctArgs: json.RawMessage(`{"value": "good"}`)}
+	observer := &recordingObserver{}
+	agent := NewAgent(model, []Tool{tool}, WithObserver(observer), WithRetryDelay(0))
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Go")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"llm_request 1",
+		"llm_response 1 1",
+		"tool_call call_1",
+		"retry call_1 1 invalid arguments",
+		`tool_result call_1 {"result": "success"}`,
+		"llm_request 2",
+		"llm_response 2 1",
+		"finish <nil>",
+	}
+	if !reflect.DeepEqual(observer.events, expected) {
+		t.Errorf("Expected events %v, got %v", expected, observer.events)
+	}
+}
+
+func TestAgentObserverFinishWithError(t *testing.T) {
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		return nil, errors.New("provider down")
+	})
+
+	var finished error
+	observer := &finishObserver{onFinish: func(err error) { finished = err }}
+	agent := NewAgent(model, nil, WithObserver(observer))
+
+	_, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Go"))))
+	if err == nil || finished != err {
+		t.Errorf("Expected OnFinish to receive the run error %v, got %v", err, finished)
+	}
+}
+
+// finishObserver only implements OnFinish, relying on NoopAgentObserver for the rest
+type finishObserver struct {
+	NoopAgentObserver
+	onFinish func(error)
+}
+
+func (f *finishObserver) OnFinish(ctx context.Context, response *LLMResponse, err error) {
+	f.onFinish(err)
+}
+
+func TestWithNilObserver(t *testing.T) {
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		return textResponse("Done"), nil
+	})
+
+	agent := NewAgent(model, nil, WithObserver(nil))
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Go")))); err != nil {
+		t.Fatalf("Expected a nil observer to be ignored, got %v", err)
+	}
+}
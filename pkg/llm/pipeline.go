@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PipelineStage is a step of a pipeline, typically a specialized agent
+type PipelineStage struct {
+	Name string
+	LLM  LLM
+
+	// Task runs the stage instead of LLM when set, e.g. a deterministic step or a custom Task that
+	// samples several agents
+	Task Task[*LLMRequest, *LLMResponse]
+
+	// Input builds the stage's request from the result of the previous stage. Nil hands the previous
+	// answer over as the single user message of a fresh conversation. It is not called for the first
+	// stage, which receives the pipeline's request.
+	Input func(ctx context.Context, previous *StageResult) (*LLMRequest, error)
+}
+
+// StageResult is the request and response of a completed pipeline stage
+type StageResult struct {
+	Stage    string
+	Request  *LLMRequest
+	Response *LLMResponse
+}
+
+// Answer returns the final assistant text of the stage, or its JSON when the stage formats its
+// answer with an output schema (structured output comes back as a user message). It is empty when
+// the stage gave neither.
+func (r *StageResult) Answer() string {
+	for i := len(r.Response.Messages) - 1; i >= 0; i-- {
+		switch msg := r.Response.Messages[i].(type) {
+		case *AssistantMessage:
+			return msg.Content
+		case *UserMessage:
+			return msg.Content
+		}
+	}
+
+	return ""
+}
+
+// Pipeline chains LLMs, e.g. an extractor, a planner and an executor, so that the output of each
+// stage seeds the input of the next. It stops at the first failing stage.
+type Pipeline struct {
+	stages []PipelineStage
+}
+
+// NewPipeline creates a pipeline running the stages in order
+func NewPipeline(stages ...PipelineStage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Invoke implements the LLM interface, returning the response of the last stage. This lets a
+// pipeline be a stage of another pipeline or a tool via AgentTool.
+func (p *Pipeline) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	results, err := p.Run(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return results[len(results)-1].Response, nil
+}
+
+// Run runs the stages in order, the first one with the given request, and returns the result of
+// every stage. When a stage fails, the results of the stages completed before it are returned along
+// with a PipelineError.
+func (p *Pipeline) Run(ctx context.Context, request *LLMRequest) ([]*StageResult, error) {
+	if len(p.stages) == 0 {
+		return nil, errors.New("pipeline has no stages")
+	}
+
+	results := make([]*StageResult, 0, len(p.stages))
+	for i, stage := range p.stages {
+		if i > 0 {
+			var err error
+			request, err = stageInput(ctx, stage, results[i-1])
+			if err != nil {
+				return results, &PipelineError{Stage: stage.Name, Err: err}
+			}
+		}
+
+		response, err := stage.run(ctx, request)
+		if err != nil {
+			return results, &PipelineError{Stage: stage.Name, Err: err}
+		}
+
+		results = append(results, &StageResult{Stage: stage.Name, Request: request, Response: response})
+	}
+
+	return results, nil
+}
+
+// run runs the stage's task, or its LLM when it has no task
+func (s PipelineStage) run(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	switch {
+	case s.Task != nil:
+		return s.Task.Run(ctx, request)
+	case s.LLM != nil:
+		return s.LLM.Invoke(ctx, request)
+	}
+
+	return nil, errors.New("stage has neither an LLM nor a task")
+}
+
+// stageInput builds the request of the stage from the result of the previous one
+func stageInput(ctx context.Context, stage PipelineStage, previous *StageResult) (*LLMRequest, error) {
+	if stage.Input != nil {
+		return stage.Input(ctx, previous)
+	}
+
+	answer := previous.Answer()
+	if answer == "" {
+		return nil, fmt.Errorf("stage %s gave no answer", previous.Stage)
+	}
+
+	return NewLLMRequest(NewHistory(NewUserMessage(answer))), nil
+}
+
+// DecodeAnswer builds a stage input from the previous stage's answer decoded as JSON into T, e.g.
+// the structured output of an extractor, passing it to build as a typed value
+func DecodeAnswer[T any](build func(ctx context.Context, input T) (*LLMRequest, error)) func(ctx context.Context, previous *StageResult) (*LLMRequest, error) {
+	return func(ctx context.Context, previous *StageResult) (*LLMRequest, error) {
+		var input T
+		if err := json.Unmarshal([]byte(previous.Answer()), &input); err != nil {
+			return nil, fmt.Errorf("failed to decode the answer of stage %s: %w", previous.Stage, err)
+		}
+
+		return build(ctx, input)
+	}
+}
@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPipelineSeedsEachStage(t *testing.T) {
+	var plannerRequest *LLMRequest
+	extractor := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		return textResponse("Flight to Paris on Friday"), nil
+	})
+	planner := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		plannerRequest = request
+		return textResponse("1. Book the flight"), nil
+	})
+
+	pipeline := NewPipeline(
+		PipelineStage{Name: "extractor", LLM: extractor},
+		PipelineStage{Name: "planner", LLM: planner},
+	)
+
+	results, err := pipeline.Run(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("I need to be in Paris by Friday"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(results) != 2 || results[0].Stage != "extractor" || results[1].Stage != "planner" {
+		t.Fatalf("Expected results of both stages, got %v", results)
+	}
+	if results[0].Answer() != "Flight to Paris on Friday" {
+		t.Errorf("Expected the intermediate answer to be kept, got %q", results[0].Answer())
+	}
+
+	if len(plannerRequest.History) != 1 {
+		t.Fatalf("Expected a fresh conversation for the planner, got %d messages", len(plannerRequest.History))
+	}
+	if msg, ok := plannerRequest.History[0].(*UserMessage); !ok || msg.Content != "Flight to Paris on Friday" {
+		t.Errorf("Expected the extractor's answer as the planner's input, got %v", plannerRequest.History[0])
+	}
+}
+
+func TestPipelineDecodeAnswer(t *testing.T) {
+	type trip struct {
+		Destination string `json:"destination"`
+		Day         string `json:"day"`
+	}
+
+	extractor := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		return textResponse(`{"destination":"Paris","day":"Friday"}`), nil
+	})
+	planner := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		return textResponse(request.System), nil
+	})
+
+	pipeline := NewPipeline(
+		PipelineStage{Name: "extractor", LLM: extractor},
+		PipelineStage{Name: "planner", LLM: planner, Input: DecodeAnswer(func(ctx context.Context, input trip) (*LLMRequest, error) {
+			system := fmt.Sprintf("Plan a trip to %s on %s", input.Destination, input.Day)
+			return NewLLMRequest(NewHistory(NewUserMessage("Make the plan")), WithSystem(system)), nil
+		})},
+	)
+
+	response, err := pipeline.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Paris, Friday"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if answer := lastAssistantMessage(response.Messages); answer == nil || answer.Content != "Plan a trip to Paris on Friday" {
+		t.Errorf("Expected the planner's response built from the decoded answer, got %v", response.Messages)
+	}
+}
+
+func TestPipelineDecodesStructuredOutput(t *testing.T) {
+	type trip struct {
+		Destination string `json:"destination"`
+		Day         string `json:"day"`
+	}
+
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		if calls == 1 {
+			return textResponse("You are going to Paris on Friday."), nil
+		}
+		return toolCallResponse("call_1", "formatter", `{"destination":"Paris","day":"Friday"}`), nil
+	})
+	schema := json.RawMessage(`{"type":"object","properties":{"destination":{"type":"string"},"day":{"type":"string"}},"required":["destination","day"]}`)
+
+	var decoded trip
+	pipeline := NewPipeline(
+		PipelineStage{Name: "extractor", LLM: NewAgent(model, nil, WithOutputSchema(schema))},
+		PipelineStage{Name: "planner", LLM: invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+			return textResponse("1. Book the flight"), nil
+		}), Input: DecodeAnswer(func(ctx context.Context, input trip) (*LLMRequest, error) {
+			decoded = input
+			return NewLLMRequest(NewHistory(NewUserMessage("Make the plan"))), nil
+		})},
+	)
+
+	if _, err := pipeline.Run(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Paris, Friday")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if decoded.Destination != "Paris" || decoded.Day != "Friday" {
+		t.Errorf("Expected the extractor's structured output to be decoded, got %+v", decoded)
+	}
+}
+
+func TestPipelineStopsAtFailingStage(t *testing.T) {
+	failure := errors.New("planner unavailable")
+	executorCalled := false
+
+	pipeline := NewPipeline(
+		PipelineStage{Name: "extractor", LLM: invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+			return textResponse("Flight to Paris"), nil
+		})},
+		PipelineStage{Name: "planner", LLM: invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+			return nil, failure
+		})},
+		PipelineStage{Name: "executor", LLM: invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+			executorCalled = true
+			return textResponse("Done"), nil
+		})},
+	)
+
+	results, err := pipeline.Run(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Paris"))))
+
+	var pipelineErr *PipelineError
+	if !errors.As(err, &pipelineErr) || pipelineErr.Stage != "planner" {
+		t.Fatalf("Expected a PipelineError for the planner, got %v", err)
+	}
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the error to wrap the stage's failure, got %v", err)
+	}
+	if executorCalled {
+		t.Error("Expected the executor not to run after the planner failed")
+	}
+	if len(results) != 1 || results[0].Stage != "extractor" {
+		t.Errorf("Expected the extractor's result to be returned, got %v", results)
+	}
+}
+
+func TestPipelineStageWithoutAnswer(t *testing.T) {
+	pipeline := NewPipeline(
+		PipelineStage{Name: "extractor", LLM: invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+			return NewLLMResponse(), nil
+		})},
+		PipelineStage{Name: "planner", LLM: invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+			t.Error("Expected the planner not to run")
+			return textResponse("Plan"), nil
+		})},
+	)
+
+	_, err := pipeline.Run(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Paris"))))
+	if err == nil || err.Error() != "pipeline stage planner failed: stage extractor gave no answer" {
+		t.Errorf("Expected a missing answer error, got %v", err)
+	}
+}
+
+// echoTask answers with the last user message of the request, standing in for a stage that is no LLM
+type echoTask struct{}
+
+func (echoTask) Run(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	return textResponse("Echo: " + request.History[len(request.History)-1].(*UserMessage).Content), nil
+}
+
+func TestPipelineTaskStage(t *testing.T) {
+	pipeline := NewPipeline(
+		PipelineStage{Name: "extractor", LLM: invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+			return textResponse("Paris"), nil
+		})},
+		PipelineStage{Name: "echo", Task: echoTask{}},
+	)
+
+	response, err := pipeline.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Where to?"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if answer := lastAssistantMessage(response.Messages); answer == nil || answer.Content != "Echo: Paris" {
+		t.Errorf("Expected the task stage to answer, got %v", response.Messages)
+	}
+}
+
+func TestPipelineStageWithoutLLMOrTask(t *testing.T) {
+	_, err := NewPipeline(PipelineStage{Name: "empty"}).Run(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Paris"))))
+	if err == nil || err.Error() != "pipeline stage empty failed: stage has neither an LLM nor a task" {
+		t.Errorf("Expected a missing LLM error, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// SystemFromFile returns an option setting the system prompt to the contents of the file,
+// so long prompts can be kept and reviewed outside of code
+func SystemFromFile(path string) (LLMRequestOpts, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system prompt: %w", err)
+	}
+
+	return WithSystem(strings.TrimSpace(string(content))), nil
+}
+
+// SystemFromFS returns an option setting the system prompt to the named file of fsys, e.g. an embed.FS
+func SystemFromFS(fsys fs.FS, name string) (LLMRequestOpts, error) {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system prompt: %w", err)
+	}
+
+	return WithSystem(strings.TrimSpace(string(content))), nil
+}
+
+// SystemFromTemplate renders the named text/template of fsys with data and returns an option
+// setting the result as the system prompt
+func SystemFromTemplate(fsys fs.FS, name string, data any) (LLMRequestOpts, error) {
+	tmpl, err := template.New(path.Base(name)).Option("missingkey=error").ParseFS(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse system prompt template: %w", err)
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return nil, fmt.Errorf("failed to render system prompt template: %w", err)
+	}
+
+	return WithSystem(strings.TrimSpace(rendered.String())), nil
+}
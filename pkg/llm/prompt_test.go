@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"embed"
+	"testing"
+)
+
+//go:embed testdata/system_prompt.txt testdata/system_prompt.tmpl
+var testPrompts embed.FS
+
+func TestSystemFromFile(t *testing.T) {
+	opt, err := SystemFromFile("testdata/system_prompt.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	request := NewLLMRequest(NewHistory(), opt)
+	if request.System != "You are a helpful assistant." {
+		t.Errorf("Expected system prompt from file, got %q", request.System)
+	}
+
+	if _, err := SystemFromFile("testdata/missing.txt"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestSystemFromFS(t *testing.T) {
+	opt, err := SystemFromFS(testPrompts, "testdata/system_prompt.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if request := NewLLMRequest(NewHistory(), opt); request.System != "You are a helpful assistant." {
+		t.Errorf("Expected system prompt from embedded file, got %q", request.System)
+	}
+}
+
+func TestSystemFromTemplate(t *testing.T) {
+	opt, err := SystemFromTemplate(testPrompts, "testdata/system_prompt.tmpl", map[string]string{
+		"Company":  "Frax Travel",
+		"Language": "French",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "You are a travel agent for Frax Travel.\nAnswer in French."
+	if request := NewLLMRequest(NewHistory(), opt); request.System != expected {
+		t.Errorf("Expected %q, got %q", expected, request.System)
+	}
+
+	// Missing template data is reported rather than rendered as "<no value>"
+	if _, err := SystemFromTemplate(testPrompts, "testdata/system_prompt.tmpl", map[string]string{"Company": "Frax"}); err == nil {
+		t.Error("Expected error for missing template data")
+	}
+}
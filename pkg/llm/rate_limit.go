@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitedLLM wraps an LLM and paces its calls with a token bucket, e.g. to stay under a provider's
+// requests-per-minute limit when many agents share it
+type RateLimitedLLM struct {
+	inner LLM
+
+	rps   float64
+	burst int
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimitedLLM creates a rate limiter around the given LLM allowing rps calls per second on average
+// and bursts of up to burst calls. A non-positive rps disables the limit.
+func NewRateLimitedLLM(inner LLM, rps float64, burst int) *RateLimitedLLM {
+	burst = max(burst, 1)
+
+	return &RateLimitedLLM{
+		inner:  inner,
+		rps:    rps,
+		burst:  burst,
+		now:    time.Now,
+		tokens: float64(burst),
+	}
+}
+
+// Invoke implements the LLM interface, waiting for the rate limit before calling the wrapped LLM
+func (r *RateLimitedLLM) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	if err := r.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.inner.Invoke(ctx, request)
+}
+
+// wait takes a token, blocking until it is available or the context is done
+func (r *RateLimitedLLM) wait(ctx context.Context) error {
+	if r.rps <= 0 {
+		return nil
+	}
+
+	delay := r.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.release()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from the bucket and returns how long to wait until it is actually available.
+// Reservations are taken in call order, so waiting callers are served first come, first served.
+func (r *RateLimitedLLM) reserve() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if !r.last.IsZero() {
+		r.tokens = min(r.tokens+now.Sub(r.last).Seconds()*r.rps, float64(r.burst))
+	}
+	r.last = now
+
+	r.tokens--
+	if r.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-r.tokens / r.rps * float64(time.Second))
+}
+
+// release returns the token of a caller that gave up waiting
+func (r *RateLimitedLLM) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens = min(r.tokens+1, float64(r.burst))
+}
+
+// Capabilities reports the capabilities of the wrapped LLM, except streaming as only Invoke is rate limited
+func (r *RateLimitedLLM) Capabilities() Capabilities {
+	capabilities := CapabilitiesOf(r.inner)
+	capabilities.Streaming = false
+
+	return capabilities
+}
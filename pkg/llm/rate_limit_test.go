@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// timedLLM records when it is invoked
+type timedLLM struct {
+	mu    sync.Mutex
+	calls []time.Time
+}
+
+func (t *timedLLM) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.calls = append(t.calls, time.Now())
+	return NewLLMResponse(), nil
+}
+
+func TestRateLimitedLLMPacesConcurrentCalls(t *testing.T) {
+	inner := &timedLLM{}
+	limited := NewRateLimitedLLM(inner, 100, 2)
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for range 12 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := limited.Invoke(context.Background(), NewLLMRequest(NewHistory())); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(inner.calls) != 12 {
+		t.Fatalf("Expected 12 calls, got %d", len(inner.calls))
+	}
+
+	// The burst goes through at once, the remaining 10 calls at 100 per second
+	elapsed := time.Since(start)
+	if elapsed < 95*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected the calls to take about 100ms, took %v", elapsed)
+	}
+
+	calls := slices.Clone(inner.calls)
+	slices.SortFunc(calls, func(a, b time.Time) int { return a.Compare(b) })
+	for i := 2; i < len(calls); i++ {
+		if since := calls[i].Sub(start); since < time.Duration(i-1)*10*time.Millisecond-time.Millisecond {
+			t.Errorf("Expected call %d no earlier than %v, got %v", i+1, time.Duration(i-1)*10*time.Millisecond, since)
+		}
+	}
+}
+
+func TestRateLimitedLLMRespectsContext(t *testing.T) {
+	inner := &timedLLM{}
+	limited := NewRateLimitedLLM(inner, 1, 1)
+	request := NewLLMRequest(NewHistory())
+
+	if _, err := limited.Invoke(context.Background(), request); err != nil {
+		t.Fatalf("Expected the first call to go through, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := limited.Invoke(ctx, request)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to be cut short by the context, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected to stop waiting when the context is done, waited %v", elapsed)
+	}
+	if len(inner.calls) != 1 {
+		t.Errorf("Expected the cancelled call not to reach the LLM, got %d calls", len(inner.calls))
+	}
+}
+
+func TestRateLimitedLLMDisabled(t *testing.T) {
+	inner := &timedLLM{}
+	limited := NewRateLimitedLLM(inner, 0, 1)
+
+	start := time.Now()
+	for range 100 {
+		limited.Invoke(context.Background(), NewLLMRequest(NewHistory()))
+	}
+
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected no pacing without a rate, took %v", elapsed)
+	}
+}
@@ -1,23 +1,101 @@
 package llm
 
+import "maps"
+
 type LLMRequest struct {
-	System    string
-	History   History
-	Tools     []Tool
+	System  string
+	History History
+	Tools   []Tool
+
+	// Examples are example conversations shown to the model before the history, they are not part of it
+	Examples  []History
 	ToolUsage ToolUsage
 
+	// ParallelToolCalls is nil when unset, leaving the provider default (parallel calls enabled on OpenAI).
+	// OpenAI, Anthropic and Mistral honor it, Gemini, Bedrock and Cohere have no such setting and ignore it.
+	ParallelToolCalls *bool
+
+	// ToolResultDelivery controls how tool results are sent, defaults to ToolResultDeliveryToolRole
+	ToolResultDelivery ToolResultDelivery
+
+	// MaxCompletionTokens limits the generated tokens, zero leaves it to the provider
 	MaxCompletionTokens int
-	Temperature         float64
+
+	// Temperature is nil when unset so that an explicit zero can request deterministic output
+	Temperature *float64
+	TopP        *float64
+
+	// Seed asks the provider for reproducible sampling, nil leaves it random
+	Seed *int64
+
+	// Stop lists sequences at which the model stops generating, the sequence itself is not returned
+	Stop []string
+
+	// FrequencyPenalty and PresencePenalty discourage repetition, zero leaves them to the provider
+	FrequencyPenalty float64
+	PresencePenalty  float64
+
+	// TopLogprobs asks for the log probability of every generated token along with this many of the
+	// most likely alternatives, nil leaves log probabilities off
+	TopLogprobs *int
+
+	// JSONMode asks for a reply that is a valid JSON object without constraining it to a schema,
+	// adapters without such a mode ignore it
+	JSONMode bool
+
+	Modalities  []string
+	AudioOutput *AudioOutput
+
+	// SafetySettings tune provider-side content filtering, providers without such filtering ignore them
+	SafetySettings []SafetySetting
+
+	// RequestID correlates the calls of one interaction in logs and at the provider, the agent
+	// generates one per run when unset
+	RequestID string
+
+	// IdempotencyKey lets the provider deduplicate a repeated call, adapters without support ignore it
+	IdempotencyKey string
+
+	// ModelParams are provider-specific parameters passed through as-is, keyed by their API name
+	ModelParams map[string]any
+}
+
+// AudioOutput configures audio responses for models that can speak
+type AudioOutput struct {
+	Voice  string
+	Format string
 }
 
 type LLMRequestOpts = func(*LLMRequest)
 
+// WithModelParams passes provider-specific parameters through to the API, e.g.
+// {"reasoning_effort": "low"}. Adapters may reject or strip parameters the model does not accept.
+func WithModelParams(params map[string]any) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		merged := maps.Clone(r.ModelParams)
+		if merged == nil {
+			merged = make(map[string]any, len(params))
+		}
+		maps.Copy(merged, params)
+
+		r.ModelParams = merged
+	}
+}
+
 func WithToolUsage(toolUsage ToolUsage) LLMRequestOpts {
 	return func(r *LLMRequest) {
 		r.ToolUsage = toolUsage
 	}
 }
 
+// WithParallelToolCalls allows or forbids several tool calls in one turn, false limits the model to at most one.
+// Providers without the setting ignore it, see LLMRequest.ParallelToolCalls.
+func WithParallelToolCalls(parallel bool) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.ParallelToolCalls = &parallel
+	}
+}
+
 func WithTools(tools ...Tool) LLMRequestOpts {
 	return func(r *LLMRequest) {
 		r.Tools = append(r.Tools, tools...)
@@ -36,6 +114,14 @@ func WithHistory(history History) LLMRequestOpts {
 	}
 }
 
+// WithFewShotExamples adds example conversations (e.g. tool-call sequences) that the provider
+// sends before the real history, clearly delimited, without adding them to the history itself
+func WithFewShotExamples(examples []History) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.Examples = append(r.Examples, examples...)
+	}
+}
+
 func WithMaxCompletionTokens(maxCompletionTokens int) LLMRequestOpts {
 	return func(r *LLMRequest) {
 		r.MaxCompletionTokens = maxCompletionTokens
@@ -44,10 +130,137 @@ func WithMaxCompletionTokens(maxCompletionTokens int) LLMRequestOpts {
 
 func WithTemperature(temperature float64) LLMRequestOpts {
 	return func(r *LLMRequest) {
-		r.Temperature = temperature
+		r.Temperature = &temperature
+	}
+}
+
+// WithModalities sets the output modalities requested from the model, e.g. []string{"text", "audio"}
+func WithModalities(modalities []string) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.Modalities = modalities
+	}
+}
+
+// WithAudioOutput requests audio output with the given voice and format (e.g. "alloy", "wav")
+func WithAudioOutput(voice, format string) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.AudioOutput = &AudioOutput{Voice: voice, Format: format}
+	}
+}
+
+func WithTopP(topP float64) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.TopP = &topP
 	}
 }
 
+// WithSeed requests deterministic sampling for reproducible outputs, on a best-effort basis by the provider
+func WithSeed(seed int64) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.Seed = &seed
+	}
+}
+
+// WithStop sets the sequences at which the model stops generating, e.g. a delimiter ending an extraction
+func WithStop(stop ...string) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.Stop = stop
+	}
+}
+
+// WithFrequencyPenalty penalizes tokens by how often they already appeared, reducing verbatim repetition
+func WithFrequencyPenalty(penalty float64) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.FrequencyPenalty = penalty
+	}
+}
+
+// WithPresencePenalty penalizes tokens that already appeared at all, nudging the model towards new topics
+func WithPresencePenalty(penalty float64) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.PresencePenalty = penalty
+	}
+}
+
+// WithLogprobs returns the log probability of every generated token in LLMResponse.Logprobs, with
+// the topK most likely alternatives at each position, zero for none
+func WithLogprobs(topK int) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.TopLogprobs = &topK
+	}
+}
+
+// WithRequestID sets the correlation ID of the request, e.g. the ID of the incoming HTTP request
+func WithRequestID(id string) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.RequestID = id
+	}
+}
+
+// WithIdempotencyKey sets the key under which the provider deduplicates the call. An agent derives a
+// key per LLM call from it, so invoking again with the same key repeats none of the calls that completed.
+func WithIdempotencyKey(key string) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.IdempotencyKey = key
+	}
+}
+
+// WithJSONMode asks the model to reply with a valid JSON object. OpenAI requires the word "json" to
+// appear in the instructions or messages, its adapter fails the request otherwise.
+func WithJSONMode() LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.JSONMode = true
+	}
+}
+
+// SamplingParams groups the generation knobs of a request so they can be set in one call.
+// Only explicitly set fields are applied: nil pointers and zero values leave the request untouched.
+type SamplingParams struct {
+	Temperature         *float64
+	TopP                *float64
+	MaxCompletionTokens int
+	FrequencyPenalty    float64
+	PresencePenalty     float64
+	Stop                []string
+	Seed                *int64
+}
+
+// WithSampling applies all explicitly set sampling parameters to the request.
+// It composes with the individual options, the option applied last wins.
+func WithSampling(params SamplingParams) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		if params.Temperature != nil {
+			temperature := *params.Temperature
+			r.Temperature = &temperature
+		}
+		if params.TopP != nil {
+			topP := *params.TopP
+			r.TopP = &topP
+		}
+		if params.MaxCompletionTokens > 0 {
+			r.MaxCompletionTokens = params.MaxCompletionTokens
+		}
+		if params.FrequencyPenalty != 0 {
+			r.FrequencyPenalty = params.FrequencyPenalty
+		}
+		if params.PresencePenalty != 0 {
+			r.PresencePenalty = params.PresencePenalty
+		}
+		if len(params.Stop) > 0 {
+			r.Stop = params.Stop
+		}
+		if params.Seed != nil {
+			seed := *params.Seed
+			r.Seed = &seed
+		}
+	}
+}
+
+// Ptr returns a pointer to the given value, handy for optional fields such as those of SamplingParams
+func Ptr[T any](v T) *T {
+	return &v
+}
+
 func NewLLMRequest(history History, opts ...LLMRequestOpts) *LLMRequest {
 	r := &LLMRequest{
 		History:   history,
@@ -64,10 +277,26 @@ func (r *LLMRequest) Clone(opts ...LLMRequestOpts) *LLMRequest {
 	req := &LLMRequest{
 		History:             r.History,
 		ToolUsage:           r.ToolUsage,
+		ParallelToolCalls:   r.ParallelToolCalls,
+		ToolResultDelivery:  r.ToolResultDelivery,
 		Tools:               r.Tools,
 		System:              r.System,
+		Examples:            r.Examples,
 		MaxCompletionTokens: r.MaxCompletionTokens,
 		Temperature:         r.Temperature,
+		TopP:                r.TopP,
+		Seed:                r.Seed,
+		Stop:                r.Stop,
+		FrequencyPenalty:    r.FrequencyPenalty,
+		PresencePenalty:     r.PresencePenalty,
+		TopLogprobs:         r.TopLogprobs,
+		JSONMode:            r.JSONMode,
+		Modalities:          r.Modalities,
+		AudioOutput:         r.AudioOutput,
+		SafetySettings:      r.SafetySettings,
+		RequestID:           r.RequestID,
+		IdempotencyKey:      r.IdempotencyKey,
+		ModelParams:         r.ModelParams,
 	}
 
 	for _, opt := range opts {
@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithSampling(t *testing.T) {
+	request := NewLLMRequest(NewHistory(), WithSampling(SamplingParams{
+		Temperature:         Ptr(0.2),
+		TopP:                Ptr(0.9),
+		MaxCompletionTokens: 500,
+		FrequencyPenalty:    0.4,
+		PresencePenalty:     0.6,
+		Stop:                []string{"###"},
+		Seed:                Ptr[int64](7),
+	}))
+
+	if request.Temperature == nil || *request.Temperature != 0.2 {
+		t.Errorf("Expected temperature 0.2, got %v", request.Temperature)
+	}
+
+	if request.TopP == nil || *request.TopP != 0.9 {
+		t.Errorf("Expected top_p 0.9, got %v", request.TopP)
+	}
+
+	if request.MaxCompletionTokens != 500 {
+		t.Errorf("Expected max completion tokens 500, got %d", request.MaxCompletionTokens)
+	}
+
+	if request.FrequencyPenalty != 0.4 || request.PresencePenalty != 0.6 {
+		t.Errorf("Expected penalties 0.4 and 0.6, got %v and %v", request.FrequencyPenalty, request.PresencePenalty)
+	}
+
+	if !reflect.DeepEqual(request.Stop, []string{"###"}) {
+		t.Errorf("Expected stop sequences, got %v", request.Stop)
+	}
+
+	if request.Seed == nil || *request.Seed != 7 {
+		t.Errorf("Expected seed 7, got %v", request.Seed)
+	}
+}
+
+func TestWithSamplingMerge(t *testing.T) {
+	tests := []struct {
+		name                string
+		opts                []LLMRequestOpts
+		temperature         float64
+		topP                *float64
+		maxCompletionTokens int
+		frequencyPenalty    float64
+		presencePenalty     float64
+		stop                []string
+		seed                *int64
+	}{
+		{
+			name: "Unset fields keep earlier values",
+			opts: []LLMRequestOpts{
+				WithTemperature(0.7),
+				WithMaxCompletionTokens(100),
+				WithSampling(SamplingParams{TopP: Ptr(0.5)}),
+			},
+			temperature:         0.7,
+			topP:                Ptr(0.5),
+			maxCompletionTokens: 100,
+		},
+		{
+			name: "Sampling overrides earlier individual options",
+			opts: []LLMRequestOpts{
+				WithTemperature(0.7),
+				WithSampling(SamplingParams{Temperature: Ptr(0.1)}),
+			},
+			temperature: 0.1,
+		},
+		{
+			name: "Later individual options override sampling",
+			opts: []LLMRequestOpts{
+				WithSampling(SamplingParams{Temperature: Ptr(0.1), MaxCompletionTokens: 50}),
+				WithTemperature(0.9),
+			},
+			temperature:         0.9,
+			maxCompletionTokens: 50,
+		},
+		{
+			name: "Unset penalties, stop sequences and seed keep earlier values",
+			opts: []LLMRequestOpts{
+				WithTemperature(0.7),
+				WithFrequencyPenalty(0.4),
+				WithPresencePenalty(0.6),
+				WithStop("###"),
+				WithSeed(7),
+				WithSampling(SamplingParams{PresencePenalty: 0.2}),
+			},
+			temperature:      0.7,
+			frequencyPenalty: 0.4,
+			presencePenalty:  0.2,
+			stop:             []string{"###"},
+			seed:             Ptr[int64](7),
+		},
+		{
+			name: "Sampling overrides earlier penalties, stop sequences and seed",
+			opts: []LLMRequestOpts{
+				WithFrequencyPenalty(0.4),
+				WithStop("###"),
+				WithSeed(7),
+				WithSampling(SamplingParams{Temperature: Ptr(0.1), FrequencyPenalty: 0.8, Stop: []string{"END"}, Seed: Ptr[int64](42)}),
+			},
+			temperature:      0.1,
+			frequencyPenalty: 0.8,
+			stop:             []string{"END"},
+			seed:             Ptr[int64](42),
+		},
+		{
+			name: "Later penalties, stop sequences and seed override sampling",
+			opts: []LLMRequestOpts{
+				WithSampling(SamplingParams{Temperature: Ptr(0.1), FrequencyPenalty: 0.8, PresencePenalty: 0.5, Stop: []string{"END"}, Seed: Ptr[int64](42)}),
+				WithFrequencyPenalty(0.3),
+				WithStop("###"),
+				WithSeed(7),
+			},
+			temperature:      0.1,
+			frequencyPenalty: 0.3,
+			presencePenalty:  0.5,
+			stop:             []string{"###"},
+			seed:             Ptr[int64](7),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := NewLLMRequest(NewHistory(), tt.opts...)
+
+			if request.Temperature == nil || *request.Temperature != tt.temperature {
+				t.Errorf("Expected temperature %f, got %v", tt.temperature, request.Temperature)
+			}
+
+			if (tt.topP == nil) != (request.TopP == nil) || (tt.topP != nil && *tt.topP != *request.TopP) {
+				t.Errorf("Expected top_p %v, got %v", tt.topP, request.TopP)
+			}
+
+			if request.MaxCompletionTokens != tt.maxCompletionTokens {
+				t.Errorf("Expected max completion tokens %d, got %d", tt.maxCompletionTokens, request.MaxCompletionTokens)
+			}
+
+			if request.FrequencyPenalty != tt.frequencyPenalty || request.PresencePenalty != tt.presencePenalty {
+				t.Errorf("Expected penalties %v and %v, got %v and %v", tt.frequencyPenalty, tt.presencePenalty, request.FrequencyPenalty, request.PresencePenalty)
+			}
+
+			if !reflect.DeepEqual(request.Stop, tt.stop) {
+				t.Errorf("Expected stop sequences %v, got %v", tt.stop, request.Stop)
+			}
+
+			if (tt.seed == nil) != (request.Seed == nil) || (tt.seed != nil && *tt.seed != *request.Seed) {
+				t.Errorf("Expected seed %v, got %v", tt.seed, request.Seed)
+			}
+		})
+	}
+}
+
+func TestCloneKeepsSampling(t *testing.T) {
+	request := NewLLMRequest(NewHistory(), WithTopP(0.3), WithStop("###"), WithSeed(7), WithParallelToolCalls(false), WithJSONMode(),
+		WithFrequencyPenalty(0.4), WithPresencePenalty(0.6), WithLogprobs(3))
+	clone := request.Clone()
+
+	if clone.TopP == nil || *clone.TopP != 0.3 {
+		t.Errorf("Expected cloned top_p 0.3, got %v", clone.TopP)
+	}
+	if clone.Seed == nil || *clone.Seed != 7 {
+		t.Errorf("Expected cloned seed 7, got %v", clone.Seed)
+	}
+	if len(clone.Stop) != 1 || clone.Stop[0] != "###" {
+		t.Errorf("Expected cloned stop sequences, got %v", clone.Stop)
+	}
+	if clone.ParallelToolCalls == nil || *clone.ParallelToolCalls {
+		t.Errorf("Expected cloned parallel tool calls to be disabled, got %v", clone.ParallelToolCalls)
+	}
+	if clone.FrequencyPenalty != 0.4 || clone.PresencePenalty != 0.6 {
+		t.Errorf("Expected cloned penalties 0.4 and 0.6, got %v and %v", clone.FrequencyPenalty, clone.PresencePenalty)
+	}
+	if clone.TopLogprobs == nil || *clone.TopLogprobs != 3 {
+		t.Errorf("Expected cloned top logprobs 3, got %v", clone.TopLogprobs)
+	}
+	if !clone.JSONMode {
+		t.Error("Expected cloned JSON mode")
+	}
+}
+
+func TestWithSafetySettings(t *testing.T) {
+	request := NewLLMRequest(NewHistory(),
+		WithSafetySettings(
+			SafetySetting{Category: HarmCategoryHarassment, Threshold: SafetyBlockOnlyHigh},
+			SafetySetting{Category: HarmCategoryHateSpeech, Threshold: SafetyBlockLowAndAbove},
+		),
+		WithSafetySettings(SafetySetting{Category: HarmCategoryHarassment, Threshold: SafetyBlockNone}),
+	)
+
+	expected := []SafetySetting{
+		{Category: HarmCategoryHateSpeech, Threshold: SafetyBlockLowAndAbove},
+		{Category: HarmCategoryHarassment, Threshold: SafetyBlockNone},
+	}
+
+	if !reflect.DeepEqual(request.SafetySettings, expected) {
+		t.Errorf("Expected safety settings %v, got %v", expected, request.SafetySettings)
+	}
+
+	if clone := request.Clone(); !reflect.DeepEqual(clone.SafetySettings, expected) {
+		t.Errorf("Expected cloned safety settings %v, got %v", expected, clone.SafetySettings)
+	}
+}
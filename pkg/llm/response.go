@@ -1,7 +1,51 @@
 package llm
 
+import "encoding/json"
+
 type LLMResponse struct {
 	Messages History
+
+	// Truncated is set when the response holds only part of what the model generated,
+	// e.g. a stream that stalled and was cut off by CollectStream
+	Truncated bool
+
+	// Blocked is set when the provider filtered the response for safety reasons instead of answering
+	Blocked *SafetyBlock
+
+	// FinishReason is the provider's reason for ending the generation, e.g. "stop" or "tool_calls"
+	FinishReason string
+
+	// Usage reports the tokens consumed by the call, nil when the provider did not report it
+	Usage *Usage
+
+	// SystemFingerprint identifies the backend configuration that served the call, when the provider
+	// reports it. A change means seeded outputs may no longer reproduce.
+	SystemFingerprint string
+
+	// Raw is the provider's response body as received, for debugging. Nil unless the adapter was
+	// asked to capture it, e.g. with openai.WithCaptureRaw.
+	Raw json.RawMessage
+
+	// Logprobs holds the log probability of each generated token in order, nil unless requested
+	// with WithLogprobs and reported by the provider
+	Logprobs []TokenLogprob
+}
+
+// TokenLogprob is the log probability of a generated token, e.g. to estimate the model's confidence
+// in an extracted value
+type TokenLogprob struct {
+	Token   string
+	Logprob float64
+
+	// Alternatives are the most likely tokens at this position, most likely first, when requested
+	Alternatives []TokenLogprob
+}
+
+// Usage reports the tokens consumed by a single LLM call
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
 }
 
 func NewLLMResponse() *LLMResponse {
@@ -19,10 +63,19 @@ func (r *LLMResponse) AddToolCall(functionCall *ToolCall) {
 func (r *LLMResponse) ToolCalls() []*ToolCall {
 	var toolCalls []*ToolCall
 	for _, msg := range r.Messages {
-		if msg.Kind() == MessageKindToolCall {
-			toolCalls = append(toolCalls, msg.(*ToolCallMessage).ToolCall)
-		}
+		toolCalls = append(toolCalls, toolCallsOf(msg)...)
 	}
 
 	return toolCalls
 }
+
+// Audio returns the audio produced by the assistant, or nil for text-only responses
+func (r *LLMResponse) Audio() *AudioMessage {
+	for _, msg := range r.Messages {
+		if audio, ok := msg.(*AudioMessage); ok {
+			return audio
+		}
+	}
+
+	return nil
+}
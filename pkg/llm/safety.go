@@ -0,0 +1,66 @@
+package llm
+
+// HarmCategory identifies a class of content providers can filter for safety
+type HarmCategory string
+
+const (
+	HarmCategoryHarassment       HarmCategory = "harassment"
+	HarmCategoryHateSpeech       HarmCategory = "hate_speech"
+	HarmCategorySexuallyExplicit HarmCategory = "sexually_explicit"
+	HarmCategoryDangerousContent HarmCategory = "dangerous_content"
+)
+
+// SafetyThreshold sets from which likelihood of harm content gets blocked
+type SafetyThreshold string
+
+const (
+	SafetyBlockNone           SafetyThreshold = "block_none"
+	SafetyBlockLowAndAbove    SafetyThreshold = "block_low_and_above"
+	SafetyBlockMediumAndAbove SafetyThreshold = "block_medium_and_above"
+	SafetyBlockOnlyHigh       SafetyThreshold = "block_only_high"
+)
+
+// SafetySetting configures the blocking threshold of a single harm category
+type SafetySetting struct {
+	Category  HarmCategory
+	Threshold SafetyThreshold
+}
+
+// WithSafetySettings sets per-category safety thresholds for providers that support them (e.g. Gemini).
+// Later settings for the same category replace earlier ones.
+func WithSafetySettings(settings ...SafetySetting) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		merged := make([]SafetySetting, 0, len(r.SafetySettings)+len(settings))
+		for _, existing := range r.SafetySettings {
+			if !hasSafetyCategory(settings, existing.Category) {
+				merged = append(merged, existing)
+			}
+		}
+
+		r.SafetySettings = append(merged, settings...)
+	}
+}
+
+func hasSafetyCategory(settings []SafetySetting, category HarmCategory) bool {
+	for _, setting := range settings {
+		if setting.Category == category {
+			return true
+		}
+	}
+	return false
+}
+
+// SafetyBlock describes why a provider withheld the response for safety reasons
+type SafetyBlock struct {
+	// Reason is the provider's own reason, e.g. "content_filter" or "SAFETY", or SafetyBlockRefusal
+	Reason string
+
+	// Categories lists the harm categories that triggered the block, when the provider reports them
+	Categories []HarmCategory
+
+	// Message is the model's explanation for a refusal, empty for blocks by the provider's filters
+	Message string
+}
+
+// SafetyBlockRefusal is the reason of a block where the model itself declined to answer
+const SafetyBlockRefusal = "refusal"
@@ -0,0 +1,254 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StreamingLLM represents a language model that can stream its response as it is generated
+type StreamingLLM interface {
+	LLM
+
+	// InvokeStream starts a streamed completion. The channel is closed after a done or error
+	// event, or when the context is cancelled.
+	InvokeStream(ctx context.Context, request *LLMRequest) (<-chan StreamEvent, error)
+}
+
+// StreamEventType represents the type of a stream event
+type StreamEventType string
+
+const (
+	// StreamEventTextDelta carries a fragment of the assistant's text
+	StreamEventTextDelta StreamEventType = "text_delta"
+
+	// StreamEventToolCallDelta carries a fragment of a tool call
+	StreamEventToolCallDelta StreamEventType = "tool_call_delta"
+
+	// StreamEventToolCall carries a complete tool call once all of its fragments have arrived and
+	// its arguments parse as JSON. StreamAccumulator assembles calls from the deltas and ignores it.
+	StreamEventToolCall StreamEventType = "tool_call"
+
+	// StreamEventDone marks the successful end of the stream
+	StreamEventDone StreamEventType = "done"
+
+	// StreamEventError terminates the stream with an error
+	StreamEventError StreamEventType = "error"
+
+	// StreamEventRestart tells the consumer to discard everything received so far,
+	// the completion starts over (see ReconnectingLLM)
+	StreamEventRestart StreamEventType = "restart"
+)
+
+// StreamEvent represents an incremental piece of a streamed response
+type StreamEvent struct {
+	Type          StreamEventType
+	Text          string
+	ToolCallDelta *ToolCallDelta
+	ToolCall      *ToolCall
+	FinishReason  string
+	Usage         *Usage
+	Err           error
+}
+
+// ToolCallDelta is a fragment of a tool call. Fragments of the same call share the Index;
+// ID and Name are set on the first fragment, and the argument fragments concatenate into the full JSON.
+type ToolCallDelta struct {
+	Index     int
+	ID        string
+	Name      string
+	ArgsDelta string
+}
+
+// StreamingTool is implemented by tools that can start working on their arguments
+// before the model has finished generating them
+type StreamingTool interface {
+	Tool
+
+	// RunStream receives argument fragments as they are generated, argStream is closed
+	// once the arguments are complete
+	RunStream(ctx context.Context, argStream <-chan json.RawMessage) (json.RawMessage, error)
+}
+
+// StreamAccumulator merges stream events into a final response, for callers that consume
+// the stream themselves but still want the assembled result
+type StreamAccumulator struct {
+	text         strings.Builder
+	toolCalls    map[int]*toolCallBuilder
+	usage        *Usage
+	finishReason string
+}
+
+type toolCallBuilder struct {
+	id   string
+	name string
+	args strings.Builder
+}
+
+// NewStreamAccumulator creates an empty accumulator
+func NewStreamAccumulator() *StreamAccumulator {
+	return &StreamAccumulator{toolCalls: make(map[int]*toolCallBuilder)}
+}
+
+// Add merges the event into the response. A restart event discards everything added so far.
+func (s *StreamAccumulator) Add(event StreamEvent) {
+	if event.Usage != nil {
+		usage := *event.Usage
+		s.usage = &usage
+	}
+
+	switch event.Type {
+	case StreamEventTextDelta:
+		s.text.WriteString(event.Text)
+
+	case StreamEventToolCallDelta:
+		delta := event.ToolCallDelta
+		if delta == nil {
+			return
+		}
+
+		builder, ok := s.toolCalls[delta.Index]
+		if !ok {
+			builder = &toolCallBuilder{}
+			s.toolCalls[delta.Index] = builder
+		}
+		if delta.ID != "" {
+			builder.id = delta.ID
+		}
+		if delta.Name != "" {
+			builder.name = delta.Name
+		}
+		builder.args.WriteString(delta.ArgsDelta)
+
+	case StreamEventDone:
+		s.finishReason = event.FinishReason
+
+	case StreamEventRestart:
+		*s = *NewStreamAccumulator()
+	}
+}
+
+// Result returns the response assembled from the events added so far
+func (s *StreamAccumulator) Result() *LLMResponse {
+	response := NewLLMResponse()
+	response.FinishReason = s.finishReason
+	response.Usage = s.usage
+
+	if s.text.Len() > 0 {
+		response.AddMessage(&AssistantMessage{Content: s.text.String()})
+	}
+
+	for _, index := range s.toolCallIndexes() {
+		builder := s.toolCalls[index]
+		response.AddToolCall(&ToolCall{
+			ID:   builder.id,
+			Name: builder.name,
+			Args: json.RawMessage(builder.args.String()),
+		})
+	}
+
+	return response
+}
+
+// toolCallIndexes returns the indexes of the assembled tool calls in order
+func (s *StreamAccumulator) toolCallIndexes() []int {
+	indexes := make([]int, 0, len(s.toolCalls))
+	for index := range s.toolCalls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	return indexes
+}
+
+// CollectStreamOpts represents options for collecting a stream
+type CollectStreamOpts = func(*collectStreamConfig)
+
+type collectStreamConfig struct {
+	idleTimeout time.Duration
+}
+
+// WithIdleTimeout cuts the stream off when no event arrives for the given duration. The content
+// received so far is returned as a response marked Truncated instead of an error.
+func WithIdleTimeout(timeout time.Duration) CollectStreamOpts {
+	return func(c *collectStreamConfig) {
+		c.idleTimeout = timeout
+	}
+}
+
+// CollectStream invokes the model in streaming mode and assembles the events into a single response
+func CollectStream(ctx context.Context, model StreamingLLM, request *LLMRequest, opts ...CollectStreamOpts) (*LLMResponse, error) {
+	config := &collectStreamConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	// Cancelling the stream context closes the stream when it is cut off
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := model.InvokeStream(streamCtx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var idle <-chan time.Time
+	var timer *time.Timer
+	if config.idleTimeout > 0 {
+		timer = time.NewTimer(config.idleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+
+	accumulator := NewStreamAccumulator()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil, errors.New("stream ended without completing")
+			}
+
+			switch event.Type {
+			case StreamEventError:
+				return nil, fmt.Errorf("stream failed: %w", event.Err)
+			case StreamEventDone:
+				accumulator.Add(event)
+				return accumulator.Result(), nil
+			}
+
+			accumulator.Add(event)
+			if timer != nil {
+				timer.Reset(config.idleTimeout)
+			}
+
+		case <-idle:
+			slog.Warn("Stream stalled, returning partial response", "idle_timeout", config.idleTimeout)
+			return accumulator.partialResponse(), nil
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// partialResponse returns what was assembled so far, dropping tool calls whose arguments are incomplete
+func (s *StreamAccumulator) partialResponse() *LLMResponse {
+	assembled := s.Result()
+
+	response := NewLLMResponse()
+	response.Truncated = true
+	response.Usage = assembled.Usage
+	for _, msg := range assembled.Messages {
+		if call, ok := msg.(*ToolCallMessage); ok && !json.Valid(call.ToolCall.Args) {
+			continue
+		}
+		response.AddMessage(msg)
+	}
+
+	return response
+}
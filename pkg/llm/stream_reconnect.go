@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// ReconnectingLLM wraps a streaming LLM, reconnecting when a stream drops mid-response.
+// Providers cannot resume a completion, so a reconnect restarts it: a StreamEventRestart is
+// emitted and consumers must discard what they received before it. Reconnects are bounded
+// by a maximum number of attempts with exponential backoff between them.
+type ReconnectingLLM struct {
+	inner StreamingLLM
+
+	maxReconnects int
+	delay         time.Duration
+	backoff       float64
+	isTransient   func(error) bool
+}
+
+// ReconnectingLLMOpts represents options for configuring the reconnecting wrapper
+type ReconnectingLLMOpts = func(*ReconnectingLLM)
+
+// WithMaxReconnects sets how many times a single stream may reconnect
+func WithMaxReconnects(maxReconnects int) ReconnectingLLMOpts {
+	return func(r *ReconnectingLLM) {
+		r.maxReconnects = maxReconnects
+	}
+}
+
+// WithReconnectDelay sets the delay before the first reconnect
+func WithReconnectDelay(delay time.Duration) ReconnectingLLMOpts {
+	return func(r *ReconnectingLLM) {
+		r.delay = delay
+	}
+}
+
+// WithReconnectBackoff sets the exponential backoff multiplier between reconnects
+func WithReconnectBackoff(backoff float64) ReconnectingLLMOpts {
+	return func(r *ReconnectingLLM) {
+		r.backoff = backoff
+	}
+}
+
+// WithTransientStreamErrors sets which stream errors warrant a reconnect. By default these are
+// errors marked with RetryableError and streams closing without a done event.
+func WithTransientStreamErrors(isTransient func(error) bool) ReconnectingLLMOpts {
+	return func(r *ReconnectingLLM) {
+		r.isTransient = isTransient
+	}
+}
+
+// NewReconnectingLLM wraps the streaming LLM with automatic reconnects
+func NewReconnectingLLM(inner StreamingLLM, opts ...ReconnectingLLMOpts) *ReconnectingLLM {
+	r := &ReconnectingLLM{
+		inner:         inner,
+		maxReconnects: 3,                      // Default: 3 reconnects
+		delay:         200 * time.Millisecond, // Default: 200ms initial delay
+		backoff:       2.0,                    // Default: 2x backoff
+		isTransient:   isTransientStreamError,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func isTransientStreamError(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) || IsRetryable(err)
+}
+
+// Invoke delegates buffered calls to the wrapped LLM
+func (r *ReconnectingLLM) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	return r.inner.Invoke(ctx, request)
+}
+
+// Capabilities reports the capabilities of the wrapped LLM
+func (r *ReconnectingLLM) Capabilities() Capabilities {
+	return CapabilitiesOf(r.inner)
+}
+
+// InvokeStream streams the completion, restarting it when the stream drops with a transient error
+func (r *ReconnectingLLM) InvokeStream(ctx context.Context, request *LLMRequest) (<-chan StreamEvent, error) {
+	events, err := r.inner.InvokeStream(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamEvent)
+
+	go func() {
+		defer close(out)
+
+		send := func(event StreamEvent) bool {
+			select {
+			case out <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		delay := r.delay
+		for attempt := 0; ; attempt++ {
+			streamErr, forwarded := r.forward(ctx, events, send)
+			if streamErr == nil || ctx.Err() != nil {
+				return
+			}
+
+			if !r.isTransient(streamErr) {
+				send(StreamEvent{Type: StreamEventError, Err: streamErr})
+				return
+			}
+
+			if attempt == r.maxReconnects {
+				send(StreamEvent{Type: StreamEventError, Err: fmt.Errorf("stream failed after %d reconnects: %w", r.maxReconnects, streamErr)})
+				return
+			}
+
+			slog.Warn("Stream dropped, reconnecting",
+				"attempt", attempt+1,
+				"max_reconnects", r.maxReconnects,
+				"error", streamErr.Error(),
+			)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+				delay = time.Duration(float64(delay) * r.backoff)
+			}
+
+			events, err = r.inner.InvokeStream(ctx, request)
+			if err != nil {
+				send(StreamEvent{Type: StreamEventError, Err: fmt.Errorf("failed to reconnect stream: %w", err)})
+				return
+			}
+
+			if forwarded && !send(StreamEvent{Type: StreamEventRestart}) {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// forward relays the events of a single attempt until it completes, returning the error that
+// ended it (nil on success) and whether any event was relayed
+func (r *ReconnectingLLM) forward(ctx context.Context, events <-chan StreamEvent, send func(StreamEvent) bool) (error, bool) {
+	forwarded := false
+
+	for event := range events {
+		if event.Type == StreamEventError {
+			return event.Err, forwarded
+		}
+
+		if !send(event) {
+			return ctx.Err(), forwarded
+		}
+		forwarded = true
+
+		if event.Type == StreamEventDone {
+			return nil, forwarded
+		}
+	}
+
+	return io.ErrUnexpectedEOF, forwarded
+}
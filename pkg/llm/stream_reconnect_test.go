@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// droppingStreamLLM drops the connection mid-response for the first drops attempts
+type droppingStreamLLM struct {
+	drops    int
+	attempts int
+	dropErr  error
+}
+
+func (d *droppingStreamLLM) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	return nil, errors.New("buffered invoke should not be used")
+}
+
+func (d *droppingStreamLLM) InvokeStream(ctx context.Context, request *LLMRequest) (<-chan StreamEvent, error) {
+	d.attempts++
+	attempt := d.attempts
+	ch := make(chan StreamEvent)
+
+	go func() {
+		defer close(ch)
+
+		ch <- StreamEvent{Type: StreamEventTextDelta, Text: "Hello, "}
+		if attempt <= d.drops {
+			if d.dropErr != nil {
+				ch <- StreamEvent{Type: StreamEventError, Err: d.dropErr}
+			}
+			// Otherwise the connection just closes
+			return
+		}
+
+		ch <- StreamEvent{Type: StreamEventTextDelta, Text: "world"}
+		ch <- StreamEvent{Type: StreamEventDone, FinishReason: "stop"}
+	}()
+
+	return ch, nil
+}
+
+func TestReconnectingLLMRestartsDroppedStream(t *testing.T) {
+	inner := &droppingStreamLLM{drops: 1}
+	model := NewReconnectingLLM(inner, WithReconnectDelay(time.Millisecond))
+
+	events, err := model.InvokeStream(context.Background(), NewLLMRequest(NewHistory()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var types []string
+	for event := range events {
+		types = append(types, string(event.Type))
+	}
+
+	expected := "text_delta restart text_delta text_delta done"
+	if got := strings.Join(types, " "); got != expected {
+		t.Errorf("Expected events %q, got %q", expected, got)
+	}
+
+	// Consumers assembling the stream only keep the restarted completion
+	inner.attempts = 0
+	response, err := CollectStream(context.Background(), model, NewLLMRequest(NewHistory()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if content := response.Messages[0].(*AssistantMessage).Content; content != "Hello, world" {
+		t.Errorf("Expected the restarted completion only, got %q", content)
+	}
+}
+
+func TestReconnectingLLMGivesUpAfterMaxReconnects(t *testing.T) {
+	inner := &droppingStreamLLM{drops: 10, dropErr: NewRetryableError(errors.New("connection reset"))}
+	model := NewReconnectingLLM(inner, WithMaxReconnects(2), WithReconnectDelay(time.Millisecond))
+
+	_, err := CollectStream(context.Background(), model, NewLLMRequest(NewHistory()))
+	if err == nil || !strings.Contains(err.Error(), "after 2 reconnects") {
+		t.Errorf("Expected the stream to fail after 2 reconnects, got %v", err)
+	}
+
+	if inner.attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", inner.attempts)
+	}
+}
+
+func TestReconnectingLLMDoesNotReconnectPermanentErrors(t *testing.T) {
+	inner := &droppingStreamLLM{drops: 10, dropErr: errors.New("invalid request")}
+	model := NewReconnectingLLM(inner, WithReconnectDelay(time.Millisecond))
+
+	if _, err := CollectStream(context.Background(), model, NewLLMRequest(NewHistory())); err == nil {
+		t.Error("Expected error")
+	}
+
+	if inner.attempts != 1 {
+		t.Errorf("Expected no reconnect for a permanent error, got %d attempts", inner.attempts)
+	}
+}
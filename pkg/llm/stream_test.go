@@ -0,0 +1,156 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// scriptedStreamLLM streams the given events, then stalls until the stream context is cancelled
+type scriptedStreamLLM struct {
+	events    []StreamEvent
+	cancelled chan struct{}
+}
+
+func (s *scriptedStreamLLM) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	return nil, errors.New("buffered invoke should not be used")
+}
+
+func (s *scriptedStreamLLM) InvokeStream(ctx context.Context, request *LLMRequest) (<-chan StreamEvent, error) {
+	ch := make(chan StreamEvent)
+
+	go func() {
+		defer close(ch)
+		for _, event := range s.events {
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		<-ctx.Done()
+		if s.cancelled != nil {
+			close(s.cancelled)
+		}
+	}()
+
+	return ch, nil
+}
+
+func TestCollectStream(t *testing.T) {
+	model := &scriptedStreamLLM{events: []StreamEvent{
+		{Type: StreamEventTextDelta, Text: "Hello, "},
+		{Type: StreamEventTextDelta, Text: "world"},
+		{Type: StreamEventDone, FinishReason: "stop"},
+	}}
+
+	response, err := CollectStream(context.Background(), model, NewLLMRequest(NewHistory()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.Truncated {
+		t.Error("Expected complete response")
+	}
+
+	if content := response.Messages[0].(*AssistantMessage).Content; content != "Hello, world" {
+		t.Errorf("Expected assembled text, got %q", content)
+	}
+}
+
+func TestCollectStreamIdleTimeoutReturnsPartialResponse(t *testing.T) {
+	model := &scriptedStreamLLM{
+		cancelled: make(chan struct{}),
+		events: []StreamEvent{
+			{Type: StreamEventTextDelta, Text: "The answer is"},
+			{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ID: "call_1", Name: "search", ArgsDelta: `{"query": "ans`}},
+		},
+	}
+
+	response, err := CollectStream(context.Background(), model, NewLLMRequest(NewHistory()), WithIdleTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Expected partial response, got error %v", err)
+	}
+
+	if !response.Truncated {
+		t.Error("Expected response to be marked truncated")
+	}
+
+	if len(response.Messages) != 1 {
+		t.Fatalf("Expected only the text, incomplete tool call dropped, got %d messages", len(response.Messages))
+	}
+
+	if content := response.Messages[0].(*AssistantMessage).Content; content != "The answer is" {
+		t.Errorf("Expected partial text, got %q", content)
+	}
+
+	// The stalled stream is closed
+	select {
+	case <-model.cancelled:
+	case <-time.After(time.Second):
+		t.Error("Expected the stream context to be cancelled")
+	}
+}
+
+func TestCollectStreamError(t *testing.T) {
+	model := &scriptedStreamLLM{events: []StreamEvent{
+		{Type: StreamEventTextDelta, Text: "partial"},
+		{Type: StreamEventError, Err: errors.New("connection reset")},
+	}}
+
+	if _, err := CollectStream(context.Background(), model, NewLLMRequest(NewHistory())); err == nil {
+		t.Error("Expected stream error to be returned")
+	}
+}
+
+func TestStreamAccumulator(t *testing.T) {
+	accumulator := NewStreamAccumulator()
+	for _, event := range []StreamEvent{
+		{Type: StreamEventTextDelta, Text: "Let me "},
+		{Type: StreamEventTextDelta, Text: "check."},
+		{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 1, ID: "call_2", Name: "weather", ArgsDelta: `{"city":`}},
+		{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 0, ID: "call_1", Name: "time", ArgsDelta: `{}`}},
+		{Type: StreamEventToolCallDelta, ToolCallDelta: &ToolCallDelta{Index: 1, ArgsDelta: `"Prague"}`}},
+		{Type: StreamEventDone, FinishReason: "tool_calls", Usage: &Usage{PromptTokens: 12, CompletionTokens: 8, TotalTokens: 20}},
+	} {
+		accumulator.Add(event)
+	}
+
+	response := accumulator.Result()
+
+	if content := response.Messages[0].(*AssistantMessage).Content; content != "Let me check." {
+		t.Errorf("Expected assembled text, got %q", content)
+	}
+
+	toolCalls := response.ToolCalls()
+	if len(toolCalls) != 2 {
+		t.Fatalf("Expected 2 tool calls, got %d", len(toolCalls))
+	}
+	if toolCalls[0].Name != "time" || toolCalls[1].Name != "weather" {
+		t.Errorf("Expected tool calls in index order, got %s and %s", toolCalls[0].Name, toolCalls[1].Name)
+	}
+	if toolCalls[1].ID != "call_2" || string(toolCalls[1].Args) != `{"city":"Prague"}` {
+		t.Errorf("Expected assembled weather call, got %s %s", toolCalls[1].ID, toolCalls[1].Args)
+	}
+
+	if response.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %q", response.FinishReason)
+	}
+
+	if response.Usage == nil || response.Usage.TotalTokens != 20 {
+		t.Errorf("Expected usage of 20 tokens, got %+v", response.Usage)
+	}
+}
+
+func TestStreamAccumulatorRestart(t *testing.T) {
+	accumulator := NewStreamAccumulator()
+	accumulator.Add(StreamEvent{Type: StreamEventTextDelta, Text: "Abandoned"})
+	accumulator.Add(StreamEvent{Type: StreamEventRestart})
+	accumulator.Add(StreamEvent{Type: StreamEventTextDelta, Text: "Fresh"})
+
+	if content := accumulator.Result().Messages[0].(*AssistantMessage).Content; content != "Fresh" {
+		t.Errorf("Expected only text after the restart, got %q", content)
+	}
+}
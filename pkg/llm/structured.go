@@ -2,8 +2,10 @@ package llm
 
 import (
 	"context"
+	_ "embed"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 )
 
 // LLMWithStructuredOutput implements the LLM interface to provide structured output formatting
@@ -21,6 +23,8 @@ type BaseLLMWithStructuredOutput struct {
 	description string
 	inputSchema json.RawMessage
 	llm         LLM // The underlying LLM to delegate to
+
+	formatRetries int
 }
 
 // LLMWithStructuredOutputOpts represents options for configuring an LLM with structured output
@@ -40,6 +44,14 @@ func WithDescription(description string) LLMWithStructuredOutputOpts {
 	}
 }
 
+// WithFormatRetries re-invokes the underlying LLM up to n times when its output fails schema validation,
+// telling it which fields were wrong
+func WithFormatRetries(n int) LLMWithStructuredOutputOpts {
+	return func(f *BaseLLMWithStructuredOutput) {
+		f.formatRetries = n
+	}
+}
+
 // NewBaseLLMWithStructuredOutput creates a new base LLM with structured output
 // Uses sensible defaults: name="formatter", description="Must be called to provide structured output"
 func NewBaseLLMWithStructuredOutput(inputSchema json.RawMessage, llm LLM, opts ...LLMWithStructuredOutputOpts) *BaseLLMWithStructuredOutput {
@@ -77,9 +89,17 @@ func (f *BaseLLMWithStructuredOutput) InputSchemaRaw() json.RawMessage {
 	return f.inputSchema
 }
 
-// ValidateInput validates the input against the schema
+// ValidateInput validates the input against the schema, returning a SchemaValidationError listing each violation
 func (f *BaseLLMWithStructuredOutput) ValidateInput(input json.RawMessage) error {
-	// For now, just return nil - could add JSON schema validation here
+	fieldErrors, err := ValidateSchema(f.inputSchema, input)
+	if err != nil {
+		return err
+	}
+
+	if len(fieldErrors) > 0 {
+		return &SchemaValidationError{Tool: f.name, Errors: fieldErrors}
+	}
+
 	return nil
 }
 
@@ -94,6 +114,9 @@ func (f *BaseLLMWithStructuredOutput) Run(ctx context.Context, args json.RawMess
 	return args, nil
 }
 
+//go:embed prompts/format_correction.txt
+var formatCorrectionPromptFormat string
+
 // Invoke implements the LLM interface
 // It ignores tool call directives and forces the use of this LLM with structured output
 func (f *BaseLLMWithStructuredOutput) Invoke(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
@@ -101,26 +124,58 @@ func (f *BaseLLMWithStructuredOutput) Invoke(ctx context.Context, request *LLMRe
 		return nil, fmt.Errorf("no underlying LLM configured")
 	}
 
-	// Create a new request that forces the use of this LLM with structured output
-	// We ignore any existing tool usage and tool configurations
-	forcedRequest := NewLLMRequest(
-		request.History,
-		WithTools(f),                       // Only include this LLM with structured output as a tool
-		WithToolUsage(ForceTool(f.Name())), // Force the use of this LLM with structured output
-	)
+	history := request.History
+	var usage *Usage
 
-	// Delegate to the underlying LLM
-	response, err := f.llm.Invoke(ctx, forcedRequest)
-	if err != nil {
-		return nil, fmt.Errorf("underlying LLM invocation failed: %w", err)
-	}
+	for attempt := 0; ; attempt++ {
+		// A correction is another call, it must not be deduplicated as a repeat of the first
+		idempotencyKey := request.IdempotencyKey
+		if idempotencyKey != "" && attempt > 0 {
+			idempotencyKey = fmt.Sprintf("%s-%d", idempotencyKey, attempt)
+		}
+
+		// Create a new request that forces the use of this LLM with structured output
+		// We ignore any existing tool usage and tool configurations
+		forcedRequest := NewLLMRequest(
+			history,
+			WithTools(f),                       // Only include this LLM with structured output as a tool
+			WithToolUsage(ForceTool(f.Name())), // Force the use of this LLM with structured output
+			WithToolResultDelivery(request.ToolResultDelivery),
+			WithRequestID(request.RequestID),
+			WithIdempotencyKey(idempotencyKey),
+		)
+
+		// Delegate to the underlying LLM
+		response, err := f.llm.Invoke(ctx, forcedRequest)
+		if err != nil {
+			return nil, fmt.Errorf("underlying LLM invocation failed: %w", err)
+		}
+		usage = addUsage(usage, response.Usage)
+
+		toolCalls := response.ToolCalls()
+		if len(toolCalls) == 0 {
+			return nil, fmt.Errorf("no tool call found in response - LLM did not follow forced tool usage")
+		}
 
-	// Execute the LLM with structured output tool with the tool call arguments
-	toolCalls := response.ToolCalls()
-	if len(toolCalls) > 0 {
+		// Execute the LLM with structured output tool with the tool call arguments
 		toolCall := toolCalls[0]
 		result, err := f.Run(ctx, toolCall.Args)
 		if err != nil {
+			if attempt < f.formatRetries {
+				slog.Info("Structured output failed validation, asking LLM to correct it",
+					"request_id", request.RequestID,
+					"format", f.Name(),
+					"attempt", attempt+1,
+					"error", err.Error(),
+				)
+
+				history = append(append(History{}, history...),
+					NewToolCallMessage(toolCall),
+					NewToolResultErrorMessage(toolCall, fmt.Sprintf(formatCorrectionPromptFormat, err.Error(), f.Name())),
+				)
+				continue
+			}
+
 			return nil, fmt.Errorf("LLM with structured output tool execution failed: %w", err)
 		}
 
@@ -136,8 +191,24 @@ func (f *BaseLLMWithStructuredOutput) Invoke(ctx context.Context, request *LLMRe
 					Content: string(content),
 				},
 			},
+			Usage: usage,
 		}, nil
 	}
+}
+
+// addUsage returns the sum of two usages, either of which may be nil
+func addUsage(total, usage *Usage) *Usage {
+	if usage == nil {
+		return total
+	}
+
+	sum := Usage{}
+	if total != nil {
+		sum = *total
+	}
+	sum.PromptTokens += usage.PromptTokens
+	sum.CompletionTokens += usage.CompletionTokens
+	sum.TotalTokens += usage.TotalTokens
 
-	return nil, fmt.Errorf("no tool call found in response - LLM did not follow forced tool usage")
+	return &sum
 }
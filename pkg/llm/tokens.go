@@ -0,0 +1,79 @@
+package llm
+
+import "strings"
+
+// TokenCounter counts tokens the way a model's tokenizer does, e.g. to trim the history or estimate
+// the prompt size before calling the API
+type TokenCounter interface {
+	// Count returns the tokens of the text
+	Count(text string) int
+
+	// CountMessages returns the tokens the messages take up in the context, including the
+	// per-message overhead of the provider's format
+	CountMessages(messages []Message) int
+}
+
+// ApproximateTokenCounter estimates tokens at four characters per token, a reasonable
+// approximation for English text when the model's tokenizer is not available
+type ApproximateTokenCounter struct{}
+
+// approximateMessageOverhead covers the role and delimiters wrapping each message
+const approximateMessageOverhead = 4
+
+func (ApproximateTokenCounter) Count(text string) int {
+	return (len(text) + 3) / 4
+}
+
+func (c ApproximateTokenCounter) CountMessages(messages []Message) int {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += c.Count(messageText(msg)) + approximateMessageOverhead
+	}
+
+	return tokens
+}
+
+// EstimatePromptTokens estimates the prompt size of the request: its system prompt, examples,
+// history and tool definitions
+func EstimatePromptTokens(counter TokenCounter, request *LLMRequest) int {
+	tokens := counter.Count(request.System)
+
+	for _, example := range request.Examples {
+		tokens += counter.CountMessages(example)
+	}
+	tokens += counter.CountMessages(request.History)
+
+	for _, tool := range request.Tools {
+		tokens += counter.Count(tool.Name() + " " + tool.Description() + " " + string(tool.InputSchemaRaw()))
+	}
+
+	return tokens
+}
+
+// messageText returns the text of a message as the model sees it
+func messageText(msg Message) string {
+	switch m := msg.(type) {
+	case *UserMessage:
+		return m.Content
+	case *AssistantMessage:
+		text := []string{m.Content}
+		for _, toolCall := range m.ToolCalls {
+			text = append(text, toolCall.Name, string(toolCall.Args))
+		}
+		return strings.Join(text, " ")
+	case *SystemMessage:
+		return m.Content
+	case *DeveloperMessage:
+		return m.Content
+	case *AudioMessage:
+		return m.Transcript
+	case *ToolCallMessage:
+		return strings.Join([]string{m.ToolCall.Name, string(m.ToolCall.Args)}, " ")
+	case *ToolResultMessage:
+		return string(m.Result)
+	case *ToolErrorMessage:
+		return m.Error
+	}
+
+	return ""
+}
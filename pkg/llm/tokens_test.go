@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApproximateTokenCounter(t *testing.T) {
+	counter := ApproximateTokenCounter{}
+
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"", 0},
+		{"Hi", 1},
+		{"Find me a flight", 4},
+	}
+
+	for _, tt := range tests {
+		if got := counter.Count(tt.text); got != tt.expected {
+			t.Errorf("Expected %d tokens for %q, got %d", tt.expected, tt.text, got)
+		}
+	}
+
+	call := &ToolCall{ID: "call_1", Name: "search", Args: json.RawMessage(`{"q":"x"}`)}
+	messages := []Message{NewUserMessage("Find me a flight"), NewToolCallMessage(call)}
+	if got := counter.CountMessages(messages); got != 4+4+counter.Count(`search {"q":"x"}`)+4 {
+		t.Errorf("Expected content plus per-message overhead, got %d", got)
+	}
+}
+
+func TestEstimatePromptTokens(t *testing.T) {
+	counter := ApproximateTokenCounter{}
+	history := NewHistory(NewUserMessage("Find me a flight"))
+
+	bare := EstimatePromptTokens(counter, NewLLMRequest(history))
+	if bare != counter.CountMessages(history) {
+		t.Errorf("Expected the history tokens, got %d", bare)
+	}
+
+	tool := &mockTool{name: "search"}
+	full := EstimatePromptTokens(counter, NewLLMRequest(history, WithSystem("You are a travel agent"), WithTools(tool)))
+	if full <= bare {
+		t.Errorf("Expected the system prompt and tools to add tokens, got %d and %d", bare, full)
+	}
+}
@@ -1,24 +1,77 @@
 package llm
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+
+	"github.com/petrjanda/frax/pkg/schemas"
 )
 
-type Toolbox = []Tool
+// Toolbox is a set of tools with unique names, indexed by name for lookup
+type Toolbox struct {
+	tools []Tool
+	index map[string]Tool
+}
+
+// NewToolbox creates a toolbox of the given tools, failing when two of them share a name
+func NewToolbox(tools ...Tool) (*Toolbox, error) {
+	toolbox := &Toolbox{
+		tools: slices.Clone(tools),
+		index: make(map[string]Tool, len(tools)),
+	}
 
-func FindTool(name string, tools Toolbox) (Tool, error) {
 	for _, tool := range tools {
-		if tool.Name() == name {
-			return tool, nil
+		if _, ok := toolbox.index[tool.Name()]; ok {
+			return nil, fmt.Errorf("duplicate tool name %s", tool.Name())
 		}
+		toolbox.index[tool.Name()] = tool
+	}
+
+	return toolbox, nil
+}
+
+// MustNewToolbox is like NewToolbox but panics on duplicate tool names
+func MustNewToolbox(tools ...Tool) *Toolbox {
+	toolbox, err := NewToolbox(tools...)
+	if err != nil {
+		panic(err)
 	}
-	return nil, fmt.Errorf("tool not found: %s", name)
+
+	return toolbox
+}
+
+// Tools returns the tools in the order they were given, e.g. to pass them to NewAgent
+func (t *Toolbox) Tools() []Tool {
+	return slices.Clone(t.tools)
+}
+
+// Names returns the names of the tools in the order they were given
+func (t *Toolbox) Names() []string {
+	names := make([]string, 0, len(t.tools))
+	for _, tool := range t.tools {
+		names = append(names, tool.Name())
+	}
+
+	return names
+}
+
+// Get returns the tool with the given name, reporting false when there is none
+func (t *Toolbox) Get(name string) (Tool, bool) {
+	tool, ok := t.index[name]
+	return tool, ok
 }
 
-func NewToolbox(tools ...Tool) Toolbox {
-	return tools
+// FindTool returns the tool with the given name, a ToolNotFoundError when there is none
+func FindTool(name string, tools []Tool) (Tool, error) {
+	for _, tool := range tools {
+		if tool.Name() == name {
+			return tool, nil
+		}
+	}
+	return nil, &ToolNotFoundError{Tool: name}
 }
 
 // Tool represents a tool that can be called by the agent
@@ -32,6 +85,44 @@ type Tool interface {
 	// InputSchemaRaw returns the JSON schema for the tool's input
 	InputSchemaRaw() json.RawMessage
 
-	// Run executes the tool with the given arguments
+	// Run executes the tool with the given arguments. Args are generated by the model, so
+	// credentials and other request-scoped data must be read from ctx (see ToolContextFrom) instead.
 	Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error)
 }
+
+// ToolWithSchemaGenerator is implemented by tools generating their input schema from a Go type, so
+// that adapters can ask for the schema in the dialect their provider prefers
+type ToolWithSchemaGenerator interface {
+	Tool
+
+	// InputSchemaWith returns the input schema generated by the given generator
+	InputSchemaWith(generator schemas.SchemaGenerator) json.RawMessage
+}
+
+// InputSchemaFor returns the tool's input schema generated by the generator when the tool supports it,
+// its InputSchemaRaw otherwise
+func InputSchemaFor(tool Tool, generator schemas.SchemaGenerator) json.RawMessage {
+	if t, ok := tool.(ToolWithSchemaGenerator); ok {
+		return t.InputSchemaWith(generator)
+	}
+
+	return tool.InputSchemaRaw()
+}
+
+// ToolWithOutputSchema is implemented by tools that can describe the JSON they return
+type ToolWithOutputSchema interface {
+	Tool
+
+	// OutputSchemaRaw returns the JSON schema for the tool's output, nil when unknown
+	OutputSchemaRaw() json.RawMessage
+}
+
+// normalizeArgs replaces blank arguments, which providers may send when calling a tool without
+// parameters, with an empty object so they decode into the tool's input
+func normalizeArgs(args json.RawMessage) json.RawMessage {
+	if len(bytes.TrimSpace(args)) == 0 {
+		return json.RawMessage("{}")
+	}
+
+	return args
+}
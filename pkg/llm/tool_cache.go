@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Cache stores tool results by key, see WithToolCache
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// CacheableTool is implemented by tools whose result depends only on their arguments, e.g. a lookup.
+// Only tools reporting Cacheable are cached, so tools with side effects such as bookings never are.
+type CacheableTool interface {
+	Tool
+
+	// Cacheable reports whether the tool's results may be served from the cache
+	Cacheable() bool
+}
+
+// WithToolCache serves repeated calls of cacheable tools (see CacheableTool) from the cache instead of
+// running them again. Results are keyed on the tool name and the canonicalized arguments, and only
+// successful results are stored.
+func WithToolCache(cache Cache) AgentOpts {
+	return func(a *Agent) {
+		a.toolCache = cache
+	}
+}
+
+// cachedToolCall runs the tool call unless its result is already in the cache
+func (a *Agent) cachedToolCall(ctx context.Context, toolCall *ToolCall, targetTool Tool) (Message, error) {
+	key, ok := a.toolCacheKey(toolCall, targetTool)
+	if !ok {
+		return a.executeToolWithRetry(ctx, toolCall, targetTool)
+	}
+
+	if result, hit := a.toolCache.Get(key); hit {
+		a.logger().Debug("Tool result served from cache", "tool", toolCall.Name)
+		return &ToolResultMessage{ToolCall: toolCall, Result: json.RawMessage(result)}, nil
+	}
+
+	msg, err := a.executeToolWithRetry(ctx, toolCall, targetTool)
+	if err != nil {
+		return nil, err
+	}
+
+	// A corrected retry ran with different arguments, so its result is stored under those
+	if result, ok := msg.(*ToolResultMessage); ok {
+		if key, ok := a.toolCacheKey(result.ToolCall, targetTool); ok {
+			a.toolCache.Set(key, result.Result)
+		}
+	}
+
+	return msg, nil
+}
+
+// toolCacheKey returns the cache key of the tool call, false when it must not be cached
+func (a *Agent) toolCacheKey(toolCall *ToolCall, targetTool Tool) (string, bool) {
+	if a.toolCache == nil || toolCall == nil {
+		return "", false
+	}
+
+	cacheable, ok := targetTool.(CacheableTool)
+	if !ok || !cacheable.Cacheable() {
+		return "", false
+	}
+
+	args, err := canonicalJSON(normalizeArgs(toolCall.Args))
+	if err != nil {
+		return "", false
+	}
+
+	return toolCall.Name + "\x00" + string(args), true
+}
+
+// canonicalJSON re-encodes the JSON with sorted object keys and no insignificant whitespace,
+// keeping numbers as written. Blank arguments are an empty object, as they are when the tool runs.
+func canonicalJSON(data json.RawMessage) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []byte("{}"), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
+
+// LRUCache is an in-memory Cache holding up to a fixed number of entries, evicting the least
+// recently used one when full. It is safe for concurrent use.
+type LRUCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// NewLRUCache creates an LRU cache holding up to capacity entries
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: max(capacity, 1),
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value stored under key, marking it as recently used
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+// Set stores a copy of value under key, evicting the least recently used entry when full
+func (c *LRUCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value = bytes.Clone(value)
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lruEntry).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of entries in the cache
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// lookupTool counts its runs and may opt into caching
+type lookupTool struct {
+	cacheable bool
+	runs      int
+}
+
+func (l *lookupTool) Name() string                    { return "lookup" }
+func (l *lookupTool) Description() string             { return "Lookup tool for testing" }
+func (l *lookupTool) InputSchemaRaw() json.RawMessage { return json.RawMessage(`{"type": "object"}`) }
+func (l *lookupTool) Cacheable() bool                 { return l.cacheable }
+func (l *lookupTool) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	l.runs++
+	return json.RawMessage(fmt.Sprintf(`{"run": %d}`, l.runs)), nil
+}
+
+func TestToolCacheServesRepeatedCalls(t *testing.T) {
+	ctx := context.Background()
+	tool := &lookupTool{cacheable: true}
+	agent := NewAgent(&mockLLM{}, []Tool{tool}, WithToolCache(NewLRUCache(10))).(*Agent)
+
+	first, err := agent.CallTool(ctx, &ToolCall{ID: "call_1", Name: "lookup", Args: json.RawMessage(`{"a": 1, "b": 2}`)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Same arguments in a different order and layout hit the cache
+	second, err := agent.CallTool(ctx, &ToolCall{ID: "call_2", Name: "lookup", Args: json.RawMessage(`{"b":2,"a":1}`)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if tool.runs != 1 {
+		t.Errorf("Expected the tool to run once, got %d runs", tool.runs)
+	}
+
+	result := second.(*ToolResultMessage)
+	if string(result.Result) != string(first.(*ToolResultMessage).Result) {
+		t.Errorf("Expected cached result %s, got %s", first.(*ToolResultMessage).Result, result.Result)
+	}
+
+	if result.ToolCall.ID != "call_2" {
+		t.Errorf("Expected cached result to answer call_2, got %s", result.ToolCall.ID)
+	}
+
+	// Different arguments run the tool again
+	if _, err := agent.CallTool(ctx, &ToolCall{ID: "call_3", Name: "lookup", Args: json.RawMessage(`{"a": 2}`)}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if tool.runs != 2 {
+		t.Errorf("Expected the tool to run for new arguments, got %d runs", tool.runs)
+	}
+}
+
+func TestToolCacheSkipsNonCacheableTools(t *testing.T) {
+	ctx := context.Background()
+	tool := &lookupTool{}
+	agent := NewAgent(&mockLLM{}, []Tool{tool}, WithToolCache(NewLRUCache(10))).(*Agent)
+
+	for i := 0; i < 2; i++ {
+		if _, err := agent.CallTool(ctx, &ToolCall{ID: fmt.Sprint(i), Name: "lookup", Args: json.RawMessage(`{}`)}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if tool.runs != 2 {
+		t.Errorf("Expected a non-cacheable tool to run every time, got %d runs", tool.runs)
+	}
+}
+
+func TestToolCacheServesBlankArgs(t *testing.T) {
+	ctx := context.Background()
+	tool := &lookupTool{cacheable: true}
+	agent := NewAgent(&mockLLM{}, []Tool{tool}, WithToolCache(NewLRUCache(10))).(*Agent)
+
+	// Blank arguments are the same call as an empty object
+	for i, args := range []string{``, ` `, `{}`} {
+		if _, err := agent.CallTool(ctx, &ToolCall{ID: fmt.Sprint(i), Name: "lookup", Args: json.RawMessage(args)}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if tool.runs != 1 {
+		t.Errorf("Expected blank arguments to hit the cache, got %d runs", tool.runs)
+	}
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewLRUCache(2)
+
+	cache.Set("a", []byte("1"))
+	cache.Set("b", []byte("2"))
+	cache.Get("a")
+	cache.Set("c", []byte("3"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("Expected b to be evicted")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("Expected %s to be cached", key)
+		}
+	}
+
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.Len())
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{`{"b": 1, "a": {"d": 2, "c": 3}}`, `{"a":{"c":3,"d":2},"b":1}`},
+		{`{"n": 12345678901234567890}`, `{"n":12345678901234567890}`},
+		{``, `{}`},
+		{` `, `{}`},
+	}
+
+	for _, tt := range tests {
+		got, err := canonicalJSON(json.RawMessage(tt.args))
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", tt.args, err)
+		}
+		if string(got) != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, got)
+		}
+	}
+}
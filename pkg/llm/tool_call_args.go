@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ArgsMap returns the tool call arguments decoded into a map. The result is cached until Args
+// changes, so callers must treat the returned map as read-only.
+func (tc *ToolCall) ArgsMap() (map[string]any, error) {
+	if tc.argsMapOf != nil && bytes.Equal(tc.argsMapOf, tc.Args) {
+		return tc.argsMap, tc.argsMapErr
+	}
+
+	var args map[string]any
+	err := json.Unmarshal(tc.Args, &args)
+	if err != nil {
+		args, err = nil, fmt.Errorf("invalid arguments for tool %s: %w", tc.Name, err)
+	}
+
+	tc.argsMap, tc.argsMapErr = args, err
+	tc.argsMapOf = append(json.RawMessage{}, tc.Args...)
+
+	return args, err
+}
+
+// ToolCallArgs decodes the tool call arguments into T
+func ToolCallArgs[T any](tc *ToolCall) (T, error) {
+	var args T
+	if err := json.Unmarshal(tc.Args, &args); err != nil {
+		return args, fmt.Errorf("invalid arguments for tool %s: %w", tc.Name, err)
+	}
+
+	return args, nil
+}
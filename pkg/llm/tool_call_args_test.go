@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToolCallArgsMap(t *testing.T) {
+	tc := &ToolCall{Name: "search", Args: json.RawMessage(`{"query": "go", "limit": 5}`)}
+
+	args, err := tc.ArgsMap()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if args["query"] != "go" || args["limit"] != float64(5) {
+		t.Errorf("Unexpected arguments: %v", args)
+	}
+
+	// Changing the arguments invalidates the cached map
+	tc.Args = json.RawMessage(`{"query": "rust"}`)
+
+	args, err = tc.ArgsMap()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if args["query"] != "rust" || len(args) != 1 {
+		t.Errorf("Expected re-decoded arguments, got %v", args)
+	}
+}
+
+func TestToolCallArgsMapInvalidJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+	}{
+		{"malformed", `{"query": `},
+		{"not an object", `["go"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &ToolCall{Name: "search", Args: json.RawMessage(tt.args)}
+
+			if _, err := tc.ArgsMap(); err == nil {
+				t.Error("Expected error for invalid arguments")
+			}
+
+			// The error is cached along with the arguments
+			if _, err := tc.ArgsMap(); err == nil {
+				t.Error("Expected error on repeated call")
+			}
+		})
+	}
+}
+
+func TestToolCallArgsTyped(t *testing.T) {
+	type searchArgs struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+
+	args, err := ToolCallArgs[searchArgs](&ToolCall{Name: "search", Args: json.RawMessage(`{"query": "go", "limit": 5}`)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if args.Query != "go" || args.Limit != 5 {
+		t.Errorf("Unexpected arguments: %+v", args)
+	}
+
+	if _, err := ToolCallArgs[searchArgs](&ToolCall{Name: "search", Args: json.RawMessage(`{"limit": "five"}`)}); err == nil {
+		t.Error("Expected error for mistyped arguments")
+	}
+}
@@ -0,0 +1,20 @@
+package llm
+
+import "context"
+
+// toolContextKey is the context key of the data set by WithToolContext
+type toolContextKey struct{}
+
+// WithToolContext attaches request-scoped data for tools to the context, e.g. the authenticated
+// user, tenant or a database handle. The agent passes the context given to Invoke on to every
+// tool run, so such data never has to appear in the model-visible arguments.
+func WithToolContext(ctx context.Context, data any) context.Context {
+	return context.WithValue(ctx, toolContextKey{}, data)
+}
+
+// ToolContextFrom returns the data attached with WithToolContext, reporting false when there is
+// none or it is not of type T
+func ToolContextFrom[T any](ctx context.Context) (T, bool) {
+	data, ok := ctx.Value(toolContextKey{}).(T)
+	return data, ok
+}
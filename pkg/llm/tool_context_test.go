@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type tenantContext struct {
+	UserID string
+	Tenant string
+}
+
+type ordersInput struct {
+	Status string `json:"status"`
+}
+
+func TestToolContextFrom(t *testing.T) {
+	ctx := WithToolContext(context.Background(), tenantContext{UserID: "u1"})
+
+	if data, ok := ToolContextFrom[tenantContext](ctx); !ok || data.UserID != "u1" {
+		t.Errorf("Expected tool context data, got %+v (ok=%v)", data, ok)
+	}
+
+	if _, ok := ToolContextFrom[string](ctx); ok {
+		t.Error("Expected lookup with a different type to fail")
+	}
+
+	if _, ok := ToolContextFrom[tenantContext](context.Background()); ok {
+		t.Error("Expected lookup without data to fail")
+	}
+}
+
+func TestAgentPassesToolContextToTools(t *testing.T) {
+	var seen tenantContext
+	listOrders := NewGenericTool("list_orders", "Lists the user's orders",
+		func(ctx context.Context, input ordersInput) (json.RawMessage, error) {
+			data, ok := ToolContextFrom[tenantContext](ctx)
+			if !ok {
+				return nil, errors.New("no authenticated user")
+			}
+			seen = data
+			return json.RawMessage(`{"orders":[]}`), nil
+		})
+
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		if len(request.History) == 1 {
+			return toolCallResponse("call_1", "list_orders", `{"status":"open"}`), nil
+		}
+		return textResponse("You have no open orders."), nil
+	})
+
+	agent := NewAgent(model, []Tool{listOrders}, WithMaxRetries(0))
+
+	ctx := WithToolContext(context.Background(), tenantContext{UserID: "u42", Tenant: "acme"})
+	if _, err := agent.Invoke(ctx, NewLLMRequest(NewHistory(NewUserMessage("Show my open orders")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if seen.UserID != "u42" || seen.Tenant != "acme" {
+		t.Errorf("Expected the tool to read the caller's context, got %+v", seen)
+	}
+}
+
+type authTokenKey struct{}
+
+func TestAgentRequestContext(t *testing.T) {
+	var seen tenantContext
+	var hasDeadline bool
+	listOrders := NewGenericTool("list_orders", "Lists the user's orders",
+		func(ctx context.Context, input ordersInput) (json.RawMessage, error) {
+			seen, _ = ToolContextFrom[tenantContext](ctx)
+			_, hasDeadline = ctx.Deadline()
+			return json.RawMessage(`{"orders":[]}`), nil
+		})
+
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		if len(request.History) == 1 {
+			return toolCallResponse("call_1", "list_orders", `{"status":"open"}`), nil
+		}
+		return textResponse("You have no open orders."), nil
+	})
+
+	// The tool context is derived from the auth token the caller's middleware put on ctx
+	agent := NewAgent(model, []Tool{listOrders},
+		WithToolTimeout(time.Minute),
+		WithRequestContext(func(ctx context.Context) context.Context {
+			token, _ := ctx.Value(authTokenKey{}).(string)
+			return WithToolContext(ctx, tenantContext{UserID: "user-of-" + token, Tenant: "acme"})
+		}),
+	)
+
+	ctx := context.WithValue(context.Background(), authTokenKey{}, "t0k3n")
+	if _, err := agent.Invoke(ctx, NewLLMRequest(NewHistory(NewUserMessage("Show my open orders")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if seen.UserID != "user-of-t0k3n" || seen.Tenant != "acme" {
+		t.Errorf("Expected the derived tool context, got %+v", seen)
+	}
+	if !hasDeadline {
+		t.Error("Expected the tool timeout to apply to the derived context")
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"sync"
 
-	"github.com/petrjanda/frax/pkg/adapters/openai/schemas"
+	"github.com/petrjanda/frax/pkg/schemas"
 )
 
 // GenericTool is a generic tool implementation that handles JSON marshalling/unmarshalling
@@ -14,6 +16,20 @@ type GenericTool[I, O any] struct {
 	name        string
 	description string
 	runner      func(ctx context.Context, input I) (O, error)
+
+	// validate checks the arguments against the input schema, and the result against the output schema, in Run
+	validate bool
+
+	// generator produces the input schema; schemas are generated once on first use and cached
+	generator    schemas.SchemaGenerator
+	inputOnce    sync.Once
+	inputSchema  json.RawMessage
+	outputOnce   sync.Once
+	outputSchema json.RawMessage
+
+	// dialectSchemas caches the input schemas generated for adapters by InputSchemaWith
+	dialectMu      sync.Mutex
+	dialectSchemas map[schemas.SchemaGenerator]json.RawMessage
 }
 
 // NewGenericTool creates a new generic tool with the given name, description, and runner function
@@ -35,14 +51,88 @@ func (g *GenericTool[I, O]) Description() string {
 	return g.description
 }
 
-// InputSchemaRaw returns the JSON schema for the tool's input type I
+// InputSchemaRaw returns the JSON schema for the tool's input type I, generated on the first call
 func (g *GenericTool[I, O]) InputSchemaRaw() json.RawMessage {
-	generator := schemas.NewOpenAISchemaGenerator()
-	return generator.MustGenerateSchema((*I)(nil))
+	g.inputOnce.Do(func() {
+		generator := g.generator
+		if generator == nil {
+			generator = schemas.NewOpenAISchemaGenerator()
+		}
+
+		schema, err := generator.GenerateSchema((*I)(nil))
+		if err != nil {
+			panic(err)
+		}
+		g.inputSchema = schema
+	})
+	return g.inputSchema
+}
+
+// InputSchemaWith returns the input schema generated by the adapter's generator. A generator set with
+// WithSchemaGenerator takes precedence, and the default schema is used when the generator fails.
+func (g *GenericTool[I, O]) InputSchemaWith(generator schemas.SchemaGenerator) json.RawMessage {
+	if g.generator != nil || generator == nil {
+		return g.InputSchemaRaw()
+	}
+
+	// Generators that can't be map keys, e.g. func types, generate the schema on every call
+	if !reflect.ValueOf(generator).Comparable() {
+		schema, err := generator.GenerateSchema((*I)(nil))
+		if err != nil {
+			return g.InputSchemaRaw()
+		}
+		return schema
+	}
+
+	g.dialectMu.Lock()
+	defer g.dialectMu.Unlock()
+
+	if schema, ok := g.dialectSchemas[generator]; ok {
+		return schema
+	}
+
+	schema, err := generator.GenerateSchema((*I)(nil))
+	if err != nil {
+		return g.InputSchemaRaw()
+	}
+
+	if g.dialectSchemas == nil {
+		g.dialectSchemas = make(map[schemas.SchemaGenerator]json.RawMessage)
+	}
+	g.dialectSchemas[generator] = schema
+
+	return schema
 }
 
-// Run executes the tool with the given arguments, automatically handling JSON marshalling/unmarshalling
+// OutputSchemaRaw returns the JSON schema for the tool's output type O, nil unless O is a named struct
+func (g *GenericTool[I, O]) OutputSchemaRaw() json.RawMessage {
+	g.outputOnce.Do(func() {
+		t := reflect.TypeFor[O]()
+		if t.Kind() != reflect.Struct || t.Name() == "" {
+			return
+		}
+
+		schema, err := schemas.NewOpenAISchemaGenerator().GenerateSchema((*O)(nil))
+		if err != nil {
+			return
+		}
+		g.outputSchema = schema
+	})
+	return g.outputSchema
+}
+
+// Run executes the tool with the given arguments, automatically handling JSON marshalling/unmarshalling.
+// With validation enabled, arguments missing required fields or otherwise not matching the input schema
+// fail with a SchemaValidationError instead of running the tool on zero values.
 func (g *GenericTool[I, O]) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	args = normalizeArgs(args)
+
+	if g.validate {
+		if err := g.validateAgainst(g.InputSchemaRaw(), args); err != nil {
+			return nil, err
+		}
+	}
+
 	// Unmarshal the input arguments to type I
 	var input I
 	if err := json.Unmarshal(args, &input); err != nil {
@@ -61,14 +151,38 @@ func (g *GenericTool[I, O]) Run(ctx context.Context, args json.RawMessage) (json
 		return nil, fmt.Errorf("failed to marshal output: %w", err)
 	}
 
+	if g.validate {
+		if schema := g.OutputSchemaRaw(); schema != nil {
+			if err := g.validateAgainst(schema, result); err != nil {
+				return nil, fmt.Errorf("tool output does not match its schema: %w", err)
+			}
+		}
+	}
+
 	return json.RawMessage(result), nil
 }
 
+// validateAgainst validates the document against the schema, returning a SchemaValidationError on violations
+func (g *GenericTool[I, O]) validateAgainst(schema, document json.RawMessage) error {
+	fieldErrors, err := ValidateSchema(schema, document)
+	if err != nil {
+		return fmt.Errorf("failed to validate against schema: %w", err)
+	}
+
+	if len(fieldErrors) > 0 {
+		return &SchemaValidationError{Tool: g.name, Errors: fieldErrors}
+	}
+
+	return nil
+}
+
 // GenericToolBuilder provides a fluent interface for building generic tools
 type GenericToolBuilder[I, O any] struct {
 	name        string
 	description string
 	runner      func(ctx context.Context, input I) (O, error)
+	validate    bool
+	generator   schemas.SchemaGenerator
 }
 
 // NewGenericToolBuilder starts building a new generic tool
@@ -94,6 +208,21 @@ func (g *GenericToolBuilder[I, O]) WithRunner(runner func(ctx context.Context, i
 	return g
 }
 
+// WithValidation toggles checking the arguments against the input schema before running the tool,
+// and its output against the output schema before returning it. Disabled by default.
+func (b *GenericToolBuilder[I, O]) WithValidation(enabled bool) *GenericToolBuilder[I, O] {
+	b.validate = enabled
+	return b
+}
+
+// WithSchemaGenerator sets the generator used for the tool's input schema, e.g. one configured
+// with enums or custom reflector settings. Without one, the tool uses the default OpenAI schema
+// generator, or the generator of the adapter it's sent with (see ToolWithSchemaGenerator).
+func (b *GenericToolBuilder[I, O]) WithSchemaGenerator(generator schemas.SchemaGenerator) *GenericToolBuilder[I, O] {
+	b.generator = generator
+	return b
+}
+
 // Build creates the final GenericTool instance
 func (b *GenericToolBuilder[I, O]) Build() (*GenericTool[I, O], error) {
 	if b.name == "" {
@@ -110,6 +239,8 @@ func (b *GenericToolBuilder[I, O]) Build() (*GenericTool[I, O], error) {
 		name:        b.name,
 		description: b.description,
 		runner:      b.runner,
+		validate:    b.validate,
+		generator:   b.generator,
 	}, nil
 }
 
@@ -3,7 +3,11 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/petrjanda/frax/pkg/schemas"
 )
 
 // Test types for the generic tool
@@ -97,6 +101,142 @@ func TestGenericToolBuilder(t *testing.T) {
 	}
 }
 
+func TestGenericToolValidation(t *testing.T) {
+	build := func(validate bool) *GenericTool[TestInput, TestOutput] {
+		return NewGenericToolBuilder[TestInput, TestOutput]().
+			WithName("greeter").
+			WithDescription("Greets people").
+			WithRunner(testRunner).
+			WithValidation(validate).
+			MustBuild()
+	}
+
+	missingAge := json.RawMessage(`{"name": "John"}`)
+
+	// Without validation a missing field silently becomes the zero value
+	if _, err := build(false).Run(context.Background(), missingAge); err != nil {
+		t.Errorf("Expected the unvalidated tool to run, got %v", err)
+	}
+
+	_, err := build(true).Run(context.Background(), missingAge)
+
+	var validationErr *SchemaValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a SchemaValidationError, got %v", err)
+	}
+	if validationErr.Tool != "greeter" || !strings.Contains(err.Error(), "age") {
+		t.Errorf("Expected the missing age to be reported for greeter, got %v", err)
+	}
+	if !RetryValidationAndTransientErrors(err) {
+		t.Error("Expected the validation error to be retryable")
+	}
+
+	if _, err := build(true).Run(context.Background(), json.RawMessage(`{"name": "John", "age": 30}`)); err != nil {
+		t.Errorf("Expected valid arguments to run, got %v", err)
+	}
+}
+
+// NoInput is the input of a tool without parameters
+type NoInput struct{}
+
+func TestGenericToolEmptyArgs(t *testing.T) {
+	tool := NewGenericToolBuilder[NoInput, string]().
+		WithName("current_time").
+		WithDescription("Tells the current time").
+		WithRunner(func(ctx context.Context, input NoInput) (string, error) { return "12:00", nil }).
+		WithValidation(true).
+		MustBuild()
+
+	for _, args := range []string{"", "  ", "{}"} {
+		result, err := tool.Run(context.Background(), json.RawMessage(args))
+		if err != nil {
+			t.Errorf("Expected arguments %q to run the tool, got %v", args, err)
+			continue
+		}
+		if string(result) != `"12:00"` {
+			t.Errorf("Expected result \"12:00\" for arguments %q, got %s", args, result)
+		}
+	}
+}
+
+func TestGenericToolSchemaGenerator(t *testing.T) {
+	tool := NewGenericToolBuilder[TestInput, TestOutput]().
+		WithName("greeter").
+		WithDescription("Greets people").
+		WithRunner(testRunner).
+		WithSchemaGenerator(schemas.NewOpenAISchemaGenerator(schemas.WithEnum("name", "John", "Jane"))).
+		MustBuild()
+
+	schema := tool.InputSchemaRaw()
+	if !strings.Contains(string(schema), `"enum":["John","Jane"]`) {
+		t.Errorf("Expected the custom generator's enum in the schema, got %s", schema)
+	}
+
+	// The schema is generated once and cached
+	if again := tool.InputSchemaRaw(); &again[0] != &schema[0] {
+		t.Error("Expected the cached input schema to be returned")
+	}
+
+	// Input enums don't leak into the output schema, which keeps the default generator
+	if output := tool.OutputSchemaRaw(); output == nil || &tool.OutputSchemaRaw()[0] != &output[0] {
+		t.Errorf("Expected the cached output schema to be returned, got %s", output)
+	}
+}
+
+func TestGenericToolInputSchemaWith(t *testing.T) {
+	tool := CreateTool[TestInput, TestOutput]("greeter", "Greets people", testRunner)
+	anthropic := schemas.NewAnthropicSchemaGenerator()
+
+	// The adapter's generator produces the schema in its dialect
+	schema := InputSchemaFor(tool, anthropic)
+	if !strings.Contains(string(schema), `"additionalProperties":false`) {
+		t.Errorf("Expected the adapter's generator to close the object, got %s", schema)
+	}
+	if again := InputSchemaFor(tool, anthropic); &again[0] != &schema[0] {
+		t.Error("Expected the generated schema to be cached per generator")
+	}
+	if strings.Contains(string(tool.InputSchemaRaw()), "additionalProperties") {
+		t.Errorf("Expected the default schema to be unaffected, got %s", tool.InputSchemaRaw())
+	}
+
+	// An explicitly set generator takes precedence
+	explicit := NewGenericToolBuilder[TestInput, TestOutput]().
+		WithName("greeter").
+		WithDescription("Greets people").
+		WithRunner(testRunner).
+		WithSchemaGenerator(schemas.NewOpenAISchemaGenerator(schemas.WithEnum("name", "John", "Jane"))).
+		MustBuild()
+	if schema := InputSchemaFor(explicit, anthropic); string(schema) != string(explicit.InputSchemaRaw()) {
+		t.Errorf("Expected the explicit generator's schema, got %s", schema)
+	}
+
+	// Other tools keep their own schema
+	plain := &mockTool{name: "plain"}
+	if schema := InputSchemaFor(plain, anthropic); string(schema) != string(plain.InputSchemaRaw()) {
+		t.Errorf("Expected the tool's own schema, got %s", schema)
+	}
+}
+
+// generatorFunc is a func-typed schema generator, which can't be used as a map key
+type generatorFunc func(v interface{}) (json.RawMessage, error)
+
+func (f generatorFunc) GenerateSchema(v interface{}) (json.RawMessage, error) {
+	return f(v)
+}
+
+func TestGenericToolInputSchemaWithFuncGenerator(t *testing.T) {
+	tool := CreateTool[TestInput, TestOutput]("greeter", "Greets people", testRunner)
+	generator := generatorFunc(func(v interface{}) (json.RawMessage, error) {
+		return json.RawMessage(`{"type":"object"}`), nil
+	})
+
+	for range 2 {
+		if schema := InputSchemaFor(tool, generator); string(schema) != `{"type":"object"}` {
+			t.Errorf("Expected the func generator's schema, got %s", schema)
+		}
+	}
+}
+
 func TestCreateTool(t *testing.T) {
 	// Test the helper function
 	tool := CreateTool[TestInput, TestOutput]("helper_tool", "A tool created with CreateTool", testRunner)
@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"slices"
+	"sync"
+)
+
+// ToolRegistry is a set of tools that can change while agents are running, e.g. tools discovered
+// at runtime. It is safe for concurrent use.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools []Tool
+}
+
+// NewToolRegistry creates a registry holding the given tools
+func NewToolRegistry(tools ...Tool) *ToolRegistry {
+	r := &ToolRegistry{}
+	r.Register(tools...)
+
+	return r
+}
+
+// Register adds the tools to the registry, replacing registered tools of the same name
+func (r *ToolRegistry) Register(tools ...Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, tool := range tools {
+		r.tools = slices.DeleteFunc(r.tools, func(t Tool) bool { return t.Name() == tool.Name() })
+		r.tools = append(r.tools, tool)
+	}
+}
+
+// Unregister removes the named tool, reporting whether it was registered
+func (r *ToolRegistry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before := len(r.tools)
+	r.tools = slices.DeleteFunc(r.tools, func(t Tool) bool { return t.Name() == name })
+
+	return len(r.tools) != before
+}
+
+// Tools returns a snapshot of the registered tools, unaffected by later changes to the registry
+func (r *ToolRegistry) Tools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.tools)
+}
@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestToolRegistry(t *testing.T) {
+	registry := NewToolRegistry(&mockTool{name: "a"}, &mockTool{name: "b"})
+
+	registry.Register(&mockTool{name: "a"}, &mockTool{name: "c"})
+	if tools := registry.Tools(); len(tools) != 3 {
+		t.Errorf("Expected re-registered tool to be replaced, got %d tools", len(tools))
+	}
+
+	if !registry.Unregister("b") {
+		t.Error("Expected b to be unregistered")
+	}
+	if registry.Unregister("b") {
+		t.Error("Expected second unregister to report false")
+	}
+
+	snapshot := registry.Tools()
+	registry.Register(&mockTool{name: "d"})
+	if len(snapshot) != 2 {
+		t.Errorf("Expected snapshot to be unaffected by later changes, got %d tools", len(snapshot))
+	}
+}
+
+func TestAgentSnapshotsToolRegistry(t *testing.T) {
+	registry := NewToolRegistry(&mockTool{name: "test_tool"})
+
+	var mu sync.Mutex
+	var toolSets [][]string
+
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		names := make([]string, len(request.Tools))
+		for i, tool := range request.Tools {
+			names[i] = tool.Name()
+		}
+
+		mu.Lock()
+		toolSets = append(toolSets, names)
+		mu.Unlock()
+
+		calls++
+		if calls < 20 {
+			return toolCallResponse("call", "test_tool", `{}`), nil
+		}
+		return textResponse("done"), nil
+	})
+
+	agent := NewAgent(model, nil, WithToolRegistry(registry), WithMaxIterations(25))
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			name := fmt.Sprintf("dynamic_%d", i)
+			registry.Register(&mockTool{name: name})
+			registry.Unregister(name)
+		}
+	}()
+
+	_, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("go"))))
+	close(stop)
+	wg.Wait()
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Every iteration of the run saw the same tools
+	for i, names := range toolSets {
+		if len(names) != len(toolSets[0]) {
+			t.Fatalf("Expected consistent tools across the run, iteration %d saw %v after %v", i, names, toolSets[0])
+		}
+		for j := range names {
+			if names[j] != toolSets[0][j] {
+				t.Fatalf("Expected consistent tools across the run, iteration %d saw %v after %v", i, names, toolSets[0])
+			}
+		}
+	}
+
+	// The next run picks up changes
+	registry.Register(&mockTool{name: "late_tool"})
+	calls = 19
+	toolSets = nil
+
+	if _, err := agent.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("go")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if last := toolSets[0]; last[len(last)-1] != "late_tool" {
+		t.Errorf("Expected the next run to see late_tool, got %v", last)
+	}
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"fmt"
+)
+
+// ToolResultDelivery represents how tool calls and their results are delivered to the provider
+type ToolResultDelivery string
+
+const (
+	// ToolResultDeliveryToolRole sends results as tool-role messages answering the assistant's tool calls (default behavior)
+	ToolResultDeliveryToolRole ToolResultDelivery = "tool"
+
+	// ToolResultDeliveryUserRole sends tool calls as assistant text and their results as user messages,
+	// for providers or models that handle tool output better as plain conversation
+	ToolResultDeliveryUserRole ToolResultDelivery = "user"
+
+	// ToolResultDeliveryText renders each tool call together with its result as plain assistant text,
+	// for models lacking native tool-result support (e.g. some local models)
+	ToolResultDeliveryText ToolResultDelivery = "text"
+)
+
+// WithToolResultDelivery sets how tool results are delivered to the provider
+func WithToolResultDelivery(delivery ToolResultDelivery) LLMRequestOpts {
+	return func(r *LLMRequest) {
+		r.ToolResultDelivery = delivery
+	}
+}
+
+// ToolMessagesAsText rewrites tool calls and their results into plain assistant messages
+// ("I called X with Y and got Z"), leaving the other messages untouched
+func ToolMessagesAsText(history History) History {
+	answered := make(map[string]bool)
+	for _, msg := range history {
+		switch m := msg.(type) {
+		case *ToolResultMessage:
+			answered[toolCallID(m.ToolCall)] = true
+		case *ToolErrorMessage:
+			answered[toolCallID(m.ToolCall)] = true
+		}
+	}
+
+	rendered := make(History, 0, len(history))
+	for _, msg := range ExpandToolCalls(history) {
+		switch m := msg.(type) {
+		case *ToolCallMessage:
+			// Calls are rendered together with their result when there is one
+			if m.ToolCall == nil || answered[m.ToolCall.ID] {
+				continue
+			}
+			rendered = append(rendered, &AssistantMessage{
+				Content: fmt.Sprintf("I called %s with %s.", m.ToolCall.Name, compactJSON(m.ToolCall.Args)),
+			})
+
+		case *ToolResultMessage:
+			rendered = append(rendered, &AssistantMessage{
+				Content: fmt.Sprintf("I called %s with %s and got %s.", m.ToolCall.Name, compactJSON(m.ToolCall.Args), compactJSON(m.Result)),
+			})
+
+		case *ToolErrorMessage:
+			rendered = append(rendered, &AssistantMessage{
+				Content: fmt.Sprintf("I called %s with %s and it failed: %s", m.ToolCall.Name, compactJSON(m.ToolCall.Args), m.Error),
+			})
+
+		default:
+			rendered = append(rendered, msg)
+		}
+	}
+
+	return rendered
+}
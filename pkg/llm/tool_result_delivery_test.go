@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToolMessagesAsText(t *testing.T) {
+	call := &ToolCall{ID: "call_1", Name: "calculator", Args: json.RawMessage(`{"a": 1, "b": 2}`)}
+	pending := &ToolCall{ID: "call_2", Name: "search", Args: json.RawMessage(`{"q": "go"}`)}
+
+	history := NewHistory(
+		NewUserMessage("What is 1 plus 2?"),
+		NewToolCallMessage(call),
+		NewToolResultMessage(call, json.RawMessage(`{"result": 3}`)),
+		NewToolCallMessage(pending),
+	)
+
+	rendered := ToolMessagesAsText(history)
+
+	if len(rendered) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(rendered))
+	}
+
+	if _, ok := rendered[0].(*UserMessage); !ok {
+		t.Errorf("Expected user message to be kept, got %T", rendered[0])
+	}
+
+	expected := []string{
+		`I called calculator with {"a":1,"b":2} and got {"result":3}.`,
+		`I called search with {"q":"go"}.`,
+	}
+	for i, content := range expected {
+		msg, ok := rendered[i+1].(*AssistantMessage)
+		if !ok {
+			t.Fatalf("Expected assistant message at %d, got %T", i+1, rendered[i+1])
+		}
+		if msg.Content != content {
+			t.Errorf("Expected %q, got %q", content, msg.Content)
+		}
+	}
+}
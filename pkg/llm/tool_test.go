@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewToolbox(t *testing.T) {
+	calculator := &mockTool{name: "calculator"}
+	weather := &mockTool{name: "weather"}
+
+	toolbox, err := NewToolbox(weather, calculator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if names := toolbox.Names(); !reflect.DeepEqual(names, []string{"weather", "calculator"}) {
+		t.Errorf("Expected the names in order, got %v", names)
+	}
+
+	if tool, ok := toolbox.Get("calculator"); !ok || tool != calculator {
+		t.Errorf("Expected to get the calculator, got %v", tool)
+	}
+	if _, ok := toolbox.Get("missing"); ok {
+		t.Error("Expected no tool for an unknown name")
+	}
+
+	// The returned tools are a copy
+	tools := toolbox.Tools()
+	tools[0] = calculator
+	if tool := toolbox.Tools()[0]; tool != weather {
+		t.Errorf("Expected the toolbox to be unaffected, got %v", tool.Name())
+	}
+}
+
+func TestNewToolboxDuplicateNames(t *testing.T) {
+	_, err := NewToolbox(&mockTool{name: "calculator"}, &mockTool{name: "weather"}, &mockTool{name: "calculator"})
+	if err == nil || !strings.Contains(err.Error(), "duplicate tool name calculator") {
+		t.Errorf("Expected a duplicate name error, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected MustNewToolbox to panic on duplicate names")
+		}
+	}()
+	MustNewToolbox(&mockTool{name: "calculator"}, &mockTool{name: "calculator"})
+}
+
+func TestFindTool(t *testing.T) {
+	tools := []Tool{&mockTool{name: "calculator"}}
+
+	if tool, err := FindTool("calculator", tools); err != nil || tool.Name() != "calculator" {
+		t.Errorf("Expected to find the calculator, got %v, %v", tool, err)
+	}
+
+	var notFound *ToolNotFoundError
+	if _, err := FindTool("missing", tools); !errors.As(err, &notFound) || notFound.Tool != "missing" {
+		t.Errorf("Expected a ToolNotFoundError, got %v", err)
+	}
+}
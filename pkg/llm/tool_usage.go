@@ -14,6 +14,9 @@ const (
 
 	// ToolUsageForced forces the LLM to use a specific tool
 	ToolUsageForced ToolUsageType = "forced"
+
+	// ToolUsageNone keeps the LLM from calling any tool, so it answers in text
+	ToolUsageNone ToolUsageType = "none"
 )
 
 // AutoToolUsage allows automatic tool selection (default behavior)
@@ -32,6 +35,13 @@ func (f ForcedToolUsage) Type() ToolUsageType {
 	return ToolUsageForced
 }
 
+// NoToolUsage forbids tool calls while keeping the tools declared, e.g. for a final summarization turn
+type NoToolUsage struct{}
+
+func (n NoToolUsage) Type() ToolUsageType {
+	return ToolUsageNone
+}
+
 // Helper functions for creating tool usage options
 func AutoToolSelection() ToolUsage {
 	return &AutoToolUsage{}
@@ -40,3 +50,7 @@ func AutoToolSelection() ToolUsage {
 func ForceTool(toolName string) ToolUsage {
 	return &ForcedToolUsage{ToolName: toolName}
 }
+
+func ForceNoTools() ToolUsage {
+	return &NoToolUsage{}
+}
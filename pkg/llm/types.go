@@ -0,0 +1,26 @@
+package llm
+
+import "context"
+
+// Task produces an output for an input, e.g. an agent answering a question
+type Task[I, O any] interface {
+	Run(ctx context.Context, input I) (O, error)
+}
+
+// Eval scores the output of a task, higher scores are better
+type Eval[O any] interface {
+	Score(ctx context.Context, output O) (float64, error)
+}
+
+// LLMTask runs the LLM as a task, so agents and pipelines can be sampled and ranked with BestOfN
+func LLMTask(model LLM) Task[*LLMRequest, *LLMResponse] {
+	return llmTask{model: model}
+}
+
+type llmTask struct {
+	model LLM
+}
+
+func (t llmTask) Run(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	return t.model.Invoke(ctx, request)
+}
@@ -0,0 +1,425 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes a single schema violation at a path within the validated document
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+// String renders the error in a form that can be relayed to the model, e.g. "field 'age' must be an integer"
+func (e FieldError) String() string {
+	if e.Path == "" {
+		return "input " + e.Message
+	}
+
+	return fmt.Sprintf("field '%s' %s", e.Path, e.Message)
+}
+
+// FieldErrors is the list of violations found when validating a document against a schema
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.String()
+	}
+
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// ValidateSchema validates the data against the JSON schema and returns every violation found.
+// The returned error is only set when the schema or the data cannot be parsed.
+func ValidateSchema(schema json.RawMessage, data json.RawMessage) (FieldErrors, error) {
+	if len(bytes.TrimSpace(schema)) == 0 {
+		return nil, nil
+	}
+
+	var schemaDoc map[string]any
+	if err := json.Unmarshal(schema, &schemaDoc); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON input: %w", err)
+	}
+
+	v := &schemaValidator{}
+	v.validate(schemaDoc, value, "")
+
+	return v.errors, nil
+}
+
+// schemaValidator walks a decoded document alongside its schema collecting violations
+type schemaValidator struct {
+	errors FieldErrors
+}
+
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	v.errors = append(v.errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validate(schema map[string]any, value any, path string) {
+	if types := schemaTypes(schema); len(types) > 0 && !matchesAnyType(value, types) {
+		v.fail(path, "must be %s", describeTypes(types))
+		return
+	}
+
+	if allowed, ok := schema["enum"].([]any); ok && !containsJSON(allowed, value) {
+		v.fail(path, "must be %s", describeValues(allowed))
+	}
+
+	if expected, ok := schema["const"]; ok && !equalJSON(expected, value) {
+		v.fail(path, "must be %s", describeValues([]any{expected}))
+	}
+
+	v.validateCombinators(schema, value, path)
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, val, path)
+	case []any:
+		v.validateArray(schema, val, path)
+	case string:
+		v.validateString(schema, val, path)
+	case json.Number:
+		v.validateNumber(schema, val, path)
+	}
+}
+
+// validateCombinators checks the allOf, anyOf and oneOf subschemas
+func (v *schemaValidator) validateCombinators(schema map[string]any, value any, path string) {
+	for _, sub := range subschemas(schema, "allOf") {
+		v.validate(sub, value, path)
+	}
+
+	if anyOf := subschemas(schema, "anyOf"); len(anyOf) > 0 && v.countMatches(anyOf, value) == 0 {
+		v.fail(path, "must match at least one of the allowed schemas")
+	}
+
+	if oneOf := subschemas(schema, "oneOf"); len(oneOf) > 0 && v.countMatches(oneOf, value) != 1 {
+		v.fail(path, "must match exactly one of the allowed schemas")
+	}
+}
+
+// countMatches returns how many of the schemas the value satisfies
+func (v *schemaValidator) countMatches(schemas []map[string]any, value any) int {
+	matches := 0
+	for _, sub := range schemas {
+		candidate := &schemaValidator{}
+		candidate.validate(sub, value, "")
+		if len(candidate.errors) == 0 {
+			matches++
+		}
+	}
+
+	return matches
+}
+
+func (v *schemaValidator) validateString(schema map[string]any, value string, path string) {
+	length := utf8.RuneCountInString(value)
+	if minLength, ok := schemaNumber(schema, "minLength"); ok && float64(length) < minLength {
+		v.fail(path, "must be at least %s characters long", formatNumber(minLength))
+	}
+	if maxLength, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > maxLength {
+		v.fail(path, "must be at most %s characters long", formatNumber(maxLength))
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		// Patterns Go cannot compile are not enforced
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+			v.fail(path, "must match the pattern %s", pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(schema map[string]any, value json.Number, path string) {
+	number, err := value.Float64()
+	if err != nil {
+		return
+	}
+
+	if minimum, ok := schemaNumber(schema, "minimum"); ok && number < minimum {
+		v.fail(path, "must be at least %s", formatNumber(minimum))
+	}
+	if maximum, ok := schemaNumber(schema, "maximum"); ok && number > maximum {
+		v.fail(path, "must be at most %s", formatNumber(maximum))
+	}
+	if minimum, ok := schemaNumber(schema, "exclusiveMinimum"); ok && number <= minimum {
+		v.fail(path, "must be greater than %s", formatNumber(minimum))
+	}
+	if maximum, ok := schemaNumber(schema, "exclusiveMaximum"); ok && number >= maximum {
+		v.fail(path, "must be less than %s", formatNumber(maximum))
+	}
+}
+
+func (v *schemaValidator) validateObject(schema map[string]any, value map[string]any, path string) {
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			key, ok := name.(string)
+			if !ok {
+				continue
+			}
+
+			if _, present := value[key]; !present {
+				v.fail(joinPath(path, key), "is required")
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if propSchema, ok := properties[key].(map[string]any); ok {
+			v.validate(propSchema, value[key], joinPath(path, key))
+			continue
+		}
+
+		if _, declared := properties[key]; declared {
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(joinPath(path, key), "is not allowed")
+			}
+		case map[string]any:
+			v.validate(additional, value[key], joinPath(path, key))
+		}
+	}
+}
+
+func (v *schemaValidator) validateArray(schema map[string]any, value []any, path string) {
+	if minItems, ok := schemaNumber(schema, "minItems"); ok && float64(len(value)) < minItems {
+		v.fail(path, "must have at least %s items", formatNumber(minItems))
+	}
+	if maxItems, ok := schemaNumber(schema, "maxItems"); ok && float64(len(value)) > maxItems {
+		v.fail(path, "must have at most %s items", formatNumber(maxItems))
+	}
+
+	items, ok := schema["items"].(map[string]any)
+	if !ok {
+		return
+	}
+
+	for i, item := range value {
+		v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))
+	}
+}
+
+// schemaTypes returns the allowed types of a schema, which may be a single type or a list
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+
+	return nil
+}
+
+func matchesAnyType(value any, types []string) bool {
+	for _, t := range types {
+		if matchesType(value, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesType(value any, schemaType string) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := number.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+
+	// Unknown types are not enforced
+	return true
+}
+
+func describeTypes(types []string) string {
+	described := make([]string, len(types))
+	for i, t := range types {
+		switch t {
+		case "object", "array", "integer":
+			described[i] = "an " + t
+		case "null":
+			described[i] = "null"
+		default:
+			described[i] = "a " + t
+		}
+	}
+
+	return strings.Join(described, " or ")
+}
+
+// subschemas returns the schemas listed under a combinator keyword such as anyOf
+func subschemas(schema map[string]any, keyword string) []map[string]any {
+	list, _ := schema[keyword].([]any)
+
+	var schemas []map[string]any
+	for _, item := range list {
+		if sub, ok := item.(map[string]any); ok {
+			schemas = append(schemas, sub)
+		}
+	}
+
+	return schemas
+}
+
+// schemaNumber returns a numeric keyword of the schema, e.g. minimum
+func schemaNumber(schema map[string]any, keyword string) (float64, bool) {
+	number, ok := schema[keyword].(float64)
+	return number, ok
+}
+
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// equalJSON compares decoded JSON values, treating numbers decoded as json.Number and float64 alike
+func equalJSON(a, b any) bool {
+	return reflect.DeepEqual(normalizeNumbers(a), normalizeNumbers(b))
+}
+
+func containsJSON(values []any, value any) bool {
+	for _, candidate := range values {
+		if equalJSON(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeNumbers(value any) any {
+	switch val := value.(type) {
+	case json.Number:
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+	case map[string]any:
+		normalized := make(map[string]any, len(val))
+		for key, item := range val {
+			normalized[key] = normalizeNumbers(item)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(val))
+		for i, item := range val {
+			normalized[i] = normalizeNumbers(item)
+		}
+		return normalized
+	}
+
+	return value
+}
+
+// describeValues renders allowed values for an error message, e.g. one of ["economy", "business"]
+func describeValues(values []any) string {
+	described := make([]string, len(values))
+	for i, value := range values {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			encoded = []byte(fmt.Sprint(value))
+		}
+		described[i] = string(encoded)
+	}
+
+	if len(described) == 1 {
+		return described[0]
+	}
+
+	return "one of [" + strings.Join(described, ", ") + "]"
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// describeSchemaShape renders a compact outline of a schema, e.g. "{name: string, tags: array of string}"
+func describeSchemaShape(schema map[string]any) string {
+	types := schemaTypes(schema)
+
+	if properties, ok := schema["properties"].(map[string]any); ok && len(properties) > 0 {
+		keys := make([]string, 0, len(properties))
+		for key := range properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fields := make([]string, len(keys))
+		for i, key := range keys {
+			propSchema, _ := properties[key].(map[string]any)
+			fields[i] = key + ": " + describeSchemaShape(propSchema)
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+
+	if items, ok := schema["items"].(map[string]any); ok {
+		return "array of " + describeSchemaShape(items)
+	}
+
+	if len(types) == 0 {
+		return "any"
+	}
+
+	return strings.Join(types, " or ")
+}
@@ -0,0 +1,236 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var personSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string"},
+		"age": {"type": "integer"},
+		"email": {"type": "string"},
+		"tags": {"type": "array", "items": {"type": "string"}},
+		"address": {
+			"type": "object",
+			"properties": {"city": {"type": "string"}},
+			"required": ["city"]
+		}
+	},
+	"required": ["name", "age", "email"]
+}`)
+
+func TestValidateSchemaFieldErrors(t *testing.T) {
+	payload := json.RawMessage(`{"age": "thirty", "tags": ["go", 3], "address": {}}`)
+
+	fieldErrors, err := ValidateSchema(personSchema, payload)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"field 'name' is required",
+		"field 'email' is required",
+		"field 'address.city' is required",
+		"field 'age' must be an integer",
+		"field 'tags[1]' must be a string",
+	}
+
+	if len(fieldErrors) != len(expected) {
+		t.Fatalf("Expected %d field errors, got %d: %v", len(expected), len(fieldErrors), fieldErrors)
+	}
+
+	for i, message := range expected {
+		if fieldErrors[i].String() != message {
+			t.Errorf("Expected field error %d to be %q, got %q", i, message, fieldErrors[i].String())
+		}
+	}
+
+	if fieldErrors[3].Path != "age" {
+		t.Errorf("Expected path 'age', got %q", fieldErrors[3].Path)
+	}
+}
+
+func TestValidateSchemaValid(t *testing.T) {
+	payload := json.RawMessage(`{"name": "Ada", "age": 36, "email": "ada@example.com", "tags": ["math"]}`)
+
+	fieldErrors, err := ValidateSchema(personSchema, payload)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(fieldErrors) != 0 {
+		t.Errorf("Expected no field errors, got %v", fieldErrors)
+	}
+}
+
+func TestValidateSchemaInvalidJSON(t *testing.T) {
+	if _, err := ValidateSchema(personSchema, json.RawMessage(`{"name":`)); err == nil {
+		t.Error("Expected error for malformed input")
+	}
+}
+
+func TestStructuredOutputValidateInput(t *testing.T) {
+	formatter := NewBaseLLMWithStructuredOutput(personSchema, nil)
+
+	err := formatter.ValidateInput(json.RawMessage(`{"name": "Ada", "age": 1.5, "email": "ada@example.com"}`))
+
+	var fieldErrors FieldErrors
+	if !errors.As(err, &fieldErrors) {
+		t.Fatalf("Expected FieldErrors, got %v", err)
+	}
+
+	if len(fieldErrors) != 1 || fieldErrors[0].Path != "age" {
+		t.Errorf("Expected a single error for 'age', got %v", fieldErrors)
+	}
+
+	if err.Error() != "validation failed: field 'age' must be an integer" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
+
+func TestStructuredOutputFormatRetries(t *testing.T) {
+	var requests []*LLMRequest
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		requests = append(requests, request)
+		if len(requests) == 1 {
+			return toolCallResponse("call_1", "formatter", `{"name": "Ada", "email": "ada@example.com"}`), nil
+		}
+		return toolCallResponse("call_2", "formatter", `{"name": "Ada", "age": 36, "email": "ada@example.com"}`), nil
+	})
+
+	formatter := NewBaseLLMWithStructuredOutput(personSchema, model, WithFormatRetries(2))
+
+	response, err := formatter.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Ada, 36, ada@example.com"))))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 LLM calls, got %d", len(requests))
+	}
+
+	// The retry sees the rejected output and why it was rejected
+	correction, ok := requests[1].History[len(requests[1].History)-1].(*ToolResultMessage)
+	if !ok || !strings.Contains(string(correction.Result), "field 'age' is required") {
+		t.Errorf("Expected the validation errors in the retry, got %v", requests[1].History)
+	}
+
+	var person map[string]any
+	if err := json.Unmarshal([]byte(response.Messages[0].(*UserMessage).Content), &person); err != nil || person["age"] != 36.0 {
+		t.Errorf("Expected the corrected output, got %s", response.Messages[0].(*UserMessage).Content)
+	}
+}
+
+func TestStructuredOutputFormatRetriesExhausted(t *testing.T) {
+	calls := 0
+	model := invokeFunc(func(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+		calls++
+		return toolCallResponse("call", "formatter", `{}`), nil
+	})
+
+	formatter := NewBaseLLMWithStructuredOutput(personSchema, model, WithFormatRetries(1))
+
+	_, err := formatter.Invoke(context.Background(), NewLLMRequest(NewHistory(NewUserMessage("Nobody"))))
+
+	var fieldErrors FieldErrors
+	if !errors.As(err, &fieldErrors) {
+		t.Fatalf("Expected FieldErrors, got %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected the initial call and 1 retry, got %d", calls)
+	}
+}
+
+func TestValidateSchemaKeywords(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"class": {"type": "string", "enum": ["economy", "business"]},
+			"passengers": {"type": "integer", "minimum": 1, "maximum": 9},
+			"discount": {"type": "number", "exclusiveMinimum": 0, "exclusiveMaximum": 1},
+			"code": {"type": "string", "minLength": 3, "maxLength": 3, "pattern": "^[A-Z]+$"},
+			"stops": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+			"version": {"const": 2},
+			"date": {"anyOf": [{"type": "string"}, {"type": "null"}]},
+			"seat": {"oneOf": [{"type": "string"}, {"type": "string", "minLength": 2}]},
+			"meta": {"type": "object", "additionalProperties": {"type": "string"}}
+		},
+		"additionalProperties": false
+	}`)
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected []string
+	}{
+		{
+			name:    "valid document",
+			payload: `{"class": "economy", "passengers": 2, "discount": 0.5, "code": "PRG", "stops": ["VIE"], "version": 2.0, "date": null, "seat": "A", "meta": {"note": "x"}}`,
+		},
+		{
+			name:     "enum",
+			payload:  `{"class": "first"}`,
+			expected: []string{`field 'class' must be one of ["economy", "business"]`},
+		},
+		{
+			name:     "numeric bounds",
+			payload:  `{"passengers": 0, "discount": 1}`,
+			expected: []string{"field 'discount' must be less than 1", "field 'passengers' must be at least 1"},
+		},
+		{
+			name:     "string length and pattern",
+			payload:  `{"code": "prgx"}`,
+			expected: []string{"field 'code' must be at most 3 characters long", "field 'code' must match the pattern ^[A-Z]+$"},
+		},
+		{
+			name:     "array size",
+			payload:  `{"stops": ["VIE", "MUC", "FRA"]}`,
+			expected: []string{"field 'stops' must have at most 2 items"},
+		},
+		{
+			name:     "const",
+			payload:  `{"version": 3}`,
+			expected: []string{"field 'version' must be 2"},
+		},
+		{
+			name:     "anyOf",
+			payload:  `{"date": 20250101}`,
+			expected: []string{"field 'date' must match at least one of the allowed schemas"},
+		},
+		{
+			name:     "oneOf",
+			payload:  `{"seat": "12A"}`,
+			expected: []string{"field 'seat' must match exactly one of the allowed schemas"},
+		},
+		{
+			name:     "additional properties",
+			payload:  `{"extra": true, "meta": {"count": 1}}`,
+			expected: []string{"field 'extra' is not allowed", "field 'meta.count' must be a string"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fieldErrors, err := ValidateSchema(schema, json.RawMessage(tt.payload))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(fieldErrors) != len(tt.expected) {
+				t.Fatalf("Expected %d field errors, got %d: %v", len(tt.expected), len(fieldErrors), fieldErrors)
+			}
+
+			for i, message := range tt.expected {
+				if fieldErrors[i].String() != message {
+					t.Errorf("Expected field error %d to be %q, got %q", i, message, fieldErrors[i].String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+// Package metrics records Prometheus metrics of LLM calls and tool executions, by wrapping an LLM
+// and observing agents, without changes to the adapters or tools themselves.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// Metrics holds the collectors shared by instrumented LLMs and agent observers
+type Metrics struct {
+	namespace string
+
+	llmRequests *prometheus.CounterVec
+	llmDuration *prometheus.HistogramVec
+	llmErrors   *prometheus.CounterVec
+	llmTokens   *prometheus.CounterVec
+
+	toolCalls    *prometheus.CounterVec
+	toolDuration *prometheus.HistogramVec
+	toolErrors   *prometheus.CounterVec
+	toolRetries  *prometheus.CounterVec
+}
+
+// MetricsOpts represents options for configuring the metrics
+type MetricsOpts = func(*Metrics)
+
+// WithNamespace sets the namespace prefixing the metric names, "frax" by default
+func WithNamespace(namespace string) MetricsOpts {
+	return func(m *Metrics) {
+		m.namespace = namespace
+	}
+}
+
+// NewMetrics creates the collectors and registers them with the registerer, e.g.
+// prometheus.DefaultRegisterer
+func NewMetrics(registerer prometheus.Registerer, opts ...MetricsOpts) (*Metrics, error) {
+	m := &Metrics{namespace: "frax"}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.llmRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "llm_requests_total",
+		Help:      "LLM calls by model.",
+	}, []string{"model"})
+	m.llmDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: m.namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Latency of LLM calls by model.",
+		Buckets:   []float64{0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"model"})
+	m.llmErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "llm_errors_total",
+		Help:      "Failed LLM calls by model and error type.",
+	}, []string{"model", "type"})
+	m.llmTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens consumed by LLM calls by model and kind, prompt or completion.",
+	}, []string{"model", "kind"})
+
+	m.toolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "tool_calls_total",
+		Help:      "Tool calls by tool.",
+	}, []string{"tool"})
+	m.toolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: m.namespace,
+		Name:      "tool_call_duration_seconds",
+		Help:      "Duration of tool calls including their retries by tool.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"tool"})
+	m.toolErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "tool_errors_total",
+		Help:      "Tool calls failing after all retries by tool and error type.",
+	}, []string{"tool", "type"})
+	m.toolRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "tool_retries_total",
+		Help:      "Retries of failed tool calls by tool.",
+	}, []string{"tool"})
+
+	for _, collector := range []prometheus.Collector{
+		m.llmRequests, m.llmDuration, m.llmErrors, m.llmTokens,
+		m.toolCalls, m.toolDuration, m.toolErrors, m.toolRetries,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, fmt.Errorf("failed to register metric: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+// WrapLLM returns the LLM recording the calls, latency, errors and tokens of every call under the
+// model label. Wrap the adapter an agent calls to measure each of its iterations.
+func (m *Metrics) WrapLLM(inner llm.LLM, model string) *InstrumentedLLM {
+	return &InstrumentedLLM{inner: inner, model: model, metrics: m, now: time.Now}
+}
+
+// Observer returns an agent observer recording the calls, duration, errors and retries of tool
+// calls under the tool label, register it with llm.WithObserver
+func (m *Metrics) Observer() *Observer {
+	return &Observer{metrics: m, now: time.Now}
+}
+
+// InstrumentedLLM wraps an LLM and records metrics of its calls
+type InstrumentedLLM struct {
+	inner   llm.LLM
+	model   string
+	metrics *Metrics
+	now     func() time.Time
+}
+
+// Invoke implements the LLM interface, recording the call to the wrapped LLM
+func (l *InstrumentedLLM) Invoke(ctx context.Context, request *llm.LLMRequest) (*llm.LLMResponse, error) {
+	start := l.now()
+	response, err := l.inner.Invoke(ctx, request)
+
+	l.metrics.llmRequests.WithLabelValues(l.model).Inc()
+	l.metrics.llmDuration.WithLabelValues(l.model).Observe(l.now().Sub(start).Seconds())
+
+	if err != nil {
+		l.metrics.llmErrors.WithLabelValues(l.model, errorType(err)).Inc()
+		return nil, err
+	}
+
+	if usage := response.Usage; usage != nil {
+		l.metrics.llmTokens.WithLabelValues(l.model, "prompt").Add(float64(usage.PromptTokens))
+		l.metrics.llmTokens.WithLabelValues(l.model, "completion").Add(float64(usage.CompletionTokens))
+	}
+
+	return response, nil
+}
+
+// Capabilities reports the capabilities of the wrapped LLM, except streaming as only Invoke is instrumented
+func (l *InstrumentedLLM) Capabilities() llm.Capabilities {
+	capabilities := llm.CapabilitiesOf(l.inner)
+	capabilities.Streaming = false
+
+	return capabilities
+}
+
+// Observer records metrics of an agent's tool calls. LLM calls are left to InstrumentedLLM, so they
+// are counted once whether or not an agent makes them.
+type Observer struct {
+	llm.NoopAgentObserver
+
+	metrics *Metrics
+	now     func() time.Time
+
+	// Start times of the running tool calls, tool calls may run concurrently
+	started sync.Map
+}
+
+// OnToolCall counts the call and starts timing it
+func (o *Observer) OnToolCall(ctx context.Context, toolCall *llm.ToolCall) {
+	o.metrics.toolCalls.WithLabelValues(toolCall.Name).Inc()
+	o.started.Store(toolCall, o.now())
+}
+
+// OnToolResult records the duration of the call and its error, if any
+func (o *Observer) OnToolResult(ctx context.Context, toolCall *llm.ToolCall, result llm.Message, err error) {
+	if start, ok := o.started.LoadAndDelete(toolCall); ok {
+		o.metrics.toolDuration.WithLabelValues(toolCall.Name).Observe(o.now().Sub(start.(time.Time)).Seconds())
+	}
+
+	if err != nil {
+		o.metrics.toolErrors.WithLabelValues(toolCall.Name, errorType(err)).Inc()
+	}
+}
+
+// OnRetry counts the retry of a failed tool call
+func (o *Observer) OnRetry(ctx context.Context, toolCall *llm.ToolCall, attempt int, err error) {
+	o.metrics.toolRetries.WithLabelValues(toolCall.Name).Inc()
+}
+
+// errorType classifies an error for the type label, keeping the label's cardinality small
+func errorType(err error) string {
+	var providerErr *llm.LLMProviderError
+	var refusalErr *llm.RefusalError
+	var validationErr *llm.SchemaValidationError
+	var notFoundErr *llm.ToolNotFoundError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &providerErr):
+		if providerErr.StatusCode == http.StatusTooManyRequests {
+			return "rate_limit"
+		}
+		return "provider"
+	case errors.As(err, &refusalErr):
+		return "refusal"
+	case errors.As(err, &validationErr):
+		return "validation"
+	case errors.As(err, &notFoundErr):
+		return "tool_not_found"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/petrjanda/frax/pkg/llm"
+	"github.com/petrjanda/frax/pkg/llm/llmtest"
+)
+
+func newTestMetrics(t *testing.T) *Metrics {
+	m, err := NewMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Failed to create metrics: %v", err)
+	}
+
+	return m
+}
+
+func TestInstrumentedLLM(t *testing.T) {
+	m := newTestMetrics(t)
+
+	mock := llmtest.NewMockLLM("Hi")
+	mock.Response.Usage = &llm.Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}
+	model := m.WrapLLM(mock, "gpt-4o")
+
+	request := llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Hi")))
+	for range 2 {
+		if _, err := model.Invoke(context.Background(), request); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	mock.Err = &llm.LLMProviderError{Provider: "openai", StatusCode: http.StatusTooManyRequests, Err: errors.New("slow down")}
+	if _, err := model.Invoke(context.Background(), request); err == nil {
+		t.Fatal("Expected the provider error to be returned")
+	}
+
+	if got := testutil.ToFloat64(m.llmRequests.WithLabelValues("gpt-4o")); got != 3 {
+		t.Errorf("Expected 3 requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.llmErrors.WithLabelValues("gpt-4o", "rate_limit")); got != 1 {
+		t.Errorf("Expected 1 rate limit error, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.llmTokens.WithLabelValues("gpt-4o", "prompt")); got != 24 {
+		t.Errorf("Expected 24 prompt tokens, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.llmTokens.WithLabelValues("gpt-4o", "completion")); got != 6 {
+		t.Errorf("Expected 6 completion tokens, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.llmDuration); got != 1 {
+		t.Errorf("Expected a latency histogram for the model, got %d", got)
+	}
+}
+
+// capableLLM is an LLM reporting streaming among its capabilities
+type capableLLM struct {
+	*llmtest.MockLLM
+}
+
+func (c capableLLM) Capabilities() llm.Capabilities {
+	return llm.Capabilities{ForcedTools: true, Streaming: true}
+}
+
+func TestInstrumentedLLMCapabilities(t *testing.T) {
+	m := newTestMetrics(t)
+	model := m.WrapLLM(capableLLM{llmtest.NewMockLLM("Hi")}, "gpt-4o")
+
+	if got := llm.CapabilitiesOf(model); got != (llm.Capabilities{ForcedTools: true}) {
+		t.Errorf("Expected the wrapped capabilities without streaming, got %+v", got)
+	}
+}
+
+func TestObserverRecordsToolCalls(t *testing.T) {
+	m := newTestMetrics(t)
+
+	lookup := llm.NewGenericTool("lookup", "Looks up an order", func(ctx context.Context, input struct {
+		ID string `json:"id"`
+	}) (string, error) {
+		return "shipped", nil
+	})
+	model := llmtest.NewScriptedLLM(
+		llmtest.CallTool("lookup", `{"id":"1"}`),
+		llmtest.CallTool("lookup", `{"id":"2"}`),
+		llmtest.Answer("Both orders shipped."),
+	)
+
+	agent := llm.NewAgent(m.WrapLLM(model, "gpt-4o"), []llm.Tool{lookup}, llm.WithObserver(m.Observer()))
+	if _, err := agent.Invoke(context.Background(), llm.NewLLMRequest(llm.NewHistory(llm.NewUserMessage("Where are my orders?")))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := testutil.ToFloat64(m.toolCalls.WithLabelValues("lookup")); got != 2 {
+		t.Errorf("Expected 2 lookup calls, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.llmRequests.WithLabelValues("gpt-4o")); got != 3 {
+		t.Errorf("Expected 3 LLM requests, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.toolErrors); got != 0 {
+		t.Errorf("Expected no tool errors, got %d", got)
+	}
+}
+
+func TestObserverRecordsToolFailures(t *testing.T) {
+	m := newTestMetrics(t)
+	observer := m.Observer()
+
+	start := time.Now()
+	observer.now = func() time.Time { return start }
+
+	ctx := context.Background()
+	toolCall := &llm.ToolCall{ID: "call_1", Name: "search", Args: json.RawMessage(`{}`)}
+
+	observer.OnToolCall(ctx, toolCall)
+	observer.OnRetry(ctx, toolCall, 1, errors.New("bad arguments"))
+	observer.OnRetry(ctx, toolCall, 2, errors.New("bad arguments"))
+
+	observer.now = func() time.Time { return start.Add(2 * time.Second) }
+	observer.OnToolResult(ctx, toolCall, nil, &llm.ToolTimeoutError{Tool: "search", Timeout: time.Second})
+
+	if got := testutil.ToFloat64(m.toolRetries.WithLabelValues("search")); got != 2 {
+		t.Errorf("Expected 2 retries, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.toolErrors.WithLabelValues("search", "timeout")); got != 1 {
+		t.Errorf("Expected 1 timeout error, got %v", got)
+	}
+
+	var metric dto.Metric
+	if err := m.toolDuration.WithLabelValues("search").(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatalf("Failed to collect the duration: %v", err)
+	}
+	if histogram := metric.GetHistogram(); histogram.GetSampleCount() != 1 || histogram.GetSampleSum() != 2 {
+		t.Errorf("Expected one call of 2s, got %d calls of %vs", histogram.GetSampleCount(), histogram.GetSampleSum())
+	}
+}
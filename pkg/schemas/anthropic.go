@@ -0,0 +1,56 @@
+package schemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/invopop/jsonschema"
+)
+
+// AnthropicSchemaGenerator generates JSON schemas for Anthropic's tool input_schema, which accepts
+// standard JSON Schema: nested structs are kept as $defs references and objects are closed with
+// additionalProperties: false.
+type AnthropicSchemaGenerator struct {
+	reflector *jsonschema.Reflector
+}
+
+// NewAnthropicSchemaGenerator creates a new Anthropic-compatible schema generator
+func NewAnthropicSchemaGenerator() *AnthropicSchemaGenerator {
+	return &AnthropicSchemaGenerator{
+		reflector: &jsonschema.Reflector{
+			// The root must be an object schema, nested types are referenced from $defs
+			ExpandedStruct: true,
+
+			// Use required tags for validation
+			RequiredFromJSONSchemaTags: true,
+
+			// Leave out the $id derived from the Go package path
+			Anonymous: true,
+		},
+	}
+}
+
+// GenerateSchema generates a JSON schema from a Go struct that's compatible with Anthropic tools
+func (g *AnthropicSchemaGenerator) GenerateSchema(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, fmt.Errorf("cannot generate schema from nil value")
+	}
+
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can only generate schemas from structs, got %s", t.Kind())
+	}
+
+	schemaBytes, err := json.Marshal(g.reflector.Reflect(v))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	// Canonical bytes keep the output stable across generations
+	return Canonicalize(schemaBytes)
+}
@@ -0,0 +1,61 @@
+package schemas
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type anthropicAddress struct {
+	City string `json:"city" jsonschema:"required"`
+}
+
+type anthropicPerson struct {
+	Name     string             `json:"name" jsonschema:"required"`
+	Home     anthropicAddress   `json:"home"`
+	Previous []anthropicAddress `json:"previous"`
+}
+
+func TestAnthropicSchemaGenerator(t *testing.T) {
+	schema, err := NewAnthropicSchemaGenerator().GenerateSchema(anthropicPerson{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	var parsed struct {
+		ID                   string         `json:"$id"`
+		Type                 string         `json:"type"`
+		AdditionalProperties *bool          `json:"additionalProperties"`
+		Required             []string       `json:"required"`
+		Properties           map[string]any `json:"properties"`
+		Defs                 map[string]struct {
+			AdditionalProperties *bool `json:"additionalProperties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(schema, &parsed); err != nil {
+		t.Fatalf("Generated schema is not valid JSON: %v", err)
+	}
+
+	if parsed.Type != "object" || parsed.ID != "" {
+		t.Errorf("Expected an anonymous object schema at the root, got type %q and $id %q", parsed.Type, parsed.ID)
+	}
+	if parsed.AdditionalProperties == nil || *parsed.AdditionalProperties {
+		t.Errorf("Expected the root to forbid additional properties, got %s", schema)
+	}
+	if len(parsed.Required) != 1 || parsed.Required[0] != "name" {
+		t.Errorf("Expected name to be required, got %v", parsed.Required)
+	}
+
+	// Nested structs are referenced rather than expanded
+	home, _ := parsed.Properties["home"].(map[string]any)
+	if home["$ref"] != "#/$defs/anthropicAddress" {
+		t.Errorf("Expected home to reference its definition, got %v", home)
+	}
+	address, ok := parsed.Defs["anthropicAddress"]
+	if !ok || address.AdditionalProperties == nil || *address.AdditionalProperties {
+		t.Errorf("Expected a closed address definition, got %s", schema)
+	}
+
+	if _, err := NewAnthropicSchemaGenerator().GenerateSchema("not a struct"); err == nil {
+		t.Error("Expected error for a non-struct value")
+	}
+}
@@ -0,0 +1,64 @@
+package schemas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Canonicalize rewrites a JSON schema into canonical bytes: object keys in lexical order,
+// "required" lists sorted and no insignificant whitespace. Equal schemas produce identical
+// bytes, which makes them usable as cache keys and in golden files.
+func Canonicalize(schema json.RawMessage) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(schema))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	// encoding/json writes map keys sorted, so only the required lists need ordering
+	sortRequired(value, false)
+
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	return json.RawMessage(canonical), nil
+}
+
+// sortRequired sorts the required lists of every schema within value. Within properties the keys
+// are property names rather than keywords, so a property called "required" is left alone.
+func sortRequired(value any, inProperties bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if key == "required" && !inProperties {
+				if names, ok := item.([]any); ok && allStrings(names) {
+					sort.Slice(names, func(i, j int) bool { return names[i].(string) < names[j].(string) })
+					continue
+				}
+			}
+
+			sortRequired(item, !inProperties && (key == "properties" || key == "$defs" || key == "definitions"))
+		}
+
+	case []any:
+		for _, item := range v {
+			sortRequired(item, false)
+		}
+	}
+}
+
+func allStrings(values []any) bool {
+	for _, value := range values {
+		if _, ok := value.(string); !ok {
+			return false
+		}
+	}
+
+	return true
+}
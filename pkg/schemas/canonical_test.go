@@ -0,0 +1,95 @@
+package schemas
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+type canonicalOrder struct {
+	Zeta  string          `json:"zeta" jsonschema:"required"`
+	Alpha int             `json:"alpha" jsonschema:"required"`
+	Mid   []canonicalItem `json:"mid" jsonschema:"required"`
+}
+
+type canonicalItem struct {
+	Name string `json:"name" jsonschema:"required"`
+	ID   int    `json:"id" jsonschema:"required"`
+}
+
+func TestGenerateSchemaIsDeterministic(t *testing.T) {
+	first, err := GenerateSchemaFromStruct[canonicalOrder]()
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	second, err := NewOpenAISchemaGenerator().GenerateSchema(canonicalOrder{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	if !bytes.Equal(first, second) {
+		t.Errorf("Expected byte-identical schemas, got\n%s\n%s", first, second)
+	}
+
+	var schema struct {
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(first, &schema); err != nil {
+		t.Fatalf("Generated schema is not valid JSON: %v", err)
+	}
+
+	expected := []string{"alpha", "mid", "zeta"}
+	for i, name := range expected {
+		if schema.Required[i] != name {
+			t.Errorf("Expected sorted required fields %v, got %v", expected, schema.Required)
+			break
+		}
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		expected string
+	}{
+		{
+			name:     "sorts keys and required",
+			schema:   `{"type": "object", "required": ["b", "a"], "properties": {"b": {"type": "string"}, "a": {"type": "integer"}}}`,
+			expected: `{"properties":{"a":{"type":"integer"},"b":{"type":"string"}},"required":["a","b"],"type":"object"}`,
+		},
+		{
+			name:     "nested required",
+			schema:   `{"items": {"required": ["y", "x"]}}`,
+			expected: `{"items":{"required":["x","y"]}}`,
+		},
+		{
+			name:     "property named required",
+			schema:   `{"properties": {"required": {"enum": ["b", "a"]}}}`,
+			expected: `{"properties":{"required":{"enum":["b","a"]}}}`,
+		},
+		{
+			name:     "keeps number precision",
+			schema:   `{"maximum": 1e400}`,
+			expected: `{"maximum":1e400}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canonical, err := Canonicalize(json.RawMessage(tt.schema))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if string(canonical) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, canonical)
+			}
+		})
+	}
+
+	if _, err := Canonicalize(json.RawMessage(`{"type": `)); err == nil {
+		t.Error("Expected error for invalid schema")
+	}
+}
@@ -0,0 +1,197 @@
+package schemas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SchemaFromExample infers a JSON schema from an example document, for structured output
+// without a Go type. Present keys become required properties, array items are merged into a
+// single items schema, and values of different types are combined with anyOf.
+func SchemaFromExample(example json.RawMessage) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(example))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON example: %w", err)
+	}
+
+	schema := inferSchema(value)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+
+	schemaBytes, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	return Canonicalize(schemaBytes)
+}
+
+func inferSchema(value any) map[string]any {
+	switch v := value.(type) {
+	case nil:
+		return map[string]any{"type": "null"}
+	case bool:
+		return map[string]any{"type": "boolean"}
+	case string:
+		return map[string]any{"type": "string"}
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+			return map[string]any{"type": "integer"}
+		}
+		return map[string]any{"type": "number"}
+
+	case []any:
+		schema := map[string]any{"type": "array"}
+		if len(v) == 0 {
+			return schema
+		}
+
+		items := inferSchema(v[0])
+		for _, item := range v[1:] {
+			items = mergeSchemas(items, inferSchema(item))
+		}
+		schema["items"] = items
+		return schema
+
+	case map[string]any:
+		properties := make(map[string]any, len(v))
+		required := make([]string, 0, len(v))
+		for key, item := range v {
+			properties[key] = inferSchema(item)
+			required = append(required, key)
+		}
+		sort.Strings(required)
+
+		return map[string]any{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	}
+
+	return map[string]any{}
+}
+
+// mergeSchemas combines two inferred schemas into one accepting values of both
+func mergeSchemas(a, b map[string]any) map[string]any {
+	typeA, _ := a["type"].(string)
+	typeB, _ := b["type"].(string)
+
+	switch {
+	case typeA == "" || typeB == "":
+		// At least one side is already an anyOf
+	case typeA == typeB && typeA == "object":
+		return mergeObjectSchemas(a, b)
+	case typeA == typeB && typeA == "array":
+		return mergeArraySchemas(a, b)
+	case typeA == typeB:
+		return a
+	case typeA == "integer" && typeB == "number", typeA == "number" && typeB == "integer":
+		return map[string]any{"type": "number"}
+	}
+
+	variants := append(anyOfVariants(a), anyOfVariants(b)...)
+
+	// Merge variants of the same type so each type appears once
+	var merged []map[string]any
+	for _, variant := range variants {
+		combined := false
+		for i, existing := range merged {
+			if existing["type"] == variant["type"] || (isNumeric(existing) && isNumeric(variant)) {
+				merged[i] = mergeSchemas(existing, variant)
+				combined = true
+				break
+			}
+		}
+		if !combined {
+			merged = append(merged, variant)
+		}
+	}
+
+	if len(merged) == 1 {
+		return merged[0]
+	}
+
+	anyOf := make([]any, len(merged))
+	for i, variant := range merged {
+		anyOf[i] = variant
+	}
+	return map[string]any{"anyOf": anyOf}
+}
+
+func mergeObjectSchemas(a, b map[string]any) map[string]any {
+	propsA, _ := a["properties"].(map[string]any)
+	propsB, _ := b["properties"].(map[string]any)
+
+	properties := make(map[string]any, len(propsA))
+	for key, prop := range propsA {
+		properties[key] = prop
+	}
+	for key, prop := range propsB {
+		if existing, ok := properties[key]; ok {
+			properties[key] = mergeSchemas(existing.(map[string]any), prop.(map[string]any))
+		} else {
+			properties[key] = prop
+		}
+	}
+
+	// Only keys present in every example stay required
+	requiredB := make(map[string]bool)
+	for _, key := range requiredKeys(b) {
+		requiredB[key] = true
+	}
+	required := []string{}
+	for _, key := range requiredKeys(a) {
+		if requiredB[key] {
+			required = append(required, key)
+		}
+	}
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+func mergeArraySchemas(a, b map[string]any) map[string]any {
+	itemsA, okA := a["items"].(map[string]any)
+	itemsB, okB := b["items"].(map[string]any)
+
+	switch {
+	case okA && okB:
+		return map[string]any{"type": "array", "items": mergeSchemas(itemsA, itemsB)}
+	case okB:
+		return b
+	}
+
+	return a
+}
+
+func anyOfVariants(schema map[string]any) []map[string]any {
+	anyOf, ok := schema["anyOf"].([]any)
+	if !ok {
+		return []map[string]any{schema}
+	}
+
+	variants := make([]map[string]any, len(anyOf))
+	for i, variant := range anyOf {
+		variants[i] = variant.(map[string]any)
+	}
+	return variants
+}
+
+func requiredKeys(schema map[string]any) []string {
+	required, _ := schema["required"].([]string)
+	return required
+}
+
+func isNumeric(schema map[string]any) bool {
+	schemaType, _ := schema["type"].(string)
+	return schemaType == "integer" || schemaType == "number"
+}
@@ -0,0 +1,81 @@
+package schemas
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaFromExample(t *testing.T) {
+	tests := []struct {
+		name     string
+		example  string
+		expected string
+	}{
+		{
+			name:     "object",
+			example:  `{"name": "Ada", "age": 36, "score": 9.5, "active": true, "manager": null}`,
+			expected: `{"type":"object","properties":{"name":{"type":"string"},"age":{"type":"integer"},"score":{"type":"number"},"active":{"type":"boolean"},"manager":{"type":"null"}},"required":["active","age","manager","name","score"]}`,
+		},
+		{
+			name:     "nested object",
+			example:  `{"address": {"city": "London"}}`,
+			expected: `{"type":"object","properties":{"address":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}},"required":["address"]}`,
+		},
+		{
+			name:     "array of scalars",
+			example:  `{"tags": ["a", "b"]}`,
+			expected: `{"type":"object","properties":{"tags":{"type":"array","items":{"type":"string"}}},"required":["tags"]}`,
+		},
+		{
+			name:     "array of objects keeps common keys required",
+			example:  `[{"id": 1, "label": "x"}, {"id": 2.5}]`,
+			expected: `{"type":"array","items":{"type":"object","properties":{"id":{"type":"number"},"label":{"type":"string"}},"required":["id"]}}`,
+		},
+		{
+			name:     "empty array",
+			example:  `{"items": []}`,
+			expected: `{"type":"object","properties":{"items":{"type":"array"}},"required":["items"]}`,
+		},
+		{
+			name:     "mixed types",
+			example:  `{"values": [1, "two", 3, {"four": 4}]}`,
+			expected: `{"type":"object","properties":{"values":{"type":"array","items":{"anyOf":[{"type":"integer"},{"type":"string"},{"type":"object","properties":{"four":{"type":"integer"}},"required":["four"]}]}}},"required":["values"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := SchemaFromExample(json.RawMessage(tt.example))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(schema, &got); err != nil {
+				t.Fatalf("Generated schema is not valid JSON: %v", err)
+			}
+
+			if got["$schema"] != "https://json-schema.org/draft/2020-12/schema" {
+				t.Errorf("Expected draft 2020-12 schema, got %v", got["$schema"])
+			}
+			delete(got, "$schema")
+
+			var expected map[string]any
+			if err := json.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatalf("Invalid expected schema: %v", err)
+			}
+
+			gotJSON, _ := json.Marshal(got)
+			expectedJSON, _ := json.Marshal(expected)
+			if string(gotJSON) != string(expectedJSON) {
+				t.Errorf("Expected %s, got %s", expectedJSON, gotJSON)
+			}
+		})
+	}
+}
+
+func TestSchemaFromExampleInvalidJSON(t *testing.T) {
+	if _, err := SchemaFromExample(json.RawMessage(`{"name": `)); err == nil {
+		t.Error("Expected error for invalid example")
+	}
+}
@@ -0,0 +1,13 @@
+package schemas
+
+import "encoding/json"
+
+// SchemaGenerator generates the JSON schema of a Go struct in the dialect a provider expects
+type SchemaGenerator interface {
+	GenerateSchema(v interface{}) (json.RawMessage, error)
+}
+
+var (
+	_ SchemaGenerator = (*OpenAISchemaGenerator)(nil)
+	_ SchemaGenerator = (*AnthropicSchemaGenerator)(nil)
+)
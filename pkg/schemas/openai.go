@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/invopop/jsonschema"
 )
@@ -11,10 +12,31 @@ import (
 // OpenAISchemaGenerator generates JSON schemas compatible with OpenAI's tool system
 type OpenAISchemaGenerator struct {
 	reflector *jsonschema.Reflector
+	enums     map[string][]any
+}
+
+// OpenAISchemaGeneratorOpts represents options for configuring the schema generator
+type OpenAISchemaGeneratorOpts = func(*OpenAISchemaGenerator)
+
+// WithEnum restricts a field to the given values. The field is the dot-separated path of JSON
+// property names from the root, e.g. "flight.class"; for an array field the values apply to its items.
+// Fields can also declare their values with the jsonschema tag, e.g. `jsonschema:"enum=economy,enum=business"`.
+func WithEnum(field string, values ...any) OpenAISchemaGeneratorOpts {
+	return func(g *OpenAISchemaGenerator) {
+		g.enums[field] = values
+	}
+}
+
+// WithReflector adjusts the underlying jsonschema reflector, e.g. to keep $defs references or to derive
+// required fields from the json tags. Settings OpenAI doesn't support make the schema incompatible with strict mode.
+func WithReflector(configure func(reflector *jsonschema.Reflector)) OpenAISchemaGeneratorOpts {
+	return func(g *OpenAISchemaGenerator) {
+		configure(g.reflector)
+	}
 }
 
 // NewOpenAISchemaGenerator creates a new OpenAI-compatible schema generator
-func NewOpenAISchemaGenerator() *OpenAISchemaGenerator {
+func NewOpenAISchemaGenerator(opts ...OpenAISchemaGeneratorOpts) *OpenAISchemaGenerator {
 	reflector := &jsonschema.Reflector{
 		// OpenAI supports JSON Schema Draft 2020-12
 		// Note: We can't set SchemaID directly, but the library uses 2020-12 by default
@@ -32,9 +54,16 @@ func NewOpenAISchemaGenerator() *OpenAISchemaGenerator {
 		AllowAdditionalProperties: true,
 	}
 
-	return &OpenAISchemaGenerator{
+	generator := &OpenAISchemaGenerator{
 		reflector: reflector,
+		enums:     make(map[string][]any),
 	}
+
+	for _, opt := range opts {
+		opt(generator)
+	}
+
+	return generator
 }
 
 // GenerateSchema generates a JSON schema from a Go struct that's compatible with OpenAI tools
@@ -57,6 +86,10 @@ func (g *OpenAISchemaGenerator) GenerateSchema(v interface{}) (json.RawMessage,
 	// Generate the schema
 	schema := g.reflector.Reflect(v)
 
+	if err := g.applyEnums(schema); err != nil {
+		return nil, err
+	}
+
 	// Post-process the schema to ensure OpenAI compatibility
 	g.postProcessSchema(schema)
 
@@ -66,7 +99,8 @@ func (g *OpenAISchemaGenerator) GenerateSchema(v interface{}) (json.RawMessage,
 		return nil, fmt.Errorf("failed to marshal schema: %w", err)
 	}
 
-	return json.RawMessage(schemaBytes), nil
+	// Canonical bytes keep the output stable across generations
+	return Canonicalize(schemaBytes)
 }
 
 func (g *OpenAISchemaGenerator) MustGenerateSchema(v interface{}) json.RawMessage {
@@ -78,6 +112,34 @@ func (g *OpenAISchemaGenerator) MustGenerateSchema(v interface{}) json.RawMessag
 	return schema
 }
 
+// applyEnums sets the registered enum values on the fields they were registered for
+func (g *OpenAISchemaGenerator) applyEnums(schema *jsonschema.Schema) error {
+	for field, values := range g.enums {
+		target := schema
+		for _, name := range strings.Split(field, ".") {
+			if target.Items != nil {
+				target = target.Items
+			}
+
+			var property *jsonschema.Schema
+			if target.Properties != nil {
+				property, _ = target.Properties.Get(name)
+			}
+			if property == nil {
+				return fmt.Errorf("enum field %s not found in schema", field)
+			}
+			target = property
+		}
+
+		if target.Items != nil {
+			target = target.Items
+		}
+		target.Enum = values
+	}
+
+	return nil
+}
+
 // postProcessSchema ensures the schema is compatible with OpenAI's tool system
 func (g *OpenAISchemaGenerator) postProcessSchema(schema *jsonschema.Schema) {
 	if schema == nil {
@@ -92,8 +154,17 @@ func (g *OpenAISchemaGenerator) postProcessSchema(schema *jsonschema.Schema) {
 
 	// Recursively process nested schemas
 	if schema.Properties != nil {
-		// Properties is an OrderedMap, so we need to iterate differently
-		// For now, we'll skip this as the main compatibility issues are at the top level
+		for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			g.postProcessSchema(pair.Value)
+		}
+	}
+	if schema.PatternProperties != nil {
+		for _, s := range schema.PatternProperties {
+			g.postProcessSchema(s)
+		}
+	}
+	for _, s := range schema.Definitions {
+		g.postProcessSchema(s)
 	}
 
 	// Process array items
@@ -0,0 +1,295 @@
+package schemas
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+)
+
+// TestPerson represents a person with structured data
+type TestPerson struct {
+	Name     string `json:"name" jsonschema:"required"`
+	Age      int    `json:"age" jsonschema:"required"`
+	Email    string `json:"email" jsonschema:"required"`
+	Location string `json:"location" jsonschema:"required"`
+}
+
+// TestTask represents a task with structured data
+type TestTask struct {
+	Title       string   `json:"title" jsonschema:"required"`
+	Description string   `json:"description" jsonschema:"required"`
+	Priority    string   `json:"priority" jsonschema:"required"`
+	Tags        []string `json:"tags" jsonschema:"required"`
+	DueDate     string   `json:"due_date" jsonschema:"required"`
+}
+
+func TestNewOpenAISchemaGenerator(t *testing.T) {
+	generator := NewOpenAISchemaGenerator()
+	if generator == nil {
+		t.Fatal("Expected generator to be created")
+	}
+	if generator.reflector == nil {
+		t.Fatal("Expected reflector to be initialized")
+	}
+}
+
+func TestWithReflector(t *testing.T) {
+	type contact struct {
+		Name  string `json:"name"`
+		Email string `json:"email,omitempty"`
+	}
+
+	// Without required tags, fields are required unless their json tag has omitempty
+	generator := NewOpenAISchemaGenerator(WithReflector(func(r *jsonschema.Reflector) {
+		r.RequiredFromJSONSchemaTags = false
+	}))
+
+	schema, err := generator.GenerateSchema(contact{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	var schemaMap map[string]any
+	if err := json.Unmarshal(schema, &schemaMap); err != nil {
+		t.Fatalf("Generated schema is not valid JSON: %v", err)
+	}
+
+	if required, _ := schemaMap["required"].([]any); !reflect.DeepEqual(required, []any{"name"}) {
+		t.Errorf("Expected only name to be required, got %v", schemaMap["required"])
+	}
+}
+
+func TestGenerateSchema(t *testing.T) {
+	generator := NewOpenAISchemaGenerator()
+
+	// Test Person schema
+	person := TestPerson{}
+	schema, err := generator.GenerateSchema(person)
+	if err != nil {
+		t.Fatalf("Failed to generate person schema: %v", err)
+	}
+
+	// Verify it's valid JSON
+	var schemaMap map[string]interface{}
+	if err := json.Unmarshal(schema, &schemaMap); err != nil {
+		t.Fatalf("Generated schema is not valid JSON: %v", err)
+	}
+
+	// Check for required fields
+	if schemaMap["type"] != "object" {
+		t.Errorf("Expected type to be 'object', got %v", schemaMap["type"])
+	}
+
+	// Check that required fields are present
+	required, ok := schemaMap["required"].([]interface{})
+	if !ok {
+		t.Fatal("Expected 'required' field to be present")
+	}
+	if len(required) != 4 {
+		t.Errorf("Expected 4 required fields, got %d", len(required))
+	}
+
+	// Test Task schema
+	task := TestTask{}
+	schema, err = generator.GenerateSchema(task)
+	if err != nil {
+		t.Fatalf("Failed to generate task schema: %v", err)
+	}
+
+	// Verify it's valid JSON
+	if err := json.Unmarshal(schema, &schemaMap); err != nil {
+		t.Fatalf("Generated schema is not valid JSON: %v", err)
+	}
+
+	// Check for required fields
+	if schemaMap["type"] != "object" {
+		t.Errorf("Expected type to be 'object', got %v", schemaMap["type"])
+	}
+
+	// Check that required fields are present
+	required, ok = schemaMap["required"].([]interface{})
+	if !ok {
+		t.Fatal("Expected 'required' field to be present")
+	}
+	if len(required) != 5 {
+		t.Errorf("Expected 5 required fields, got %d", len(required))
+	}
+}
+
+func TestGenerateSchemaFromStruct(t *testing.T) {
+	// Test the generic function
+	schema, err := GenerateSchemaFromStruct[TestPerson]()
+	if err != nil {
+		t.Fatalf("Failed to generate schema from struct type: %v", err)
+	}
+
+	// Verify it's valid JSON
+	var schemaMap map[string]interface{}
+	if err := json.Unmarshal(schema, &schemaMap); err != nil {
+		t.Fatalf("Generated schema is not valid JSON: %v", err)
+	}
+
+	if schemaMap["type"] != "object" {
+		t.Errorf("Expected type to be 'object', got %v", schemaMap["type"])
+	}
+}
+
+func TestValidateOpenAICompatibility(t *testing.T) {
+	generator := NewOpenAISchemaGenerator()
+
+	// Test with a valid schema
+	person := TestPerson{}
+	schema, err := generator.GenerateSchema(person)
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	// Validate compatibility
+	if err := generator.ValidateOpenAICompatibility(schema); err != nil {
+		t.Errorf("Expected schema to be OpenAI compatible: %v", err)
+	}
+}
+
+func TestGenerateSchemaFromType(t *testing.T) {
+	generator := NewOpenAISchemaGenerator()
+
+	// Test with a type
+	schema, err := generator.GenerateSchemaFromType(reflect.TypeOf(TestPerson{}))
+	if err != nil {
+		t.Fatalf("Failed to generate schema from type: %v", err)
+	}
+
+	// Verify it's valid JSON
+	var schemaMap map[string]interface{}
+	if err := json.Unmarshal(schema, &schemaMap); err != nil {
+		t.Fatalf("Generated schema is not valid JSON: %v", err)
+	}
+
+	if schemaMap["type"] != "object" {
+		t.Errorf("Expected type to be 'object', got %v", schemaMap["type"])
+	}
+}
+
+type testAirport struct {
+	Code string `json:"code" jsonschema:"required"`
+}
+
+type testFlight struct {
+	Number string        `json:"number" jsonschema:"required"`
+	From   testAirport   `json:"from" jsonschema:"required"`
+	Stops  []testAirport `json:"stops"`
+}
+
+type testHotel struct {
+	Name      string            `json:"name" jsonschema:"required"`
+	Amenities map[string]string `json:"amenities"`
+}
+
+type testItinerary struct {
+	Flight testFlight `json:"flight" jsonschema:"required"`
+	Hotel  testHotel  `json:"hotel" jsonschema:"required"`
+}
+
+func TestGenerateSchemaStripsNestedAdditionalProperties(t *testing.T) {
+	schema, err := NewOpenAISchemaGenerator().GenerateSchema(testItinerary{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(schema, &doc); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	// Make sure the nested objects are actually there
+	flight := doc["properties"].(map[string]any)["flight"].(map[string]any)
+	if _, ok := flight["properties"].(map[string]any)["from"].(map[string]any)["properties"]; !ok {
+		t.Fatalf("Expected the nested airport object in the schema, got %s", schema)
+	}
+
+	var walk func(node any, path string)
+	walk = func(node any, path string) {
+		switch n := node.(type) {
+		case map[string]any:
+			if _, ok := n["additionalProperties"]; ok {
+				t.Errorf("Expected no additionalProperties, found one at %s", path)
+			}
+			for key, value := range n {
+				walk(value, path+"/"+key)
+			}
+		case []any:
+			for _, value := range n {
+				walk(value, path+"[]")
+			}
+		}
+	}
+	walk(doc, "")
+}
+
+type testBooking struct {
+	Class   string     `json:"class" jsonschema:"required"`
+	Flight  testFlight `json:"flight" jsonschema:"required"`
+	Meals   []string   `json:"meals"`
+	Seating string     `json:"seating" jsonschema:"enum=aisle,enum=window"`
+}
+
+func TestGenerateSchemaWithEnum(t *testing.T) {
+	generator := NewOpenAISchemaGenerator(
+		WithEnum("class", "economy", "business", "first"),
+		WithEnum("flight.from.code", "PRG", "LHR"),
+		WithEnum("meals", "vegan", "standard"),
+	)
+
+	schema, err := generator.GenerateSchema(testBooking{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	var doc struct {
+		Properties struct {
+			Class  map[string]any `json:"class"`
+			Flight struct {
+				Properties struct {
+					From struct {
+						Properties map[string]map[string]any `json:"properties"`
+					} `json:"from"`
+				} `json:"properties"`
+			} `json:"flight"`
+			Meals struct {
+				Items map[string]any `json:"items"`
+			} `json:"meals"`
+			Seating map[string]any `json:"seating"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(schema, &doc); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		enum     any
+		expected []any
+	}{
+		{"top-level field", doc.Properties.Class["enum"], []any{"economy", "business", "first"}},
+		{"nested field", doc.Properties.Flight.Properties.From.Properties["code"]["enum"], []any{"PRG", "LHR"}},
+		{"array items", doc.Properties.Meals.Items["enum"], []any{"vegan", "standard"}},
+		{"tag", doc.Properties.Seating["enum"], []any{"aisle", "window"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.enum, tt.expected) {
+				t.Errorf("Expected enum %v, got %v", tt.expected, tt.enum)
+			}
+		})
+	}
+}
+
+func TestGenerateSchemaWithEnumUnknownField(t *testing.T) {
+	_, err := NewOpenAISchemaGenerator(WithEnum("flight.gate", "A1")).GenerateSchema(testBooking{})
+	if err == nil || err.Error() != "enum field flight.gate not found in schema" {
+		t.Errorf("Expected an unknown field error, got %v", err)
+	}
+}
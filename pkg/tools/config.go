@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// Config declares HTTP-backed tools, loaded with LoadToolsFromConfig
+type Config struct {
+	Tools []ToolConfig `yaml:"tools" json:"tools"`
+}
+
+// ToolConfig declares a single HTTP-backed tool. Header values may reference environment
+// variables as ${NAME}, so secrets stay out of the config file.
+type ToolConfig struct {
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description" json:"description"`
+	Method      string            `yaml:"method" json:"method"`
+	URL         string            `yaml:"url" json:"url"`
+	Headers     map[string]string `yaml:"headers" json:"headers"`
+	Timeout     string            `yaml:"timeout" json:"timeout"`
+	InputSchema map[string]any    `yaml:"input_schema" json:"input_schema"`
+}
+
+// toolNamePattern matches the tool names accepted by providers
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+var allowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// LoadToolsFromConfig builds HTTP tools from a YAML or JSON config of the form
+//
+//	tools:
+//	  - name: get_weather
+//	    description: Gets the weather for a city
+//	    method: GET
+//	    url: https://weather.example.com/v1/current
+//	    headers:
+//	      Authorization: Bearer ${WEATHER_API_KEY}
+//	    timeout: 5s
+//	    input_schema:
+//	      type: object
+//	      properties:
+//	        city: {type: string}
+//	      required: [city]
+//
+// The tools are returned as a toolbox, so their names are unique. Every malformed entry is
+// reported in the returned error.
+func LoadToolsFromConfig(r io.Reader) (*llm.Toolbox, error) {
+	var config Config
+
+	// JSON is a subset of YAML, so a single decoder handles both formats
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid tool config: %w", err)
+	}
+
+	var errs []error
+	var tools []llm.Tool
+
+	for i, toolConfig := range config.Tools {
+		tool, err := toolConfig.build()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tool %d (%s): %w", i, toolConfig.Name, err))
+			continue
+		}
+
+		tools = append(tools, tool)
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid tool config: %w", errors.Join(errs...))
+	}
+
+	toolbox, err := llm.NewToolbox(tools...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tool config: %w", err)
+	}
+
+	return toolbox, nil
+}
+
+// build validates the declaration and creates the tool
+func (c ToolConfig) build() (*HTTPTool, error) {
+	if !toolNamePattern.MatchString(c.Name) {
+		return nil, errors.New("name must be 1-64 letters, digits, underscores or dashes")
+	}
+
+	if c.Description == "" {
+		return nil, errors.New("description is required")
+	}
+
+	endpoint, err := url.Parse(c.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("url must be an absolute http(s) URL, got %q", c.URL)
+	}
+
+	opts := []HTTPToolOpts{}
+
+	if c.Method != "" {
+		if !slices.Contains(allowedMethods, c.Method) {
+			return nil, fmt.Errorf("unsupported method %q", c.Method)
+		}
+		opts = append(opts, WithMethod(c.Method))
+	}
+
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", c.Timeout)
+		}
+		opts = append(opts, WithTimeout(timeout))
+	}
+
+	for name, value := range c.Headers {
+		opts = append(opts, WithHeader(name, os.ExpandEnv(value)))
+	}
+
+	schema := c.InputSchema
+	if schema == nil {
+		schema = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	if schema["type"] != "object" {
+		return nil, errors.New("input_schema must be of type object")
+	}
+
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input_schema: %w", err)
+	}
+
+	return NewHTTPTool(c.Name, c.Description, schemaJSON, c.URL, opts...), nil
+}
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const sampleConfig = `
+tools:
+  - name: get_weather
+    description: Gets the current weather for a city
+    method: GET
+    url: %s/weather
+    headers:
+      Authorization: Bearer ${FRAX_TEST_WEATHER_KEY}
+    timeout: 5s
+    input_schema:
+      type: object
+      properties:
+        city: {type: string}
+      required: [city]
+  - name: create_ticket
+    description: Opens a support ticket
+    url: %s/tickets
+`
+
+func TestLoadToolsFromConfig(t *testing.T) {
+	t.Setenv("FRAX_TEST_WEATHER_KEY", "k123")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k123" {
+			t.Errorf("Expected header expanded from the environment, got %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	config := strings.ReplaceAll(sampleConfig, "%s", server.URL)
+	toolbox, err := LoadToolsFromConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if names := toolbox.Names(); len(names) != 2 || names[0] != "get_weather" || names[1] != "create_ticket" {
+		t.Fatalf("Expected the tools in config order, got %v", names)
+	}
+
+	weather, _ := toolbox.Get("get_weather")
+	if weather.Name() != "get_weather" || weather.Description() != "Gets the current weather for a city" {
+		t.Errorf("Unexpected tool %s: %s", weather.Name(), weather.Description())
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(weather.InputSchemaRaw(), &schema); err != nil {
+		t.Fatalf("Invalid input schema: %v", err)
+	}
+	if _, ok := schema["properties"].(map[string]any)["city"]; !ok {
+		t.Errorf("Expected city property in schema, got %v", schema)
+	}
+
+	result, err := weather.Run(context.Background(), json.RawMessage(`{"city":"Paris"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(result) != `{"path":"/weather"}` {
+		t.Errorf("Expected the configured endpoint to be called, got %s", result)
+	}
+}
+
+func TestLoadToolsFromJSONConfig(t *testing.T) {
+	config := `{"tools": [{"name": "ping", "description": "Pings", "url": "https://example.com/ping"}]}`
+
+	toolbox, err := LoadToolsFromConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if names := toolbox.Names(); len(names) != 1 || names[0] != "ping" {
+		t.Errorf("Expected the ping tool, got %v", names)
+	}
+}
+
+func TestLoadToolsFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name:     "malformed YAML",
+			config:   "tools: [",
+			expected: []string{"invalid tool config"},
+		},
+		{
+			name:     "unknown field",
+			config:   "tools:\n  - name: a\n    endpoint: https://example.com\n",
+			expected: []string{"field endpoint not found"},
+		},
+		{
+			name: "invalid entries",
+			config: `
+tools:
+  - name: "bad name!"
+    description: d
+    url: https://example.com
+  - name: no_url
+    description: d
+  - name: bad_method
+    description: d
+    url: https://example.com
+    method: TRACE
+  - name: bad_schema
+    description: d
+    url: https://example.com
+    input_schema: {type: string}
+`,
+			expected: []string{
+				"tool 0 (bad name!): name must be",
+				"tool 1 (no_url): url must be an absolute http(s) URL",
+				`tool 2 (bad_method): unsupported method "TRACE"`,
+				"tool 3 (bad_schema): input_schema must be of type object",
+			},
+		},
+		{
+			name: "duplicate names",
+			config: `
+tools:
+  - {name: a, description: d, url: "https://example.com/1"}
+  - {name: a, description: d, url: "https://example.com/2"}
+`,
+			expected: []string{"invalid tool config: duplicate tool name a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadToolsFromConfig(strings.NewReader(tt.config))
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("Expected error to contain %q, got %v", expected, err)
+				}
+			}
+		})
+	}
+}
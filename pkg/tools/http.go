@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+// maxErrorBody bounds how much of an error response is included in the tool error
+const maxErrorBody = 512
+
+// HTTPTool is a tool backed by an HTTP endpoint. The arguments are sent as the JSON body,
+// or as query parameters for GET requests, and the response body is the tool result.
+type HTTPTool struct {
+	name        string
+	description string
+	inputSchema json.RawMessage
+
+	method  string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// HTTPToolOpts represents options for configuring an HTTP tool
+type HTTPToolOpts = func(*HTTPTool)
+
+// WithMethod sets the HTTP method, POST by default
+func WithMethod(method string) HTTPToolOpts {
+	return func(t *HTTPTool) {
+		t.method = method
+	}
+}
+
+// WithHeader sets a header sent with every request, e.g. an API key
+func WithHeader(name, value string) HTTPToolOpts {
+	return func(t *HTTPTool) {
+		t.headers[name] = value
+	}
+}
+
+// WithHTTPClient sets the client used to call the endpoint
+func WithHTTPClient(client *http.Client) HTTPToolOpts {
+	return func(t *HTTPTool) {
+		t.client = client
+	}
+}
+
+// WithTimeout bounds each call of the endpoint
+func WithTimeout(timeout time.Duration) HTTPToolOpts {
+	return func(t *HTTPTool) {
+		client := *t.client
+		client.Timeout = timeout
+		t.client = &client
+	}
+}
+
+// NewHTTPTool creates a tool calling the endpoint at url with arguments matching inputSchema
+func NewHTTPTool(name, description string, inputSchema json.RawMessage, url string, opts ...HTTPToolOpts) *HTTPTool {
+	t := &HTTPTool{
+		name:        name,
+		description: description,
+		inputSchema: inputSchema,
+		method:      http.MethodPost,
+		url:         url,
+		headers:     make(map[string]string),
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Name returns the name of the tool
+func (t *HTTPTool) Name() string {
+	return t.name
+}
+
+// Description returns the description of the tool
+func (t *HTTPTool) Description() string {
+	return t.description
+}
+
+// InputSchemaRaw returns the JSON schema for the tool's input
+func (t *HTTPTool) InputSchemaRaw() json.RawMessage {
+	return t.inputSchema
+}
+
+// Run calls the endpoint with the arguments. Server errors and rate limiting are reported as
+// retryable errors, other unsuccessful responses as permanent ones.
+func (t *HTTPTool) Run(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	req, err := t.newRequest(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, llm.NewRetryableError(fmt.Errorf("request to %s failed: %w", t.name, err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, llm.NewRetryableError(fmt.Errorf("failed to read response of %s: %w", t.name, err))
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		statusErr := fmt.Errorf("%s returned %s: %s", t.name, resp.Status, truncate(body, maxErrorBody))
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, llm.NewRetryableError(statusErr)
+		}
+		return nil, llm.NewPermanentError(statusErr)
+	}
+
+	if json.Valid(body) {
+		return json.RawMessage(body), nil
+	}
+
+	// Plain text responses are passed on as a JSON string
+	return json.Marshal(string(body))
+}
+
+// newRequest builds the HTTP request carrying the arguments
+func (t *HTTPTool) newRequest(ctx context.Context, args json.RawMessage) (*http.Request, error) {
+	var body io.Reader
+	target := t.url
+
+	if t.method == http.MethodGet {
+		query, err := argsToQuery(args)
+		if err != nil {
+			return nil, err
+		}
+
+		// Merge into the query of the URL, whose parameters are fixed and not overridden by arguments
+		u, err := url.Parse(t.url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build request for %s: %w", t.name, err)
+		}
+
+		values := u.Query()
+		for name, vals := range query {
+			if !values.Has(name) {
+				values[name] = vals
+			}
+		}
+		u.RawQuery = values.Encode()
+		target = u.String()
+	} else {
+		body = bytes.NewReader(args)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, t.method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request for %s: %w", t.name, err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+
+	return req, nil
+}
+
+// argsToQuery flattens the top-level arguments into query parameters
+func argsToQuery(args json.RawMessage) (url.Values, error) {
+	var fields map[string]any
+	if len(bytes.TrimSpace(args)) > 0 {
+		if err := json.Unmarshal(args, &fields); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	query := url.Values{}
+	for key, value := range fields {
+		switch v := value.(type) {
+		case string:
+			query.Set(key, v)
+		case nil:
+		default:
+			encoded, _ := json.Marshal(v)
+			query.Set(key, string(encoded))
+		}
+	}
+
+	return query, nil
+}
+
+func truncate(body []byte, limit int) string {
+	if len(body) <= limit {
+		return string(body)
+	}
+
+	return string(body[:limit]) + "..."
+}
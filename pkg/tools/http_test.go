@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/petrjanda/frax/pkg/llm"
+)
+
+func TestHTTPToolPost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != `{"city":"Paris"}` {
+			t.Errorf("Unexpected request %s %s", r.Method, body)
+		}
+		if r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("Expected API key header, got %q", r.Header.Get("X-Api-Key"))
+		}
+		w.Write([]byte(`{"temperature": 21}`))
+	}))
+	defer server.Close()
+
+	tool := NewHTTPTool("get_weather", "Gets the weather", json.RawMessage(`{"type":"object"}`), server.URL,
+		WithHeader("X-Api-Key", "secret"))
+
+	result, err := tool.Run(context.Background(), json.RawMessage(`{"city":"Paris"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(result) != `{"temperature": 21}` {
+		t.Errorf("Expected response body as result, got %s", result)
+	}
+}
+
+func TestHTTPToolGetSendsQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("city") != "Paris" || r.URL.Query().Get("days") != "3" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		w.Write([]byte("sunny"))
+	}))
+	defer server.Close()
+
+	tool := NewHTTPTool("forecast", "Gets the forecast", nil, server.URL, WithMethod(http.MethodGet))
+
+	result, err := tool.Run(context.Background(), json.RawMessage(`{"city":"Paris","days":3}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(result) != `"sunny"` {
+		t.Errorf("Expected plain text as JSON string, got %s", result)
+	}
+}
+
+func TestHTTPToolGetMergesQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/forecast" || query.Get("units") != "metric" || query.Get("city") != "Paris" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte("sunny"))
+	}))
+	defer server.Close()
+
+	tool := NewHTTPTool("forecast", "Gets the forecast", nil, server.URL+"/forecast?units=metric", WithMethod(http.MethodGet))
+
+	// Arguments don't override the parameters of the URL
+	if _, err := tool.Run(context.Background(), json.RawMessage(`{"city":"Paris","units":"imperial"}`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestHTTPToolErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		retryable bool
+	}{
+		{"server error", http.StatusBadGateway, true},
+		{"rate limited", http.StatusTooManyRequests, true},
+		{"not found", http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte("oops"))
+			}))
+			defer server.Close()
+
+			tool := NewHTTPTool("lookup", "Looks up", nil, server.URL)
+
+			_, err := tool.Run(context.Background(), json.RawMessage(`{}`))
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+
+			if llm.IsRetryable(err) != tt.retryable {
+				t.Errorf("Expected retryable %v, got %v", tt.retryable, err)
+			}
+
+			var permanent *llm.PermanentError
+			if !tt.retryable && !errors.As(err, &permanent) {
+				t.Errorf("Expected a permanent error, got %v", err)
+			}
+		})
+	}
+}